@@ -20,10 +20,15 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/TesraSupernet/Tesra/cmd/utils"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/p2pserver/link"
+	"github.com/TesraSupernet/Tesra/p2pserver/nat"
 	"github.com/TesraSupernet/Tesra/smartcontract/service/native/governance"
 	"github.com/urfave/cli"
 )
@@ -36,7 +41,10 @@ func SetTesranodeConfig(ctx *cli.Context) (*config.TesranodeConfig, error) {
 	}
 	setCommonConfig(ctx, cfg.Common)
 	setConsensusConfig(ctx, cfg.Consensus)
-	setP2PNodeConfig(ctx, cfg.P2PNode)
+	err = setP2PNodeConfig(ctx, cfg.P2PNode)
+	if err != nil {
+		return nil, fmt.Errorf("setP2PNodeConfig error:%s", err)
+	}
 	setRpcConfig(ctx, cfg.Rpc)
 	setRestfulConfig(ctx, cfg.Restful)
 	setWebSocketConfig(ctx, cfg.Ws)
@@ -100,25 +108,58 @@ func setGenesis(ctx *cli.Context, cfg *config.TesranodeConfig) error {
 	log.Infof("Load genesis config:%s", genesisFile)
 
 	switch cfg.Genesis.ConsensusType {
+	case config.CONSENSUS_TYPE_DBFT, config.CONSENSUS_TYPE_VBFT:
+	default:
+		if len(cfg.Genesis.ConsensusForks) == 0 {
+			return fmt.Errorf("Unknow consensus:%s", cfg.Genesis.ConsensusType)
+		}
+	}
+	err = checkConsensusConfig(cfg.Genesis, cfg.Genesis.ConsensusType)
+	if err != nil {
+		return err
+	}
+	var lastHeight uint32
+	for _, fork := range cfg.Genesis.ConsensusForks {
+		if fork.Height <= lastHeight {
+			return fmt.Errorf("consensus fork height %d should be larger than %d", fork.Height, lastHeight)
+		}
+		lastHeight = fork.Height
+		err = checkConsensusConfig(cfg.Genesis, fork.ConsensusType)
+		if err != nil {
+			return fmt.Errorf("consensus fork at height %d: %s", fork.Height, err)
+		}
+	}
+
+	return nil
+}
+
+func checkConsensusConfig(genesis *config.GenesisConfig, consensusType string) error {
+	switch consensusType {
 	case config.CONSENSUS_TYPE_DBFT:
-		if len(cfg.Genesis.DBFT.Bookkeepers) < config.DBFT_MIN_NODE_NUM {
+		if len(genesis.DBFT.Bookkeepers) < config.DBFT_MIN_NODE_NUM {
 			return fmt.Errorf("DBFT consensus at least need %d bookkeepers in config", config.DBFT_MIN_NODE_NUM)
 		}
-		if cfg.Genesis.DBFT.GenBlockTime <= 0 {
-			cfg.Genesis.DBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
+		if genesis.DBFT.GenBlockTime <= 0 {
+			genesis.DBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
 		}
 	case config.CONSENSUS_TYPE_VBFT:
-		err = governance.CheckVBFTConfig(cfg.Genesis.VBFT)
+		err := governance.CheckVBFTConfig(genesis.VBFT)
 		if err != nil {
 			return fmt.Errorf("VBFT config error %v", err)
 		}
-		if len(cfg.Genesis.VBFT.Peers) < config.VBFT_MIN_NODE_NUM {
+		if len(genesis.VBFT.Peers) < config.VBFT_MIN_NODE_NUM {
 			return fmt.Errorf("VBFT consensus at least need %d peers in config", config.VBFT_MIN_NODE_NUM)
 		}
+	case config.CONSENSUS_TYPE_SOLO:
+		if len(genesis.SOLO.Bookkeepers) < config.SOLO_MIN_NODE_NUM {
+			return fmt.Errorf("SOLO consensus at least need %d bookkeepers in config", config.SOLO_MIN_NODE_NUM)
+		}
+		if genesis.SOLO.GenBlockTime <= 1 {
+			genesis.SOLO.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
+		}
 	default:
-		return fmt.Errorf("Unknow consensus:%s", cfg.Genesis.ConsensusType)
+		return fmt.Errorf("Unknow consensus:%s", consensusType)
 	}
-
 	return nil
 }
 
@@ -128,43 +169,101 @@ func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.GasLimit = ctx.Uint64(utils.GetFlagName(utils.GasLimitFlag))
 	cfg.GasPrice = ctx.Uint64(utils.GetFlagName(utils.GasPriceFlag))
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
+	cfg.PruneRetention = uint32(ctx.Uint(utils.GetFlagName(utils.PruneRetentionFlag)))
+	if cfg.PruneRetention != 0 && cfg.PruneRetention < config.MIN_PRUNE_RETENTION {
+		log.Warnf("prune retention %d too small, use %d", cfg.PruneRetention, config.MIN_PRUNE_RETENTION)
+		cfg.PruneRetention = config.MIN_PRUNE_RETENTION
+	}
+	cfg.DBBackend = ctx.String(utils.GetFlagName(utils.DBBackendFlag))
+	cfg.DBCacheSize = ctx.Uint(utils.GetFlagName(utils.DBCacheSizeFlag))
+	cfg.DBWriteBuffer = ctx.Uint(utils.GetFlagName(utils.DBWriteBufferFlag))
+	cfg.DBOpenFiles = ctx.Uint(utils.GetFlagName(utils.DBOpenFilesFlag))
+	cfg.DBCompression = ctx.String(utils.GetFlagName(utils.DBCompressionFlag))
+	cfg.ExecWorkers = ctx.Uint(utils.GetFlagName(utils.ExecWorkersFlag))
 }
 
 func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
 	cfg.EnableConsensus = ctx.Bool(utils.GetFlagName(utils.EnableConsensusFlag))
 	cfg.MaxTxInBlock = ctx.Uint(utils.GetFlagName(utils.MaxTxInBlockFlag))
+	cfg.SignerType = ctx.String(utils.GetFlagName(utils.ConsensusSignerFlag))
+	cfg.SignerAddress = ctx.String(utils.GetFlagName(utils.ConsensusSignerAddressFlag))
 }
 
-func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
+func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) error {
 	cfg.NetworkId = uint32(ctx.Uint(utils.GetFlagName(utils.NetworkIdFlag)))
 	cfg.NetworkMagic = config.GetNetworkMagic(cfg.NetworkId)
 	cfg.NetworkName = config.GetNetworkName(cfg.NetworkId)
 	cfg.NodePort = ctx.Uint(utils.GetFlagName(utils.NodePortFlag))
+	cfg.ConsensusPort = ctx.Uint(utils.GetFlagName(utils.ConsensusPortFlag))
+	cfg.Transport = ctx.String(utils.GetFlagName(utils.TransportFlag))
+	if err := link.CheckTransport(cfg.Transport); err != nil {
+		return err
+	}
+	cfg.ConsensusTransport = ctx.String(utils.GetFlagName(utils.ConsensusTransportFlag))
+	if err := link.CheckTransport(cfg.ConsensusTransport); err != nil {
+		return err
+	}
 	cfg.HttpInfoPort = ctx.Uint(utils.GetFlagName(utils.HttpInfoPortFlag))
 	cfg.ReservedPeersOnly = ctx.Bool(utils.GetFlagName(utils.ReservedPeersOnlyFlag))
 	cfg.MaxConnInBound = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundFlag))
 	cfg.MaxConnOutBound = ctx.Uint(utils.GetFlagName(utils.MaxConnOutBoundFlag))
 	cfg.MaxConnInBoundForSingleIP = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundForSingleIPFlag))
-
-	rsvfile := ctx.String(utils.GetFlagName(utils.ReservedPeersFileFlag))
-	if cfg.ReservedPeersOnly {
-		if !common.FileExisted(rsvfile) {
-			log.Infof("file %s not exist\n", rsvfile)
-			return
-		}
-		err := utils.GetJsonObjectFromFile(rsvfile, &cfg.ReservedCfg)
+	cfg.AuthEnabled = ctx.Bool(utils.GetFlagName(utils.P2PAuthFlag))
+	cfg.NodeKeyPath = ctx.String(utils.GetFlagName(utils.NodeKeyFileFlag))
+	if cfg.NodeKeyPath == "" {
+		cfg.NodeKeyPath = filepath.Join(ctx.String(utils.GetFlagName(utils.DataDirFlag)), config.DEFAULT_NODE_KEY_FILE)
+	}
+	cfg.EnableDiscovery = !ctx.Bool(utils.GetFlagName(utils.DisableDiscoveryFlag))
+	cfg.EnableCompression = !ctx.Bool(utils.GetFlagName(utils.DisableCompressionFlag))
+	cfg.NodeTablePath = filepath.Join(ctx.String(utils.GetFlagName(utils.DataDirFlag)), config.DEFAULT_NODE_TABLE_FILE)
+	cfg.BanListPath = filepath.Join(ctx.String(utils.GetFlagName(utils.DataDirFlag)), config.DEFAULT_BAN_LIST_FILE)
+	cfg.NAT = ctx.String(utils.GetFlagName(utils.NATFlag))
+	if _, err := nat.Parse(cfg.NAT); err != nil {
+		return err
+	}
+	if addr := ctx.String(utils.GetFlagName(utils.PublicAddressFlag)); addr != "" {
+		publicAddr, err := config.ParsePublicAddress(addr, cfg.NodePort)
 		if err != nil {
-			log.Errorf("Get ReservedCfg error:%s", err)
-			return
+			return err
 		}
-		for i := 0; i < len(cfg.ReservedCfg.ReservedPeers); i++ {
-			log.Info("reserved addr: " + cfg.ReservedCfg.ReservedPeers[i])
-		}
-		for i := 0; i < len(cfg.ReservedCfg.MaskPeers); i++ {
-			log.Info("mask addr: " + cfg.ReservedCfg.MaskPeers[i])
+		cfg.PublicAddress = publicAddr
+	}
+	cfg.CheckpointSync = ctx.Bool(utils.GetFlagName(utils.CheckpointSyncFlag))
+	checkpoints := ctx.String(utils.GetFlagName(utils.CheckpointsFlag))
+	if checkpoints != "" {
+		for _, s := range strings.Split(checkpoints, ",") {
+			checkpoint, err := config.ParseCheckpoint(s)
+			if err != nil {
+				return err
+			}
+			cfg.Checkpoints = append(cfg.Checkpoints, checkpoint)
 		}
 	}
+	if cfg.CheckpointSync && len(cfg.Checkpoints) == 0 {
+		return fmt.Errorf("--%s requires --%s, there are no built-in checkpoints",
+			utils.CheckpointSyncFlag.Name, utils.CheckpointsFlag.Name)
+	}
 
+	//reserved peers pinning public keys are checked without reserved only, so the file is loaded if exists
+	rsvfile := ctx.String(utils.GetFlagName(utils.ReservedPeersFileFlag))
+	if !common.FileExisted(rsvfile) {
+		if cfg.ReservedPeersOnly {
+			log.Infof("file %s not exist\n", rsvfile)
+		}
+		return nil
+	}
+	err := utils.GetJsonObjectFromFile(rsvfile, &cfg.ReservedCfg)
+	if err != nil {
+		log.Errorf("Get ReservedCfg error:%s", err)
+		return nil
+	}
+	for i := 0; i < len(cfg.ReservedCfg.ReservedPeers); i++ {
+		log.Info("reserved addr: " + cfg.ReservedCfg.ReservedPeers[i])
+	}
+	for i := 0; i < len(cfg.ReservedCfg.MaskPeers); i++ {
+		log.Info("mask addr: " + cfg.ReservedCfg.MaskPeers[i])
+	}
+	return nil
 }
 
 func setRpcConfig(ctx *cli.Context, cfg *config.RpcConfig) {
@@ -19,12 +19,14 @@
 package cmd
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/gosuri/uiprogress"
 	"github.com/TesraSupernet/Tesra/cmd/utils"
-	"github.com/TesraSupernet/Tesra/common/serialization"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/urfave/cli"
+	"io"
 	"os"
 	"time"
 )
@@ -40,8 +42,16 @@ var ExportCommand = cli.Command{
 		utils.ExportStartHeightFlag,
 		utils.ExportEndHeightFlag,
 		utils.ExportSpeedFlag,
+		utils.ExportChunkBlocksFlag,
+		utils.StateSnapshotFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.DBBackendFlag,
 	},
-	Description: "",
+	Description: `Blocks are exported in chunks with checksums, and an index file is written next to the export file.
+If the export file exists, blocks after the last exported one are appended to it.
+With --state-snapshot, the state db of a stopped node in data dir is exported instead of blocks`,
 }
 
 func exportBlocks(ctx *cli.Context) error {
@@ -52,6 +62,9 @@ func exportBlocks(ctx *cli.Context) error {
 		cli.ShowSubcommandHelp(ctx)
 		return nil
 	}
+	if ctx.Bool(utils.GetFlagName(utils.StateSnapshotFlag)) {
+		return exportStateSnapshot(ctx, exportFile)
+	}
 
 	startHeight := ctx.Uint(utils.GetFlagName(utils.ExportStartHeightFlag))
 	endHeight := ctx.Uint(utils.GetFlagName(utils.ExportEndHeightFlag))
@@ -82,63 +95,208 @@ func exportBlocks(ctx *cli.Context) error {
 		sleepTime = time.Millisecond * 5
 	}
 
-	exportFile = utils.GenExportBlocksFileName(exportFile, uint32(startHeight), uint32(endHeight))
+	chunkBlocks := ctx.Uint(utils.GetFlagName(utils.ExportChunkBlocksFlag))
+	if chunkBlocks == 0 || chunkBlocks > utils.MAX_EXPORT_CHUNK_BLOCKS {
+		return fmt.Errorf("export error: chunk blocks should between 1 and %d", utils.MAX_EXPORT_CHUNK_BLOCKS)
+	}
+
 	ef, err := os.OpenFile(exportFile, os.O_RDWR|os.O_CREATE, 0664)
 	if err != nil {
 		return fmt.Errorf("open file:%s error:%s", exportFile, err)
 	}
 	defer ef.Close()
-	fWriter := bufio.NewWriter(ef)
-
-	metadata := utils.NewExportBlockMetadata()
-	metadata.StartBlockHeight = uint32(startHeight)
-	metadata.EndBlockHeight = uint32(endHeight)
-	err = metadata.Serialize(fWriter)
+	metadata, entries, fileEnd, err := openExportFile(ef, uint32(startHeight))
+	if err != nil {
+		return fmt.Errorf("open export file:%s error:%s", exportFile, err)
+	}
+	nextHeight := metadata.StartBlockHeight
+	if len(entries) > 0 {
+		nextHeight = metadata.EndBlockHeight + 1
+		PrintInfoMsg("Resume export from block height:%d", nextHeight)
+	}
+	if uint(nextHeight) > endHeight {
+		PrintWarnMsg("Blocks up to height:%d already exported, No blocks to export.", nextHeight-1)
+		return nil
+	}
+	indexFile := utils.GetExportIndexFileName(exportFile)
+	err = utils.WriteExportIndex(indexFile, entries)
+	if err != nil {
+		return fmt.Errorf("write index file:%s error:%s", indexFile, err)
+	}
+	idxf, err := os.OpenFile(indexFile, os.O_WRONLY|os.O_APPEND, 0664)
 	if err != nil {
-		return fmt.Errorf("write export metadata error:%s", err)
+		return fmt.Errorf("open file:%s error:%s", indexFile, err)
 	}
+	defer idxf.Close()
 
 	//progress bar
 	uiprogress.Start()
-	bar := uiprogress.AddBar(int(endHeight - startHeight + 1)).
+	bar := uiprogress.AddBar(int(endHeight - uint(nextHeight) + 1)).
 		AppendCompleted().
 		AppendElapsed().
 		PrependFunc(func(b *uiprogress.Bar) string {
-			return fmt.Sprintf("Block(%d/%d)", b.Current()+int(startHeight), int(endHeight))
+			return fmt.Sprintf("Block(%d/%d)", b.Current()+int(nextHeight), int(endHeight))
 		})
 
 	PrintInfoMsg("Start export.")
-	for i := uint32(startHeight); i <= uint32(endHeight); i++ {
-		blockData, err := utils.GetBlockData(i)
+	for height := nextHeight; height <= uint32(endHeight); {
+		count := uint32(chunkBlocks)
+		if uint32(endHeight)-height+1 < count {
+			count = uint32(endHeight) - height + 1
+		}
+		blocks := make([][]byte, 0, count)
+		for i := height; i < height+count; i++ {
+			blockData, err := utils.GetBlockData(i)
+			if err != nil {
+				return fmt.Errorf("GetBlockData:%d error:%s", i, err)
+			}
+			blocks = append(blocks, blockData)
+			if sleepTime > 0 {
+				time.Sleep(sleepTime)
+			}
+			bar.Incr()
+		}
+		chunk, err := utils.NewExportChunk(height, blocks, metadata.CompressType)
 		if err != nil {
-			return fmt.Errorf("GetBlockData:%d error:%s", i, err)
+			return fmt.Errorf("NewExportChunk height:%d error:%s", height, err)
 		}
-		data, err := utils.CompressBlockData(blockData, metadata.CompressType)
+		err = writeExportChunk(ef, fileEnd, chunk, metadata)
 		if err != nil {
-			return fmt.Errorf("CompressBlockData height:%d error:%s", i, err)
+			return fmt.Errorf("write chunk height:%d error:%s", height, err)
 		}
-		err = serialization.WriteUint32(fWriter, uint32(len(data)))
+		err = utils.WriteExportIndexEntry(idxf, utils.ExportIndexEntry{StartHeight: height, Offset: uint64(fileEnd)})
 		if err != nil {
-			return fmt.Errorf("write block data height:%d len:%d error:%s", i, uint32(len(data)), err)
+			return fmt.Errorf("write index file:%s error:%s", indexFile, err)
 		}
-		_, err = fWriter.Write(data)
+		fileEnd += utils.EXPORT_CHUNK_HEADER_LEN + int64(chunk.Header.DataSize)
+		height += count
+	}
+	uiprogress.Stop()
+
+	PrintInfoMsg("Export blocks successfully.")
+	PrintInfoMsg("StartBlockHeight:%d", metadata.StartBlockHeight)
+	PrintInfoMsg("EndBlockHeight:%d", metadata.EndBlockHeight)
+	PrintInfoMsg("Export file:%s", exportFile)
+	return nil
+}
+
+//openExportFile write metadata to a new export file, or load the chunks of an existing export file to append
+//blocks to it. The chunk partly written by an interrupted export is truncated
+func openExportFile(ef *os.File, startHeight uint32) (*utils.ExportBlockMetadata, []utils.ExportIndexEntry, int64, error) {
+	info, err := ef.Stat()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	metadata := utils.NewExportBlockMetadata()
+	if info.Size() == 0 {
+		metadata.StartBlockHeight = startHeight
+		err = metadata.Serialize(ef)
 		if err != nil {
-			return fmt.Errorf("write block data height:%d error:%s", i, err)
+			return nil, nil, 0, fmt.Errorf("write export metadata error:%s", err)
+		}
+		return metadata, nil, utils.EXPORT_BLOCK_METADATA_LEN, nil
+	}
+
+	err = metadata.Deserialize(io.NewSectionReader(ef, 0, utils.EXPORT_BLOCK_METADATA_LEN))
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("export metadata deserialize error:%s", err)
+	}
+	if metadata.Version != utils.EXPORT_BLOCK_METADATA_VERSION {
+		return nil, nil, 0, fmt.Errorf("export file version:%d can not be appended", metadata.Version)
+	}
+	entries, fileEnd, err := utils.ScanExportChunks(ef)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if len(entries) > 0 {
+		//the last chunk may be not synced to disk completely
+		last := entries[len(entries)-1]
+		chunk, err := utils.ReadExportChunk(ef, int64(last.Offset))
+		if err == nil {
+			err = chunk.Verify()
 		}
-		if sleepTime > 0 {
-			time.Sleep(sleepTime)
+		if err != nil {
+			PrintWarnMsg("Drop chunk start height:%d, %s", last.StartHeight, err)
+			entries = entries[:len(entries)-1]
+			fileEnd = int64(last.Offset)
+		} else {
+			metadata.EndBlockHeight = chunk.Header.EndHeight()
 		}
-		bar.Incr()
 	}
-	uiprogress.Stop()
+	if len(entries) == 0 {
+		metadata.StartBlockHeight = startHeight
+		metadata.EndBlockHeight = 0
+	} else if entries[0].StartHeight != metadata.StartBlockHeight {
+		return nil, nil, 0, fmt.Errorf("first chunk height:%d mismatch with start height:%d", entries[0].StartHeight,
+			metadata.StartBlockHeight)
+	}
+	err = ef.Truncate(fileEnd)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return metadata, entries, fileEnd, nil
+}
 
-	err = fWriter.Flush()
+//writeExportChunk write chunk at the end of export file, and then update the end height in metadata
+func writeExportChunk(ef *os.File, offset int64, chunk *utils.ExportChunk, metadata *utils.ExportBlockMetadata) error {
+	buf := bytes.NewBuffer(nil)
+	err := chunk.Serialize(buf)
 	if err != nil {
-		return fmt.Errorf("export flush file error:%s", err)
+		return err
 	}
-	PrintInfoMsg("Export blocks successfully.")
-	PrintInfoMsg("StartBlockHeight:%d", startHeight)
-	PrintInfoMsg("EndBlockHeight:%d", endHeight)
+	_, err = ef.WriteAt(buf.Bytes(), offset)
+	if err != nil {
+		return err
+	}
+	err = ef.Sync()
+	if err != nil {
+		return err
+	}
+	metadata.EndBlockHeight = chunk.Header.EndHeight()
+	buf.Reset()
+	err = metadata.Serialize(buf)
+	if err != nil {
+		return err
+	}
+	_, err = ef.WriteAt(buf.Bytes(), 0)
+	return err
+}
+
+func exportStateSnapshot(ctx *cli.Context, exportFile string) error {
+	log.InitLog(log.InfoLog)
+	cfg, err := SetTesranodeConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("SetTesranodeConfig error:%s", err)
+	}
+	err = openLedger(cfg)
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+	err = initLedger()
+	if err != nil {
+		return err
+	}
+
+	ef, err := os.OpenFile(exportFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return fmt.Errorf("open file:%s error:%s", exportFile, err)
+	}
+	defer ef.Close()
+
+	PrintInfoMsg("Start export state snapshot.")
+	info, err := ledger.DefLedger.ExportStateSnapshot(ef)
+	if err != nil {
+		return fmt.Errorf("ExportStateSnapshot error:%s", err)
+	}
+	PrintInfoMsg("Export state snapshot successfully.")
+	PrintInfoMsg("BlockHeight:%d", info.Height)
+	PrintInfoMsg("BlockHash:%s", info.BlockHash.ToHexString())
+	PrintInfoMsg("StateMerkleRoot:%s", info.StateMerkleRoot.ToHexString())
+	PrintInfoMsg("StateHash:%s", info.StateHash.ToHexString())
+	PrintInfoMsg("States:%d", info.StateCount)
 	PrintInfoMsg("Export file:%s", exportFile)
+	PrintInfoMsg("Import it with --%s=%d:%s:%s", utils.StateSnapshotCheckpointFlag.Name, info.Height,
+		info.BlockHash.ToHexString(), info.StateHash.ToHexString())
 	return nil
 }
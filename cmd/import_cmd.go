@@ -23,13 +23,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/TesraSupernet/Tesra/cmd/utils"
+	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/common/serialization"
 	"github.com/TesraSupernet/Tesra/core/genesis"
 	"github.com/TesraSupernet/Tesra/core/ledger"
+	"github.com/TesraSupernet/Tesra/core/store"
 	"github.com/TesraSupernet/Tesra/core/types"
 	"github.com/gosuri/uiprogress"
 	"github.com/urfave/cli"
@@ -47,8 +52,14 @@ var ImportCommand = cli.Command{
 		utils.ConfigFlag,
 		utils.NetworkIdFlag,
 		utils.DisableEventLogFlag,
+		utils.StateSnapshotFlag,
+		utils.StateSnapshotCheckpointFlag,
+		utils.DBBackendFlag,
 	},
-	Description: "Note that import cmd doesn't support testmode",
+	Description: `Note that import cmd doesn't support testmode.
+Import continues from the current block height of ledger, so an interrupted import can be resumed by running it again.
+A state snapshot is only imported with --snapshot-checkpoint got from a trusted node, the snapshot must be taken
+at that block and its states must match the state hash.`,
 }
 
 func importBlocks(ctx *cli.Context) error {
@@ -60,25 +71,16 @@ func importBlocks(ctx *cli.Context) error {
 		cli.ShowSubcommandHelp(ctx)
 		return nil
 	}
-	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
-
-	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
-	ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
-	if err != nil {
-		return fmt.Errorf("NewLedger error:%s", err)
-	}
-	bookKeepers, err := config.DefConfig.GetBookkeepers()
-	if err != nil {
-		return fmt.Errorf("GetBookkeepers error:%s", err)
+	if ctx.Bool(utils.GetFlagName(utils.StateSnapshotFlag)) {
+		return importStateSnapshot(ctx, cfg)
 	}
-	genesisConfig := config.DefConfig.Genesis
-	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, genesisConfig)
+	err = openLedger(cfg)
 	if err != nil {
-		return fmt.Errorf("BuildGenesisBlock error %s", err)
+		return err
 	}
-	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	err = initLedger()
 	if err != nil {
-		return fmt.Errorf("init ledger error:%s", err)
+		return err
 	}
 
 	dataDir := ctx.String(utils.GetFlagName(utils.DataDirFlag))
@@ -113,6 +115,9 @@ func importBlocks(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("block data file metadata deserialize error:%s", err)
 	}
+	if metadata.Version == utils.EXPORT_BLOCK_METADATA_VERSION {
+		return importBlockChunks(ifile, importFile, metadata.CompressType, currBlockHeight, endBlockHeight)
+	}
 	if metadata.EndBlockHeight <= currBlockHeight {
 		PrintWarnMsg("CurrentBlockHeight:%d larger than or equal to EndBlockHeight:%d, No blocks to import.", currBlockHeight, endBlockHeight)
 		return nil
@@ -173,3 +178,224 @@ func importBlocks(ctx *cli.Context) error {
 	PrintInfoMsg("Import block completed, current block height:%d.", ledger.DefLedger.GetCurrentBlockHeight())
 	return nil
 }
+
+type blockChunkResult struct {
+	blocks []*types.Block
+	err    error
+}
+
+//importBlockChunks import the blocks in chunked export file from current block height. Chunks are read and
+//verified in parallel, and then the blocks are executed in order
+func importBlockChunks(ifile *os.File, importFile string, compressType byte, currBlockHeight, endBlockHeight uint32) error {
+	entries, err := utils.LoadExportIndex(ifile, utils.GetExportIndexFileName(importFile))
+	if err != nil {
+		return fmt.Errorf("load index of file:%s error:%s", importFile, err)
+	}
+	if len(entries) == 0 {
+		PrintWarnMsg("No blocks in file:%s.", importFile)
+		return nil
+	}
+	lastHeader, err := utils.ReadExportChunkHeader(ifile, int64(entries[len(entries)-1].Offset))
+	if err != nil {
+		return fmt.Errorf("read chunk header error:%s", err)
+	}
+	if endBlockHeight == 0 || endBlockHeight > lastHeader.EndHeight() {
+		endBlockHeight = lastHeader.EndHeight()
+	}
+	if currBlockHeight >= endBlockHeight {
+		PrintWarnMsg("CurrentBlockHeight:%d larger than or equal to EndBlockHeight:%d, No blocks to import.", currBlockHeight, endBlockHeight)
+		return nil
+	}
+	first := utils.FindExportChunk(entries, currBlockHeight+1)
+	if first < 0 {
+		return fmt.Errorf("import block error: StartBlockHeight:%d larger than NextBlockHeight:%d", entries[0].StartHeight, currBlockHeight+1)
+	}
+	last := utils.FindExportChunk(entries, endBlockHeight)
+
+	//progress bar
+	uiprogress.Start()
+	bar := uiprogress.AddBar(int(endBlockHeight - currBlockHeight)).
+		AppendCompleted().
+		AppendElapsed().
+		PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("Block(%d/%d)", b.Current()+int(currBlockHeight), int(endBlockHeight))
+		})
+
+	PrintInfoMsg("Start import blocks.")
+	quit := make(chan bool)
+	defer close(quit)
+	for result := range loadBlockChunks(ifile, entries[first:last+1], compressType, quit) {
+		chunk := <-result
+		if chunk.err != nil {
+			return chunk.err
+		}
+		for _, block := range chunk.blocks {
+			height := block.Header.Height
+			if height <= currBlockHeight {
+				continue
+			}
+			if height > endBlockHeight {
+				break
+			}
+			execResult, err := ledger.DefLedger.ExecuteBlock(block)
+			if err != nil {
+				return fmt.Errorf("block height:%d ExecuteBlock error:%s", height, err)
+			}
+			err = ledger.DefLedger.SubmitBlock(block, execResult)
+			if err != nil {
+				return fmt.Errorf("SubmitBlock block height:%d error:%s", height, err)
+			}
+			bar.Incr()
+		}
+	}
+	uiprogress.Stop()
+	PrintInfoMsg("Import block completed, current block height:%d.", ledger.DefLedger.GetCurrentBlockHeight())
+	return nil
+}
+
+//loadBlockChunks read, verify and deserialize the chunks by parallel workers, the results are returned in order
+func loadBlockChunks(ifile *os.File, entries []utils.ExportIndexEntry, compressType byte, quit chan bool) <-chan chan *blockChunkResult {
+	pending := make(chan chan *blockChunkResult, runtime.NumCPU())
+	go func() {
+		defer close(pending)
+		for _, entry := range entries {
+			result := make(chan *blockChunkResult, 1)
+			select {
+			case pending <- result:
+			case <-quit:
+				return
+			}
+			go func(offset int64) {
+				blocks, err := loadBlockChunk(ifile, offset, compressType)
+				result <- &blockChunkResult{blocks: blocks, err: err}
+			}(int64(entry.Offset))
+		}
+	}()
+	return pending
+}
+
+func loadBlockChunk(ifile *os.File, offset int64, compressType byte) ([]*types.Block, error) {
+	chunk, err := utils.ReadExportChunk(ifile, offset)
+	if err != nil {
+		return nil, err
+	}
+	err = chunk.Verify()
+	if err != nil {
+		return nil, err
+	}
+	rawBlocks, err := chunk.Blocks(compressType)
+	if err != nil {
+		return nil, fmt.Errorf("chunk start height:%d decompress error:%s", chunk.Header.StartHeight, err)
+	}
+	blocks := make([]*types.Block, 0, len(rawBlocks))
+	for i, data := range rawBlocks {
+		height := chunk.Header.StartHeight + uint32(i)
+		block, err := types.BlockFromRawBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("block height:%d deserialize error:%s", height, err)
+		}
+		if block.Header.Height != height {
+			return nil, fmt.Errorf("block height:%d mismatch with chunk height:%d", block.Header.Height, height)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func openLedger(cfg *config.TesranodeConfig) error {
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	var err error
+	ledger.DefLedger, err = ledger.NewLedger(dbDir, stateHashHeight)
+	if err != nil {
+		return fmt.Errorf("NewLedger error:%s", err)
+	}
+	return nil
+}
+
+func initLedger() error {
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisConfig := config.DefConfig.Genesis
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, genesisConfig)
+	if err != nil {
+		return fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	err = ledger.DefLedger.Init(bookKeepers, genesisBlock)
+	if err != nil {
+		return fmt.Errorf("init ledger error:%s", err)
+	}
+	return nil
+}
+
+func importStateSnapshot(ctx *cli.Context, cfg *config.TesranodeConfig) error {
+	importFile := ctx.String(utils.GetFlagName(utils.ImportFileFlag))
+	if importFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.ImportFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	checkpointArg := ctx.String(utils.GetFlagName(utils.StateSnapshotCheckpointFlag))
+	if checkpointArg == "" {
+		PrintErrorMsg("Missing %s argument.", utils.StateSnapshotCheckpointFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	checkpoint, err := parseSnapshotCheckpoint(checkpointArg)
+	if err != nil {
+		PrintErrorMsg("%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	ifile, err := os.OpenFile(importFile, os.O_RDONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	defer ifile.Close()
+
+	err = openLedger(cfg)
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+
+	PrintInfoMsg("Start import state snapshot.")
+	info, err := ledger.DefLedger.ImportStateSnapshot(ifile, checkpoint)
+	if err != nil {
+		return fmt.Errorf("ImportStateSnapshot error:%s", err)
+	}
+	err = initLedger()
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Import state snapshot completed.")
+	PrintInfoMsg("BlockHeight:%d", info.Height)
+	PrintInfoMsg("BlockHash:%s", info.BlockHash.ToHexString())
+	PrintInfoMsg("StateMerkleRoot:%s", info.StateMerkleRoot.ToHexString())
+	PrintInfoMsg("States:%d", info.StateCount)
+	return nil
+}
+
+//parseSnapshotCheckpoint parse trusted checkpoint of state snapshot of format height:blockhash:statehash
+func parseSnapshotCheckpoint(s string) (*store.StateSnapshotCheckpoint, error) {
+	items := strings.Split(strings.TrimSpace(s), ":")
+	if len(items) != 3 {
+		return nil, fmt.Errorf("invalid snapshot checkpoint %s, should be height:blockhash:statehash", s)
+	}
+	height, err := strconv.ParseUint(items[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot checkpoint height %s", items[0])
+	}
+	blockHash, err := common.Uint256FromHexString(items[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot checkpoint block hash %s:%s", items[1], err)
+	}
+	stateHash, err := common.Uint256FromHexString(items[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot checkpoint state hash %s:%s", items[2], err)
+	}
+	return &store.StateSnapshotCheckpoint{Height: uint32(height), BlockHash: blockHash, StateHash: stateHash}, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TesraSupernet/Tesra/cmd/utils"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/genesis"
+	"github.com/TesraSupernet/Tesra/core/store/ledgerstore"
+	"github.com/urfave/cli"
+)
+
+const (
+	VERIFY_REPLAY_DIR      = "verify-replay" //Scratch dir of ledger re-executing blocks, under data dir
+	VERIFY_PROGRESS_BLOCKS = 10000           //Print progress every count of blocks
+)
+
+var LedgerCommand = cli.Command{
+	Action:      cli.ShowSubcommandHelp,
+	Name:        "ledger",
+	Usage:       "Ledger maintenance",
+	ArgsUsage:   "[arguments...]",
+	Description: "Ledger maintenance commands, node should be stopped before running them",
+	Subcommands: []cli.Command{
+		{
+			Action:    verifyLedger,
+			Name:      "verify",
+			Usage:     "Verify integrity of ledger",
+			ArgsUsage: "",
+			Flags: []cli.Flag{
+				utils.VerifyNoReplayFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+				utils.DBBackendFlag,
+				utils.DisableEventLogFlag,
+			},
+			Description: `Walk all blocks of ledger, check header hash links, transactions roots, block merkle tree,
+state merkle root chain and event store, and report the first inconsistent height.
+Blocks are re-executed in a scratch ledger to check the write set hashes, unless --no-replay is set or ledger is pruned.`,
+		},
+	},
+}
+
+func verifyLedger(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	cfg, err := SetTesranodeConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetTesranodeConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(cfg.Common.DataDir, cfg.P2PNode.NetworkName)
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	ldgStore, err := ledgerstore.NewLedgerStore(dbDir, stateHashHeight)
+	if err != nil {
+		return fmt.Errorf("NewLedgerStore error:%s", err)
+	}
+	defer ldgStore.Close()
+
+	var replay *ledgerstore.LedgerStoreImp
+	if !ctx.Bool(utils.GetFlagName(utils.VerifyNoReplayFlag)) {
+		replayDir := filepath.Join(cfg.Common.DataDir, VERIFY_REPLAY_DIR)
+		err = os.RemoveAll(replayDir)
+		if err != nil {
+			return fmt.Errorf("remove %s error:%s", replayDir, err)
+		}
+		replay, err = newReplayLedgerStore(replayDir, stateHashHeight)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(replayDir)
+		defer replay.Close()
+	}
+
+	PrintInfoMsg("Start verify ledger.")
+	err = ldgStore.VerifyLedger(replay, func(height uint32) {
+		if height%VERIFY_PROGRESS_BLOCKS == 0 {
+			PrintInfoMsg("Verified block height:%d", height)
+		}
+	})
+	if err != nil {
+		if verifyErr, ok := err.(*ledgerstore.LedgerVerifyError); ok {
+			PrintErrorMsg("Ledger is inconsistent.")
+			PrintErrorMsg("First inconsistent block height:%d", verifyErr.Height)
+			PrintErrorMsg("Reason:%s", verifyErr.Err)
+			return fmt.Errorf("verify ledger failed")
+		}
+		return fmt.Errorf("VerifyLedger error:%s", err)
+	}
+	PrintInfoMsg("Verify ledger successfully.")
+	PrintInfoMsg("Block height:%d", ldgStore.GetCurrentBlockHeight())
+	return nil
+}
+
+func newReplayLedgerStore(dbDir string, stateHashHeight uint32) (*ledgerstore.LedgerStoreImp, error) {
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		return nil, fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	replay, err := ledgerstore.NewLedgerStore(dbDir, stateHashHeight)
+	if err != nil {
+		return nil, fmt.Errorf("NewLedgerStore error:%s", err)
+	}
+	err = replay.InitLedgerStoreWithGenesisBlock(genesisBlock, bookKeepers)
+	if err != nil {
+		replay.Close()
+		return nil, fmt.Errorf("init replay ledger error:%s", err)
+	}
+	return replay, nil
+}
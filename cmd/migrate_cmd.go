@@ -0,0 +1,129 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TesraSupernet/Tesra/cmd/utils"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/store/ledgerstore"
+	"github.com/urfave/cli"
+)
+
+var MigrateDBCommand = cli.Command{
+	Name:      "migratedb",
+	Usage:     "Convert ledger DB to another storage backend",
+	ArgsUsage: "",
+	Action:    migrateDB,
+	Flags: []cli.Flag{
+		utils.MigrateBackendFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.DBCacheSizeFlag,
+		utils.DBWriteBufferFlag,
+		utils.DBOpenFilesFlag,
+		utils.DBCompressionFlag,
+	},
+	Description: "Node should be stopped before migration. The old DB is kept in <db>.bak directory",
+}
+
+func migrateDB(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	cfg, err := SetTesranodeConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetTesranodeConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	to := ctx.String(utils.GetFlagName(utils.MigrateBackendFlag))
+	if to != config.DB_BACKEND_LEVELDB && to != config.DB_BACKEND_BADGER {
+		PrintErrorMsg("Invalid %s argument:%s", utils.MigrateBackendFlag.Name, to)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(cfg.Common.DataDir, cfg.P2PNode.NetworkName)
+	for _, name := range []string{ledgerstore.DBDirBlock, ledgerstore.DBDirState, ledgerstore.DBDirEvent} {
+		err = migrateStore(filepath.Join(dbDir, name), to, cfg.Common)
+		if err != nil {
+			return fmt.Errorf("migrate %s error:%s", name, err)
+		}
+	}
+	PrintInfoMsg("Migrate DB successfully, start node with --%s=%s", utils.DBBackendFlag.Name, to)
+	return nil
+}
+
+func migrateStore(dir, to string, cfg *config.CommonConfig) error {
+	from := ledgerstore.DetectBackend(dir)
+	if from == "" {
+		PrintInfoMsg("%s not exist, skip", dir)
+		return nil
+	}
+	if from == to {
+		PrintInfoMsg("%s already use %s backend, skip", dir, to)
+		return nil
+	}
+	backupDir := dir + ".bak"
+	if _, err := os.Stat(backupDir); err == nil {
+		return fmt.Errorf("backup dir %s already exist", backupDir)
+	}
+	tmpDir := dir + ".migrating"
+	err := os.RemoveAll(tmpDir)
+	if err != nil {
+		return err
+	}
+
+	opts := ledgerstore.GetPersistStoreOptions(cfg)
+	src, err := ledgerstore.OpenPersistStore(from, dir, opts)
+	if err != nil {
+		return fmt.Errorf("open %s error:%s", dir, err)
+	}
+	dst, err := ledgerstore.OpenPersistStore(to, tmpDir, opts)
+	if err != nil {
+		src.Close()
+		return fmt.Errorf("open %s error:%s", tmpDir, err)
+	}
+	PrintInfoMsg("Start migrate %s from %s to %s.", dir, from, to)
+	count, err := ledgerstore.CopyPersistStore(dst, src)
+	src.Close()
+	if err != nil {
+		dst.Close()
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	err = dst.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(dir, backupDir)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpDir, dir)
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Migrate %s done, %d records", dir, count)
+	return nil
+}
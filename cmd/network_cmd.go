@@ -0,0 +1,103 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/cmd/utils"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/urfave/cli"
+)
+
+var NetworkCommand = cli.Command{
+	Action:      cli.ShowSubcommandHelp,
+	Name:        "network",
+	Usage:       "Inspect p2p network",
+	ArgsUsage:   "[arguments...]",
+	Description: "Inspect the neighbors of node and the topology of network, to diagnose partitions",
+	Subcommands: []cli.Command{
+		{
+			Action:    listPeerInfos,
+			Name:      "peers",
+			Usage:     "Display detail of neighbors",
+			ArgsUsage: "",
+			Flags: []cli.Flag{
+				utils.RPCLocalProtFlag,
+			},
+			Description: `Display direction, connected time, height, latency, reputation score, sync role and traffic by message
+type of each neighbor. Local rpc server of node should be enabled by --localrpc.`,
+		},
+		{
+			Action:    crawlNetwork,
+			Name:      "crawl",
+			Usage:     "Crawl network and output the graph",
+			ArgsUsage: "[httpInfoAddr]",
+			Flags: []cli.Flag{
+				utils.HttpInfoPortFlag,
+				utils.CrawlMaxNodesFlag,
+				utils.CrawlTimeoutFlag,
+				utils.GraphFormatFlag,
+			},
+			Description: `Crawl network breadth first by the neighbors served by http info server of each node, from local
+node or the http info server at httpInfoAddr, and output the graph in dot or json. Edges point from the node dialed to
+the node accepted. Nodes without http info server are in graph, but their neighbors are not known.`,
+		},
+	},
+}
+
+func listPeerInfos(ctx *cli.Context) error {
+	if ctx.IsSet(utils.GetFlagName(utils.RPCLocalProtFlag)) {
+		config.DefConfig.Rpc.HttpLocalPort = ctx.Uint(utils.GetFlagName(utils.RPCLocalProtFlag))
+	}
+	data, err := utils.GetPeerInfos()
+	if err != nil {
+		return fmt.Errorf("GetPeerInfos error:%s", err)
+	}
+	PrintJsonData(data)
+	return nil
+}
+
+func crawlNetwork(ctx *cli.Context) error {
+	format := ctx.String(utils.GetFlagName(utils.GraphFormatFlag))
+	if format != utils.GRAPH_FORMAT_DOT && format != utils.GRAPH_FORMAT_JSON {
+		PrintErrorMsg("Invalid graph format:%s, dot or json expected.", format)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	addr := "localhost:" + strconv.Itoa(int(ctx.Uint(utils.GetFlagName(utils.HttpInfoPortFlag))))
+	if ctx.NArg() > 0 {
+		addr = ctx.Args().First()
+	}
+	maxNodes := int(ctx.Uint(utils.GetFlagName(utils.CrawlMaxNodesFlag)))
+	timeout := time.Duration(ctx.Uint(utils.GetFlagName(utils.CrawlTimeoutFlag))) * time.Second
+
+	graph, err := utils.CrawlNetwork(addr, maxNodes, timeout)
+	if err != nil {
+		return fmt.Errorf("CrawlNetwork error:%s", err)
+	}
+	if format == utils.GRAPH_FORMAT_JSON {
+		PrintJsonObject(graph)
+		return nil
+	}
+	fmt.Print(graph.Dot())
+	return nil
+}
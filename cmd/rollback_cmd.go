@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TesraSupernet/Tesra/cmd/utils"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/ledger"
+	"github.com/TesraSupernet/Tesra/core/store/ledgerstore"
+	"github.com/urfave/cli"
+)
+
+var RollbackCommand = cli.Command{
+	Name:      "rollback",
+	Usage:     "Rollback ledger to a previous block height",
+	ArgsUsage: "",
+	Action:    rollbackLedger,
+	Flags: []cli.Flag{
+		utils.RollbackHeightFlag,
+		utils.DataDirFlag,
+		utils.ConfigFlag,
+		utils.NetworkIdFlag,
+		utils.DBBackendFlag,
+	},
+	Description: fmt.Sprintf("Node should be stopped before rollback. Blocks above the height are removed and should be synced again. "+
+		"Only the last %d blocks can be rolled back", ledgerstore.MAX_ROLLBACK_BLOCKS),
+}
+
+func rollbackLedger(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	if !ctx.IsSet(utils.GetFlagName(utils.RollbackHeightFlag)) {
+		PrintErrorMsg("Missing %s argument.", utils.RollbackHeightFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	height := uint32(ctx.Uint(utils.GetFlagName(utils.RollbackHeightFlag)))
+	cfg, err := SetTesranodeConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetTesranodeConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	err = openLedger(cfg)
+	if err != nil {
+		return err
+	}
+	defer ledger.DefLedger.Close()
+	err = initLedger()
+	if err != nil {
+		return err
+	}
+	currHeight := ledger.DefLedger.GetCurrentBlockHeight()
+	err = ledger.DefLedger.RollbackTo(height)
+	if err != nil {
+		return fmt.Errorf("rollback error:%s", err)
+	}
+	PrintInfoMsg("Rollback ledger successfully.")
+	PrintInfoMsg("From block height:%d", currHeight)
+	PrintInfoMsg("To block height:%d", height)
+	blockHash := ledger.DefLedger.GetCurrentBlockHash()
+	PrintInfoMsg("Block hash:%s", blockHash.ToHexString())
+	return nil
+}
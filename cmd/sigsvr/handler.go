@@ -24,6 +24,8 @@ func init() {
 	DefCliRpcSvr.RegHandler("createaccount", handlers.CreateAccount)
 	DefCliRpcSvr.RegHandler("exportaccount", handlers.ExportAccount)
 	DefCliRpcSvr.RegHandler("sigdata", handlers.SigData)
+	DefCliRpcSvr.RegHandler("sigvrf", handlers.SigVrf)
+	DefCliRpcSvr.RegHandler("getpublickey", handlers.GetPublicKey)
 	DefCliRpcSvr.RegHandler("sigrawtx", handlers.SigRawTransaction)
 	DefCliRpcSvr.RegHandler("sigmutilrawtx", handlers.SigMutilRawTransaction)
 	DefCliRpcSvr.RegHandler("sigtransfertx", handlers.SigTransferTransaction)
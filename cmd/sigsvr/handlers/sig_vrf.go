@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/TesraSupernet/tesracrypto/vrf"
+	clisvrcom "github.com/TesraSupernet/Tesra/cmd/sigsvr/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+)
+
+type SigVrfReq struct {
+	RawData string `json:"raw_data"`
+}
+
+type SigVrfRsp struct {
+	Value string `json:"value"`
+	Proof string `json:"proof"`
+}
+
+type GetPublicKeyRsp struct {
+	PublicKey string `json:"public_key"`
+}
+
+//SigVrf compute the vrf value and proof of raw data, using by consensus remote signer
+func SigVrf(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	rawReq := &SigVrfReq{}
+	err := json.Unmarshal(req.Params, rawReq)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		return
+	}
+	rawData, err := hex.DecodeString(rawReq.RawData)
+	if err != nil {
+		log.Infof("Cli Qid:%s SigVrf hex.DecodeString error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		return
+	}
+	signer, err := req.GetAccount()
+	if err != nil {
+		log.Infof("Cli Qid:%s SigVrf GetAccount:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_ACCOUNT_UNLOCK
+		return
+	}
+	value, proof, err := vrf.Vrf(signer.PrivateKey, rawData)
+	if err != nil {
+		log.Infof("Cli Qid:%s SigVrf Vrf error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	resp.Result = &SigVrfRsp{
+		Value: hex.EncodeToString(value),
+		Proof: hex.EncodeToString(proof),
+	}
+}
+
+//GetPublicKey return the public key of account
+func GetPublicKey(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	signer, err := req.GetAccount()
+	if err != nil {
+		log.Infof("Cli Qid:%s GetPublicKey GetAccount:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_ACCOUNT_UNLOCK
+		return
+	}
+	resp.Result = &GetPublicKeyRsp{
+		PublicKey: hex.EncodeToString(keypair.SerializePublicKey(signer.PublicKey)),
+	}
+}
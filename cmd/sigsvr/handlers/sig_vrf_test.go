@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/TesraSupernet/tesracrypto/vrf"
+	clisvrcom "github.com/TesraSupernet/Tesra/cmd/sigsvr/common"
+	"testing"
+)
+
+func TestSigVrf(t *testing.T) {
+	defAcc, err := testWallet.GetDefaultAccount(pwd)
+	if err != nil {
+		t.Errorf("GetDefaultAccount error:%s", err)
+		return
+	}
+
+	rawData := []byte("HelloWorld")
+	data, err := json.Marshal(&SigVrfReq{RawData: hex.EncodeToString(rawData)})
+	if err != nil {
+		t.Errorf("json.Marshal SigVrfReq error:%s", err)
+		return
+	}
+	req := &clisvrcom.CliRpcRequest{
+		Qid:     "t",
+		Method:  "sigvrf",
+		Params:  data,
+		Account: defAcc.Address.ToBase58(),
+		Pwd:     string(pwd),
+	}
+	resp := &clisvrcom.CliRpcResponse{}
+	SigVrf(req, resp)
+	if resp.ErrorCode != 0 {
+		t.Errorf("SigVrf failed. ErrorCode:%d", resp.ErrorCode)
+		return
+	}
+	rsp := resp.Result.(*SigVrfRsp)
+	value, _ := hex.DecodeString(rsp.Value)
+	proof, _ := hex.DecodeString(rsp.Proof)
+	ok, err := vrf.Verify(defAcc.PublicKey, rawData, value, proof)
+	if err != nil || !ok {
+		t.Errorf("vrf.Verify failed")
+		return
+	}
+}
+
+func TestGetPublicKey(t *testing.T) {
+	defAcc, err := testWallet.GetDefaultAccount(pwd)
+	if err != nil {
+		t.Errorf("GetDefaultAccount error:%s", err)
+		return
+	}
+	req := &clisvrcom.CliRpcRequest{
+		Qid:     "t",
+		Method:  "getpublickey",
+		Account: defAcc.Address.ToBase58(),
+		Pwd:     string(pwd),
+	}
+	resp := &clisvrcom.CliRpcResponse{}
+	GetPublicKey(req, resp)
+	if resp.ErrorCode != 0 {
+		t.Errorf("GetPublicKey failed. ErrorCode:%d", resp.ErrorCode)
+		return
+	}
+}
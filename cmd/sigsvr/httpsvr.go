@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/TesraSupernet/Tesra/cmd/sigsvr/common"
+	tcom "github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"io/ioutil"
 	"net/http"
@@ -35,6 +36,7 @@ type CliRpcServer struct {
 	handlers   map[string]func(req *common.CliRpcRequest, resp *common.CliRpcResponse)
 	httpSvr    *http.Server
 	httpSvtMux *http.ServeMux
+	unixSvr    *http.Server
 }
 
 func NewCliRpcServer() *CliRpcServer {
@@ -61,6 +63,26 @@ func (this *CliRpcServer) Start(address string, port uint) {
 	}
 }
 
+//StartUnix serve rpc on unix domain socket, which is accessible only to the user running sigsvr
+func (this *CliRpcServer) StartUnix(path string) {
+	listener, err := tcom.ListenUnix(path, -1)
+	if err != nil {
+		panic(fmt.Sprintf("listen unix:%s error:%s", path, err))
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cli", this.Handler)
+	this.unixSvr = &http.Server{
+		Handler: mux,
+	}
+	err = this.unixSvr.Serve(listener)
+	if err != nil {
+		if err == http.ErrServerClosed {
+			return
+		}
+		panic(fmt.Sprintf("unixSvr.Serve error:%s", err))
+	}
+}
+
 func (this *CliRpcServer) RegHandler(method string, handler func(req *common.CliRpcRequest, resp *common.CliRpcResponse)) {
 	this.handlers[method] = handler
 }
@@ -141,4 +163,10 @@ func (this *CliRpcServer) Close() {
 	if err != nil {
 		log.Error("httpSvr close error:%s", err)
 	}
+	if this.unixSvr != nil {
+		err = this.unixSvr.Close()
+		if err != nil {
+			log.Error("unixSvr close error:%s", err)
+		}
+	}
 }
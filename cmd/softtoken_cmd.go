@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/TesraSupernet/Tesra/cmd/utils"
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
+	"github.com/urfave/cli"
+)
+
+var SoftTokenCommand = cli.Command{
+	Name:      "softtoken",
+	Usage:     "Serve token file to softtoken consensus signer",
+	ArgsUsage: "",
+	Action:    serveSoftToken,
+	Flags: []cli.Flag{
+		utils.ConsensusSignerTokenFlag,
+		utils.ConsensusSignerAddressFlag,
+		utils.ConsensusSignerGroupFlag,
+	},
+	Description: `Run a PKCS#11 style token out of node process. Node opens a session with pin by --consensus-signer softtoken
+and the same --consensus-signer-address, so the consensus key never enters node process. Run it as another user than
+node, with unix:<socket path> address and --consensus-signer-group set to a group the node user is in. The socket is
+then only accessible by the token user and the group, which requires the directory of the socket searchable by the
+node user. Without the group, the socket is only accessible by the token user itself.`,
+}
+
+func serveSoftToken(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	tokenFile := ctx.String(utils.GetFlagName(utils.ConsensusSignerTokenFlag))
+	if !common.FileExisted(tokenFile) {
+		return fmt.Errorf("Cannot find token file: %s", tokenFile)
+	}
+	address := ctx.String(utils.GetFlagName(utils.ConsensusSignerAddressFlag))
+	if address == "" {
+		return fmt.Errorf("Please config token address using --%s flag", utils.GetFlagName(utils.ConsensusSignerAddressFlag))
+	}
+	gid := -1
+	if name := ctx.String(utils.GetFlagName(utils.ConsensusSignerGroupFlag)); name != "" {
+		group, err := user.LookupGroup(name)
+		if err != nil {
+			return fmt.Errorf("lookup group %s error:%s", name, err)
+		}
+		gid, err = strconv.Atoi(group.Gid)
+		if err != nil {
+			return fmt.Errorf("invalid gid %s of group %s", group.Gid, name)
+		}
+	}
+	server, err := signer.NewTokenServer(tokenFile)
+	if err != nil {
+		return err
+	}
+	if err := server.Listen(address, gid); err != nil {
+		return err
+	}
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-sc
+		log.Infof("softtoken received exit signal: %v.", sig.String())
+		server.Close()
+	}()
+	log.Infof("softtoken serving %s at %s", tokenFile, address)
+	server.Serve()
+	return nil
+}
@@ -102,6 +102,15 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DisableLogFileFlag,
 			utils.DisableEventLogFlag,
 			utils.DataDirFlag,
+			utils.PruneRetentionFlag,
+			utils.DBBackendFlag,
+			utils.DBCacheSizeFlag,
+			utils.DBWriteBufferFlag,
+			utils.DBOpenFilesFlag,
+			utils.DBCompressionFlag,
+			utils.ExecWorkersFlag,
+			utils.ReadOnlyFlag,
+			utils.ReadOnlyRefreshFlag,
 		},
 	},
 	{
@@ -151,10 +160,21 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ReservedPeersFileFlag,
 			utils.NetworkIdFlag,
 			utils.NodePortFlag,
+			utils.ConsensusPortFlag,
+			utils.TransportFlag,
+			utils.ConsensusTransportFlag,
 			utils.HttpInfoPortFlag,
 			utils.MaxConnInBoundFlag,
 			utils.MaxConnOutBoundFlag,
 			utils.MaxConnInBoundForSingleIPFlag,
+			utils.P2PAuthFlag,
+			utils.NodeKeyFileFlag,
+			utils.DisableDiscoveryFlag,
+			utils.DisableCompressionFlag,
+			utils.NATFlag,
+			utils.PublicAddressFlag,
+			utils.CheckpointsFlag,
+			utils.CheckpointSyncFlag,
 		},
 	},
 	{
@@ -241,8 +261,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.ExportFileFlag,
 			utils.ExportSpeedFlag,
+			utils.ExportChunkBlocksFlag,
 			utils.ExportStartHeightFlag,
 			utils.ExportEndHeightFlag,
+			utils.StateSnapshotFlag,
 		},
 	},
 	{
@@ -250,6 +272,34 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.ImportFileFlag,
 			utils.ImportEndHeightFlag,
+			utils.StateSnapshotFlag,
+			utils.StateSnapshotCheckpointFlag,
+		},
+	},
+	{
+		Name: "MIGRATE",
+		Flags: []cli.Flag{
+			utils.MigrateBackendFlag,
+		},
+	},
+	{
+		Name: "ROLLBACK",
+		Flags: []cli.Flag{
+			utils.RollbackHeightFlag,
+		},
+	},
+	{
+		Name: "LEDGER",
+		Flags: []cli.Flag{
+			utils.VerifyNoReplayFlag,
+		},
+	},
+	{
+		Name: "NETWORK",
+		Flags: []cli.Flag{
+			utils.CrawlMaxNodesFlag,
+			utils.CrawlTimeoutFlag,
+			utils.GraphFormatFlag,
 		},
 	},
 	{
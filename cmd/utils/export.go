@@ -34,7 +34,8 @@ const (
 const (
 	DEFAULT_COMPRESS_TYPE         = COMPRESS_TYPE_ZLIB
 	EXPORT_BLOCK_METADATA_LEN     = 256
-	EXPORT_BLOCK_METADATA_VERSION = 1
+	EXPORT_BLOCK_METADATA_VERSION = 2 //Chunked export file
+	EXPORT_BLOCK_METADATA_V1      = 1 //Export file of blocks one by one, only supported by import
 )
 
 type ExportBlockMetadata struct {
@@ -85,7 +86,7 @@ func (this *ExportBlockMetadata) Deserialize(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	if metadata[0] != EXPORT_BLOCK_METADATA_VERSION {
+	if metadata[0] != EXPORT_BLOCK_METADATA_VERSION && metadata[0] != EXPORT_BLOCK_METADATA_V1 {
 		return fmt.Errorf("version unmatch")
 	}
 	reader := bytes.NewBuffer(metadata)
@@ -0,0 +1,331 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/serialization"
+)
+
+//Chunked export file (metadata version 2) layout:
+//  metadata   EXPORT_BLOCK_METADATA_LEN bytes, EndBlockHeight is updated after each chunk is written
+//  chunk      header (start height, block count, data size, sha256 checksum of data) + compressed data
+//  chunk      ...
+//Compressed data of chunk is the block count of (uint32 size + raw block). The index of chunks is kept
+//in a side file with EXPORT_INDEX_FILE_SUFFIX, which can be rebuilt by scanning the chunk headers.
+const (
+	DEFAULT_EXPORT_CHUNK_BLOCKS = 1000 //Count of blocks in a chunk
+	MAX_EXPORT_CHUNK_BLOCKS     = 100000
+	EXPORT_CHUNK_HEADER_LEN     = 44
+	EXPORT_INDEX_ENTRY_LEN      = 12
+	EXPORT_INDEX_FILE_SUFFIX    = ".idx"
+	MAX_EXPORT_CHUNK_DATA_SIZE  = 1024 * 1024 * 1024
+)
+
+//ExportChunkHeader is the header of a chunk of blocks in export file
+type ExportChunkHeader struct {
+	StartHeight uint32
+	BlockCount  uint32
+	DataSize    uint32
+	Checksum    common.Uint256
+}
+
+func (this *ExportChunkHeader) Serialize(w io.Writer) error {
+	err := serialization.WriteUint32(w, this.StartHeight)
+	if err != nil {
+		return err
+	}
+	err = serialization.WriteUint32(w, this.BlockCount)
+	if err != nil {
+		return err
+	}
+	err = serialization.WriteUint32(w, this.DataSize)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(this.Checksum[:])
+	return err
+}
+
+func (this *ExportChunkHeader) Deserialize(r io.Reader) error {
+	var err error
+	this.StartHeight, err = serialization.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	this.BlockCount, err = serialization.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	this.DataSize, err = serialization.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, this.Checksum[:])
+	if err != nil {
+		return err
+	}
+	if this.BlockCount == 0 || this.BlockCount > MAX_EXPORT_CHUNK_BLOCKS || this.DataSize > MAX_EXPORT_CHUNK_DATA_SIZE {
+		return fmt.Errorf("invalid chunk header, block count:%d, data size:%d", this.BlockCount, this.DataSize)
+	}
+	return nil
+}
+
+//EndHeight return the height of last block in chunk
+func (this *ExportChunkHeader) EndHeight() uint32 {
+	return this.StartHeight + this.BlockCount - 1
+}
+
+//ExportChunk is a chunk of compressed blocks in export file
+type ExportChunk struct {
+	Header ExportChunkHeader
+	Data   []byte
+}
+
+//NewExportChunk compress the raw blocks start from startHeight to a chunk
+func NewExportChunk(startHeight uint32, blocks [][]byte, compressType byte) (*ExportChunk, error) {
+	buf := bytes.NewBuffer(nil)
+	for _, block := range blocks {
+		err := serialization.WriteUint32(buf, uint32(len(block)))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(block)
+	}
+	data, err := CompressBlockData(buf.Bytes(), compressType)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportChunk{
+		Header: ExportChunkHeader{
+			StartHeight: startHeight,
+			BlockCount:  uint32(len(blocks)),
+			DataSize:    uint32(len(data)),
+			Checksum:    sha256.Sum256(data),
+		},
+		Data: data,
+	}, nil
+}
+
+func (this *ExportChunk) Serialize(w io.Writer) error {
+	err := this.Header.Serialize(w)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(this.Data)
+	return err
+}
+
+//ReadExportChunkHeader read the header of chunk at offset of export file
+func ReadExportChunkHeader(r io.ReaderAt, offset int64) (*ExportChunkHeader, error) {
+	header := &ExportChunkHeader{}
+	err := header.Deserialize(io.NewSectionReader(r, offset, EXPORT_CHUNK_HEADER_LEN))
+	if err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+//ReadExportChunk read the chunk at offset of export file
+func ReadExportChunk(r io.ReaderAt, offset int64) (*ExportChunk, error) {
+	header, err := ReadExportChunkHeader(r, offset)
+	if err != nil {
+		return nil, fmt.Errorf("read chunk header error:%s", err)
+	}
+	chunk := &ExportChunk{Header: *header, Data: make([]byte, header.DataSize)}
+	_, err = r.ReadAt(chunk.Data, offset+EXPORT_CHUNK_HEADER_LEN)
+	if err != nil {
+		return nil, fmt.Errorf("read chunk data error:%s", err)
+	}
+	return chunk, nil
+}
+
+//Verify check the checksum of chunk data
+func (this *ExportChunk) Verify() error {
+	if sha256.Sum256(this.Data) != this.Header.Checksum {
+		return fmt.Errorf("chunk start height:%d checksum mismatch", this.Header.StartHeight)
+	}
+	return nil
+}
+
+//Blocks decompress the raw blocks in chunk
+func (this *ExportChunk) Blocks(compressType byte) ([][]byte, error) {
+	data, err := DecompressBlockData(this.Data, compressType)
+	if err != nil {
+		return nil, err
+	}
+	reader := bytes.NewReader(data)
+	blocks := make([][]byte, 0, this.Header.BlockCount)
+	for i := uint32(0); i < this.Header.BlockCount; i++ {
+		size, err := serialization.ReadUint32(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read block size error:%s", err)
+		}
+		if int(size) > reader.Len() {
+			return nil, fmt.Errorf("block size:%d out of chunk data", size)
+		}
+		block := make([]byte, size)
+		_, err = io.ReadFull(reader, block)
+		if err != nil {
+			return nil, fmt.Errorf("read block error:%s", err)
+		}
+		blocks = append(blocks, block)
+	}
+	if reader.Len() != 0 {
+		return nil, fmt.Errorf("unexpected data after %d blocks", this.Header.BlockCount)
+	}
+	return blocks, nil
+}
+
+//ExportIndexEntry locate a chunk in export file
+type ExportIndexEntry struct {
+	StartHeight uint32
+	Offset      uint64
+}
+
+//ScanExportChunks read the chunk headers of export file and return the index of complete chunks and the end
+//offset of them. A chunk partly written by an interrupted export is ignored
+func ScanExportChunks(f *os.File) ([]ExportIndexEntry, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]ExportIndexEntry, 0)
+	offset := int64(EXPORT_BLOCK_METADATA_LEN)
+	nextHeight := uint32(0)
+	for offset+EXPORT_CHUNK_HEADER_LEN <= info.Size() {
+		header, err := ReadExportChunkHeader(f, offset)
+		if err != nil {
+			break
+		}
+		end := offset + EXPORT_CHUNK_HEADER_LEN + int64(header.DataSize)
+		if end > info.Size() {
+			break
+		}
+		if len(entries) > 0 && header.StartHeight != nextHeight {
+			break
+		}
+		entries = append(entries, ExportIndexEntry{StartHeight: header.StartHeight, Offset: uint64(offset)})
+		nextHeight = header.EndHeight() + 1
+		offset = end
+	}
+	return entries, offset, nil
+}
+
+//GetExportIndexFileName return the index file name of export file
+func GetExportIndexFileName(exportFile string) string {
+	return exportFile + EXPORT_INDEX_FILE_SUFFIX
+}
+
+//ReadExportIndex read the index file of export file
+func ReadExportIndex(indexFile string) ([]ExportIndexEntry, error) {
+	data, err := ioutil.ReadFile(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%EXPORT_INDEX_ENTRY_LEN != 0 {
+		return nil, fmt.Errorf("invalid index file size:%d", len(data))
+	}
+	reader := bytes.NewReader(data)
+	entries := make([]ExportIndexEntry, 0, len(data)/EXPORT_INDEX_ENTRY_LEN)
+	for reader.Len() > 0 {
+		entry := ExportIndexEntry{}
+		entry.StartHeight, err = serialization.ReadUint32(reader)
+		if err != nil {
+			return nil, err
+		}
+		entry.Offset, err = serialization.ReadUint64(reader)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+//WriteExportIndexEntry append an entry to index file
+func WriteExportIndexEntry(w io.Writer, entry ExportIndexEntry) error {
+	err := serialization.WriteUint32(w, entry.StartHeight)
+	if err != nil {
+		return err
+	}
+	return serialization.WriteUint64(w, entry.Offset)
+}
+
+//WriteExportIndex rewrite the index file of export file
+func WriteExportIndex(indexFile string, entries []ExportIndexEntry) error {
+	buf := bytes.NewBuffer(nil)
+	for _, entry := range entries {
+		err := WriteExportIndexEntry(buf, entry)
+		if err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(indexFile, buf.Bytes(), 0664)
+}
+
+//LoadExportIndex return the index of export file, the index file is rebuilt if it is missing or
+//inconsistent with the export file
+func LoadExportIndex(f *os.File, indexFile string) ([]ExportIndexEntry, error) {
+	entries, err := ReadExportIndex(indexFile)
+	if err == nil && checkExportIndex(f, entries) {
+		return entries, nil
+	}
+	entries, _, err = ScanExportChunks(f)
+	if err != nil {
+		return nil, err
+	}
+	err = WriteExportIndex(indexFile, entries)
+	if err != nil {
+		return nil, fmt.Errorf("write index file error:%s", err)
+	}
+	return entries, nil
+}
+
+func checkExportIndex(f *os.File, entries []ExportIndexEntry) bool {
+	if len(entries) == 0 {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	last := entries[len(entries)-1]
+	header, err := ReadExportChunkHeader(f, int64(last.Offset))
+	if err != nil || header.StartHeight != last.StartHeight {
+		return false
+	}
+	return int64(last.Offset)+EXPORT_CHUNK_HEADER_LEN+int64(header.DataSize) == info.Size()
+}
+
+//FindExportChunk return the position in index of chunk containing the block height, -1 if the height is
+//below the first chunk
+func FindExportChunk(entries []ExportIndexEntry, height uint32) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return entries[i].StartHeight > height
+	}) - 1
+}
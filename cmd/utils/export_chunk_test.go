@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportChunk(t *testing.T) {
+	blocks := [][]byte{[]byte("block1"), []byte("block2"), {}}
+	chunk, err := NewExportChunk(10, blocks, DEFAULT_COMPRESS_TYPE)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(12), chunk.Header.EndHeight())
+	assert.Nil(t, chunk.Verify())
+	data, err := chunk.Blocks(DEFAULT_COMPRESS_TYPE)
+	assert.Nil(t, err)
+	assert.Equal(t, blocks, data)
+
+	chunk.Data[0] ^= 0xff
+	assert.NotNil(t, chunk.Verify())
+}
+
+func TestExportFileIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	exportFile := filepath.Join(dir, "blocks.dat")
+	f, err := os.OpenFile(exportFile, os.O_RDWR|os.O_CREATE, 0664)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	assert.Nil(t, NewExportBlockMetadata().Serialize(f))
+	offsets := make([]uint64, 0)
+	for _, start := range []uint32{0, 2, 4} {
+		chunk, err := NewExportChunk(start, [][]byte{[]byte("a"), []byte("b")}, DEFAULT_COMPRESS_TYPE)
+		assert.Nil(t, err)
+		info, err := f.Stat()
+		assert.Nil(t, err)
+		offsets = append(offsets, uint64(info.Size()))
+		assert.Nil(t, chunk.Serialize(f))
+	}
+	//partly written chunk
+	_, err = f.Write([]byte{1, 2, 3})
+	assert.Nil(t, err)
+
+	entries, end, err := ScanExportChunks(f)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	info, err := f.Stat()
+	assert.Nil(t, err)
+	assert.Equal(t, info.Size()-3, end)
+	for i, entry := range entries {
+		assert.Equal(t, offsets[i], entry.Offset)
+	}
+	assert.Nil(t, f.Truncate(end))
+
+	indexFile := GetExportIndexFileName(exportFile)
+	loaded, err := LoadExportIndex(f, indexFile)
+	assert.Nil(t, err)
+	assert.Equal(t, entries, loaded)
+	loaded, err = ReadExportIndex(indexFile)
+	assert.Nil(t, err)
+	assert.Equal(t, entries, loaded)
+
+	assert.Equal(t, -1, FindExportChunk([]ExportIndexEntry{{StartHeight: 1}}, 0))
+	assert.Equal(t, 0, FindExportChunk(entries, 1))
+	assert.Equal(t, 2, FindExportChunk(entries, 4))
+	assert.Equal(t, 2, FindExportChunk(entries, 100))
+}
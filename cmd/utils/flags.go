@@ -52,6 +52,50 @@ var (
 		Name:  "disable-event-log",
 		Usage: "Discard event log output by smart contract execution",
 	}
+	PruneRetentionFlag = cli.UintFlag{
+		Name:  "prune-retention",
+		Usage: "Prune block bodies, transactions and events older than the recent `<count>` blocks. 0 keeps all blocks",
+		Value: uint(config.DEFAULT_PRUNE_RETENTION),
+	}
+	DBBackendFlag = cli.StringFlag{
+		Name:  "db-backend",
+		Usage: "Storage `<backend>` of ledger db. leveldb or badger. Existing db should be converted by migratedb command",
+		Value: config.DEFAULT_DB_BACKEND,
+	}
+	DBCacheSizeFlag = cli.UintFlag{
+		Name:  "db-cache",
+		Usage: "Block cache `<size>` of each ledger db in MB. 0 means backend default",
+		Value: config.DEFAULT_DB_CACHE_SIZE,
+	}
+	DBWriteBufferFlag = cli.UintFlag{
+		Name:  "db-write-buffer",
+		Usage: "Write buffer `<size>` of each ledger db in MB. 0 means backend default",
+		Value: config.DEFAULT_DB_WRITE_BUFFER,
+	}
+	DBOpenFilesFlag = cli.UintFlag{
+		Name:  "db-open-files",
+		Usage: "Max open `<files>` of each leveldb ledger db. 0 means derive from process limit",
+		Value: config.DEFAULT_DB_OPEN_FILES,
+	}
+	DBCompressionFlag = cli.StringFlag{
+		Name:  "db-compression",
+		Usage: "Block `<compression>` of ledger db. snappy or none",
+		Value: config.DEFAULT_DB_COMPRESSION,
+	}
+	ExecWorkersFlag = cli.UintFlag{
+		Name:  "exec-workers",
+		Usage: "Count of `<workers>` executing transactions of block in parallel. 0 means count of CPUs, 1 means sequential",
+		Value: config.DEFAULT_EXEC_WORKERS,
+	}
+	ReadOnlyFlag = cli.BoolFlag{
+		Name:  "readonly",
+		Usage: "Serve rpc, restful and websocket from the ledger in data dir used by another node, without p2p and consensus",
+	}
+	ReadOnlyRefreshFlag = cli.UintFlag{
+		Name:  "readonly-refresh",
+		Usage: "Interval `<seconds>` of catching up with the ledger of read only node. 0 means never",
+		Value: config.DEFAULT_READONLY_REFRESH_INTERVAL,
+	}
 	WalletFileFlag = cli.StringFlag{
 		Name:  "wallet,w",
 		Value: config.DEFAULT_WALLET_FILE_NAME,
@@ -83,6 +127,23 @@ var (
 		Usage: "Max transaction `<number>` in block",
 		Value: config.DEFAULT_MAX_TX_IN_BLOCK,
 	}
+	ConsensusSignerFlag = cli.StringFlag{
+		Name:  "consensus-signer",
+		Usage: "Consensus signer `<type>`. local=wallet account, sigsvr=remote sig server, softtoken=PKCS#11 style token process",
+		Value: config.DEFAULT_CONSENSUS_SIGNER,
+	}
+	ConsensusSignerAddressFlag = cli.StringFlag{
+		Name:  "consensus-signer-address",
+		Usage: "Sig server or token `<address>` of consensus signer, host:port or unix:<socket path>",
+	}
+	ConsensusSignerTokenFlag = cli.StringFlag{
+		Name:  "consensus-signer-token",
+		Usage: "Token `<file>` served by softtoken command",
+	}
+	ConsensusSignerGroupFlag = cli.StringFlag{
+		Name:  "consensus-signer-group",
+		Usage: "Unix `<group>` allowed to connect to the token socket besides the owner. Empty means owner only",
+	}
 	GasLimitFlag = cli.Uint64Flag{
 		Name:  "gaslimit",
 		Usage: "Min gas limit `<value>` of transaction to be accepted by tx pool.",
@@ -125,6 +186,21 @@ var (
 		Usage: "P2P network port `<number>`",
 		Value: config.DEFAULT_NODE_PORT,
 	}
+	ConsensusPortFlag = cli.UintFlag{
+		Name:  "consensus-port",
+		Usage: "Consensus overlay port `<number>` between validators, 0 to disable",
+		Value: config.DEFAULT_CONSENSUS_PORT,
+	}
+	TransportFlag = cli.StringFlag{
+		Name:  "transport",
+		Usage: "Transport `<name>` of p2p network port, tcp or quic",
+		Value: config.DEFAULT_TRANSPORT,
+	}
+	ConsensusTransportFlag = cli.StringFlag{
+		Name:  "consensus-transport",
+		Usage: "Transport `<name>` of consensus overlay port, tcp or quic",
+		Value: config.DEFAULT_TRANSPORT,
+	}
 	HttpInfoPortFlag = cli.UintFlag{
 		Name:  "httpinfo-port",
 		Usage: "The listening port of http server for viewing node information `<number>`",
@@ -145,6 +221,39 @@ var (
 		Usage: "Max connection `<number>` in bound for single ip",
 		Value: config.DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
 	}
+	P2PAuthFlag = cli.BoolFlag{
+		Name:  "p2p-auth",
+		Usage: "Authenticate peers by node keys and encrypt p2p sessions. Always on if reserved peers pin public keys.",
+	}
+	NodeKeyFileFlag = cli.StringFlag{
+		Name:  "nodekey",
+		Usage: "Node key `<file>` of p2p identity, default is nodekey under data dir",
+	}
+	DisableDiscoveryFlag = cli.BoolFlag{
+		Name:  "disable-discovery",
+		Usage: "Disable finding nodes by the routing table, only connect seeds and gossiped addresses.",
+	}
+	DisableCompressionFlag = cli.BoolFlag{
+		Name:  "disable-compression",
+		Usage: "Disable compressing blocks and headers sent to peers, and advertising compression to peers.",
+	}
+	NATFlag = cli.StringFlag{
+		Name:  "nat",
+		Usage: "NAT port mapping `<mechanism>` of none, any, upnp, pmp or pmp:<gateway ip>. Any maps by UPnP or NAT-PMP, whichever is found",
+		Value: config.DEFAULT_NAT,
+	}
+	PublicAddressFlag = cli.StringFlag{
+		Name:  "public-address",
+		Usage: "Public address `<ip:port>` advertised to peers, which is detected by NAT and peers if not set",
+	}
+	CheckpointsFlag = cli.StringFlag{
+		Name:  "checkpoints",
+		Usage: "Trusted block checkpoints `<height:hash,...>`. There are no built-in checkpoints of network",
+	}
+	CheckpointSyncFlag = cli.BoolFlag{
+		Name:  "checkpoint-sync",
+		Usage: "Skip verifying signatures of headers and blocks linked by hash to a checkpoint when syncing. Requires --checkpoints",
+	}
 	// RPC settings
 	RPCDisabledFlag = cli.BoolFlag{
 		Name:  "disable-rpc",
@@ -436,6 +545,10 @@ var (
 		Usage: "Rpc bind port `<number>`",
 		Value: config.DEFAULT_CLI_RPC_PORT,
 	}
+	CliUnixSocketFlag = cli.StringFlag{
+		Name:  "cliunixsocket",
+		Usage: "Rpc also listen on unix socket `<path>`, for consensus remote signer",
+	}
 	CliABIPathFlag = cli.StringFlag{
 		Name:  "abi",
 		Usage: "Abi `<file>` path",
@@ -463,6 +576,46 @@ var (
 		Usage: "Stop block height `<number>` to export",
 		Value: DEFAULT_EXPORT_HEIGHT,
 	}
+	StateSnapshotFlag = cli.BoolFlag{
+		Name:  "state-snapshot",
+		Usage: "Export or import a snapshot of the state db at current block height instead of blocks",
+	}
+	StateSnapshotCheckpointFlag = cli.StringFlag{
+		Name:  "snapshot-checkpoint",
+		Usage: "Trusted `<height:blockhash:statehash>` of the state snapshot to import, printed by the node exported it",
+	}
+	MigrateBackendFlag = cli.StringFlag{
+		Name:  "to",
+		Usage: "Target storage `<backend>` of migration. leveldb or badger",
+	}
+	RollbackHeightFlag = cli.UintFlag{
+		Name:  "height",
+		Usage: "Rollback ledger to block `<height>`",
+	}
+	VerifyNoReplayFlag = cli.BoolFlag{
+		Name:  "no-replay",
+		Usage: "Skip re-executing blocks to check write set hashes when verifying ledger",
+	}
+	CrawlMaxNodesFlag = cli.UintFlag{
+		Name:  "max-nodes",
+		Usage: "Max `<count>` of nodes crawled",
+		Value: DEFAULT_CRAWL_MAX_NODES,
+	}
+	CrawlTimeoutFlag = cli.UintFlag{
+		Name:  "crawl-timeout",
+		Usage: "Timeout `<seconds>` of fetching neighbors of a node",
+		Value: DEFAULT_CRAWL_TIMEOUT,
+	}
+	GraphFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Output `<format>` of network graph, dot or json",
+		Value: GRAPH_FORMAT_DOT,
+	}
+	ExportChunkBlocksFlag = cli.UintFlag{
+		Name:  "chunk-blocks",
+		Usage: "Count of `<blocks>` compressed in a chunk of export file",
+		Value: DEFAULT_EXPORT_CHUNK_BLOCKS,
+	}
 	ExportSpeedFlag = cli.StringFlag{
 		Name:  "export-speed",
 		Usage: "Export block speed `<level>` (h|m|l), h for high speed, m for middle speed and l for low speed",
@@ -0,0 +1,235 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/http/nodeinfo"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+const (
+	GRAPH_FORMAT_DOT  = "dot"
+	GRAPH_FORMAT_JSON = "json"
+
+	DEFAULT_CRAWL_MAX_NODES = 1000    //Max count of nodes crawled
+	DEFAULT_CRAWL_TIMEOUT   = 5       //Timeout of fetching neighbors of a node in second
+	MAX_CRAWL_RESP_SIZE     = 1 << 20 //Max size of the neighbors fetched from a node
+)
+
+//GetPeerInfos return the detail of neighbors from local rpc server of node
+func GetPeerInfos() ([]byte, error) {
+	data, tstErr := sendLocalRpcRequest("getpeerinfos", []interface{}{})
+	if tstErr != nil {
+		return nil, tstErr.Error
+	}
+	return data, nil
+}
+
+//GraphNode is a node found by crawling
+type GraphNode struct {
+	Id           string `json:"id"`
+	Addr         string `json:"addr"`
+	HttpInfoAddr string `json:"http_info_addr"`
+	Version      string `json:"version"`
+	Height       uint64 `json:"height"`
+	Crawled      bool   `json:"crawled"` //whether the neighbors of node are fetched
+	Error        string `json:"error,omitempty"`
+}
+
+//GraphEdge is a link between nodes, from the node dialed to the node accepted
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+//NetworkGraph is the network crawled from a node
+type NetworkGraph struct {
+	Root  string       `json:"root"`
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+//crawler walk the network by the neighbors served by the http info server of each node
+type crawler struct {
+	client    *http.Client
+	maxNodes  int
+	nodes     map[string]*GraphNode
+	edges     map[GraphEdge]bool
+	scheduled int //count of nodes crawled or to crawl
+}
+
+//CrawlNetwork crawl the network from the http info server at addr breadth first, until maxNodes nodes are crawled.
+//The nodes without http info server are in graph, but their neighbors are not known
+func CrawlNetwork(addr string, maxNodes int, timeout time.Duration) (*NetworkGraph, error) {
+	//a node never responding must not stall crawling
+	if timeout <= 0 {
+		timeout = DEFAULT_CRAWL_TIMEOUT * time.Second
+	}
+	c := &crawler{
+		client:    &http.Client{Timeout: timeout},
+		maxNodes:  maxNodes,
+		nodes:     make(map[string]*GraphNode),
+		edges:     make(map[GraphEdge]bool),
+		scheduled: 1,
+	}
+	root, err := c.fetch(addr)
+	if err != nil {
+		return nil, err
+	}
+	c.nodes[root.Id] = &GraphNode{Id: root.Id, HttpInfoAddr: addr}
+	level := c.visit(c.nodes[root.Id], root)
+	for len(level) > 0 {
+		topologies := make([]*nodeinfo.Topology, len(level))
+		errs := make([]error, len(level))
+		var wg sync.WaitGroup
+		for i, node := range level {
+			wg.Add(1)
+			go func(i int, node *GraphNode) {
+				defer wg.Done()
+				topologies[i], errs[i] = c.fetch(node.HttpInfoAddr)
+			}(i, node)
+		}
+		wg.Wait()
+
+		next := make([]*GraphNode, 0)
+		for i, node := range level {
+			switch {
+			case errs[i] != nil:
+				node.Error = errs[i].Error()
+			case topologies[i].Id != node.Id:
+				node.Error = fmt.Sprintf("http info server is of node %s", topologies[i].Id)
+			default:
+				next = append(next, c.visit(node, topologies[i])...)
+			}
+		}
+		level = next
+	}
+	return c.graph(root.Id), nil
+}
+
+//fetch the node with neighbors from http info server at addr
+func (this *crawler) fetch(addr string) (*nodeinfo.Topology, error) {
+	resp, err := this.client.Get("http://" + addr + "/neighbors")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch neighbors from %s:%s", addr, resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MAX_CRAWL_RESP_SIZE+1))
+	if err != nil {
+		return nil, fmt.Errorf("read neighbors from %s error:%s", addr, err)
+	}
+	if len(body) > MAX_CRAWL_RESP_SIZE {
+		return nil, fmt.Errorf("neighbors from %s exceed %d bytes", addr, MAX_CRAWL_RESP_SIZE)
+	}
+	topology := &nodeinfo.Topology{}
+	if err := json.Unmarshal(body, topology); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal neighbors from %s error:%s", addr, err)
+	}
+	return topology, nil
+}
+
+//visit record the neighbors of node, return the new nodes to crawl
+func (this *crawler) visit(node *GraphNode, topology *nodeinfo.Topology) []*GraphNode {
+	node.Crawled = true
+	node.Version = topology.Version
+	node.Height = uint64(topology.Height)
+
+	next := make([]*GraphNode, 0)
+	for _, ngb := range topology.Neighbors {
+		edge := GraphEdge{From: node.Id, To: ngb.Id}
+		if ngb.Direction == common.DIRECTION_INBOUND {
+			edge = GraphEdge{From: ngb.Id, To: node.Id}
+		}
+		this.edges[edge] = true
+
+		if _, ok := this.nodes[ngb.Id]; ok {
+			continue
+		}
+		n := &GraphNode{Id: ngb.Id, Addr: ngb.Addr, HttpInfoAddr: ngb.HttpInfoAddr, Height: ngb.Height}
+		this.nodes[ngb.Id] = n
+		if n.HttpInfoAddr != "" && this.scheduled < this.maxNodes {
+			this.scheduled++
+			next = append(next, n)
+		}
+	}
+	return next
+}
+
+//graph return the nodes and edges crawled sorted by id
+func (this *crawler) graph(root string) *NetworkGraph {
+	graph := &NetworkGraph{
+		Root:  root,
+		Nodes: make([]*GraphNode, 0, len(this.nodes)),
+		Edges: make([]*GraphEdge, 0, len(this.edges)),
+	}
+	for _, node := range this.nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for edge := range this.edges {
+		e := edge
+		graph.Edges = append(graph.Edges, &e)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Id < graph.Nodes[j].Id
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph
+}
+
+//Dot return the graph in graphviz dot language. The root is bold, the nodes not crawled are dashed, and the nodes
+//failed to crawl are red
+func (this *NetworkGraph) Dot() string {
+	var sb strings.Builder
+	sb.WriteString("digraph tesra {\n")
+	for _, node := range this.Nodes {
+		attrs := []string{fmt.Sprintf("label=\"%s\\n%s\\nheight %d\"", node.Id, node.Addr, node.Height)}
+		switch {
+		case node.Id == this.Root:
+			attrs = append(attrs, "style=bold")
+		case node.Error != "":
+			attrs = append(attrs, "color=red")
+		case !node.Crawled:
+			attrs = append(attrs, "style=dashed")
+		}
+		sb.WriteString(fmt.Sprintf("\t\"%s\" [%s];\n", node.Id, strings.Join(attrs, ", ")))
+	}
+	for _, edge := range this.Edges {
+		sb.WriteString(fmt.Sprintf("\t\"%s\" -> \"%s\";\n", edge.From, edge.To))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/http/nodeinfo"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveTopology(topology *nodeinfo.Topology) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(topology)
+	}))
+}
+
+func TestCrawlNetwork(t *testing.T) {
+	b := &nodeinfo.Topology{Id: "0xb", Height: 9}
+	serverB := serveTopology(b)
+	defer serverB.Close()
+	addrB := strings.TrimPrefix(serverB.URL, "http://")
+	a := &nodeinfo.Topology{Id: "0xa", Height: 10, Neighbors: []nodeinfo.TopologyNeighbor{
+		{Id: "0xb", Addr: "127.0.0.1:20338", Direction: common.DIRECTION_OUTBOUND, HttpInfoAddr: addrB},
+		{Id: "0xc", Addr: "127.0.0.1:20339", Direction: common.DIRECTION_INBOUND},
+		{Id: "0xd", Addr: "127.0.0.1:20340", Direction: common.DIRECTION_OUTBOUND, HttpInfoAddr: "127.0.0.1:1"},
+	}}
+	b.Neighbors = []nodeinfo.TopologyNeighbor{{Id: "0xa", Direction: common.DIRECTION_INBOUND}}
+	serverA := serveTopology(a)
+	defer serverA.Close()
+
+	graph, err := CrawlNetwork(strings.TrimPrefix(serverA.URL, "http://"), DEFAULT_CRAWL_MAX_NODES, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "0xa", graph.Root)
+	assert.Equal(t, 4, len(graph.Nodes))
+	assert.True(t, graph.Nodes[1].Crawled)
+	assert.Equal(t, uint64(9), graph.Nodes[1].Height)
+	assert.False(t, graph.Nodes[2].Crawled)
+	assert.NotEqual(t, "", graph.Nodes[3].Error)
+	assert.Equal(t, []*GraphEdge{{From: "0xa", To: "0xb"}, {From: "0xa", To: "0xd"}, {From: "0xc", To: "0xa"}},
+		graph.Edges)
+	assert.Contains(t, graph.Dot(), "\"0xc\" -> \"0xa\";")
+
+	graph, err = CrawlNetwork(strings.TrimPrefix(serverA.URL, "http://"), 1, time.Second)
+	assert.Nil(t, err)
+	assert.False(t, graph.Nodes[1].Crawled)
+}
+
+func TestCrawlNetworkRespTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"0xa","neighbors":[`))
+		w.Write([]byte(strings.Repeat(`{"id":"0xb"},`, MAX_CRAWL_RESP_SIZE/13)))
+		w.Write([]byte(`{"id":"0xb"}]}`))
+	}))
+	defer server.Close()
+
+	_, err := CrawlNetwork(strings.TrimPrefix(server.URL, "http://"), DEFAULT_CRAWL_MAX_NODES, 0)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "exceed")
+}
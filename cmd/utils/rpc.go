@@ -71,6 +71,15 @@ type JsonRpcResponse struct {
 }
 
 func sendRpcRequest(method string, params []interface{}) ([]byte, *TesranodeError) {
+	return sendRpcRequestTo(config.DefConfig.Rpc.HttpJsonPort, method, params)
+}
+
+//sendLocalRpcRequest send request to local rpc server, which serves admin methods
+func sendLocalRpcRequest(method string, params []interface{}) ([]byte, *TesranodeError) {
+	return sendRpcRequestTo(config.DefConfig.Rpc.HttpLocalPort, method, params)
+}
+
+func sendRpcRequestTo(port uint, method string, params []interface{}) ([]byte, *TesranodeError) {
 	rpcReq := &JsonRpcRequest{
 		Version: JSON_RPC_VERSION,
 		Id:      "cli",
@@ -82,7 +91,7 @@ func sendRpcRequest(method string, params []interface{}) ([]byte, *TesranodeErro
 		return nil, NewTesranodeError(fmt.Errorf("JsonRpcRequest json.Marshal error:%s", err))
 	}
 
-	addr := fmt.Sprintf("http://localhost:%d", config.DefConfig.Rpc.HttpJsonPort)
+	addr := fmt.Sprintf("http://localhost:%d", port)
 	resp, err := http.Post(addr, "application/json", strings.NewReader(string(data)))
 	if err != nil {
 		return nil, NewTesranodeError(err)
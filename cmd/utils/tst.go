@@ -300,6 +300,27 @@ func NewInvokeTransaction(gasPrice, gasLimit uint64, invokeCode []byte) *types.M
 	return tx
 }
 
+//NewBookkeeperTransaction return a transaction to add or remove a dbft/solo bookkeeper.
+//Issuer must be a current bookkeeper, and the transaction should be signed by a 2/3+1 multi-signature of the current
+//bookkeepers, see MultiSigTransaction
+func NewBookkeeperTransaction(gasPrice, gasLimit uint64, pubKey keypair.PublicKey, action payload.BookkeeperAction, issuer keypair.PublicKey) *types.MutableTransaction {
+	bookkeeperPayload := &payload.Bookkeeper{
+		PubKey: pubKey,
+		Action: action,
+		Cert:   []byte{},
+		Issuer: issuer,
+	}
+	tx := &types.MutableTransaction{
+		GasPrice: gasPrice,
+		GasLimit: gasLimit,
+		TxType:   types.Bookkeeper,
+		Nonce:    rand.Uint32(),
+		Payload:  bookkeeperPayload,
+		Sigs:     make([]types.Sig, 0, 0),
+	}
+	return tx
+}
+
 func SignTransaction(signer *account.Account, tx *types.MutableTransaction) error {
 	if tx.Payer == common.ADDRESS_EMPTY {
 		tx.Payer = signer.Address
@@ -25,6 +25,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/constants"
@@ -51,7 +55,7 @@ const (
 	DEFAULT_LOG_LEVEL                       = log.InfoLog
 	DEFAULT_MAX_LOG_SIZE                    = 100         //MByte
 	DEFAULT_NODE_PORT                       = uint(25766) //uint(20338)
-	DEFAULT_CONSENSUS_PORT                  = uint(25767) //uint(20339)
+	DEFAULT_CONSENSUS_PORT                  = uint(0)     //consensus overlay disabled, opt in by a port such as 25767
 	DEFAULT_RPC_PORT                        = uint(25768) //uint(20336)
 	DEFAULT_RPC_LOCAL_PORT                  = uint(25769) //uint(20337)
 	DEFAULT_REST_PORT                       = uint(25770) //uint(20334)
@@ -62,6 +66,8 @@ const (
 	DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP = uint(16)
 	DEFAULT_HTTP_INFO_PORT                  = uint(0)
 	DEFAULT_MAX_TX_IN_BLOCK                 = 60000
+	DEFAULT_CONSENSUS_SIGNER                = "local"
+	DEFAULT_SIGN_STATE_FILE                 = "consensus_sign_state.json"
 	DEFAULT_MAX_SYNC_HEADER                 = 500
 	DEFAULT_ENABLE_CONSENSUS                = true
 	DEFAULT_ENABLE_EVENT_LOG                = true
@@ -72,8 +78,33 @@ const (
 	DEFAULT_WASM_GAS_FACTOR                 = uint64(10)
 	DEFAULT_WASM_MAX_STEPCOUNT              = uint64(8000000)
 
-	DEFAULT_DATA_DIR      = "./Chain"
-	DEFAULT_RESERVED_FILE = "./peers.rsv"
+	DEFAULT_DATA_DIR        = "./Chain"
+	DEFAULT_RESERVED_FILE   = "./peers.rsv"
+	DEFAULT_NODE_KEY_FILE   = "nodekey"     //Node key of p2p identity, under data dir
+	DEFAULT_NODE_TABLE_FILE = "nodes.table" //Routing table of p2p discovery, under data dir
+	DEFAULT_BAN_LIST_FILE   = "peers.ban"   //Banned p2p peers, under data dir
+	DEFAULT_NAT             = "none"        //NAT port mapping disabled, opt in by any, upnp or pmp
+	DEFAULT_TRANSPORT       = "tcp"         //Transport of p2p listeners, tcp or quic
+
+	DEFAULT_PRUNE_RETENTION = uint32(0)    //keep all blocks
+	MIN_PRUNE_RETENTION     = uint32(1000) //min count of recent blocks kept by pruning node
+
+	DB_BACKEND_LEVELDB = "leveldb"
+	DB_BACKEND_BADGER  = "badger"
+
+	DB_COMPRESSION_SNAPPY = "snappy"
+	DB_COMPRESSION_NONE   = "none"
+
+	DEFAULT_DB_BACKEND      = DB_BACKEND_LEVELDB
+	DEFAULT_DB_CACHE_SIZE   = 0 //MB, 0 means the backend default
+	DEFAULT_DB_WRITE_BUFFER = 0 //MB, 0 means the backend default
+	DEFAULT_DB_OPEN_FILES   = 0 //0 means derive from the process fd limit
+	DEFAULT_DB_COMPRESSION  = DB_COMPRESSION_SNAPPY
+
+	DEFAULT_EXEC_WORKERS = 1 //sequential, parallel execution is opt-in
+
+	DEFAULT_READONLY_REFRESH_INTERVAL = 10         //seconds
+	READONLY_CHECKPOINT_DIR           = "readonly" //Checkpoints of read only node, under data dir
 )
 
 const (
@@ -119,6 +150,58 @@ func GetStateHashCheckHeight(id uint32) uint32 {
 	return STATE_HASH_CHECK_HEIGHT[id]
 }
 
+//Checkpoint is a trusted block hash at height, the chain synced must pass through it
+type Checkpoint struct {
+	Height uint32
+	Hash   common.Uint256
+}
+
+//GetCheckpoints return the configured checkpoints sorted by height. There is no built-in checkpoint of network,
+//only the checkpoints passed by --checkpoints are trusted
+func GetCheckpoints() []*Checkpoint {
+	checkpoints := make([]*Checkpoint, 0, len(DefConfig.P2PNode.Checkpoints))
+	checkpoints = append(checkpoints, DefConfig.P2PNode.Checkpoints...)
+	sort.SliceStable(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Height < checkpoints[j].Height
+	})
+	return checkpoints
+}
+
+//ParseCheckpoint parse checkpoint of format height:hash
+func ParseCheckpoint(s string) (*Checkpoint, error) {
+	items := strings.Split(strings.TrimSpace(s), ":")
+	if len(items) != 2 {
+		return nil, fmt.Errorf("invalid checkpoint %s, should be height:hash", s)
+	}
+	height, err := strconv.ParseUint(items[0], 10, 32)
+	if err != nil || height == 0 {
+		return nil, fmt.Errorf("invalid checkpoint height %s", items[0])
+	}
+	hash, err := common.Uint256FromHexString(items[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint hash %s:%s", items[1], err)
+	}
+	return &Checkpoint{Height: uint32(height), Hash: hash}, nil
+}
+
+//ParsePublicAddress parse public address of format ip:port or ip, in which case the port is defaultPort
+func ParsePublicAddress(s string, defaultPort uint) (string, error) {
+	s = strings.TrimSpace(s)
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		host, port = s, strconv.Itoa(int(defaultPort))
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return "", fmt.Errorf("invalid public address ip %s", host)
+	}
+	portNum, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || portNum == 0 {
+		return "", fmt.Errorf("invalid public address port %s", port)
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
 var OPCODE_HASKEY_ENABLE_HEIGHT = map[uint32]uint32{
 	NETWORK_ID_MAIN_NET:    constants.OPCODE_HEIGHT_UPDATE_FIRST_MAINNET, //Network main
 	NETWORK_ID_SCORPIO_NET: constants.OPCODE_HEIGHT_UPDATE_FIRST_SCORPIO, //Network scorpio
@@ -347,11 +430,38 @@ var MainNetConfig = &GenesisConfig{
 var DefConfig = NewTesranodeConfig()
 
 type GenesisConfig struct {
-	SeedList      []string
+	SeedList       []string
+	ConsensusType  string
+	ConsensusForks []*ConsensusForkConfig `json:",omitempty"`
+	VBFT           *VBFTConfig
+	DBFT           *DBFTConfig
+	SOLO           *SOLOConfig
+}
+
+//ConsensusForkConfig switch the consensus engine from the block at Height
+type ConsensusForkConfig struct {
+	Height        uint32
 	ConsensusType string
-	VBFT          *VBFTConfig
-	DBFT          *DBFTConfig
-	SOLO          *SOLOConfig
+}
+
+//GetConsensusType return the consensus type of the block at height
+func (this *GenesisConfig) GetConsensusType(height uint32) string {
+	consensusType := this.ConsensusType
+	for _, fork := range this.ConsensusForks {
+		if fork.Height > height {
+			break
+		}
+		consensusType = fork.ConsensusType
+	}
+	return strings.ToLower(consensusType)
+}
+
+//IsConsensusSwitchHeight return whether the block at height is the first block of a new consensus engine
+func (this *GenesisConfig) IsConsensusSwitchHeight(height uint32) bool {
+	if height == 0 {
+		return false
+	}
+	return this.GetConsensusType(height) != this.GetConsensusType(height-1)
 }
 
 func NewGenesisConfig() *GenesisConfig {
@@ -381,8 +491,13 @@ type VBFTConfig struct {
 	VrfValue             string               `json:"vrf_value"`
 	VrfProof             string               `json:"vrf_proof"`
 	Peers                []*VBFTPeerStakeInfo `json:"peers"`
+	BlockTime            uint32               `json:"block_time,omitempty"`    // target block time in ms, 0 for default
+	MaxIdleTime          uint32               `json:"max_idle_time,omitempty"` // max interval of empty blocks in ms, 0 for not suppressing empty blocks
 }
 
+//VBFT_CONFIG_EXT_VERSION flags the extended fields of VBFTConfig
+const VBFT_CONFIG_EXT_VERSION byte = 1
+
 func (self *VBFTConfig) Serialization(sink *common.ZeroCopySink) error {
 	sink.WriteUint32(self.N)
 	sink.WriteUint32(self.C)
@@ -402,6 +517,13 @@ func (self *VBFTConfig) Serialization(sink *common.ZeroCopySink) error {
 			return err
 		}
 	}
+	//extended fields behind the version byte, nothing is written when they are not set,
+	//so genesis config without block time keeps the same encoding
+	if self.BlockTime != 0 || self.MaxIdleTime != 0 {
+		sink.WriteByte(VBFT_CONFIG_EXT_VERSION)
+		sink.WriteUint32(self.BlockTime)
+		sink.WriteUint32(self.MaxIdleTime)
+	}
 
 	return nil
 }
@@ -480,6 +602,24 @@ func (this *VBFTConfig) Deserialization(source *common.ZeroCopySource) error {
 		}
 		peers = append(peers, peer)
 	}
+	var blockTime, maxIdleTime uint32
+	if source.Len() > 0 {
+		version, eof := source.NextByte()
+		if eof {
+			return errors.NewDetailErr(io.ErrUnexpectedEOF, errors.ErrNoCode, "serialization.ReadUint8, deserialize version error!")
+		}
+		if version != VBFT_CONFIG_EXT_VERSION {
+			return fmt.Errorf("unsupported vbft config version %d", version)
+		}
+		blockTime, eof = source.NextUint32()
+		if eof {
+			return errors.NewDetailErr(io.ErrUnexpectedEOF, errors.ErrNoCode, "serialization.ReadUint32, deserialize blockTime error!")
+		}
+		maxIdleTime, eof = source.NextUint32()
+		if eof {
+			return errors.NewDetailErr(io.ErrUnexpectedEOF, errors.ErrNoCode, "serialization.ReadUint32, deserialize maxIdleTime error!")
+		}
+	}
 	this.N = n
 	this.C = c
 	this.K = k
@@ -493,6 +633,8 @@ func (this *VBFTConfig) Deserialization(source *common.ZeroCopySource) error {
 	this.VrfValue = vrfValue
 	this.VrfProof = vrfProof
 	this.Peers = peers
+	this.BlockTime = blockTime
+	this.MaxIdleTime = maxIdleTime
 	return nil
 }
 
@@ -547,11 +689,13 @@ func (this *VBFTPeerStakeInfo) Deserialization(source *common.ZeroCopySource) er
 type DBFTConfig struct {
 	GenBlockTime uint
 	Bookkeepers  []string
+	EpochPeriod  uint32 `json:",omitempty"` // block count of a bookkeeper epoch, 0 means the bookkeepers never change
 }
 
 type SOLOConfig struct {
 	GenBlockTime uint
 	Bookkeepers  []string
+	EpochPeriod  uint32 `json:",omitempty"` // block count of a bookkeeper epoch, 0 means the bookkeeper never changes. Adding a bookkeeper replaces it
 }
 
 type CommonConfig struct {
@@ -562,11 +706,20 @@ type CommonConfig struct {
 	GasLimit       uint64
 	GasPrice       uint64
 	DataDir        string
+	PruneRetention uint32 //count of recent blocks whose bodies and events are kept, 0 means archive node
+	DBBackend      string //storage backend of ledger, leveldb or badger
+	DBCacheSize    uint   //block cache size of storage backend in MB
+	DBWriteBuffer  uint   //write buffer size of storage backend in MB
+	DBOpenFiles    uint   //max open files of storage backend
+	DBCompression  string //block compression of storage backend, snappy or none
+	ExecWorkers    uint   //count of workers executing transactions of block in parallel, 1 means sequential
 }
 
 type ConsensusConfig struct {
 	EnableConsensus bool
 	MaxTxInBlock    uint
+	SignerType      string
+	SignerAddress   string
 }
 
 type P2PRsvConfig struct {
@@ -581,6 +734,11 @@ type P2PNodeConfig struct {
 	NetworkId                 uint32
 	NetworkName               string
 	NodePort                  uint
+	ConsensusPort             uint   //port of consensus overlay between validators, 0 means disabled
+	Transport                 string //transport of sync port, tcp or quic
+	ConsensusTransport        string //transport of consensus port, tcp or quic
+	NAT                       string //NAT port mapping of none, any, upnp, pmp or pmp:<gateway ip>
+	PublicAddress             string //public ip:port advertised to peers, detected by NAT and peers if empty
 	IsTLS                     bool
 	CertPath                  string
 	KeyPath                   string
@@ -590,6 +748,14 @@ type P2PNodeConfig struct {
 	MaxConnInBound            uint
 	MaxConnOutBound           uint
 	MaxConnInBoundForSingleIP uint
+	AuthEnabled               bool          //authenticate peers by node keys and encrypt p2p sessions
+	NodeKeyPath               string        //file of node key, empty means a new node key each start
+	EnableDiscovery           bool          //find nodes by kademlia lookups over p2p links
+	EnableCompression         bool          //compress blocks and headers sent to peers supporting compression
+	NodeTablePath             string        //file of persisted routing table, empty means not persisted
+	BanListPath               string        //file of persisted banned peers, empty means not persisted
+	Checkpoints               []*Checkpoint //trusted checkpoints, the only trust anchors of checkpoint sync
+	CheckpointSync            bool          //skip verifying signatures of headers and blocks below checkpoints
 }
 
 type RpcConfig struct {
@@ -632,10 +798,18 @@ func NewTesranodeConfig() *TesranodeConfig {
 			SystemFee:      make(map[string]int64),
 			GasLimit:       DEFAULT_GAS_LIMIT,
 			DataDir:        DEFAULT_DATA_DIR,
+			PruneRetention: DEFAULT_PRUNE_RETENTION,
+			DBBackend:      DEFAULT_DB_BACKEND,
+			DBCacheSize:    DEFAULT_DB_CACHE_SIZE,
+			DBWriteBuffer:  DEFAULT_DB_WRITE_BUFFER,
+			DBOpenFiles:    DEFAULT_DB_OPEN_FILES,
+			DBCompression:  DEFAULT_DB_COMPRESSION,
+			ExecWorkers:    DEFAULT_EXEC_WORKERS,
 		},
 		Consensus: &ConsensusConfig{
 			EnableConsensus: true,
 			MaxTxInBlock:    DEFAULT_MAX_TX_IN_BLOCK,
+			SignerType:      DEFAULT_CONSENSUS_SIGNER,
 		},
 		P2PNode: &P2PNodeConfig{
 			ReservedCfg:               &P2PRsvConfig{},
@@ -644,6 +818,10 @@ func NewTesranodeConfig() *TesranodeConfig {
 			NetworkName:               GetNetworkName(NETWORK_ID_MAIN_NET),
 			NetworkMagic:              GetNetworkMagic(NETWORK_ID_MAIN_NET),
 			NodePort:                  DEFAULT_NODE_PORT,
+			ConsensusPort:             DEFAULT_CONSENSUS_PORT,
+			Transport:                 DEFAULT_TRANSPORT,
+			ConsensusTransport:        DEFAULT_TRANSPORT,
+			NAT:                       DEFAULT_NAT,
 			IsTLS:                     false,
 			CertPath:                  "",
 			KeyPath:                   "",
@@ -653,6 +831,8 @@ func NewTesranodeConfig() *TesranodeConfig {
 			MaxConnInBound:            DEFAULT_MAX_CONN_IN_BOUND,
 			MaxConnOutBound:           DEFAULT_MAX_CONN_OUT_BOUND,
 			MaxConnInBoundForSingleIP: DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
+			EnableDiscovery:           true,
+			EnableCompression:         true,
 		},
 		Rpc: &RpcConfig{
 			EnableHttpJsonRpc: true,
@@ -671,8 +851,13 @@ func NewTesranodeConfig() *TesranodeConfig {
 }
 
 func (this *TesranodeConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
+	return this.GetBookkeepersOfConsensus(this.Genesis.ConsensusType)
+}
+
+//GetBookkeepersOfConsensus return the configured bookkeepers of consensus type
+func (this *TesranodeConfig) GetBookkeepersOfConsensus(consensusType string) ([]keypair.PublicKey, error) {
 	var bookKeepers []string
-	switch this.Genesis.ConsensusType {
+	switch consensusType {
 	case CONSENSUS_TYPE_VBFT:
 		for _, peer := range this.Genesis.VBFT.Peers {
 			bookKeepers = append(bookKeepers, peer.PeerPubkey)
@@ -682,7 +867,7 @@ func (this *TesranodeConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 	case CONSENSUS_TYPE_SOLO:
 		bookKeepers = this.Genesis.SOLO.Bookkeepers
 	default:
-		return nil, fmt.Errorf("Does not support %s consensus", this.Genesis.ConsensusType)
+		return nil, fmt.Errorf("Does not support %s consensus", consensusType)
 	}
 
 	pubKeys := make([]keypair.PublicKey, 0, len(bookKeepers))
@@ -698,6 +883,18 @@ func (this *TesranodeConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 	return pubKeys, nil
 }
 
+//GetBookkeeperEpochPeriod return the epoch period at which bookkeeper changes take effect for the block at height.
+//Only dbft and solo consensus support bookkeeper change by transaction, 0 means disabled
+func (this *TesranodeConfig) GetBookkeeperEpochPeriod(height uint32) uint32 {
+	switch this.Genesis.GetConsensusType(height) {
+	case CONSENSUS_TYPE_DBFT:
+		return this.Genesis.DBFT.EpochPeriod
+	case CONSENSUS_TYPE_SOLO:
+		return this.Genesis.SOLO.EpochPeriod
+	}
+	return 0
+}
+
 func (this *TesranodeConfig) GetDefaultNetworkId() (uint32, error) {
 	defaultNetworkId, err := this.getDefNetworkIDFromGenesisConfig(this.Genesis)
 	if err != nil {
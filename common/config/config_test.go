@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetConsensusType(t *testing.T) {
+	genesis := NewGenesisConfig()
+	genesis.ConsensusType = CONSENSUS_TYPE_SOLO
+	assert.Equal(t, CONSENSUS_TYPE_SOLO, genesis.GetConsensusType(100))
+	assert.False(t, genesis.IsConsensusSwitchHeight(100))
+
+	genesis.ConsensusForks = []*ConsensusForkConfig{
+		{Height: 100, ConsensusType: CONSENSUS_TYPE_DBFT},
+		{Height: 200, ConsensusType: "VBFT"},
+	}
+	assert.Equal(t, CONSENSUS_TYPE_SOLO, genesis.GetConsensusType(0))
+	assert.Equal(t, CONSENSUS_TYPE_SOLO, genesis.GetConsensusType(99))
+	assert.Equal(t, CONSENSUS_TYPE_DBFT, genesis.GetConsensusType(100))
+	assert.Equal(t, CONSENSUS_TYPE_DBFT, genesis.GetConsensusType(199))
+	assert.Equal(t, CONSENSUS_TYPE_VBFT, genesis.GetConsensusType(200))
+	assert.Equal(t, CONSENSUS_TYPE_VBFT, genesis.GetConsensusType(1000))
+
+	assert.False(t, genesis.IsConsensusSwitchHeight(0))
+	assert.False(t, genesis.IsConsensusSwitchHeight(99))
+	assert.True(t, genesis.IsConsensusSwitchHeight(100))
+	assert.True(t, genesis.IsConsensusSwitchHeight(200))
+	assert.False(t, genesis.IsConsensusSwitchHeight(201))
+}
+
+func TestVBFTConfigBlockTime(t *testing.T) {
+	cfg := &VBFTConfig{N: 7, C: 2, K: 7, L: 112, BlockMsgDelay: 10000, HashMsgDelay: 10000,
+		PeerHandshakeTimeout: 10, MaxBlockChangeView: 3000, AdminTstID: "did:tst:test"}
+	sink := common.NewZeroCopySink(nil)
+	assert.Nil(t, cfg.Serialization(sink))
+	legacy := sink.Bytes()
+
+	cfg.BlockTime = 5000
+	cfg.MaxIdleTime = 60000
+	sink = common.NewZeroCopySink(nil)
+	assert.Nil(t, cfg.Serialization(sink))
+	decoded := new(VBFTConfig)
+	assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, uint32(5000), decoded.BlockTime)
+	assert.Equal(t, uint32(60000), decoded.MaxIdleTime)
+
+	decoded = new(VBFTConfig)
+	assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(legacy)))
+	assert.Equal(t, uint32(0), decoded.BlockTime)
+	assert.Equal(t, uint32(0), decoded.MaxIdleTime)
+
+	// unknown version of extended fields
+	decoded = new(VBFTConfig)
+	assert.NotNil(t, decoded.Deserialization(common.NewZeroCopySource(append(legacy, 2, 0, 0, 0, 0, 0, 0, 0, 0))))
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	hash := common.Uint256{1, 2, 3}
+	checkpoint, err := ParseCheckpoint("1000:" + hash.ToHexString())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1000), checkpoint.Height)
+	assert.Equal(t, hash, checkpoint.Hash)
+
+	_, err = ParseCheckpoint("1000")
+	assert.NotNil(t, err)
+	_, err = ParseCheckpoint("0:" + hash.ToHexString())
+	assert.NotNil(t, err)
+	_, err = ParseCheckpoint("1000:abc")
+	assert.NotNil(t, err)
+}
+
+func TestGetCheckpoints(t *testing.T) {
+	defer func(checkpoints []*Checkpoint) { DefConfig.P2PNode.Checkpoints = checkpoints }(DefConfig.P2PNode.Checkpoints)
+
+	DefConfig.P2PNode.Checkpoints = nil
+	assert.Equal(t, 0, len(GetCheckpoints()))
+
+	DefConfig.P2PNode.Checkpoints = []*Checkpoint{{Height: 2000}, {Height: 1000}}
+	checkpoints := GetCheckpoints()
+	assert.Equal(t, uint32(1000), checkpoints[0].Height)
+	assert.Equal(t, uint32(2000), checkpoints[1].Height)
+	assert.Equal(t, uint32(2000), DefConfig.P2PNode.Checkpoints[0].Height)
+}
+
+func TestParsePublicAddress(t *testing.T) {
+	addr, err := ParsePublicAddress("8.8.8.8:30000", 25766)
+	assert.Nil(t, err)
+	assert.Equal(t, "8.8.8.8:30000", addr)
+	addr, err = ParsePublicAddress("8.8.8.8", 25766)
+	assert.Nil(t, err)
+	assert.Equal(t, "8.8.8.8:25766", addr)
+
+	_, err = ParsePublicAddress("example.com:30000", 25766)
+	assert.NotNil(t, err)
+	_, err = ParsePublicAddress("0.0.0.0", 25766)
+	assert.NotNil(t, err)
+	_, err = ParsePublicAddress("8.8.8.8:0", 25766)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := "test_unix.sock"
+	listener, err := ListenUnix(path, -1)
+	assert.Nil(t, err)
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Write([]byte{1})
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	assert.Nil(t, err)
+	buf := make([]byte, 1)
+	_, err = conn.Read(buf)
+	assert.Nil(t, err)
+	conn.Close()
+
+	listener.Close()
+	assert.False(t, FileExisted(path))
+}
+
+//TestUnixDialHelper dial the socket of env UNIX_DIAL_PATH, run in a process of another user by TestListenUnixGroup
+func TestUnixDialHelper(t *testing.T) {
+	path := os.Getenv("UNIX_DIAL_PATH")
+	if path == "" {
+		t.Skip("run by TestListenUnixGroup")
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestListenUnixGroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unix_listener")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.Chmod(dir, 0755))
+	path := filepath.Join(dir, "test_unix.sock")
+	gid := os.Getgid()
+	listener, err := ListenUnix(path, gid)
+	assert.Nil(t, err)
+	defer listener.Close()
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+	assert.Equal(t, uint32(gid), info.Sys().(*syscall.Stat_t).Gid)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if os.Getuid() != 0 {
+		t.Skip("dialing as another user requires root")
+	}
+	//another user connects only in the group of socket
+	bin, err := ioutil.ReadFile(os.Args[0])
+	assert.Nil(t, err)
+	helper := filepath.Join(dir, "dial.test")
+	assert.Nil(t, ioutil.WriteFile(helper, bin, 0755))
+	dial := func(gid int) error {
+		cmd := exec.Command(helper, "-test.run=TestUnixDialHelper")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "UNIX_DIAL_PATH="+path)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: 65534, Gid: uint32(gid)}}
+		return cmd.Run()
+	}
+	assert.Nil(t, dial(gid))
+	assert.NotNil(t, dial(65534))
+}
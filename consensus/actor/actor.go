@@ -79,6 +79,13 @@ func (self *P2PActor) Transmit(target uint64, msg ptypes.Message) {
 	})
 }
 
+//SetValidators update the validators allowed in consensus overlay of p2p, by public key ids
+func (self *P2PActor) SetValidators(validators []string) {
+	self.P2P.Tell(&netActor.SetValidatorsReq{
+		Validators: validators,
+	})
+}
+
 type LedgerActor struct {
 	Ledger *actor.PID
 }
@@ -20,9 +20,10 @@ package consensus
 
 import (
 	"github.com/TesraSupernet/tesraevent/actor"
-	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/consensus/dbft"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/consensus/solo"
 	"github.com/TesraSupernet/Tesra/consensus/vbft"
 )
@@ -39,7 +40,17 @@ const (
 	CONSENSUS_VBFT = "vbft"
 )
 
-func NewConsensusService(consensusType string, account *account.Account, txpool *actor.PID, ledger *actor.PID, p2p *actor.PID) (ConsensusService, error) {
+func NewConsensusService(consensusType string, consensusSigner *signer.ConsensusSigner, txpool *actor.PID, ledger *actor.PID, p2p *actor.PID) (ConsensusService, error) {
+	if len(config.DefConfig.Genesis.ConsensusForks) != 0 {
+		log.Infof("ConsensusType:%s with %d consensus forks", consensusType, len(config.DefConfig.Genesis.ConsensusForks))
+		return NewSwitchService(consensusSigner, txpool, p2p)
+	}
+	consensus, err := newConsensusEngine(consensusType, consensusSigner, txpool, p2p)
+	log.Infof("ConsensusType:%s", consensusType)
+	return consensus, err
+}
+
+func newConsensusEngine(consensusType string, consensusSigner *signer.ConsensusSigner, txpool *actor.PID, p2p *actor.PID) (ConsensusService, error) {
 	if consensusType == "" {
 		consensusType = CONSENSUS_DBFT
 	}
@@ -47,12 +58,11 @@ func NewConsensusService(consensusType string, account *account.Account, txpool
 	var err error
 	switch consensusType {
 	case CONSENSUS_DBFT:
-		consensus, err = dbft.NewDbftService(account, txpool, p2p)
+		consensus, err = dbft.NewDbftService(consensusSigner, txpool, p2p)
 	case CONSENSUS_SOLO:
-		consensus, err = solo.NewSoloService(account, txpool)
+		consensus, err = solo.NewSoloService(consensusSigner, txpool)
 	case CONSENSUS_VBFT:
-		consensus, err = vbft.NewVbftServer(account, txpool, p2p)
+		consensus, err = vbft.NewVbftServer(consensusSigner, txpool, p2p)
 	}
-	log.Infof("ConsensusType:%s", consensusType)
 	return consensus, err
 }
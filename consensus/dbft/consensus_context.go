@@ -22,12 +22,12 @@ import (
 	"fmt"
 
 	"github.com/TesraSupernet/tesracrypto/keypair"
-	"github.com/TesraSupernet/Tesra/account"
 	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
+	vconfig "github.com/TesraSupernet/Tesra/consensus/vbft/config"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/types"
-	"github.com/TesraSupernet/Tesra/core/vote"
 	msg "github.com/TesraSupernet/Tesra/p2pserver/message/types"
 )
 
@@ -101,6 +101,14 @@ func (ctx *ConsensusContext) MakeHeader() *types.Block {
 		}
 		txRoot := common.ComputeMerkleRoot(txHash)
 		blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(ctx.Height, []common.Uint256{txRoot})
+		var consensusPayload []byte
+		if config.DefConfig.Genesis.GetConsensusType(ctx.Height+1) == config.CONSENSUS_TYPE_VBFT {
+			var err error
+			consensusPayload, err = vconfig.SwitchConsensusPayload(ctx.PrevHash, ctx.Height)
+			if err != nil {
+				log.Errorf("[ConsensusContext] SwitchConsensusPayload failed: %s", err)
+			}
+		}
 		header := &types.Header{
 			Version:          ContextVersion,
 			PrevBlockHash:    ctx.PrevHash,
@@ -110,6 +118,7 @@ func (ctx *ConsensusContext) MakeHeader() *types.Block {
 			Height:           ctx.Height,
 			ConsensusData:    ctx.Nonce,
 			NextBookkeeper:   ctx.NextBookkeeper,
+			ConsensusPayload: consensusPayload,
 		}
 		ctx.header = &types.Block{
 			Header:       header,
@@ -189,17 +198,18 @@ func (ctx *ConsensusContext) GetStateDetail() string {
 
 }
 
-func (ctx *ConsensusContext) Reset(bkAccount *account.Account) {
+func (ctx *ConsensusContext) Reset(owner keypair.PublicKey) {
 	preHash := ledger.DefLedger.GetCurrentBlockHash()
 	height := ledger.DefLedger.GetCurrentBlockHeight()
 	header := ctx.MakeHeader()
 
 	if height != ctx.Height || header == nil || header.Hash() != preHash || len(ctx.NextBookkeepers) == 0 {
 		log.Info("[ConsensusContext] Calculate Bookkeepers from db")
-		var err error
-		ctx.Bookkeepers, err = vote.GetValidators([]*types.Transaction{})
+		bookkeeperState, err := ledger.DefLedger.GetBookkeeperState()
 		if err != nil {
-			log.Error("[ConsensusContext] GetNextBookkeeper failed", err)
+			log.Error("[ConsensusContext] GetBookkeeperState failed", err)
+		} else {
+			ctx.Bookkeepers = bookkeeperState.CurrBookkeeper
 		}
 	} else {
 		ctx.Bookkeepers = ctx.NextBookkeepers
@@ -220,7 +230,7 @@ func (ctx *ConsensusContext) Reset(bkAccount *account.Account) {
 
 	log.Debugf("bookkeepers number: %d", bookkeeperLen)
 	for i := 0; i < bookkeeperLen; i++ {
-		if keypair.ComparePublicKey(bkAccount.PublicKey, ctx.Bookkeepers[i]) {
+		if keypair.ComparePublicKey(owner, ctx.Bookkeepers[i]) {
 			log.Debugf("this node is bookkeeper %d", i)
 			ctx.BookkeeperIndex = i
 			ctx.Owner = ctx.Bookkeepers[i]
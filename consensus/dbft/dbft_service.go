@@ -23,12 +23,13 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/TesraSupernet/tesracrypto/keypair"
 	"github.com/TesraSupernet/tesraevent/actor"
-	"github.com/TesraSupernet/Tesra/account"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	actorTypes "github.com/TesraSupernet/Tesra/consensus/actor"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/core/genesis"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/signature"
@@ -42,7 +43,7 @@ import (
 
 type DbftService struct {
 	context           ConsensusContext
-	Account           *account.Account
+	Signer            *signer.ConsensusSigner
 	timer             *time.Timer
 	timerHeight       uint32
 	timeView          byte
@@ -57,9 +58,9 @@ type DbftService struct {
 	sub *events.ActorSubscriber
 }
 
-func NewDbftService(bkAccount *account.Account, txpool, p2p *actor.PID) (*DbftService, error) {
+func NewDbftService(bkSigner *signer.ConsensusSigner, txpool, p2p *actor.PID) (*DbftService, error) {
 	service := &DbftService{
-		Account:       bkAccount,
+		Signer:        bkSigner,
 		timer:         time.NewTimer(time.Second * 15),
 		started:       false,
 		ledger:        ledger.DefLedger,
@@ -268,7 +269,7 @@ func (ds *DbftService) InitializeConsensus(viewNum byte) error {
 	log.Debug("[InitializeConsensus] viewNum: ", viewNum)
 
 	if viewNum == 0 {
-		ds.context.Reset(ds.Account)
+		ds.context.Reset(ds.Signer.PublicKey())
 	} else {
 		if ds.context.State.HasFlag(BlockGenerated) {
 			return nil
@@ -281,6 +282,11 @@ func (ds *DbftService) InitializeConsensus(viewNum byte) error {
 		return nil
 	}
 
+	if config.DefConfig.Genesis.GetConsensusType(ds.context.Height) != config.CONSENSUS_TYPE_DBFT {
+		log.Infof("block %d is not generated by dbft", ds.context.Height)
+		return nil
+	}
+
 	if ds.context.BookkeeperIndex == int(ds.context.PrimaryIndex) {
 
 		//primary peer
@@ -434,6 +440,19 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 	ds.context.Signatures = make([][]byte, len(ds.context.Bookkeepers))
 	ds.context.Signatures[payload.BookkeeperIndex] = message.Signature
 
+	blockLimit, err := ds.ledger.GetBlockLimit()
+	if err != nil {
+		log.Error("PrepareRequestReceived GetBlockLimit failed", err)
+		ds.context = backupContext
+		return
+	}
+	if err := blockLimit.CheckTransactions(ds.context.Transactions); err != nil {
+		log.Error("PrepareRequestReceived block limit verification failed, will not sent Prepare Response", err)
+		ds.context = backupContext
+		ds.RequestChangeView()
+		return
+	}
+
 	if len(ds.context.Transactions) > 0 {
 		height := ds.context.Height - 1
 		start, end := ds.incrValidator.BlockRange()
@@ -464,7 +483,7 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 		}
 	}
 
-	ds.context.NextBookkeepers, err = vote.GetValidators(ds.context.Transactions)
+	ds.context.NextBookkeepers, err = ds.getValidators(ds.context.Transactions)
 	if err != nil {
 		ds.context = backupContext
 		log.Error("[PrepareRequestReceived] GetValidators failed")
@@ -492,7 +511,7 @@ func (ds *DbftService) PrepareRequestReceived(payload *p2pmsg.ConsensusPayload,
 		return
 	}
 
-	sig, err := signature.Sign(ds.Account, blockHash[:])
+	sig, err := ds.Signer.SignVote(signer.STEP_BLOCK, ds.context.Height, uint32(ds.context.ViewNumber), blockHash[:])
 	if err != nil {
 		log.Error("[DbftService] signing failed")
 		return
@@ -584,6 +603,15 @@ func (ds *DbftService) BlockSignaturesReceived(payload *p2pmsg.ConsensusPayload,
 	log.Info("BlockSignatures finished")
 }
 
+//getValidators return the bookkeepers of the block following the block in consensus
+func (ds *DbftService) getValidators(txs []*types.Transaction) ([]keypair.PublicKey, error) {
+	bookkeeperState, err := ds.ledger.GetBookkeeperState()
+	if err != nil {
+		return nil, err
+	}
+	return vote.GetValidators(bookkeeperState, ds.context.Height, txs)
+}
+
 func (ds *DbftService) RefreshPolicy() {
 }
 
@@ -610,7 +638,7 @@ func (ds *DbftService) RequestChangeView() {
 func (ds *DbftService) SignAndRelay(payload *p2pmsg.ConsensusPayload) {
 	sink := common.NewZeroCopySink(nil)
 	payload.SerializationUnsigned(sink)
-	payload.Signature, _ = signature.Sign(ds.Account, sink.Bytes())
+	payload.Signature, _ = ds.Signer.SignMessage(sink.Bytes())
 
 	ds.p2p.Broadcast(payload)
 }
@@ -684,10 +712,16 @@ func (ds *DbftService) Timeout() {
 					transactions = append(transactions, txEntry.Tx)
 				}
 			}
+			blockLimit, err := ds.ledger.GetBlockLimit()
+			if err != nil {
+				log.Error("[Timeout] GetBlockLimit failed", err.Error())
+				return
+			}
+			transactions = blockLimit.SelectTransactions(nil, transactions)
 
 			ds.context.Transactions = transactions
 
-			ds.context.NextBookkeepers, err = vote.GetValidators(ds.context.Transactions)
+			ds.context.NextBookkeepers, err = ds.getValidators(ds.context.Transactions)
 			if err != nil {
 				log.Error("[Timeout] GetValidators failed", err.Error())
 				return
@@ -701,7 +735,11 @@ func (ds *DbftService) Timeout() {
 			//build block and sign
 			block := ds.context.MakeHeader()
 			blockHash := block.Hash()
-			ds.context.Signatures[ds.context.BookkeeperIndex], _ = signature.Sign(ds.Account, blockHash[:])
+			ds.context.Signatures[ds.context.BookkeeperIndex], err = ds.Signer.SignVote(signer.STEP_BLOCK, ds.context.Height, uint32(ds.context.ViewNumber), blockHash[:])
+			if err != nil {
+				log.Error("[Timeout] sign block failed", err.Error())
+				return
+			}
 		}
 		payload := ds.context.MakePrepareRequest()
 		ds.SignAndRelay(payload)
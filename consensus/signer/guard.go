@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package signer
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/TesraSupernet/Tesra/common"
+)
+
+//Consensus sign steps protected by SignGuard
+const (
+	STEP_PROPOSAL       = "proposal"
+	STEP_EMPTY_PROPOSAL = "empty_proposal"
+	STEP_ENDORSE        = "endorse"
+	STEP_COMMIT         = "commit"
+	STEP_BLOCK          = "block"
+	STEP_SUBMIT         = "submit"
+)
+
+//SignState is the last signed position of a sign step
+type SignState struct {
+	Height   uint32         `json:"height"`
+	Round    uint32         `json:"round"`
+	DataHash common.Uint256 `json:"data_hash"`
+}
+
+//SignGuard refuse to sign conflicting data at the same height and round, or to sign
+//an older height or round, the last signed state is persisted to survive restart
+type SignGuard struct {
+	lock   sync.Mutex
+	path   string
+	states map[string]*SignState
+}
+
+//NewSignGuard load sign state from path. Empty path means sign state only kept in memory
+func NewSignGuard(path string) (*SignGuard, error) {
+	guard := &SignGuard{
+		path:   path,
+		states: make(map[string]*SignState),
+	}
+	if path == "" || !common.FileExisted(path) {
+		return guard, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sign state file %s error:%s", path, err)
+	}
+	err = json.Unmarshal(data, &guard.states)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal sign state file %s error:%s", path, err)
+	}
+	return guard, nil
+}
+
+//GetSignState return the last signed state of step
+func (self *SignGuard) GetSignState(step string) *SignState {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	state, ok := self.states[step]
+	if !ok {
+		return nil
+	}
+	return &SignState{Height: state.Height, Round: state.Round, DataHash: state.DataHash}
+}
+
+//Sign check the sign position of data, and call sign if it is safe
+func (self *SignGuard) Sign(step string, height, round uint32, data []byte, sign func([]byte) ([]byte, error)) ([]byte, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	dataHash := common.Uint256(sha256.Sum256(data))
+	last, ok := self.states[step]
+	if ok {
+		if height < last.Height || (height == last.Height && round < last.Round) {
+			return nil, fmt.Errorf("double sign protection: %s at height:%d round:%d, already signed height:%d round:%d",
+				step, height, round, last.Height, last.Round)
+		}
+		if height == last.Height && round == last.Round && dataHash != last.DataHash {
+			return nil, fmt.Errorf("double sign protection: conflict %s at height:%d round:%d", step, height, round)
+		}
+	}
+	sig, err := sign(data)
+	if err != nil {
+		return nil, err
+	}
+	if ok && height == last.Height && round == last.Round {
+		return sig, nil
+	}
+	self.states[step] = &SignState{Height: height, Round: round, DataHash: dataHash}
+	if err := self.save(); err != nil {
+		self.states[step] = last
+		if !ok {
+			delete(self.states, step)
+		}
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (self *SignGuard) save() error {
+	if self.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(self.states)
+	if err != nil {
+		return fmt.Errorf("marshal sign state error:%s", err)
+	}
+	err = os.MkdirAll(filepath.Dir(self.path), 0700)
+	if err != nil {
+		return fmt.Errorf("create sign state dir error:%s", err)
+	}
+	tmp := self.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return fmt.Errorf("write sign state file error:%s", err)
+	}
+	return os.Rename(tmp, self.path)
+}
+
+//ConsensusSigner is a Signer with double sign protection for blocks and votes
+type ConsensusSigner struct {
+	Signer
+	guard *SignGuard
+}
+
+func NewConsensusSigner(signer Signer, stateFile string) (*ConsensusSigner, error) {
+	guard, err := NewSignGuard(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsensusSigner{
+		Signer: signer,
+		guard:  guard,
+	}, nil
+}
+
+//SignVote sign block hash of step at height and round
+func (self *ConsensusSigner) SignVote(step string, height, round uint32, data []byte) ([]byte, error) {
+	return self.guard.Sign(step, height, round, data, self.Signer.Sign)
+}
+
+//GetSignState return the last position signed by SignVote of step
+func (self *ConsensusSigner) GetSignState(step string) *SignState {
+	return self.guard.GetSignState(step)
+}
+
+//SignMessage sign the serialized envelope of a consensus message, which only authenticates the sender to peers and
+//is not guarded. An envelope is never counted as a vote: the blocks and votes it carries are signed by SignVote, and
+//the serialized envelope is longer than the block hashes signed by SignVote, so one can't be taken for the other
+func (self *ConsensusSigner) SignMessage(data []byte) ([]byte, error) {
+	return self.Signer.Sign(data)
+}
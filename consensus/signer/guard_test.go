@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package signer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalSigner(t *testing.T) {
+	acc := account.NewAccount("")
+	signer, err := NewLocalSigner(acc)
+	assert.Nil(t, err)
+	data := []byte("hello")
+	sig, err := signer.Sign(data)
+	assert.Nil(t, err)
+	assert.Nil(t, signature.Verify(signer.PublicKey(), data, sig))
+}
+
+func TestSignGuard(t *testing.T) {
+	path := "sign_state_test.json"
+	defer os.Remove(path)
+
+	signer, _ := NewLocalSigner(account.NewAccount(""))
+	consensusSigner, err := NewConsensusSigner(signer, path)
+	assert.Nil(t, err)
+
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 10, 0, []byte("block1"))
+	assert.Nil(t, err)
+	//sign the same data again
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 10, 0, []byte("block1"))
+	assert.Nil(t, err)
+	//conflict data at same height and round
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 10, 0, []byte("block2"))
+	assert.NotNil(t, err)
+	//other step is not affected
+	_, err = consensusSigner.SignVote(STEP_COMMIT, 10, 0, []byte("block2"))
+	assert.Nil(t, err)
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 10, 1, []byte("block2"))
+	assert.Nil(t, err)
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 9, 0, []byte("block0"))
+	assert.NotNil(t, err)
+
+	//sign state survive restart
+	consensusSigner, err = NewConsensusSigner(signer, path)
+	assert.Nil(t, err)
+	state := consensusSigner.guard.GetSignState(STEP_ENDORSE)
+	assert.Equal(t, uint32(10), state.Height)
+	assert.Equal(t, uint32(1), state.Round)
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 10, 1, []byte("block3"))
+	assert.NotNil(t, err)
+	_, err = consensusSigner.SignVote(STEP_ENDORSE, 11, 0, []byte("block3"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+)
+
+const (
+	REMOTE_SIGNER_TIMEOUT = 5 * time.Second
+	UNIX_SOCKET_PREFIX    = "unix:"
+)
+
+type remoteRequest struct {
+	Qid     string      `json:"qid"`
+	Params  interface{} `json:"params"`
+	Account string      `json:"account"`
+	Pwd     string      `json:"pwd"`
+	Method  string      `json:"method"`
+}
+
+type remoteResponse struct {
+	Qid       string          `json:"qid"`
+	Method    string          `json:"method"`
+	Result    json.RawMessage `json:"result"`
+	ErrorCode int             `json:"error_code"`
+	ErrorInfo string          `json:"error_info"`
+}
+
+type rawDataParams struct {
+	RawData string `json:"raw_data"`
+}
+
+//RemoteSigner sign by sigsvr, address can be host:port or unix:<socket path>
+type RemoteSigner struct {
+	url     string
+	account string
+	passwd  string
+	client  *http.Client
+	pubKey  keypair.PublicKey
+	qid     uint64
+}
+
+func NewRemoteSigner(address, account string, passwd []byte) (*RemoteSigner, error) {
+	signer := &RemoteSigner{
+		account: account,
+		passwd:  string(passwd),
+	}
+	transport := &http.Transport{}
+	if strings.HasPrefix(address, UNIX_SOCKET_PREFIX) {
+		socket := strings.TrimPrefix(address, UNIX_SOCKET_PREFIX)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			dialer := &net.Dialer{}
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		signer.url = "http://sigsvr/cli"
+	} else {
+		address = strings.TrimSuffix(strings.TrimPrefix(address, "http://"), "/cli")
+		signer.url = "http://" + address + "/cli"
+	}
+	signer.client = &http.Client{
+		Transport: transport,
+		Timeout:   REMOTE_SIGNER_TIMEOUT,
+	}
+
+	data, err := signer.call("getpublickey", nil)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &struct {
+		PublicKey string `json:"public_key"`
+	}{}
+	err = json.Unmarshal(data, rsp)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal public key error:%s", err)
+	}
+	buf, err := hex.DecodeString(rsp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key error:%s", err)
+	}
+	signer.pubKey, err = keypair.DeserializePublicKey(buf)
+	if err != nil {
+		return nil, fmt.Errorf("deserialize public key error:%s", err)
+	}
+	return signer, nil
+}
+
+func (self *RemoteSigner) PublicKey() keypair.PublicKey {
+	return self.pubKey
+}
+
+func (self *RemoteSigner) Sign(data []byte) ([]byte, error) {
+	result, err := self.call("sigdata", &rawDataParams{RawData: hex.EncodeToString(data)})
+	if err != nil {
+		return nil, err
+	}
+	rsp := &struct {
+		SignedData string `json:"signed_data"`
+	}{}
+	err = json.Unmarshal(result, rsp)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal signed data error:%s", err)
+	}
+	return hex.DecodeString(rsp.SignedData)
+}
+
+func (self *RemoteSigner) Vrf(data []byte) ([]byte, []byte, error) {
+	result, err := self.call("sigvrf", &rawDataParams{RawData: hex.EncodeToString(data)})
+	if err != nil {
+		return nil, nil, err
+	}
+	rsp := &struct {
+		Value string `json:"value"`
+		Proof string `json:"proof"`
+	}{}
+	err = json.Unmarshal(result, rsp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unmarshal vrf error:%s", err)
+	}
+	value, err := hex.DecodeString(rsp.Value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode vrf value error:%s", err)
+	}
+	proof, err := hex.DecodeString(rsp.Proof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode vrf proof error:%s", err)
+	}
+	return value, proof, nil
+}
+
+func (self *RemoteSigner) call(method string, params interface{}) (json.RawMessage, error) {
+	req := &remoteRequest{
+		Qid:     fmt.Sprintf("%d", atomic.AddUint64(&self.qid, 1)),
+		Params:  params,
+		Account: self.account,
+		Pwd:     self.passwd,
+		Method:  method,
+	}
+	if req.Params == nil {
+		req.Params = struct{}{}
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request error:%s", err)
+	}
+	httpRsp, err := self.client.Post(self.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("remote signer %s error:%s", method, err)
+	}
+	defer httpRsp.Body.Close()
+	body, err := ioutil.ReadAll(httpRsp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read remote signer response error:%s", err)
+	}
+	rsp := &remoteResponse{}
+	err = json.Unmarshal(body, rsp)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal remote signer response error:%s", err)
+	}
+	if rsp.ErrorCode != 0 {
+		return nil, fmt.Errorf("remote signer %s error code:%d, %s", method, rsp.ErrorCode, rsp.ErrorInfo)
+	}
+	return rsp.Result, nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package signer
+
+import (
+	"fmt"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/TesraSupernet/tesracrypto/vrf"
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/core/signature"
+)
+
+const (
+	SIGNER_TYPE_LOCAL     = "local"
+	SIGNER_TYPE_SIGSVR    = "sigsvr"
+	SIGNER_TYPE_SOFTTOKEN = "softtoken"
+)
+
+//Signer sign consensus messages, the private key may be kept out of node process
+type Signer interface {
+	PublicKey() keypair.PublicKey
+	Sign(data []byte) ([]byte, error)
+	Vrf(data []byte) ([]byte, []byte, error)
+}
+
+//LocalSigner sign with the account loaded in node process
+type LocalSigner struct {
+	account *account.Account
+}
+
+func NewLocalSigner(acc *account.Account) (*LocalSigner, error) {
+	if acc == nil {
+		return nil, fmt.Errorf("account is nil")
+	}
+	return &LocalSigner{account: acc}, nil
+}
+
+func (self *LocalSigner) PublicKey() keypair.PublicKey {
+	return self.account.PublicKey
+}
+
+func (self *LocalSigner) Sign(data []byte) ([]byte, error) {
+	return signature.Sign(self.account, data)
+}
+
+func (self *LocalSigner) Vrf(data []byte) ([]byte, []byte, error) {
+	if !vrf.ValidatePrivateKey(self.account.PrivateKey) {
+		return nil, nil, fmt.Errorf("invalid account key for VRF")
+	}
+	return vrf.Vrf(self.account.PrivateKey, data)
+}
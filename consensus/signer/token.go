@@ -0,0 +1,309 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package signer
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+)
+
+//TOKEN_SERVICE is the rpc service name of token served by TokenServer
+const TOKEN_SERVICE = "Token"
+
+type TokenLoginArgs struct {
+	Label string //address or label of key object, empty for default key
+	Pin   []byte
+}
+
+type TokenLoginReply struct {
+	PublicKey []byte
+}
+
+type TokenSignArgs struct {
+	Data []byte
+}
+
+type TokenSignReply struct {
+	Signature []byte
+}
+
+type TokenVrfReply struct {
+	Value []byte
+	Proof []byte
+}
+
+//tokenAddress return the network and address of token, address can be host:port or unix:<socket path>
+func tokenAddress(address string) (string, string) {
+	if strings.HasPrefix(address, UNIX_SOCKET_PREFIX) {
+		return "unix", strings.TrimPrefix(address, UNIX_SOCKET_PREFIX)
+	}
+	return "tcp", address
+}
+
+//TokenServer is a PKCS#11 style stand-in of hardware token, running out of node process. Each connection is a
+//session, which opens the key object found by label in token file with pin, and signs with it
+type TokenServer struct {
+	token    account.Client
+	listener net.Listener
+	lock     sync.Mutex
+	conns    map[net.Conn]bool //connections of sessions, closed with server
+}
+
+func NewTokenServer(tokenFile string) (*TokenServer, error) {
+	token, err := account.Open(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("open token %s error:%s", tokenFile, err)
+	}
+	return &TokenServer{token: token, conns: make(map[net.Conn]bool)}, nil
+}
+
+//Listen on address, the unix socket is only accessible by owner, and the group gid if it's not -1
+func (self *TokenServer) Listen(address string, gid int) error {
+	var listener net.Listener
+	var err error
+	network, addr := tokenAddress(address)
+	if network == "unix" {
+		listener, err = common.ListenUnix(addr, gid)
+	} else {
+		listener, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return fmt.Errorf("listen %s error:%s", address, err)
+	}
+	self.listener = listener
+	return nil
+}
+
+//Serve sessions until listener closed
+func (self *TokenServer) Serve() error {
+	for {
+		conn, err := self.listener.Accept()
+		if err != nil {
+			return err
+		}
+		self.lock.Lock()
+		self.conns[conn] = true
+		self.lock.Unlock()
+		go self.serveSession(conn)
+	}
+}
+
+func (self *TokenServer) serveSession(conn net.Conn) {
+	server := rpc.NewServer()
+	server.RegisterName(TOKEN_SERVICE, &tokenSession{token: self.token})
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	self.lock.Lock()
+	delete(self.conns, conn)
+	self.lock.Unlock()
+}
+
+//Close listener and all sessions
+func (self *TokenServer) Close() error {
+	err := self.listener.Close()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for conn := range self.conns {
+		conn.Close()
+	}
+	return err
+}
+
+//tokenSession is the session of a connection, the key object is opened by login
+type tokenSession struct {
+	token account.Client
+	lock  sync.Mutex
+	key   *LocalSigner
+}
+
+func (self *tokenSession) Login(args *TokenLoginArgs, reply *TokenLoginReply) error {
+	var acc *account.Account
+	var err error
+	if args.Label == "" {
+		acc, err = self.token.GetDefaultAccount(args.Pin)
+	} else {
+		acc, err = self.token.GetAccountByAddress(args.Label, args.Pin)
+		if acc == nil && err == nil {
+			acc, err = self.token.GetAccountByLabel(args.Label, args.Pin)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("token login error:%s", err)
+	}
+	if acc == nil {
+		return fmt.Errorf("cannot find key object:%s in token", args.Label)
+	}
+	key, err := NewLocalSigner(acc)
+	if err != nil {
+		return err
+	}
+	self.lock.Lock()
+	self.key = key
+	self.lock.Unlock()
+	reply.PublicKey = keypair.SerializePublicKey(acc.PublicKey)
+	log.Infof("token session login, key object:%s", acc.Address.ToBase58())
+	return nil
+}
+
+func (self *tokenSession) getKey() (*LocalSigner, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.key == nil {
+		return nil, fmt.Errorf("token session not login")
+	}
+	return self.key, nil
+}
+
+func (self *tokenSession) Sign(args *TokenSignArgs, reply *TokenSignReply) error {
+	key, err := self.getKey()
+	if err != nil {
+		return err
+	}
+	reply.Signature, err = key.Sign(args.Data)
+	return err
+}
+
+func (self *tokenSession) Vrf(args *TokenSignArgs, reply *TokenVrfReply) error {
+	key, err := self.getKey()
+	if err != nil {
+		return err
+	}
+	reply.Value, reply.Proof, err = key.Vrf(args.Data)
+	return err
+}
+
+//SoftTokenSigner sign by the session opened with pin on TokenServer, so the private key never enters node process.
+//The session is opened again if connection to token is lost
+type SoftTokenSigner struct {
+	address string
+	label   string
+	pin     []byte
+	lock    sync.Mutex
+	client  *rpc.Client
+	pubKey  keypair.PublicKey
+}
+
+func NewSoftTokenSigner(address, label string, pin []byte) (*SoftTokenSigner, error) {
+	signer := &SoftTokenSigner{
+		address: address,
+		label:   label,
+		pin:     append([]byte{}, pin...),
+	}
+	signer.lock.Lock()
+	defer signer.lock.Unlock()
+	if err := signer.login(); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+//login open a session on token, the key object must not change
+func (self *SoftTokenSigner) login() error {
+	network, addr := tokenAddress(self.address)
+	conn, err := net.DialTimeout(network, addr, REMOTE_SIGNER_TIMEOUT)
+	if err != nil {
+		return fmt.Errorf("connect token %s error:%s", self.address, err)
+	}
+	client := jsonrpc.NewClient(conn)
+	reply := &TokenLoginReply{}
+	err = self.call(client, "Login", &TokenLoginArgs{Label: self.label, Pin: self.pin}, reply)
+	if err != nil {
+		client.Close()
+		return err
+	}
+	pubKey, err := keypair.DeserializePublicKey(reply.PublicKey)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("deserialize public key error:%s", err)
+	}
+	if self.pubKey != nil && !keypair.ComparePublicKey(self.pubKey, pubKey) {
+		client.Close()
+		return fmt.Errorf("key object:%s in token changed", self.label)
+	}
+	self.client = client
+	self.pubKey = pubKey
+	return nil
+}
+
+//call method of token with timeout, the error returned by token is rpc.ServerError
+func (self *SoftTokenSigner) call(client *rpc.Client, method string, args interface{}, reply interface{}) error {
+	timer := time.NewTimer(REMOTE_SIGNER_TIMEOUT)
+	defer timer.Stop()
+	select {
+	case call := <-client.Go(TOKEN_SERVICE+"."+method, args, reply, make(chan *rpc.Call, 1)).Done:
+		if _, ok := call.Error.(rpc.ServerError); ok {
+			return rpc.ServerError(fmt.Sprintf("token %s error:%s", method, call.Error))
+		}
+		if call.Error != nil {
+			return fmt.Errorf("token %s error:%s", method, call.Error)
+		}
+		return nil
+	case <-timer.C:
+		client.Close()
+		return fmt.Errorf("token %s timeout", method)
+	}
+}
+
+//session call method in session, which is opened again if connection lost
+func (self *SoftTokenSigner) session(method string, args interface{}, reply interface{}) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.client == nil {
+		if err := self.login(); err != nil {
+			return err
+		}
+	}
+	err := self.call(self.client, method, args, reply)
+	if err != nil {
+		if _, ok := err.(rpc.ServerError); !ok {
+			self.client.Close()
+			self.client = nil
+		}
+	}
+	return err
+}
+
+func (self *SoftTokenSigner) PublicKey() keypair.PublicKey {
+	return self.pubKey
+}
+
+func (self *SoftTokenSigner) Sign(data []byte) ([]byte, error) {
+	reply := &TokenSignReply{}
+	if err := self.session("Sign", &TokenSignArgs{Data: data}, reply); err != nil {
+		return nil, err
+	}
+	return reply.Signature, nil
+}
+
+func (self *SoftTokenSigner) Vrf(data []byte) ([]byte, []byte, error) {
+	reply := &TokenVrfReply{}
+	if err := self.session("Vrf", &TokenSignArgs{Data: data}, reply); err != nil {
+		return nil, nil, err
+	}
+	return reply.Value, reply.Proof, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	s "github.com/TesraSupernet/tesracrypto/signature"
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func startTokenServer(t *testing.T, tokenFile, address string) *TokenServer {
+	server, err := NewTokenServer(tokenFile)
+	assert.Nil(t, err)
+	assert.Nil(t, server.Listen(address, -1))
+	go server.Serve()
+	return server
+}
+
+func TestSoftTokenSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "softtoken")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token.dat")
+	token, err := account.Open(tokenFile)
+	assert.Nil(t, err)
+	pin := []byte("1234")
+	acc, err := token.NewAccount("consensus", keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, pin)
+	assert.Nil(t, err)
+
+	address := UNIX_SOCKET_PREFIX + filepath.Join(dir, "token.sock")
+	server := startTokenServer(t, tokenFile, address)
+
+	_, err = NewSoftTokenSigner(address, "consensus", []byte("0000"))
+	assert.NotNil(t, err)
+	signer, err := NewSoftTokenSigner(address, "consensus", pin)
+	assert.Nil(t, err)
+	assert.True(t, keypair.ComparePublicKey(acc.PublicKey, signer.PublicKey()))
+	data := []byte("hello")
+	sig, err := signer.Sign(data)
+	assert.Nil(t, err)
+	assert.Nil(t, signature.Verify(signer.PublicKey(), data, sig))
+
+	//session is opened again after token restarted
+	server.Close()
+	_, err = signer.Sign(data)
+	assert.NotNil(t, err)
+	server = startTokenServer(t, tokenFile, address)
+	defer server.Close()
+	sig, err = signer.Sign(data)
+	assert.Nil(t, err)
+	assert.Nil(t, signature.Verify(signer.PublicKey(), data, sig))
+}
@@ -25,14 +25,15 @@ import (
 
 	"github.com/TesraSupernet/tesracrypto/keypair"
 	"github.com/TesraSupernet/tesraevent/actor"
-	"github.com/TesraSupernet/Tesra/account"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	actorTypes "github.com/TesraSupernet/Tesra/consensus/actor"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
+	vconfig "github.com/TesraSupernet/Tesra/consensus/vbft/config"
 	"github.com/TesraSupernet/Tesra/core/ledger"
-	"github.com/TesraSupernet/Tesra/core/signature"
 	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/core/vote"
 	"github.com/TesraSupernet/Tesra/events"
 	"github.com/TesraSupernet/Tesra/events/message"
 	"github.com/TesraSupernet/Tesra/validator/increment"
@@ -44,18 +45,19 @@ import (
 const ContextVersion uint32 = 0
 
 type SoloService struct {
-	Account          *account.Account
+	Signer           *signer.ConsensusSigner
 	poolActor        *actorTypes.TxPoolActor
 	incrValidator    *increment.IncrementValidator
 	existCh          chan interface{}
 	genBlockInterval time.Duration
 	pid              *actor.PID
 	sub              *events.ActorSubscriber
+	signedBlock      *types.Block //block signed but not saved, made again at the same height since double sign is refused
 }
 
-func NewSoloService(bkAccount *account.Account, txpool *actor.PID) (*SoloService, error) {
+func NewSoloService(bkSigner *signer.ConsensusSigner, txpool *actor.PID) (*SoloService, error) {
 	service := &SoloService{
-		Account:          bkAccount,
+		Signer:           bkSigner,
 		poolActor:        &actorTypes.TxPoolActor{Pool: txpool},
 		incrValidator:    increment.NewIncrementValidator(20),
 		genBlockInterval: time.Duration(config.DefConfig.Genesis.SOLO.GenBlockTime) * time.Second,
@@ -142,6 +144,11 @@ func (self *SoloService) Halt() error {
 }
 
 func (self *SoloService) genBlock() error {
+	height := ledger.DefLedger.GetCurrentBlockHeight() + 1
+	if config.DefConfig.Genesis.GetConsensusType(height) != config.CONSENSUS_TYPE_SOLO {
+		log.Debugf("block %d is not generated by solo", height)
+		return nil
+	}
 	block, err := self.makeBlock()
 	if err != nil {
 		return fmt.Errorf("makeBlock error %s", err)
@@ -160,14 +167,24 @@ func (self *SoloService) genBlock() error {
 
 func (self *SoloService) makeBlock() (*types.Block, error) {
 	log.Debug()
-	owner := self.Account.PublicKey
-	nextBookkeeper, err := types.AddressFromBookkeepers([]keypair.PublicKey{owner})
-	if err != nil {
-		return nil, fmt.Errorf("GetBookkeeperAddress error:%s", err)
-	}
+	owner := self.Signer.PublicKey()
 	prevHash := ledger.DefLedger.GetCurrentBlockHash()
 	height := ledger.DefLedger.GetCurrentBlockHeight()
 
+	if block := self.signedBlock; block != nil && block.Header.Height == height+1 && block.Header.PrevBlockHash == prevHash {
+		return block, nil
+	}
+
+	bookkeeperState, err := ledger.DefLedger.GetBookkeeperState()
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeeperState error:%s", err)
+	}
+	if config.DefConfig.GetBookkeeperEpochPeriod(height+1) != 0 {
+		if len(bookkeeperState.CurrBookkeeper) != 1 || !keypair.ComparePublicKey(owner, bookkeeperState.CurrBookkeeper[0]) {
+			return nil, fmt.Errorf("account is not the bookkeeper of height:%d", height+1)
+		}
+	}
+
 	validHeight := height
 
 	start, end := self.incrValidator.BlockRange()
@@ -190,6 +207,23 @@ func (self *SoloService) makeBlock() (*types.Block, error) {
 			transactions = append(transactions, txEntry.Tx)
 		}
 	}
+	blockLimit, err := ledger.DefLedger.GetBlockLimit()
+	if err != nil {
+		return nil, fmt.Errorf("GetBlockLimit error:%s", err)
+	}
+	transactions = blockLimit.SelectTransactions(nil, transactions)
+
+	nextBookkeepers := []keypair.PublicKey{owner}
+	if config.DefConfig.GetBookkeeperEpochPeriod(height+1) != 0 {
+		nextBookkeepers, err = vote.GetValidators(bookkeeperState, height+1, transactions)
+		if err != nil {
+			return nil, fmt.Errorf("GetValidators error:%s", err)
+		}
+	}
+	nextBookkeeper, err := types.AddressFromBookkeepers(nextBookkeepers)
+	if err != nil {
+		return nil, fmt.Errorf("GetBookkeeperAddress error:%s", err)
+	}
 
 	txHash := []common.Uint256{}
 	for _, t := range transactions {
@@ -198,6 +232,13 @@ func (self *SoloService) makeBlock() (*types.Block, error) {
 	txRoot := common.ComputeMerkleRoot(txHash)
 
 	blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(height+1, []common.Uint256{txRoot})
+	var consensusPayload []byte
+	if config.DefConfig.Genesis.GetConsensusType(height+2) == config.CONSENSUS_TYPE_VBFT {
+		consensusPayload, err = vconfig.SwitchConsensusPayload(prevHash, height+1)
+		if err != nil {
+			return nil, fmt.Errorf("SwitchConsensusPayload error:%s", err)
+		}
+	}
 	header := &types.Header{
 		Version:          ContextVersion,
 		PrevBlockHash:    prevHash,
@@ -207,6 +248,7 @@ func (self *SoloService) makeBlock() (*types.Block, error) {
 		Height:           height + 1,
 		ConsensusData:    common.GetNonce(),
 		NextBookkeeper:   nextBookkeeper,
+		ConsensusPayload: consensusPayload,
 	}
 	block := &types.Block{
 		Header:       header,
@@ -215,12 +257,13 @@ func (self *SoloService) makeBlock() (*types.Block, error) {
 
 	blockHash := block.Hash()
 
-	sig, err := signature.Sign(self.Account, blockHash[:])
+	sig, err := self.Signer.SignVote(signer.STEP_BLOCK, height+1, 0, blockHash[:])
 	if err != nil {
 		return nil, fmt.Errorf("[Signature],Sign error:%s.", err)
 	}
 
 	block.Header.Bookkeepers = []keypair.PublicKey{owner}
 	block.Header.SigData = [][]byte{sig}
+	self.signedBlock = block
 	return block, nil
 }
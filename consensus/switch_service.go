@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package consensus
+
+import (
+	"fmt"
+
+	"github.com/TesraSupernet/tesraevent/actor"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	actorTypes "github.com/TesraSupernet/Tesra/consensus/actor"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
+	"github.com/TesraSupernet/Tesra/core/ledger"
+	"github.com/TesraSupernet/Tesra/events"
+	"github.com/TesraSupernet/Tesra/events/message"
+)
+
+//SwitchService runs the consensus engine configured for the next block height,
+//and switches to another engine at the configured consensus fork heights
+type SwitchService struct {
+	signer   *signer.ConsensusSigner
+	txpool   *actor.PID
+	p2p      *actor.PID
+	services map[string]ConsensusService
+	current  string
+	started  bool
+	pid      *actor.PID
+	sub      *events.ActorSubscriber
+}
+
+func NewSwitchService(consensusSigner *signer.ConsensusSigner, txpool *actor.PID, p2p *actor.PID) (*SwitchService, error) {
+	service := &SwitchService{
+		signer:   consensusSigner,
+		txpool:   txpool,
+		p2p:      p2p,
+		services: make(map[string]ConsensusService),
+	}
+
+	props := actor.FromProducer(func() actor.Actor {
+		return service
+	})
+	pid, err := actor.SpawnNamed(props, "consensus_switch")
+	service.pid = pid
+	service.sub = events.NewActorSubscriber(pid)
+	return service, err
+}
+
+func (self *SwitchService) Receive(context actor.Context) {
+	switch msg := context.Message().(type) {
+	case *actor.Restarting:
+		log.Info("consensus switch actor restarting")
+	case *actor.Stopping:
+		log.Info("consensus switch actor stopping")
+	case *actor.Stopped:
+		log.Info("consensus switch actor stopped")
+	case *actor.Started:
+		log.Info("consensus switch actor started")
+	case *actor.Restart:
+		log.Info("consensus switch actor restart")
+	case *actorTypes.StartConsensus:
+		if self.started {
+			return
+		}
+		self.started = true
+		self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+		if err := self.switchTo(self.nextConsensusType()); err != nil {
+			log.Errorf("consensus switch start error: %s", err)
+		}
+	case *actorTypes.StopConsensus:
+		if !self.started {
+			return
+		}
+		self.started = false
+		self.sub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+		if service, ok := self.services[self.current]; ok {
+			service.Halt()
+		}
+		self.current = ""
+	case *message.SaveBlockCompleteMsg:
+		height := msg.Block.Header.Height + 1
+		consensusType := config.DefConfig.Genesis.GetConsensusType(height)
+		if consensusType != self.current {
+			log.Infof("switch consensus from %s to %s at block height %d", self.current, consensusType, height)
+			if err := self.switchTo(consensusType); err != nil {
+				log.Errorf("consensus switch error: %s", err)
+			}
+		}
+	default:
+		if service, ok := self.services[self.current]; ok {
+			service.GetPID().Tell(msg)
+		}
+	}
+}
+
+func (self *SwitchService) nextConsensusType() string {
+	return config.DefConfig.Genesis.GetConsensusType(ledger.DefLedger.GetCurrentBlockHeight() + 1)
+}
+
+func (self *SwitchService) switchTo(consensusType string) error {
+	if service, ok := self.services[self.current]; ok {
+		service.Halt()
+	}
+	self.current = consensusType
+	service, ok := self.services[consensusType]
+	if !ok {
+		var err error
+		service, err = newConsensusEngine(consensusType, self.signer, self.txpool, self.p2p)
+		if err != nil {
+			return err
+		}
+		if service == nil {
+			return fmt.Errorf("unknown consensus type %s", consensusType)
+		}
+		self.services[consensusType] = service
+	}
+	return service.Start()
+}
+
+func (self *SwitchService) GetPID() *actor.PID {
+	return self.pid
+}
+
+func (self *SwitchService) Start() error {
+	self.pid.Tell(&actorTypes.StartConsensus{})
+	return nil
+}
+
+func (self *SwitchService) Halt() error {
+	self.pid.Tell(&actorTypes.StopConsensus{})
+	return nil
+}
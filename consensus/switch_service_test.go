@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TesraSupernet/tesraevent/actor"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/events/message"
+	"github.com/stretchr/testify/assert"
+)
+
+type testConsensusService struct {
+	name   string
+	events chan string
+	pid    *actor.PID
+}
+
+func newTestConsensusService(name string, events chan string) *testConsensusService {
+	service := &testConsensusService{name: name, events: events}
+	service.pid = actor.Spawn(actor.FromFunc(func(context actor.Context) {
+		if msg, ok := context.Message().(string); ok {
+			events <- name + " received " + msg
+		}
+	}))
+	return service
+}
+
+func (self *testConsensusService) Start() error {
+	self.events <- self.name + " started"
+	return nil
+}
+
+func (self *testConsensusService) Halt() error {
+	self.events <- self.name + " halted"
+	return nil
+}
+
+func (self *testConsensusService) GetPID() *actor.PID {
+	return self.pid
+}
+
+func nextEvent(t *testing.T, events chan string) string {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait consensus event timeout")
+	}
+	return ""
+}
+
+func TestSwitchServiceHandoff(t *testing.T) {
+	genesis := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesis }()
+	config.DefConfig.Genesis = &config.GenesisConfig{
+		ConsensusType: config.CONSENSUS_TYPE_SOLO,
+		ConsensusForks: []*config.ConsensusForkConfig{
+			{Height: 10, ConsensusType: config.CONSENSUS_TYPE_VBFT},
+		},
+	}
+
+	events := make(chan string, 16)
+	solo := newTestConsensusService(CONSENSUS_SOLO, events)
+	vbft := newTestConsensusService(CONSENSUS_VBFT, events)
+
+	service, err := NewSwitchService(nil, nil, nil)
+	assert.Nil(t, err)
+	service.services[CONSENSUS_SOLO] = solo
+	service.services[CONSENSUS_VBFT] = vbft
+	service.current = CONSENSUS_SOLO
+	service.started = true
+
+	saveBlock := func(height uint32) {
+		service.pid.Tell(&message.SaveBlockCompleteMsg{Block: &types.Block{Header: &types.Header{Height: height}}})
+	}
+
+	//block 9 is still made by solo
+	saveBlock(8)
+	service.pid.Tell("probe")
+	assert.Equal(t, "solo received probe", nextEvent(t, events))
+
+	//block 10 is the first vbft block
+	saveBlock(9)
+	assert.Equal(t, "solo halted", nextEvent(t, events))
+	assert.Equal(t, "vbft started", nextEvent(t, events))
+
+	saveBlock(10)
+	service.pid.Tell("probe")
+	assert.Equal(t, "vbft received probe", nextEvent(t, events))
+}
@@ -49,6 +49,8 @@ type ChainConfig struct {
 	Peers                []*PeerConfig `json:"peers"`
 	PosTable             []uint32      `json:"pos_table"`
 	MaxBlockChangeView   uint32        `json:"MaxBlockChangeView"`
+	BlockTime            time.Duration `json:"block_time,omitempty"`
+	MaxIdleTime          time.Duration `json:"max_idle_time,omitempty"`
 }
 
 //
@@ -190,6 +190,8 @@ func GenesisChainConfig(conf *config.VBFTConfig, peers []*config.VBFTPeerStakeIn
 		Peers:                peerCfgs,
 		PosTable:             posTable,
 		MaxBlockChangeView:   conf.MaxBlockChangeView,
+		BlockTime:            time.Duration(conf.BlockTime) * time.Millisecond,
+		MaxIdleTime:          time.Duration(conf.MaxIdleTime) * time.Millisecond,
 	}
 	return chainConfig, nil
 }
@@ -203,3 +205,9 @@ func GenesisConsensusPayload(txhash common.Uint256, height uint32) ([]byte, erro
 	}
 	return nil, nil
 }
+
+//SwitchConsensusPayload return the consensus payload of the last block before switching to vbft.
+//Like the genesis block, it carries the initial chain config, and takes the previous block hash as random source
+func SwitchConsensusPayload(prevHash common.Uint256, height uint32) ([]byte, error) {
+	return genConsensusPayload(config.DefConfig.Genesis.VBFT, prevHash, height)
+}
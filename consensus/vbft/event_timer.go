@@ -44,15 +44,26 @@ const (
 	EventMax
 )
 
-var (
-	makeProposalTimeout    = 300 * time.Millisecond
-	make2ndProposalTimeout = 300 * time.Millisecond
-	endorseBlockTimeout    = 100 * time.Millisecond
-	commitBlockTimeout     = 200 * time.Millisecond
-	peerHandshakeTimeout   = 10 * time.Second
-	txPooltimeout          = 1 * time.Second
-	zeroTxBlockTimeout     = 10 * time.Second
-)
+//msgDelays timeouts of consensus events, updated with chain config
+type msgDelays struct {
+	makeProposalTimeout    time.Duration
+	make2ndProposalTimeout time.Duration
+	endorseBlockTimeout    time.Duration
+	commitBlockTimeout     time.Duration
+	peerHandshakeTimeout   time.Duration
+	txPooltimeout          time.Duration
+	zeroTxBlockTimeout     time.Duration
+}
+
+var defaultMsgDelays = msgDelays{
+	makeProposalTimeout:    300 * time.Millisecond,
+	make2ndProposalTimeout: 300 * time.Millisecond,
+	endorseBlockTimeout:    100 * time.Millisecond,
+	commitBlockTimeout:     200 * time.Millisecond,
+	peerHandshakeTimeout:   10 * time.Second,
+	txPooltimeout:          1 * time.Second,
+	zeroTxBlockTimeout:     10 * time.Second,
+}
 
 type SendMsgEvent struct {
 	ToPeer uint32 // peer index
@@ -152,33 +163,34 @@ func (self *EventTimer) CancelTimer(idx uint32) {
 }
 
 func (self *EventTimer) getEventTimeout(evtType TimerEventType) time.Duration {
+	delays := self.server.getMsgDelays()
 	switch evtType {
 	case EventProposeBlockTimeout:
-		return makeProposalTimeout
+		return delays.makeProposalTimeout
 	case EventPropose2ndBlockTimeout:
-		return make2ndProposalTimeout
+		return delays.make2ndProposalTimeout
 	case EventEndorseBlockTimeout:
-		return endorseBlockTimeout
+		return delays.endorseBlockTimeout
 	case EventEndorseEmptyBlockTimeout:
-		return endorseBlockTimeout
+		return delays.endorseBlockTimeout
 	case EventCommitBlockTimeout:
-		return commitBlockTimeout
+		return delays.commitBlockTimeout
 	case EventPeerHeartbeat:
-		return peerHandshakeTimeout
+		return delays.peerHandshakeTimeout
 	case EventProposalBackoff:
 		rank := self.server.getProposerRank(self.server.GetCurrentBlockNo(), self.server.Index)
 		if rank >= 0 {
-			d := int64(rank+1) * int64(make2ndProposalTimeout) / 3
+			d := int64(rank+1) * int64(delays.make2ndProposalTimeout) / 3
 			return time.Duration(d)
 		}
 		return time.Duration(100 * time.Second)
 	case EventRandomBackoff:
-		d := (rand.Int63n(100) + 50) * int64(endorseBlockTimeout) / 10
+		d := (rand.Int63n(100) + 50) * int64(delays.endorseBlockTimeout) / 10
 		return time.Duration(d)
 	case EventTxPool:
-		return txPooltimeout
+		return delays.txPooltimeout
 	case EventTxBlockTimeout:
-		return zeroTxBlockTimeout
+		return delays.zeroTxBlockTimeout
 	}
 
 	return 0
@@ -18,7 +18,11 @@
 
 package vbft
 
-import "testing"
+import (
+	"sync"
+	"testing"
+	"time"
+)
 
 func constructEventTimer() *EventTimer {
 	server := constructServer()
@@ -47,3 +51,31 @@ func TestCancelEventTimer(t *testing.T) {
 	t.Logf("startEventTimer: %v", err)
 	eventtimer.cancelEventTimer(EventProposeBlockTimeout, 1)
 }
+
+func TestEventTimeoutWithChainConfig(t *testing.T) {
+	eventtimer := constructEventTimer()
+	server := eventtimer.server
+	server.config.BlockTime = 2 * time.Second
+	server.config.MaxIdleTime = 30 * time.Second
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		server.metaLock.Lock()
+		server.updateMsgDelays()
+		server.metaLock.Unlock()
+	}()
+	eventtimer.getEventTimeout(EventTxPool)
+	wg.Wait()
+
+	if d := eventtimer.getEventTimeout(EventTxPool); d != 2*time.Second {
+		t.Errorf("txpool timeout: %v", d)
+	}
+	if d := eventtimer.getEventTimeout(EventTxBlockTimeout); d != 30*time.Second {
+		t.Errorf("zero tx block timeout: %v", d)
+	}
+	if d := eventtimer.getEventTimeout(EventProposeBlockTimeout); d != 2000 {
+		t.Errorf("propose block timeout: %v", d)
+	}
+}
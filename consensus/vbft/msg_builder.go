@@ -26,9 +26,9 @@ import (
 	"github.com/TesraSupernet/tesracrypto/keypair"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/consensus/vbft/config"
 	"github.com/TesraSupernet/Tesra/core/ledger"
-	"github.com/TesraSupernet/Tesra/core/signature"
 	"github.com/TesraSupernet/Tesra/core/types"
 )
 
@@ -183,7 +183,7 @@ func (self *Server) constructHeartbeatMsg() (*peerHeartbeatMsg, error) {
 	return msg, nil
 }
 
-func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, txs []*types.Transaction, consensusPayload []byte, blocktimestamp uint32) (*types.Block, error) {
+func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, txs []*types.Transaction, consensusPayload []byte, blocktimestamp uint32, step string, round uint32) (*types.Block, error) {
 	txHash := []common.Uint256{}
 	for _, t := range txs {
 		txHash = append(txHash, t.Hash())
@@ -211,17 +211,17 @@ func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, tx
 		Transactions: txs,
 	}
 	blkHash := blk.Hash()
-	sig, err := signature.Sign(self.account, blkHash[:])
+	sig, err := self.signer.SignVote(step, blkNum, round, blkHash[:])
 	if err != nil {
 		return nil, fmt.Errorf("sign block failed, block hash:%s, error: %s", blkHash.ToHexString(), err)
 	}
-	blkHeader.Bookkeepers = []keypair.PublicKey{self.account.PublicKey}
+	blkHeader.Bookkeepers = []keypair.PublicKey{self.signer.PublicKey()}
 	blkHeader.SigData = [][]byte{sig}
 
 	return blk, nil
 }
 
-func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types.Transaction, chainconfig *vconfig.ChainConfig) (*blockProposalMsg, error) {
+func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types.Transaction, chainconfig *vconfig.ChainConfig, forEmpty bool) (*blockProposalMsg, error) {
 
 	prevBlk, prevBlkHash := self.blockPool.getSealedBlock(blkNum - 1)
 	if prevBlk == nil {
@@ -232,7 +232,7 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 		blocktimestamp = prevBlk.Block.Header.Timestamp + 1
 	}
 
-	vrfValue, vrfProof, err := computeVrf(self.account.PrivateKey, blkNum, prevBlk.getVrfValue())
+	vrfValue, vrfProof, err := computeVrf(self.signer, blkNum, prevBlk.getVrfValue())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vrf and proof: %s", err)
 	}
@@ -256,11 +256,13 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 		return nil, err
 	}
 
-	emptyBlk, err := self.constructBlock(blkNum, prevBlkHash, sysTxs, consensusPayload, blocktimestamp)
+	// proposal for empty is signed at another round, it is not a conflict of the first proposal
+	round := emptyRound(forEmpty)
+	emptyBlk, err := self.constructBlock(blkNum, prevBlkHash, sysTxs, consensusPayload, blocktimestamp, signer.STEP_EMPTY_PROPOSAL, round)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct empty block: %s", err)
 	}
-	blk, err := self.constructBlock(blkNum, prevBlkHash, append(sysTxs, userTxs...), consensusPayload, blocktimestamp)
+	blk, err := self.constructBlock(blkNum, prevBlkHash, append(sysTxs, userTxs...), consensusPayload, blocktimestamp, signer.STEP_PROPOSAL, round)
 	if err != nil {
 		return nil, fmt.Errorf("failed to constuct blk: %s", err)
 	}
@@ -301,7 +303,7 @@ func (self *Server) constructEndorseMsg(proposal *blockProposalMsg, forEmpty boo
 		proposerSig = proposal.Block.EmptyBlock.Header.SigData[0]
 		blkHash = proposal.Block.EmptyBlock.Hash()
 	}
-	endorserSig, err = signature.Sign(self.account, blkHash[:])
+	endorserSig, err = self.signer.SignVote(signer.STEP_ENDORSE, proposal.GetBlockNum(), emptyRound(forEmpty), blkHash[:])
 	if err != nil {
 		return nil, fmt.Errorf("endorser failed to sign block. hash:%x, err: %s", blkHash, err)
 	}
@@ -339,7 +341,7 @@ func (self *Server) constructCommitMsg(proposal *blockProposalMsg, endorses []*b
 		proposerSig = proposal.Block.EmptyBlock.Header.SigData[0]
 		blkHash = proposal.Block.EmptyBlock.Hash()
 	}
-	committerSig, err = signature.Sign(self.account, blkHash[:])
+	committerSig, err = self.signer.SignVote(signer.STEP_COMMIT, proposal.GetBlockNum(), emptyRound(forEmpty), blkHash[:])
 	if err != nil {
 		return nil, fmt.Errorf("endorser failed to sign block. hash:%x, caused by: %s", blkHash, err)
 	}
@@ -397,7 +399,7 @@ func (self *Server) constructProposalFetchMsg(blkNum uint32, proposer uint32) *p
 }
 
 func (self *Server) constructBlockSubmitMsg(blkNum uint32, stateRoot common.Uint256) (*blockSubmitMsg, error) {
-	submitSig, err := signature.Sign(self.account, stateRoot[:])
+	submitSig, err := self.signer.SignVote(signer.STEP_SUBMIT, blkNum, 0, stateRoot[:])
 	if err != nil {
 		return nil, fmt.Errorf("submit failed to sign stateroot hash:%x, err: %s", stateRoot, err)
 	}
@@ -19,10 +19,14 @@
 package vbft
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
+	"github.com/TesraSupernet/Tesra/consensus/vbft/config"
+	"github.com/TesraSupernet/Tesra/core/types"
 )
 
 func constructMsg() *blockProposalMsg {
@@ -58,3 +62,131 @@ func TestDeserializeVbftMsg(t *testing.T) {
 	}
 	t.Logf("TestDeserializeVbftMsg succ")
 }
+
+func constructVoteProposal(proposer uint32, nonce uint64) *blockProposalMsg {
+	info := &vconfig.VbftBlockInfo{Proposer: proposer}
+	payload, _ := json.Marshal(info)
+	header := func(nonce uint64) *types.Header {
+		return &types.Header{
+			Height:           20,
+			ConsensusData:    nonce,
+			ConsensusPayload: payload,
+			SigData:          [][]byte{{}},
+		}
+	}
+	return &blockProposalMsg{
+		Block: &Block{
+			Block:      &types.Block{Header: header(nonce)},
+			EmptyBlock: &types.Block{Header: header(nonce + 1)},
+			Info:       info,
+		},
+	}
+}
+
+func TestEndorseEmptyOnTimeout(t *testing.T) {
+	localSigner, err := signer.NewLocalSigner(account.NewAccount(""))
+	if err != nil {
+		t.Fatalf("new local signer: %s", err)
+	}
+	consensusSigner, err := signer.NewConsensusSigner(localSigner, "")
+	if err != nil {
+		t.Fatalf("new consensus signer: %s", err)
+	}
+	server := &Server{Index: 3, signer: consensusSigner}
+
+	p1 := constructVoteProposal(1, 100)
+	p2 := constructVoteProposal(2, 200)
+
+	// endorse proposal of proposer 1, then its empty block on endorse timeout
+	if _, err := server.constructEndorseMsg(p1, false); err != nil {
+		t.Fatalf("endorse proposal: %s", err)
+	}
+	if _, err := server.constructEndorseMsg(p1, true); err != nil {
+		t.Fatalf("endorse empty proposal: %s", err)
+	}
+	// rebroadcast of the same endorsement
+	if _, err := server.constructEndorseMsg(p1, true); err != nil {
+		t.Fatalf("rebroadcast endorse: %s", err)
+	}
+	if _, err := server.constructCommitMsg(p1, nil, true); err != nil {
+		t.Fatalf("commit empty proposal: %s", err)
+	}
+
+	// proposals of another proposer at the same height are refused
+	if _, err := server.constructEndorseMsg(p2, true); err == nil {
+		t.Fatalf("endorse empty proposal of another proposer should fail")
+	}
+	if _, err := server.constructCommitMsg(p2, nil, true); err == nil {
+		t.Fatalf("commit empty proposal of another proposer should fail")
+	}
+	// a block is never signed after the empty block
+	if _, err := server.constructEndorseMsg(p1, false); err == nil {
+		t.Fatalf("endorse proposal after empty proposal should fail")
+	}
+}
+
+func TestVoteConflictingProposers(t *testing.T) {
+	localSigner, err := signer.NewLocalSigner(account.NewAccount(""))
+	if err != nil {
+		t.Fatalf("new local signer: %s", err)
+	}
+	consensusSigner, err := signer.NewConsensusSigner(localSigner, "")
+	if err != nil {
+		t.Fatalf("new consensus signer: %s", err)
+	}
+	server := &Server{Index: 3, signer: consensusSigner}
+
+	// proposals of different proposers at the same height and round conflict
+	p1 := constructVoteProposal(1, 100)
+	p2 := constructVoteProposal(2, 200)
+	if _, err := server.constructEndorseMsg(p1, false); err != nil {
+		t.Fatalf("endorse proposal: %s", err)
+	}
+	if _, err := server.constructEndorseMsg(p2, false); err == nil {
+		t.Fatalf("endorse proposal of another proposer should fail")
+	}
+	if _, err := server.constructCommitMsg(p2, nil, false); err != nil {
+		t.Fatalf("commit proposal: %s", err)
+	}
+	if _, err := server.constructCommitMsg(p1, nil, false); err == nil {
+		t.Fatalf("commit proposal of another proposer should fail")
+	}
+}
+
+func TestFindSignedProposal(t *testing.T) {
+	localSigner, err := signer.NewLocalSigner(account.NewAccount(""))
+	if err != nil {
+		t.Fatalf("new local signer: %s", err)
+	}
+	consensusSigner, err := signer.NewConsensusSigner(localSigner, "")
+	if err != nil {
+		t.Fatalf("new consensus signer: %s", err)
+	}
+	server := &Server{Index: 3, signer: consensusSigner}
+	server.msgPool = newMsgPool(server, 10)
+
+	own := constructVoteProposal(3, 100)
+	other := constructVoteProposal(2, 100)
+	server.msgPool.rounds[20] = newConsensusRound(20)
+	for _, p := range []*blockProposalMsg{other, own} {
+		h, _ := HashMsg(p)
+		server.msgPool.rounds[20].addMsg(p, h)
+	}
+	blkHash := own.Block.Block.Hash()
+	if _, err := consensusSigner.SignVote(signer.STEP_PROPOSAL, 20, 0, blkHash[:]); err != nil {
+		t.Fatalf("sign proposal: %s", err)
+	}
+
+	// the proposal made again at the height is the one signed before
+	state := consensusSigner.GetSignState(signer.STEP_PROPOSAL)
+	if p := server.findSignedProposal(20, state.DataHash); p != own {
+		t.Fatalf("signed proposal not found")
+	}
+	if p := server.findSignedProposal(21, state.DataHash); p != nil {
+		t.Fatalf("signed proposal found at another height")
+	}
+	conflict := constructVoteProposal(3, 300).Block.Block.Hash()
+	if _, err := consensusSigner.SignVote(signer.STEP_PROPOSAL, 20, 0, conflict[:]); err == nil {
+		t.Fatalf("sign conflicting proposal should fail")
+	}
+}
@@ -448,7 +448,7 @@ func (self *PeerSyncer) requestBlock(blkNum uint32) (*Block, error) {
 		Msg:    msg,
 	}
 
-	t := time.NewTimer(makeProposalTimeout * 2)
+	t := time.NewTimer(self.server.getMsgDelays().makeProposalTimeout * 2)
 	defer t.Stop()
 
 	select {
@@ -479,7 +479,7 @@ func (self *PeerSyncer) requestBlockInfo(startBlkNum uint32) ([]*BlockInfo_, err
 		Msg:    msg,
 	}
 
-	t := time.NewTimer(makeProposalTimeout * 2)
+	t := time.NewTimer(self.server.getMsgDelays().makeProposalTimeout * 2)
 	defer t.Stop()
 
 	select {
@@ -25,7 +25,6 @@ import (
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/consensus/vbft/config"
-	"github.com/TesraSupernet/Tesra/core/signature"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
 	p2pmsg "github.com/TesraSupernet/Tesra/p2pserver/message/types"
 )
@@ -363,7 +362,14 @@ func (self *Server) findBlockProposal(blkNum uint32, proposer uint32, forEmpty b
 
 func (self *Server) validateTxsInProposal(proposal *blockProposalMsg) error {
 	// TODO: add VBFT specific verifications
-	return nil
+	if proposal.Block == nil || proposal.Block.Block == nil {
+		return nil
+	}
+	blockLimit, err := self.ledger.GetBlockLimit()
+	if err != nil {
+		return fmt.Errorf("GetBlockLimit failed:%s", err)
+	}
+	return blockLimit.CheckTransactions(proposal.Block.Block.Transactions)
 }
 
 func (self *Server) heartbeat() {
@@ -404,12 +410,12 @@ func (self *Server) sendToPeer(peerIdx uint32, data []byte) error {
 	}
 	msg := &p2pmsg.ConsensusPayload{
 		Data:  data,
-		Owner: self.account.PublicKey,
+		Owner: self.signer.PublicKey(),
 	}
 
 	sink := common.NewZeroCopySink(nil)
 	msg.SerializationUnsigned(sink)
-	msg.Signature, _ = signature.Sign(self.account, sink.Bytes())
+	msg.Signature, _ = self.signer.SignMessage(sink.Bytes())
 
 	cons := msgpack.NewConsensus(msg)
 	p2pid, present := self.peerPool.getP2pId(peerIdx)
@@ -431,12 +437,12 @@ func (self *Server) broadcast(msg ConsensusMsg) {
 func (self *Server) broadcastToAll(data []byte) error {
 	msg := &p2pmsg.ConsensusPayload{
 		Data:  data,
-		Owner: self.account.PublicKey,
+		Owner: self.signer.PublicKey(),
 	}
 
 	sink := common.NewZeroCopySink(nil)
 	msg.SerializationUnsigned(sink)
-	msg.Signature, _ = signature.Sign(self.account, sink.Bytes())
+	msg.Signature, _ = self.signer.SignMessage(sink.Bytes())
 
 	self.p2p.Broadcast(msg)
 	return nil
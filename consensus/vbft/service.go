@@ -20,6 +20,7 @@ package vbft
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"math"
 	"reflect"
@@ -29,10 +30,11 @@ import (
 	"github.com/TesraSupernet/tesracrypto/keypair"
 	"github.com/TesraSupernet/tesracrypto/vrf"
 	"github.com/TesraSupernet/tesraevent/actor"
-	"github.com/TesraSupernet/Tesra/account"
 	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	actorTypes "github.com/TesraSupernet/Tesra/consensus/actor"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/consensus/vbft/config"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/payload"
@@ -88,7 +90,7 @@ type p2pMsgPayload struct {
 
 type Server struct {
 	Index         uint32
-	account       *account.Account
+	signer        *signer.ConsensusSigner
 	poolActor     *actorTypes.TxPoolActor
 	p2p           *actorTypes.P2PActor
 	ledger        *ledger.Ledger
@@ -110,6 +112,7 @@ type Server struct {
 	LastConfigBlockNum       uint32
 	config                   *vconfig.ChainConfig
 	currentParticipantConfig *BlockParticipantConfig
+	msgDelays                msgDelays // timeouts of consensus events
 
 	chainStore *ChainStore // block store
 	msgPool    *MsgPool    // consensus msg pool
@@ -129,14 +132,15 @@ type Server struct {
 	quitWg     sync.WaitGroup
 }
 
-func NewVbftServer(account *account.Account, txpool, p2p *actor.PID) (*Server, error) {
+func NewVbftServer(consensusSigner *signer.ConsensusSigner, txpool, p2p *actor.PID) (*Server, error) {
 	server := &Server{
 		msgHistoryDuration: 64,
-		account:            account,
+		signer:             consensusSigner,
 		poolActor:          &actorTypes.TxPoolActor{Pool: txpool},
 		p2p:                &actorTypes.P2PActor{P2P: p2p},
 		ledger:             ledger.DefLedger,
 		incrValidator:      increment.NewIncrementValidator(20),
+		msgDelays:          defaultMsgDelays,
 	}
 	server.stateMgr = newStateMgr(server)
 
@@ -300,23 +304,19 @@ func (self *Server) LoadChainConfig(blkNum uint32) error {
 		cfg = *cfgBlock.getNewChainConfig()
 		self.LastConfigBlockNum = cfgBlock.getLastConfigBlockNum()
 	}
+	if cfg.View == 0 || cfg.MaxBlockChangeView == 0 {
+		panic("invalid view or maxblockchangeview ")
+	}
 	self.metaLock.Lock()
 	self.config = &cfg
+	// update msg delays
+	self.updateMsgDelays()
 	self.metaLock.Unlock()
 
 	self.metaLock.RLock()
 	defer self.metaLock.RUnlock()
 
-	if self.config.View == 0 || self.config.MaxBlockChangeView == 0 {
-		panic("invalid view or maxblockchangeview ")
-	}
-	// update msg delays
-	makeProposalTimeout = time.Duration(self.config.BlockMsgDelay * 2)
-	make2ndProposalTimeout = time.Duration(self.config.BlockMsgDelay)
-	endorseBlockTimeout = time.Duration(self.config.HashMsgDelay * 2)
-	commitBlockTimeout = time.Duration(self.config.HashMsgDelay * 3)
-	peerHandshakeTimeout = time.Duration(self.config.PeerHandshakeTimeout)
-	zeroTxBlockTimeout = time.Duration(self.config.BlockMsgDelay * 3)
+	self.updateOverlayValidators()
 	// TODO: load sealed blocks from chainStore
 
 	// protected by server.metaLock
@@ -342,6 +342,44 @@ func (self *Server) nonConsensusNode() bool {
 	return self.Index == math.MaxUint32
 }
 
+//updateMsgDelays update timeouts with chain config, should call with server.metaLock held exclusively
+func (self *Server) updateMsgDelays() {
+	delays := msgDelays{
+		makeProposalTimeout:    time.Duration(self.config.BlockMsgDelay * 2),
+		make2ndProposalTimeout: time.Duration(self.config.BlockMsgDelay),
+		endorseBlockTimeout:    time.Duration(self.config.HashMsgDelay * 2),
+		commitBlockTimeout:     time.Duration(self.config.HashMsgDelay * 3),
+		peerHandshakeTimeout:   time.Duration(self.config.PeerHandshakeTimeout),
+		txPooltimeout:          time.Second,
+		zeroTxBlockTimeout:     time.Duration(self.config.BlockMsgDelay * 3),
+	}
+	// proposal is made when txnpool has txs at the target block time
+	if self.config.BlockTime != 0 {
+		delays.txPooltimeout = self.config.BlockTime
+	}
+	// without txs, only an empty block is made in max idle time
+	if self.config.MaxIdleTime != 0 {
+		delays.zeroTxBlockTimeout = self.config.MaxIdleTime
+	}
+	self.msgDelays = delays
+}
+
+func (self *Server) getMsgDelays() msgDelays {
+	self.metaLock.RLock()
+	defer self.metaLock.RUnlock()
+
+	return self.msgDelays
+}
+
+//updateOverlayValidators allow the peers of chain config in consensus overlay of p2p, protected by server.metaLock
+func (self *Server) updateOverlayValidators() {
+	validators := make([]string, 0, len(self.config.Peers))
+	for _, p := range self.config.Peers {
+		validators = append(validators, p.ID)
+	}
+	self.p2p.SetValidators(validators)
+}
+
 //updateChainCofig
 func (self *Server) updateChainConfig() error {
 	block, _ := self.blockPool.getSealedBlock(self.completedBlockNum)
@@ -355,17 +393,19 @@ func (self *Server) updateChainConfig() error {
 	self.metaLock.Lock()
 	self.config = block.Info.NewChainConfig
 	self.LastConfigBlockNum = block.getLastConfigBlockNum()
+	self.updateMsgDelays()
 	self.metaLock.Unlock()
 
 	self.metaLock.RLock()
 	defer self.metaLock.RUnlock()
+	self.updateOverlayValidators()
 
 	// TODO
 	// 1. update peer pool
 	// 2. remove nonparticipation consensus node
 	// 3. update statemgr peers
 	// 4. reset remove peer connections, create new connections with new peers
-	pubkey := vconfig.PubkeyID(self.account.PublicKey)
+	pubkey := vconfig.PubkeyID(self.signer.PublicKey())
 	peermap := make(map[uint32]string)
 	for _, p := range self.config.Peers {
 		peermap[p.Index] = p.ID
@@ -426,7 +466,7 @@ func (self *Server) initialize() error {
 	// TODO: load config from chain
 
 	// TODO: configurable log
-	selfNodeId := vconfig.PubkeyID(self.account.PublicKey)
+	selfNodeId := vconfig.PubkeyID(self.signer.PublicKey())
 	log.Infof("server: %s starting", selfNodeId)
 
 	store, err := OpenBlockStore(self.ledger, self.pid)
@@ -475,7 +515,7 @@ func (self *Server) initialize() error {
 	}
 
 	//index equal math.MaxUint32  is noconsensus node
-	id := vconfig.PubkeyID(self.account.PublicKey)
+	id := vconfig.PubkeyID(self.signer.PublicKey())
 	index, present := self.peerPool.GetPeerIndex(id)
 	if present {
 		self.Index = index
@@ -515,7 +555,7 @@ func (self *Server) initialize() error {
 
 func (self *Server) start() error {
 	// check if server pubkey support VRF
-	if !vrf.ValidatePrivateKey(self.account.PrivateKey) || !vrf.ValidatePublicKey(self.account.PublicKey) {
+	if !vrf.ValidatePublicKey(self.signer.PublicKey()) {
 		return fmt.Errorf("server %d consensus start failed: invalid account key for VRF", self.Index)
 	}
 
@@ -2183,6 +2223,9 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 		return fmt.Errorf("server %d ignore deprecatd blk proposal %d, current %d",
 			self.Index, blkNum, self.GetCurrentBlockNo())
 	}
+	if config.DefConfig.Genesis.GetConsensusType(blkNum) != config.CONSENSUS_TYPE_VBFT {
+		return fmt.Errorf("server %d ignore blk proposal %d, which is not generated by vbft", self.Index, blkNum)
+	}
 
 	validHeight := self.validHeight(blkNum)
 	sysTxs := make([]*types.Transaction, 0)
@@ -2218,8 +2261,24 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 				userTxs = append(userTxs, e.Tx)
 			}
 		}
+		blockLimit, err := self.ledger.GetBlockLimit()
+		if err != nil {
+			return fmt.Errorf("GetBlockLimit failed:%s", err)
+		}
+		userTxs = blockLimit.SelectTransactions(sysTxs, userTxs)
 	}
-	proposal, err := self.constructProposalMsg(blkNum, sysTxs, userTxs, cfg)
+	//the sign guard refuses another block at the same height and round, broadcast the proposal signed before
+	if state := self.signer.GetSignState(signer.STEP_PROPOSAL); state != nil && state.Height == blkNum &&
+		state.Round == emptyRound(forEmpty) {
+		proposal := self.findSignedProposal(blkNum, state.DataHash)
+		if proposal == nil {
+			return fmt.Errorf("server %d already signed another proposal for block %d", self.Index, blkNum)
+		}
+		log.Infof("server %d rebroadcast proposal for block %d", self.Index, blkNum)
+		self.broadcast(proposal)
+		return nil
+	}
+	proposal, err := self.constructProposalMsg(blkNum, sysTxs, userTxs, cfg, forEmpty)
 	if err != nil {
 		return fmt.Errorf("failed to construct proposal: %s", err)
 	}
@@ -2234,6 +2293,21 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 	return nil
 }
 
+//findSignedProposal return the proposal of self at block whose block hash is signed with the data hash
+func (self *Server) findSignedProposal(blkNum uint32, dataHash common.Uint256) *blockProposalMsg {
+	for _, msg := range self.msgPool.GetProposalMsgs(blkNum) {
+		p, ok := msg.(*blockProposalMsg)
+		if !ok || p.Block.getProposer() != self.Index {
+			continue
+		}
+		blkHash := p.Block.Block.Hash()
+		if common.Uint256(sha256.Sum256(blkHash[:])) == dataHash {
+			return p
+		}
+	}
+	return nil
+}
+
 func (self *Server) makeCommitment(proposal *blockProposalMsg, blkNum uint32, forEmpty bool) error {
 	if err := self.commitBlock(proposal, forEmpty); err != nil {
 		return fmt.Errorf("failed to commit block proposal (%d): %s", blkNum, err)
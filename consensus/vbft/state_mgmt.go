@@ -103,12 +103,12 @@ func (self *StateMgr) getState() ServerState {
 }
 
 func (self *StateMgr) run() {
-	self.liveTicker = time.AfterFunc(peerHandshakeTimeout*5, func() {
+	self.liveTicker = time.AfterFunc(self.server.getMsgDelays().peerHandshakeTimeout*5, func() {
 		self.StateEventC <- &StateEvent{
 			Type:     LiveTick,
 			blockNum: self.server.GetCommittedBlockNo(),
 		}
-		self.liveTicker.Reset(peerHandshakeTimeout * 3)
+		self.liveTicker.Reset(self.server.getMsgDelays().peerHandshakeTimeout * 3)
 	})
 
 	// wait config done
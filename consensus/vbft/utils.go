@@ -30,6 +30,7 @@ import (
 	"github.com/TesraSupernet/Tesra/account"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/consensus/vbft/config"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/signature"
@@ -95,7 +96,7 @@ type vrfData struct {
 	PrevVrf  []byte `json:"prev_vrf"`
 }
 
-func computeVrf(sk keypair.PrivateKey, blkNum uint32, prevVrf []byte) ([]byte, []byte, error) {
+func computeVrf(vrfSigner signer.Signer, blkNum uint32, prevVrf []byte) ([]byte, []byte, error) {
 	data, err := json.Marshal(&vrfData{
 		BlockNum: blkNum,
 		PrevVrf:  prevVrf,
@@ -104,7 +105,18 @@ func computeVrf(sk keypair.PrivateKey, blkNum uint32, prevVrf []byte) ([]byte, [
 		return nil, nil, fmt.Errorf("computeVrf failed to marshal vrfData: %s", err)
 	}
 
-	return vrf.Vrf(sk, data)
+	return vrfSigner.Vrf(data)
+}
+
+//emptyRound is the sign round of a block, proposal for empty block is signed at round 1. Vbft has no view number,
+//so a node signs at most one block of each round per height, and a proposal made again is the one signed before.
+//Endorse and commit votes are guarded the same way regardless of proposer: at most one block, and on timeout one
+//empty block after it, but never a block after the empty one
+func emptyRound(forEmpty bool) uint32 {
+	if forEmpty {
+		return 1
+	}
+	return 0
 }
 
 func verifyVrf(pk keypair.PublicKey, blkNum uint32, prevVrf, newVrf, proof []byte) error {
@@ -157,6 +169,8 @@ func GetVbftConfigInfo(memdb *overlaydb.MemDB) (*config.VBFTConfig, error) {
 			HashMsgDelay:         uint32(preCfg.Configuration.HashMsgDelay),
 			PeerHandshakeTimeout: uint32(preCfg.Configuration.PeerHandshakeTimeout),
 			MaxBlockChangeView:   uint32(preCfg.Configuration.MaxBlockChangeView),
+			BlockTime:            preCfg.Configuration.BlockTime,
+			MaxIdleTime:          preCfg.Configuration.MaxIdleTime,
 		}
 	} else {
 		data, err := GetStorageValue(memdb, ledger.DefLedger, nutils.GovernanceContractAddress, []byte(gov.VBFT_CONFIG))
@@ -177,6 +191,8 @@ func GetVbftConfigInfo(memdb *overlaydb.MemDB) (*config.VBFTConfig, error) {
 			HashMsgDelay:         uint32(cfg.HashMsgDelay),
 			PeerHandshakeTimeout: uint32(cfg.PeerHandshakeTimeout),
 			MaxBlockChangeView:   uint32(cfg.MaxBlockChangeView),
+			BlockTime:            cfg.BlockTime,
+			MaxIdleTime:          cfg.MaxIdleTime,
 		}
 	}
 	return chainconfig, nil
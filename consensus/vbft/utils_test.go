@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/common"
 )
 
@@ -88,9 +89,10 @@ func TestVrfParticipantSeed(t *testing.T) {
 
 func TestVrf(t *testing.T) {
 	user := account.NewAccount("")
+	vrfSigner, _ := signer.NewLocalSigner(user)
 	prevVrf := []byte("test string")
 	blkNum := uint32(10)
-	v1, p1, err := computeVrf(user.PrivKey(), blkNum, prevVrf)
+	v1, p1, err := computeVrf(vrfSigner, blkNum, prevVrf)
 	if err != nil {
 		t.Fatalf("compute vrf: %s", err)
 	}
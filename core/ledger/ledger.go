@@ -20,6 +20,9 @@ package ledger
 
 import (
 	"fmt"
+	"io"
+	"time"
+
 	"github.com/TesraSupernet/tesracrypto/keypair"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/log"
@@ -48,6 +51,17 @@ func NewLedger(dataDir string, stateHashHeight uint32) (*Ledger, error) {
 	}, nil
 }
 
+//NewReadOnlyLedger open the ledger in data dir used by another process read only, see ledgerstore.NewReadOnlyLedgerStore
+func NewReadOnlyLedger(dataDir, checkpointDir string, stateHashHeight uint32, refreshInterval time.Duration) (*Ledger, error) {
+	ldgStore, err := ledgerstore.NewReadOnlyLedgerStore(dataDir, checkpointDir, stateHashHeight, refreshInterval)
+	if err != nil {
+		return nil, fmt.Errorf("NewReadOnlyLedgerStore error %s", err)
+	}
+	return &Ledger{
+		ldgStore: ldgStore,
+	}, nil
+}
+
 func (self *Ledger) GetStore() store.LedgerStore {
 	return self.ldgStore
 }
@@ -64,6 +78,10 @@ func (self *Ledger) AddHeaders(headers []*types.Header) error {
 	return self.ldgStore.AddHeaders(headers)
 }
 
+func (self *Ledger) AddCheckpointHeaders(headers []*types.Header, checkpoint common.Uint256) error {
+	return self.ldgStore.AddCheckpointHeaders(headers, checkpoint)
+}
+
 func (self *Ledger) AddBlock(block *types.Block, stateMerkleRoot common.Uint256) error {
 	err := self.ldgStore.AddBlock(block, stateMerkleRoot)
 	if err != nil {
@@ -167,6 +185,10 @@ func (self *Ledger) GetStorageItem(codeHash common.Address, key []byte) ([]byte,
 	return storageItem.Value, nil
 }
 
+func (self *Ledger) GetStorageUsage(contractAddress common.Address) (*states.StorageUsage, error) {
+	return self.ldgStore.GetStorageUsage(contractAddress)
+}
+
 func (self *Ledger) GetContractState(contractHash common.Address) (*payload.DeployCode, error) {
 	return self.ldgStore.GetContractState(contractHash)
 }
@@ -175,6 +197,10 @@ func (self *Ledger) GetMerkleProof(proofHeight, rootHeight uint32) ([]common.Uin
 	return self.ldgStore.GetMerkleProof(proofHeight, rootHeight)
 }
 
+func (self *Ledger) GetBlockLimit() (*types.BlockLimit, error) {
+	return self.ldgStore.GetBlockLimit()
+}
+
 func (self *Ledger) PreExecuteContract(tx *types.Transaction) (*cstate.PreExecResult, error) {
 	return self.ldgStore.PreExecuteContract(tx)
 }
@@ -187,6 +213,29 @@ func (self *Ledger) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify
 	return self.ldgStore.GetEventNotifyByBlock(height)
 }
 
+func (self *Ledger) FindEvents(contract common.Address, topic string, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error) {
+	return self.ldgStore.FindEvents(contract, topic, fromHeight, toHeight)
+}
+
+//GetPrunedHeight return the height up to which block bodies and events have been pruned, 0 for archive node
+func (self *Ledger) GetPrunedHeight() uint32 {
+	return self.ldgStore.GetPrunedHeight()
+}
+
+func (self *Ledger) ExportStateSnapshot(w io.Writer) (*store.StateSnapshotInfo, error) {
+	return self.ldgStore.ExportStateSnapshot(w)
+}
+
+//ImportStateSnapshot should be called before Init, on an empty ledger
+func (self *Ledger) ImportStateSnapshot(r io.ReadSeeker, checkpoint *store.StateSnapshotCheckpoint) (*store.StateSnapshotInfo, error) {
+	return self.ldgStore.ImportStateSnapshot(r, checkpoint)
+}
+
+//RollbackTo revert the ledger to the block at height, should be called when node services are stopped
+func (self *Ledger) RollbackTo(height uint32) error {
+	return self.ldgStore.RollbackTo(height)
+}
+
 func (self *Ledger) Close() error {
 	return self.ldgStore.Close()
 }
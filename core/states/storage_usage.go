@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package states
+
+import (
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/errors"
+)
+
+//StorageUsage is the bytes and count of storage items occupied by a contract
+type StorageUsage struct {
+	Bytes uint64 //Sum of key and value lengths of storage items
+	Keys  uint64
+}
+
+func (this *StorageUsage) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Bytes)
+	sink.WriteUint64(this.Keys)
+}
+
+func (this *StorageUsage) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Bytes, eof = source.NextUint64()
+	if eof {
+		return errors.NewDetailErr(io.ErrUnexpectedEOF, errors.ErrNoCode, "[StorageUsage], Bytes Deserialize failed.")
+	}
+	this.Keys, eof = source.NextUint64()
+	if eof {
+		return errors.NewDetailErr(io.ErrUnexpectedEOF, errors.ErrNoCode, "[StorageUsage], Keys Deserialize failed.")
+	}
+	return nil
+}
+
+//Add apply the change of bytes and keys to usage
+func (this *StorageUsage) Add(bytes, keys int64) {
+	this.Bytes = addUsage(this.Bytes, bytes)
+	this.Keys = addUsage(this.Keys, keys)
+}
+
+func addUsage(usage uint64, delta int64) uint64 {
+	if delta < 0 && uint64(-delta) > usage {
+		return 0
+	}
+	return uint64(int64(usage) + delta)
+}
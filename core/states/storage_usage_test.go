@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package states
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageUsage(t *testing.T) {
+	usage := &StorageUsage{Bytes: 100, Keys: 3}
+	usage.Add(50, 1)
+	assert.Equal(t, &StorageUsage{Bytes: 150, Keys: 4}, usage)
+	usage.Add(-200, -1)
+	assert.Equal(t, &StorageUsage{Bytes: 0, Keys: 3}, usage)
+
+	other := &StorageUsage{}
+	assert.Nil(t, other.Deserialization(common.NewZeroCopySource(common.SerializeToBytes(usage))))
+	assert.Equal(t, usage, other)
+	assert.NotNil(t, other.Deserialization(common.NewZeroCopySource([]byte{1, 2, 3})))
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package badgerstore
+
+import (
+	"errors"
+	"runtime"
+
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
+)
+
+//default block cache size when block compression enabled, badger needs cache to hold decompressed blocks
+const DEFAULT_BLOCK_CACHE_SIZE = 64 << 20
+
+//value log gc discard ratio used by Compact
+const VALUE_LOG_GC_DISCARD_RATIO = 0.5
+
+//min max table size in MB, the badger default. The size limit of a transaction is 15% of max table size, so write
+//buffers below it are ignored to keep the batch of a block fit in one transaction
+const MIN_MAX_TABLE_SIZE = 64
+
+//BadgerDB store
+type BadgerStore struct {
+	db    *badger.DB // BadgerDB instance
+	batch []batchOp  // ops of batch, written only by BatchCommit
+}
+
+//batchOp is a put, or a delete if value is nil
+type batchOp struct {
+	key   []byte
+	value []byte
+}
+
+//NewBadgerStore return BadgerStore instance tuned by options, nil options means default
+func NewBadgerStore(dir string, opts *common.PersistStoreOptions) (*BadgerStore, error) {
+	return open(badger.DefaultOptions(dir).WithTruncate(true), opts)
+}
+
+//NewMemBadgerStore return BadgerStore instance hold all data in memory
+func NewMemBadgerStore() (*BadgerStore, error) {
+	return open(badger.DefaultOptions("").WithInMemory(true), nil)
+}
+
+func open(o badger.Options, opts *common.PersistStoreOptions) (*BadgerStore, error) {
+	if opts == nil {
+		opts = &common.PersistStoreOptions{}
+	}
+	if opts.OpenFiles > 0 {
+		return nil, errors.New("max open files is not supported by badger backend")
+	}
+	o = o.WithLogger(logger{}).WithDetectConflicts(false)
+	if !opts.DisableCompression {
+		o = o.WithCompression(options.Snappy).WithBlockCacheSize(DEFAULT_BLOCK_CACHE_SIZE)
+	}
+	if opts.CacheSize > 0 {
+		o = o.WithBlockCacheSize(int64(opts.CacheSize) << 20)
+	}
+	if opts.WriteBuffer >= MIN_MAX_TABLE_SIZE {
+		o = o.WithMaxTableSize(int64(opts.WriteBuffer) << 20)
+	} else if opts.WriteBuffer > 0 {
+		log.Warnf("[badger]write buffer %dMB is below the minimum %dMB, ignored", opts.WriteBuffer, MIN_MAX_TABLE_SIZE)
+	}
+	db, err := badger.Open(o)
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{
+		db: db,
+	}, nil
+}
+
+//Put a key-value pair to badger
+func (self *BadgerStore) Put(key []byte, value []byte) error {
+	return self.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(copyBytes(key), copyBytes(value))
+	})
+}
+
+//Get the value of a key from badger
+func (self *BadgerStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := self.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+//Has return whether the key is exist in badger
+func (self *BadgerStore) Has(key []byte) (bool, error) {
+	err := self.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//Delete the the in badger
+func (self *BadgerStore) Delete(key []byte) error {
+	return self.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(copyBytes(key))
+	})
+}
+
+//NewBatch start commit batch
+func (self *BadgerStore) NewBatch() {
+	self.batch = nil
+}
+
+//BatchPut put a key-value pair to badger batch
+func (self *BadgerStore) BatchPut(key []byte, value []byte) {
+	self.batch = append(self.batch, batchOp{key: copyBytes(key), value: copyBytes(value)})
+}
+
+//BatchDelete delete a key to badger batch
+func (self *BadgerStore) BatchDelete(key []byte) {
+	self.batch = append(self.batch, batchOp{key: copyBytes(key)})
+}
+
+//BatchCommit commit batch to badger in one transaction, nothing of the batch is written if failed. A batch exceeding
+//the size limit of a transaction fails with badger.ErrTxnTooBig
+func (self *BadgerStore) BatchCommit() error {
+	batch := self.batch
+	self.batch = nil
+	txn := self.db.NewTransaction(true)
+	for _, op := range batch {
+		if err := op.apply(txn); err != nil {
+			txn.Discard()
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+func (self batchOp) apply(txn *badger.Txn) error {
+	if self.value == nil {
+		return txn.Delete(self.key)
+	}
+	return txn.Set(self.key, self.value)
+}
+
+//Compact the whole badger, reclaim the space of deleted keys in lsm tree and value log
+func (self *BadgerStore) Compact() error {
+	err := self.db.Flatten(runtime.NumCPU())
+	if err != nil {
+		return err
+	}
+	for {
+		err = self.db.RunValueLogGC(VALUE_LOG_GC_DISCARD_RATIO)
+		if err == badger.ErrNoRewrite || err == badger.ErrGCInMemoryMode {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+//Close badger
+func (self *BadgerStore) Close() error {
+	self.batch = nil
+	return self.db.Close()
+}
+
+//NewIterator return a iterator of badger with the key prefix
+func (self *BadgerStore) NewIterator(prefix []byte) common.StoreIterator {
+	txn := self.db.NewTransaction(false)
+	opt := badger.DefaultIteratorOptions
+	opt.Prefix = prefix
+	return &Iterator{
+		txn:    txn,
+		iter:   txn.NewIterator(opt),
+		prefix: prefix,
+	}
+}
+
+func copyBytes(data []byte) []byte {
+	return append([]byte{}, data...)
+}
+
+//logger route badger log to node log, badger info log is too verbose so it's logged as debug
+type logger struct{}
+
+func (logger) Errorf(format string, a ...interface{})   { log.Errorf(format, a...) }
+func (logger) Warningf(format string, a ...interface{}) { log.Warnf(format, a...) }
+func (logger) Infof(format string, a ...interface{})    { log.Debugf(format, a...) }
+func (logger) Debugf(format string, a ...interface{})   { log.Debugf(format, a...) }
@@ -0,0 +1,129 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package badgerstore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadgerStore(t *testing.T) {
+	store, err := NewMemBadgerStore()
+	assert.Nil(t, err)
+	defer store.Close()
+
+	err = store.Put([]byte("foo"), []byte("bar"))
+	assert.Nil(t, err)
+	v, err := store.Get([]byte("foo"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("bar"), v)
+	err = store.Delete([]byte("foo"))
+	assert.Nil(t, err)
+	ok, err := store.Has([]byte("foo"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, err = store.Get([]byte("foo"))
+	assert.Equal(t, common.ErrNotFound, err)
+
+	store.NewBatch()
+	store.BatchPut([]byte("a1"), []byte("v1"))
+	store.BatchPut([]byte("a2"), []byte("v2"))
+	store.BatchPut([]byte("b1"), []byte("v3"))
+	store.BatchPut([]byte("a3"), []byte("v4"))
+	store.BatchDelete([]byte("a3"))
+	err = store.BatchCommit()
+	assert.Nil(t, err)
+
+	iter := store.NewIterator([]byte("a"))
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	assert.Equal(t, []string{"a1", "a2"}, keys)
+	assert.True(t, iter.First())
+	assert.Equal(t, []byte("v1"), iter.Value())
+	iter.Release()
+	assert.Nil(t, iter.Error())
+
+	assert.Nil(t, store.Compact())
+}
+
+func TestBadgerStoreBatchTooBig(t *testing.T) {
+	store, err := NewMemBadgerStore()
+	assert.Nil(t, err)
+	defer store.Close()
+
+	//the batch exceeds the size limit of a transaction
+	value := make([]byte, 1<<20)
+	txn := store.db.NewTransaction(true)
+	for i := 0; err == nil; i++ {
+		err = txn.Set([]byte{byte(i)}, value)
+	}
+	txn.Discard()
+	assert.Equal(t, badger.ErrTxnTooBig, err)
+
+	//none of the batch is written
+	store.NewBatch()
+	for i := 0; i < 64; i++ {
+		store.BatchPut([]byte{byte(i)}, value)
+	}
+	assert.Equal(t, badger.ErrTxnTooBig, store.BatchCommit())
+	for i := 0; i < 64; i++ {
+		ok, err := store.Has([]byte{byte(i)})
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	}
+
+	//nothing of a batch is written before commit
+	store.NewBatch()
+	store.BatchPut([]byte("foo"), []byte("bar"))
+	ok, err := store.Has([]byte("foo"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, store.BatchCommit())
+	v, err := store.Get([]byte("foo"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("bar"), v)
+}
+
+func TestBadgerStoreMinWriteBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewBadgerStore(dir, &common.PersistStoreOptions{WriteBuffer: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15*MIN_MAX_TABLE_SIZE<<20/100), store.db.MaxBatchSize())
+	assert.Nil(t, store.Close())
+
+	store, err = NewBadgerStore(dir, &common.PersistStoreOptions{WriteBuffer: 2 * MIN_MAX_TABLE_SIZE})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(15*2*MIN_MAX_TABLE_SIZE<<20/100), store.db.MaxBatchSize())
+	assert.Nil(t, store.Close())
+}
+
+func TestBadgerStoreOpenFiles(t *testing.T) {
+	_, err := NewBadgerStore(t.Name(), &common.PersistStoreOptions{OpenFiles: 100})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package badgerstore
+
+import (
+	"github.com/dgraph-io/badger/v2"
+)
+
+//Iterator of badger, behave like leveldb iterator which is positioned before the first item when created
+type Iterator struct {
+	txn     *badger.Txn
+	iter    *badger.Iterator
+	prefix  []byte
+	started bool
+	key     []byte
+	value   []byte
+	err     error
+}
+
+//Next item. If item available return true, otherwise return false
+func (self *Iterator) Next() bool {
+	if self.err != nil {
+		return false
+	}
+	if !self.started {
+		return self.First()
+	}
+	self.iter.Next()
+	return self.load()
+}
+
+//First item. If item available return true, otherwise return false
+func (self *Iterator) First() bool {
+	if self.err != nil {
+		return false
+	}
+	self.started = true
+	self.iter.Seek(self.prefix)
+	return self.load()
+}
+
+func (self *Iterator) load() bool {
+	self.key, self.value = nil, nil
+	if !self.iter.ValidForPrefix(self.prefix) {
+		return false
+	}
+	item := self.iter.Item()
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		self.err = err
+		return false
+	}
+	self.key = item.KeyCopy(nil)
+	self.value = value
+	return true
+}
+
+//Key return the current item key
+func (self *Iterator) Key() []byte {
+	return self.key
+}
+
+//Value return the current item value
+func (self *Iterator) Value() []byte {
+	return self.value
+}
+
+//Release close iterator
+func (self *Iterator) Release() {
+	self.iter.Close()
+	self.txn.Discard()
+}
+
+//Error returns any accumulated error
+func (self *Iterator) Error() error {
+	return self.err
+}
@@ -27,6 +27,7 @@ const (
 	DATA_HEADER                            = 0x01 //Block hash => block hash key prefix
 	DATA_TRANSACTION                       = 0x02 //Transction hash = > transaction key prefix
 	DATA_STATE_MERKLE_ROOT                 = 0x21 // block height => write set hash + state merkle root
+	DATA_REVERSE_WRITE_SET                 = 0x22 // block height => previous values of keys written by block
 
 	// Transaction
 	ST_BOOKKEEPER DataEntryPrefix = 0x03 //BookKeeper state key prefix
@@ -36,6 +37,7 @@ const (
 	ST_VOTE       DataEntryPrefix = 0x08 //Vote state key prefix
 
 	IX_HEADER_HASH_LIST DataEntryPrefix = 0x09 //Block height => block hash key prefix
+	IX_STORAGE_USAGE    DataEntryPrefix = 0x23 //Contract address => storage usage, in state hash while storage quota or rent enabled
 
 	//SYSTEM
 	SYS_CURRENT_BLOCK      DataEntryPrefix = 0x10 //Current block key prefix
@@ -43,6 +45,8 @@ const (
 	SYS_CURRENT_STATE_ROOT DataEntryPrefix = 0x12 //no use
 	SYS_BLOCK_MERKLE_TREE  DataEntryPrefix = 0x13 // Block merkle tree root key prefix
 	SYS_STATE_MERKLE_TREE  DataEntryPrefix = 0x20 // state merkle tree root key prefix
+	SYS_PRUNED_HEIGHT      DataEntryPrefix = 0x15 // Pruned block height key prefix
 
 	EVENT_NOTIFY DataEntryPrefix = 0x14 //Event notify key prefix
+	EVENT_BLOOM  DataEntryPrefix = 0x16 //Block height => bloom filter of event notifies key prefix
 )
@@ -26,6 +26,7 @@ import (
 )
 
 var ErrNotFound = errors.New("not found")
+var ErrPruned = errors.New("data pruned")
 
 //Store iterator for iterate store
 type StoreIterator interface {
@@ -52,6 +53,15 @@ type PersistStore interface {
 	BatchCommit() error                      //Commit batch to store
 	Close() error                            //Close store
 	NewIterator(prefix []byte) StoreIterator //Return the iterator of store
+	Compact() error                          //Reclaim the space of deleted keys
+}
+
+//PersistStoreOptions tuning options of persist store, zero value means backend default
+type PersistStoreOptions struct {
+	CacheSize          int  //block cache size in MB
+	WriteBuffer        int  //write buffer size in MB
+	OpenFiles          int  //max open files
+	DisableCompression bool //store blocks without compression
 }
 
 //StateStore save result of smart contract execution, before commit to store
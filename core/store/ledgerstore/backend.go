@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/store/badgerstore"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/store/leveldbstore"
+)
+
+//count of key-value pairs committed in one batch when copying store
+const MIGRATE_BATCH_SIZE = 10000
+
+//bytes of key-value pairs committed in one batch when copying store, badger refuses too big batch
+const MIGRATE_BATCH_BYTES = 4 << 20
+
+//files only created by one backend, used to detect the backend of an existing db dir
+var backendMarkFiles = map[string]string{
+	config.DB_BACKEND_LEVELDB: "CURRENT",
+	config.DB_BACKEND_BADGER:  "KEYREGISTRY",
+}
+
+//NewPersistStore open db dir with the storage backend and tuning options in node config
+func NewPersistStore(dbDir string) (scom.PersistStore, error) {
+	cfg := config.DefConfig.Common
+	return OpenPersistStore(cfg.DBBackend, dbDir, GetPersistStoreOptions(cfg))
+}
+
+//GetPersistStoreOptions return tuning options of persist store in node config
+func GetPersistStoreOptions(cfg *config.CommonConfig) *scom.PersistStoreOptions {
+	return &scom.PersistStoreOptions{
+		CacheSize:          int(cfg.DBCacheSize),
+		WriteBuffer:        int(cfg.DBWriteBuffer),
+		OpenFiles:          int(cfg.DBOpenFiles),
+		DisableCompression: cfg.DBCompression == config.DB_COMPRESSION_NONE,
+	}
+}
+
+//OpenPersistStore open db dir with the storage backend. Opening a dir created by another backend is refused,
+//it should be converted by migratedb command first
+func OpenPersistStore(backend, dbDir string, opts *scom.PersistStoreOptions) (scom.PersistStore, error) {
+	if backend == "" {
+		backend = config.DEFAULT_DB_BACKEND
+	}
+	if _, ok := backendMarkFiles[backend]; !ok {
+		return nil, fmt.Errorf("unsupported storage backend %s", backend)
+	}
+	if other := DetectBackend(dbDir); other != "" && other != backend {
+		return nil, fmt.Errorf("db %s was created by %s backend, cannot open with %s backend", dbDir, other, backend)
+	}
+	switch backend {
+	case config.DB_BACKEND_BADGER:
+		return badgerstore.NewBadgerStore(dbDir, opts)
+	default:
+		return leveldbstore.NewLevelDBStoreWithOptions(dbDir, opts)
+	}
+}
+
+//OpenSecondaryStore open db dir used by another process read only, checkpoints of it are made in checkpoint dir.
+//Only leveldb backend is supported
+func OpenSecondaryStore(dbDir, checkpointDir string) (*leveldbstore.SecondaryLevelDBStore, error) {
+	backend := DetectBackend(dbDir)
+	if backend == "" {
+		return nil, fmt.Errorf("db %s not found", dbDir)
+	}
+	if backend != config.DB_BACKEND_LEVELDB {
+		return nil, fmt.Errorf("db %s of %s backend cannot be opened read only", dbDir, backend)
+	}
+	return leveldbstore.NewSecondaryLevelDBStore(dbDir, checkpointDir, GetPersistStoreOptions(config.DefConfig.Common))
+}
+
+//DetectBackend return the backend which created db dir, empty if dir not exist or empty
+func DetectBackend(dbDir string) string {
+	for backend, file := range backendMarkFiles {
+		if _, err := os.Stat(filepath.Join(dbDir, file)); err == nil {
+			return backend
+		}
+	}
+	return ""
+}
+
+//CopyPersistStore copy all key-value pairs of src to dst in batches, return the count of copied pairs
+func CopyPersistStore(dst, src scom.PersistStore) (uint64, error) {
+	iter := src.NewIterator(nil)
+	defer iter.Release()
+	count := uint64(0)
+	size := 0
+	dst.NewBatch()
+	for iter.Next() {
+		dst.BatchPut(iter.Key(), iter.Value())
+		count++
+		size += len(iter.Key()) + len(iter.Value())
+		if count%MIGRATE_BATCH_SIZE == 0 || size >= MIGRATE_BATCH_BYTES {
+			if err := dst.BatchCommit(); err != nil {
+				return count, err
+			}
+			dst.NewBatch()
+			size = 0
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return count, err
+	}
+	return count, dst.BatchCommit()
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/core/store/badgerstore"
+	"github.com/TesraSupernet/Tesra/core/store/leveldbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyPersistStoreToBadger(t *testing.T) {
+	src, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	defer src.Close()
+	dst, err := badgerstore.NewMemBadgerStore()
+	assert.Nil(t, err)
+	defer dst.Close()
+
+	//larger than a badger transaction in total
+	value := make([]byte, 1<<20)
+	for i := 0; i < 32; i++ {
+		assert.Nil(t, src.Put([]byte{byte(i)}, value))
+	}
+	count, err := CopyPersistStore(dst, src)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(32), count)
+	for i := 0; i < 32; i++ {
+		ok, err := dst.Has([]byte{byte(i)})
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+}
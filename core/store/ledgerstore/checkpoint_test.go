@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	vconfig "github.com/TesraSupernet/Tesra/consensus/vbft/config"
+	"github.com/TesraSupernet/Tesra/core/genesis"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddCheckpointHeaders(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore("test/checkpoint", 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(block, bookkeepers))
+
+	payload, err := json.Marshal(&vconfig.VbftBlockInfo{})
+	assert.Nil(t, err)
+	headers := make([]*types.Header, 0)
+	prev := block.Header
+	for i := uint32(1); i <= 3; i++ {
+		header := &types.Header{
+			PrevBlockHash:    prev.Hash(),
+			Height:           i,
+			Timestamp:        prev.Timestamp + 1,
+			ConsensusPayload: payload,
+			Bookkeepers:      bookkeepers,
+		}
+		headers = append(headers, header)
+		prev = header
+	}
+
+	//bookkeeper is not a vbft peer
+	assert.NotNil(t, ledgerStore.AddHeaders(headers))
+	assert.NotNil(t, ledgerStore.AddCheckpointHeaders(headers, common.Uint256{1}))
+	assert.NotNil(t, ledgerStore.AddCheckpointHeaders(headers[1:], prev.Hash()))
+	assert.Equal(t, uint32(0), ledgerStore.GetCurrentHeaderHeight())
+	assert.Nil(t, ledgerStore.AddCheckpointHeaders(headers, prev.Hash()))
+	assert.Equal(t, uint32(3), ledgerStore.GetCurrentHeaderHeight())
+	assert.Equal(t, prev.Hash(), ledgerStore.GetCurrentHeaderHash())
+
+	assert.Nil(t, ledgerStore.verifyBlockHeader(headers[0]))
+	forged := &types.Header{
+		PrevBlockHash:    block.Hash(),
+		Height:           1,
+		Timestamp:        headers[0].Timestamp + 1,
+		ConsensusPayload: payload,
+		Bookkeepers:      bookkeepers,
+	}
+	assert.NotNil(t, ledgerStore.verifyBlockHeader(forged))
+}
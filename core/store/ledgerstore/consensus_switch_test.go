@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	vconfig "github.com/TesraSupernet/Tesra/consensus/vbft/config"
+	"github.com/TesraSupernet/Tesra/core/genesis"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func signHeader(t *testing.T, header *types.Header, acc *account.Account) *types.Header {
+	hash := header.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	assert.Nil(t, err)
+	header.Bookkeepers = []keypair.PublicKey{acc.PublicKey}
+	header.SigData = [][]byte{sig}
+	return header
+}
+
+func TestVerifyHeaderConsensusSwitch(t *testing.T) {
+	acc := account.NewAccount("")
+	other := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+
+	genesisConfig := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesisConfig }()
+	config.DefConfig.Genesis = &config.GenesisConfig{
+		ConsensusType: config.CONSENSUS_TYPE_SOLO,
+		ConsensusForks: []*config.ConsensusForkConfig{
+			{Height: 2, ConsensusType: config.CONSENSUS_TYPE_VBFT},
+		},
+		SOLO: &config.SOLOConfig{Bookkeepers: []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))}},
+	}
+
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore("test/switch", 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(block, bookkeepers))
+
+	//the last solo block carries the vbft chain config
+	switchPayload, err := json.Marshal(&vconfig.VbftBlockInfo{
+		NewChainConfig: &vconfig.ChainConfig{
+			Peers: []*vconfig.PeerConfig{{Index: 1, ID: vconfig.PubkeyID(acc.PublicKey)}},
+		},
+	})
+	assert.Nil(t, err)
+	newSoloHeader := func(payload []byte) *types.Header {
+		return &types.Header{
+			PrevBlockHash:    block.Hash(),
+			Height:           1,
+			Timestamp:        block.Header.Timestamp + 1,
+			NextBookkeeper:   block.Header.NextBookkeeper,
+			ConsensusPayload: payload,
+		}
+	}
+	//solo block is signed by the next bookkeeper of prev block
+	assert.NotNil(t, ledgerStore.AddHeader(signHeader(t, newSoloHeader(switchPayload), other)))
+	assert.NotNil(t, ledgerStore.AddHeader(signHeader(t, newSoloHeader(nil), acc)))
+	soloHeader := signHeader(t, newSoloHeader(switchPayload), acc)
+	assert.Nil(t, ledgerStore.AddHeader(soloHeader))
+	assert.Equal(t, map[string]uint32{vconfig.PubkeyID(acc.PublicKey): 1}, ledgerStore.vbftPeerInfoheader)
+
+	vbftPayload, err := json.Marshal(&vconfig.VbftBlockInfo{})
+	assert.Nil(t, err)
+	newVbftHeader := func() *types.Header {
+		return &types.Header{
+			PrevBlockHash:    soloHeader.Hash(),
+			Height:           2,
+			Timestamp:        soloHeader.Timestamp + 1,
+			ConsensusPayload: vbftPayload,
+		}
+	}
+	//vbft block is signed by the peers of the vbft chain config
+	assert.NotNil(t, ledgerStore.AddHeader(signHeader(t, newVbftHeader(), other)))
+	assert.Nil(t, ledgerStore.AddHeader(signHeader(t, newVbftHeader(), acc)))
+	assert.Equal(t, uint32(2), ledgerStore.GetCurrentHeaderHeight())
+}
+
+func TestExecuteBlockNextBookkeeper(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+
+	genesisConfig := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesisConfig }()
+	config.DefConfig.Genesis = &config.GenesisConfig{
+		ConsensusType: config.CONSENSUS_TYPE_SOLO,
+		SOLO: &config.SOLOConfig{
+			Bookkeepers: []string{hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))},
+			EpochPeriod: 10,
+		},
+	}
+
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+	ledgerStore, err := NewLedgerStore("test/next_bookkeeper", 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(block, bookkeepers))
+
+	newBlock := func(nextBookkeeper common.Address) *types.Block {
+		return &types.Block{Header: &types.Header{
+			PrevBlockHash:  block.Hash(),
+			Height:         1,
+			Timestamp:      block.Header.Timestamp + 1,
+			NextBookkeeper: nextBookkeeper,
+		}}
+	}
+	//the block is refused before executed
+	_, err = ledgerStore.ExecuteBlock(newBlock(account.NewAccount("").Address))
+	assert.NotNil(t, err)
+	_, err = ledgerStore.ExecuteBlock(newBlock(block.Header.NextBookkeeper))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/TesraSupernet/Tesra/common"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/smartcontract/event"
+)
+
+const (
+	EVENT_BLOOM_BYTE_LEN   = 256 //Bits of bloom is 8 times of bytes
+	EVENT_BLOOM_HASH_COUNT = 3   //Bits set by each item
+)
+
+//EventBloom is bloom filter of contract addresses and topics of event notifies in a block
+type EventBloom [EVENT_BLOOM_BYTE_LEN]byte
+
+//NewEventBloom return bloom of contract addresses and topics of notifies
+func NewEventBloom(notifies []*event.ExecuteNotify) *EventBloom {
+	bloom := &EventBloom{}
+	for _, notify := range notifies {
+		for _, info := range notify.Notify {
+			bloom.Add(genBloomContractItem(info.ContractAddress))
+			bloom.Add(genBloomTopicItem(info.Topic()))
+		}
+	}
+	return bloom
+}
+
+//Add item to bloom
+func (this *EventBloom) Add(item []byte) {
+	for _, bit := range bloomBits(item) {
+		this[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+//Test return false if item is not in bloom, true means item may be in bloom
+func (this *EventBloom) Test(item []byte) bool {
+	for _, bit := range bloomBits(item) {
+		if this[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//MayContain return whether notify of contract with topic may be in bloom, empty topic matches any topic
+func (this *EventBloom) MayContain(contract common.Address, topic string) bool {
+	if !this.Test(genBloomContractItem(contract)) {
+		return false
+	}
+	return topic == "" || this.Test(genBloomTopicItem(topic))
+}
+
+func bloomBits(item []byte) [EVENT_BLOOM_HASH_COUNT]uint {
+	hash := sha256.Sum256(item)
+	var bits [EVENT_BLOOM_HASH_COUNT]uint
+	for i := range bits {
+		bits[i] = uint(binary.BigEndian.Uint16(hash[2*i:])) % (EVENT_BLOOM_BYTE_LEN * 8)
+	}
+	return bits
+}
+
+func genBloomContractItem(contract common.Address) []byte {
+	return append([]byte{0}, contract[:]...)
+}
+
+func genBloomTopicItem(topic string) []byte {
+	return append([]byte{1}, topic...)
+}
+
+//SaveEventBloomByBlock persist bloom of event notifies of block
+func (this *EventStore) SaveEventBloomByBlock(height uint32, notifies []*event.ExecuteNotify) {
+	this.store.BatchPut(genEventBloomKey(height), NewEventBloom(notifies)[:])
+}
+
+//GetEventBloomByBlock return bloom of event notifies of block
+func (this *EventStore) GetEventBloomByBlock(height uint32) (*EventBloom, error) {
+	data, err := this.store.Get(genEventBloomKey(height))
+	if err != nil {
+		return nil, err
+	}
+	bloom := &EventBloom{}
+	copy(bloom[:], data)
+	return bloom, nil
+}
+
+//FindEventNotify return the event notifies of contract with topic in blocks of height range, empty topic matches
+//any topic. Only the notifies matched are kept in result. Blooms of blocks are tested before loading the event
+//notifies, blocks saved without bloom are always loaded
+func (this *EventStore) FindEventNotify(contract common.Address, topic string, fromHeight,
+	toHeight uint32) ([]*event.ExecuteNotify, error) {
+	result := make([]*event.ExecuteNotify, 0)
+	for height := fromHeight; height <= toHeight; height++ {
+		bloom, err := this.GetEventBloomByBlock(height)
+		if err != nil && err != scom.ErrNotFound {
+			return nil, err
+		}
+		if bloom != nil && !bloom.MayContain(contract, topic) {
+			continue
+		}
+		notifies, err := this.GetEventNotifyByBlock(height)
+		if err != nil {
+			if err == scom.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		for _, notify := range notifies {
+			infos := make([]*event.NotifyEventInfo, 0)
+			for _, info := range notify.Notify {
+				if info.ContractAddress == contract && (topic == "" || info.Topic() == topic) {
+					infos = append(infos, info)
+				}
+			}
+			if len(infos) > 0 {
+				matched := *notify
+				matched.Notify = infos
+				result = append(result, &matched)
+			}
+		}
+		if height == toHeight {
+			break
+		}
+	}
+	return result, nil
+}
+
+func genEventBloomKey(height uint32) []byte {
+	key := make([]byte, 5, 5)
+	key[0] = byte(scom.EVENT_BLOOM)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBloom(t *testing.T) {
+	contract := common.Address{1}
+	notifies := []*event.ExecuteNotify{{
+		TxHash: common.Uint256{1},
+		Notify: []*event.NotifyEventInfo{{ContractAddress: contract, States: []interface{}{"transfer", "from", 100}}},
+	}}
+	bloom := NewEventBloom(notifies)
+	assert.True(t, bloom.MayContain(contract, "transfer"))
+	assert.True(t, bloom.MayContain(contract, ""))
+	assert.False(t, bloom.MayContain(common.Address{2}, ""))
+	assert.False(t, bloom.MayContain(contract, "approve"))
+}
+
+func TestFindEventNotify(t *testing.T) {
+	eventStore, err := NewEventStore("test/event_bloom")
+	assert.Nil(t, err)
+	defer eventStore.Close()
+
+	contract := common.Address{1}
+	eventStore.NewBatch()
+	for height := uint32(0); height < 10; height++ {
+		notify := &event.ExecuteNotify{TxHash: common.Uint256{byte(height)}, State: event.CONTRACT_STATE_SUCCESS}
+		topic := "approve"
+		if height%3 == 0 {
+			topic = "transfer"
+		}
+		notify.Notify = []*event.NotifyEventInfo{
+			{ContractAddress: contract, States: []interface{}{topic, height}},
+			{ContractAddress: common.Address{2}, States: "transfer"},
+		}
+		assert.Nil(t, eventStore.SaveEventNotifyByTx(notify.TxHash, notify))
+		eventStore.SaveEventNotifyByBlock(height, []common.Uint256{notify.TxHash})
+		if height != 9 {
+			eventStore.SaveEventBloomByBlock(height, []*event.ExecuteNotify{notify})
+		}
+	}
+	assert.Nil(t, eventStore.CommitTo())
+
+	notifies, err := eventStore.FindEventNotify(contract, "transfer", 1, 9)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(notifies))
+	for i, notify := range notifies {
+		assert.Equal(t, common.Uint256{byte(3 * (i + 1))}, notify.TxHash)
+		assert.Equal(t, 1, len(notify.Notify))
+		assert.Equal(t, "transfer", notify.Notify[0].Topic())
+	}
+	notifies, err = eventStore.FindEventNotify(contract, "", 0, 9)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(notifies))
+	notifies, err = eventStore.FindEventNotify(common.Address{3}, "", 0, 9)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(notifies))
+}
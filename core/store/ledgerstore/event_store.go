@@ -27,19 +27,18 @@ import (
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/common/serialization"
 	scom "github.com/TesraSupernet/Tesra/core/store/common"
-	"github.com/TesraSupernet/Tesra/core/store/leveldbstore"
 	"github.com/TesraSupernet/Tesra/smartcontract/event"
 )
 
 //Saving event notifies gen by smart contract execution
 type EventStore struct {
-	dbDir string                     //Store path
-	store *leveldbstore.LevelDBStore //Store handler
+	dbDir string            //Store path
+	store scom.PersistStore //Store handler
 }
 
 //NewEventStore return event store instance
 func NewEventStore(dbDir string) (*EventStore, error) {
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := NewPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -27,7 +27,6 @@ import (
 	"math"
 	"os"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
@@ -41,8 +40,10 @@ import (
 	"github.com/TesraSupernet/Tesra/core/states"
 	"github.com/TesraSupernet/Tesra/core/store"
 	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/store/leveldbstore"
 	"github.com/TesraSupernet/Tesra/core/store/overlaydb"
 	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/core/vote"
 	"github.com/TesraSupernet/Tesra/errors"
 	"github.com/TesraSupernet/Tesra/events"
 	"github.com/TesraSupernet/Tesra/events/message"
@@ -83,6 +84,13 @@ type LedgerStoreImp struct {
 	vbftPeerInfoblock    map[string]uint32 //pubInfo save pubkey,peerindex
 	lock                 sync.RWMutex
 	stateHashCheckHeight uint32
+	pruneRetention       uint32 //Count of recent blocks kept by pruning, 0 means no pruning
+	prunedHeight         uint32 //Block bodies and events up to this height have been pruned
+	pruneExit            chan bool
+	execWorkers          int  //Count of workers executing transactions of block in parallel
+	readOnly             bool //Ledger is used by another process, and opened on checkpoints of its stores
+	secondaryStores      []*leveldbstore.SecondaryLevelDBStore
+	trustedHeight        uint32 //Headers up to this height are trusted by the hash chain to a checkpoint
 }
 
 //NewLedgerStore return LedgerStoreImp instance
@@ -94,6 +102,9 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, er
 		vbftPeerInfoblock:    make(map[string]uint32),
 		savingBlockSemaphore: make(chan bool, 1),
 		stateHashCheckHeight: stateHashHeight,
+		pruneRetention:       config.DefConfig.Common.PruneRetention,
+		pruneExit:            make(chan bool),
+		execWorkers:          getExecWorkers(),
 	}
 
 	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), true)
@@ -176,9 +187,27 @@ func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.
 			return fmt.Errorf("init error %s", err)
 		}
 	}
-	//load vbft peerInfo
-	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
-	if consensusType == "vbft" {
+	err = this.loadVbftPeerInfo()
+	if err != nil {
+		return err
+	}
+	// check and fix imcompatible states
+	err = this.stateStore.CheckStorage()
+	if err != nil {
+		return err
+	}
+	err = this.stateStore.CheckStorageUsage()
+	if err != nil {
+		return fmt.Errorf("CheckStorageUsage error %s", err)
+	}
+	this.startPruning()
+	return nil
+}
+
+//loadVbftPeerInfo load the vbft peers of current chain config to verify following headers and blocks
+func (this *LedgerStoreImp) loadVbftPeerInfo() error {
+	consensusType := config.DefConfig.Genesis.GetConsensusType(this.GetCurrentBlockHeight() + 1)
+	if consensusType == config.CONSENSUS_TYPE_VBFT {
 		header, err := this.GetHeaderByHash(this.currBlockHash)
 		if err != nil {
 			return err
@@ -213,9 +242,7 @@ func (this *LedgerStoreImp) InitLedgerStoreWithGenesisBlock(genesisBlock *types.
 		}
 		this.lock.Unlock()
 	}
-	// check and fix imcompatible states
-	err = this.stateStore.CheckStorage()
-	return err
+	return nil
 }
 
 func (this *LedgerStoreImp) hasAlreadyInitGenesisBlock() (bool, error) {
@@ -243,6 +270,10 @@ func (this *LedgerStoreImp) init() error {
 	if err != nil {
 		return fmt.Errorf("recoverStore error %s", err)
 	}
+	err = this.loadPrunedHeight()
+	if err != nil {
+		return fmt.Errorf("loadPrunedHeight error %s", err)
+	}
 	return nil
 }
 
@@ -411,29 +442,37 @@ func (this *LedgerStoreImp) getHeaderCache(blockHash common.Uint256) *types.Head
 	return header
 }
 
-func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
-	if header.Height == 0 {
-		return vbftPeerInfo, nil
-	}
-	var prevHeader *types.Header
+//verifyPrevHeader check the header follows its prev header, and return the prev header
+func (this *LedgerStoreImp) verifyPrevHeader(header *types.Header) (*types.Header, error) {
 	prevHeaderHash := header.PrevBlockHash
 	prevHeader, err := this.GetHeaderByHash(prevHeaderHash)
 	if err != nil && err != scom.ErrNotFound {
-		return vbftPeerInfo, fmt.Errorf("get prev header error %s", err)
+		return nil, fmt.Errorf("get prev header error %s", err)
 	}
 	if prevHeader == nil {
-		return vbftPeerInfo, fmt.Errorf("cannot find pre header by blockHash %s", prevHeaderHash.ToHexString())
+		return nil, fmt.Errorf("cannot find pre header by blockHash %s", prevHeaderHash.ToHexString())
 	}
 
 	if prevHeader.Height+1 != header.Height {
-		return vbftPeerInfo, fmt.Errorf("block height is incorrect")
+		return nil, fmt.Errorf("block height is incorrect")
 	}
 
 	if prevHeader.Timestamp >= header.Timestamp {
-		return vbftPeerInfo, fmt.Errorf("block timestamp is incorrect")
+		return nil, fmt.Errorf("block timestamp is incorrect")
+	}
+	return prevHeader, nil
+}
+
+func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	if header.Height == 0 {
+		return vbftPeerInfo, nil
 	}
-	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
-	if consensusType == "vbft" {
+	prevHeader, err := this.verifyPrevHeader(header)
+	if err != nil {
+		return vbftPeerInfo, err
+	}
+	consensusType := config.DefConfig.Genesis.GetConsensusType(header.Height)
+	if consensusType == config.CONSENSUS_TYPE_VBFT {
 		//check bookkeeppers
 		m := len(vbftPeerInfo) - (len(vbftPeerInfo)*6)/7
 		if len(header.Bookkeepers) < m {
@@ -470,7 +509,19 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[
 		if err != nil {
 			return vbftPeerInfo, err
 		}
-		if prevHeader.NextBookkeeper != address {
+		expected := prevHeader.NextBookkeeper
+		if config.DefConfig.Genesis.IsConsensusSwitchHeight(header.Height) {
+			// the first block after consensus switch is signed by the configured bookkeepers
+			bookkeepers, err := config.DefConfig.GetBookkeepersOfConsensus(consensusType)
+			if err != nil {
+				return vbftPeerInfo, err
+			}
+			expected, err = types.AddressFromBookkeepers(bookkeepers)
+			if err != nil {
+				return vbftPeerInfo, err
+			}
+		}
+		if expected != address {
 			return vbftPeerInfo, fmt.Errorf("bookkeeper address error")
 		}
 
@@ -480,12 +531,58 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[
 		if err != nil {
 			return vbftPeerInfo, err
 		}
+		if config.DefConfig.Genesis.GetConsensusType(header.Height+1) == config.CONSENSUS_TYPE_VBFT {
+			// the last block before switching to vbft carries the vbft chain config
+			return vbftPeerInfoFromHeader(header)
+		}
+	}
+	return vbftPeerInfo, nil
+}
+
+//trustHeader check the header follows its prev header without verifying bookkeepers and signatures, which is
+//used for the headers trusted by the hash chain to a checkpoint. The vbft peers are updated by the header
+func (this *LedgerStoreImp) trustHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	if header.Height == 0 {
+		return vbftPeerInfo, nil
+	}
+	_, err := this.verifyPrevHeader(header)
+	if err != nil {
+		return vbftPeerInfo, err
+	}
+	if config.DefConfig.Genesis.GetConsensusType(header.Height) == config.CONSENSUS_TYPE_VBFT {
+		blkInfo, err := vconfig.VbftBlock(header)
+		if err != nil {
+			return vbftPeerInfo, err
+		}
+		if blkInfo.NewChainConfig != nil {
+			return vbftPeerInfoFromHeader(header)
+		}
+	} else if config.DefConfig.Genesis.GetConsensusType(header.Height+1) == config.CONSENSUS_TYPE_VBFT {
+		return vbftPeerInfoFromHeader(header)
 	}
 	return vbftPeerInfo, nil
 }
 
+func vbftPeerInfoFromHeader(header *types.Header) (map[string]uint32, error) {
+	blkInfo, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return nil, err
+	}
+	if blkInfo.NewChainConfig == nil {
+		return nil, fmt.Errorf("missing vbft chain config at height:%d", header.Height)
+	}
+	peerInfo := make(map[string]uint32)
+	for _, p := range blkInfo.NewChainConfig.Peers {
+		peerInfo[p.ID] = p.Index
+	}
+	return peerInfo, nil
+}
+
 //AddHeader add header to cache, and add the mapping of block height to block hash. Using in block sync
 func (this *LedgerStoreImp) AddHeader(header *types.Header) error {
+	if this.readOnly {
+		return ErrReadOnlyLedger
+	}
 	nextHeaderHeight := this.GetCurrentHeaderHeight() + 1
 	if header.Height != nextHeaderHeight {
 		return fmt.Errorf("header height %d not equal next header height %d", header.Height, nextHeaderHeight)
@@ -515,11 +612,71 @@ func (this *LedgerStoreImp) AddHeaders(headers []*types.Header) error {
 	return nil
 }
 
+//AddCheckpointHeaders add the headers ending at checkpoint without verifying signatures. The headers are trusted
+//only if they are linked by hash from the current header to the checkpoint hash
+func (this *LedgerStoreImp) AddCheckpointHeaders(headers []*types.Header, checkpoint common.Uint256) error {
+	if this.readOnly {
+		return ErrReadOnlyLedger
+	}
+	if len(headers) == 0 {
+		return fmt.Errorf("no headers")
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Height < headers[j].Height
+	})
+	prevHash := this.GetCurrentHeaderHash()
+	for _, header := range headers {
+		if header.PrevBlockHash != prevHash {
+			return fmt.Errorf("header height %d not linked to prev header", header.Height)
+		}
+		prevHash = header.Hash()
+	}
+	if prevHash != checkpoint {
+		return fmt.Errorf("header height %d hash %s not match checkpoint %s", headers[len(headers)-1].Height,
+			prevHash.ToHexString(), checkpoint.ToHexString())
+	}
+	var err error
+	for _, header := range headers {
+		nextHeaderHeight := this.GetCurrentHeaderHeight() + 1
+		if header.Height != nextHeaderHeight {
+			return fmt.Errorf("header height %d not equal next header height %d", header.Height, nextHeaderHeight)
+		}
+		this.vbftPeerInfoheader, err = this.trustHeader(header, this.vbftPeerInfoheader)
+		if err != nil {
+			return fmt.Errorf("trustHeader error %s", err)
+		}
+		this.addHeaderCache(header)
+		this.setHeaderIndex(header.Height, header.Hash())
+	}
+	this.lock.Lock()
+	this.trustedHeight = headers[len(headers)-1].Height
+	this.lock.Unlock()
+	return nil
+}
+
+//verifyBlockHeader verify the header of block, the signatures are not verified if the header has been trusted
+//by a checkpoint
+func (this *LedgerStoreImp) verifyBlockHeader(header *types.Header) (err error) {
+	this.lock.RLock()
+	trusted := header.Height <= this.trustedHeight
+	this.lock.RUnlock()
+	if trusted && header.Hash() == this.getHeaderIndex(header.Height) {
+		this.vbftPeerInfoblock, err = this.trustHeader(header, this.vbftPeerInfoblock)
+	} else {
+		this.vbftPeerInfoblock, err = this.verifyHeader(header, this.vbftPeerInfoblock)
+	}
+	return
+}
+
 func (this *LedgerStoreImp) GetStateMerkleRoot(height uint32) (common.Uint256, error) {
 	return this.stateStore.GetStateMerkleRoot(height)
 }
 
 func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.ExecuteResult, err error) {
+	if this.readOnly {
+		err = ErrReadOnlyLedger
+		return
+	}
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
 	currBlockHeight := this.GetCurrentBlockHeight()
@@ -539,6 +696,9 @@ func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.Execu
 }
 
 func (this *LedgerStoreImp) SubmitBlock(block *types.Block, result store.ExecuteResult) error {
+	if this.readOnly {
+		return ErrReadOnlyLedger
+	}
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
 	if this.closing {
@@ -570,6 +730,9 @@ func (this *LedgerStoreImp) SubmitBlock(block *types.Block, result store.Execute
 //AddBlock add the block to store.
 //When the block is not the next block, it will be cache. until the missing block arrived
 func (this *LedgerStoreImp) AddBlock(block *types.Block, stateMerkleRoot common.Uint256) error {
+	if this.readOnly {
+		return ErrReadOnlyLedger
+	}
 	currBlockHeight := this.GetCurrentBlockHeight()
 	blockHeight := block.Header.Height
 	if blockHeight <= currBlockHeight {
@@ -579,8 +742,7 @@ func (this *LedgerStoreImp) AddBlock(block *types.Block, stateMerkleRoot common.
 	if blockHeight != nextBlockHeight {
 		return fmt.Errorf("block height %d not equal next block height %d", blockHeight, nextBlockHeight)
 	}
-	var err error
-	this.vbftPeerInfoblock, err = this.verifyHeader(block.Header, this.vbftPeerInfoblock)
+	err := this.verifyBlockHeader(block.Header)
 	if err != nil {
 		return fmt.Errorf("verifyHeader error %s", err)
 	}
@@ -617,6 +779,10 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.ExecuteResult, err error) {
 	overlay := this.stateStore.NewOverlayDB()
 	if block.Header.Height != 0 {
+		//a block with wrong next bookkeeper is refused before executed
+		if _, err = this.nextBookkeeperState(block); err != nil {
+			return
+		}
 		config := &smartcontract.Config{
 			Time:   block.Header.Timestamp,
 			Height: block.Header.Height,
@@ -637,16 +803,23 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 		return true
 	})
 
-	cache := storage.NewCacheDB(overlay)
-	for _, tx := range block.Transactions {
-		cache.Reset()
-		notify, e := this.handleTransaction(overlay, cache, gasTable, block, tx)
-		if e != nil {
-			err = e
+	if this.execWorkers > 1 && len(block.Transactions) >= PARALLEL_EXEC_MIN_TXS {
+		result.Notify, _, err = this.executeTransactionsParallel(overlay, gasTable, block)
+		if err != nil {
 			return
 		}
+	} else {
+		cache := storage.NewCacheDB(overlay)
+		for _, tx := range block.Transactions {
+			cache.Reset()
+			notify, e := this.handleTransaction(this, overlay, cache, gasTable, block, tx)
+			if e != nil {
+				err = e
+				return
+			}
 
-		result.Notify = append(result.Notify, notify)
+			result.Notify = append(result.Notify, notify)
+		}
 	}
 
 	result.Hash = overlay.ChangeHash()
@@ -706,6 +879,9 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 	for _, notify := range result.Notify {
 		SaveNotify(this.eventStore, notify.TxHash, notify)
 	}
+	if config.DefConfig.Common.EnableEventLog && len(block.Transactions) > 0 {
+		this.eventStore.SaveEventBloomByBlock(blockHeight, result.Notify)
+	}
 
 	err := this.stateStore.AddStateMerkleTreeRoot(blockHeight, result.Hash)
 	if err != nil {
@@ -722,8 +898,22 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 		return fmt.Errorf("SaveCurrentBlock error %s", err)
 	}
 
+	err = this.saveBookkeeperState(block)
+	if err != nil {
+		return fmt.Errorf("saveBookkeeperState error %s", err)
+	}
+
 	log.Debugf("the state transition hash of block %d is:%s", blockHeight, result.Hash.ToHexString())
 
+	err = this.stateStore.BatchSaveReverseWriteSet(blockHeight, result.WriteSet)
+	if err != nil {
+		return fmt.Errorf("BatchSaveReverseWriteSet error %s", err)
+	}
+	err = this.stateStore.BatchSaveStorageUsage(result.WriteSet)
+	if err != nil {
+		return fmt.Errorf("BatchSaveStorageUsage error %s", err)
+	}
+
 	result.WriteSet.ForEach(func(key, val []byte) {
 		if len(val) == 0 {
 			this.stateStore.BatchDeleteRawKey(key)
@@ -735,6 +925,60 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 	return nil
 }
 
+//saveBookkeeperState save the bookkeeper state after block
+func (this *LedgerStoreImp) saveBookkeeperState(block *types.Block) error {
+	newState, err := this.nextBookkeeperState(block)
+	if err != nil {
+		return err
+	}
+	if newState != nil {
+		this.stateStore.BatchSaveBookkeeperState(newState)
+	}
+	return nil
+}
+
+//nextBookkeeperState apply the bookkeeper transactions of block to bookkeeper state, and check the next bookkeeper
+//of block header is consistent with the new bookkeeper state. Nil state means bookkeeper state is not changed
+func (this *LedgerStoreImp) nextBookkeeperState(block *types.Block) (*states.BookkeeperState, error) {
+	height := block.Header.Height
+	if config.DefConfig.Genesis.IsConsensusSwitchHeight(height + 1) {
+		// dbft and solo take their bookkeepers from config when switched to
+		nextConsensusType := config.DefConfig.Genesis.GetConsensusType(height + 1)
+		if nextConsensusType == config.CONSENSUS_TYPE_VBFT {
+			return nil, nil
+		}
+		bookkeepers, err := config.DefConfig.GetBookkeepersOfConsensus(nextConsensusType)
+		if err != nil {
+			return nil, err
+		}
+		return &states.BookkeeperState{
+			CurrBookkeeper: bookkeepers,
+			NextBookkeeper: bookkeepers,
+		}, nil
+	}
+	if config.DefConfig.GetBookkeeperEpochPeriod(height) == 0 {
+		return nil, nil
+	}
+	bookkeeperState, err := this.stateStore.GetBookkeeperState()
+	if err != nil {
+		return nil, err
+	}
+	newState, err := vote.GetBookkeeperState(bookkeeperState, height, block.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	if height != 0 {
+		address, err := types.AddressFromBookkeepers(newState.CurrBookkeeper)
+		if err != nil {
+			return nil, err
+		}
+		if address != block.Header.NextBookkeeper {
+			return nil, fmt.Errorf("next bookkeeper mismatch at height:%d", block.Header.Height)
+		}
+	}
+	return newState, nil
+}
+
 func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block) {
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
@@ -847,20 +1091,25 @@ func (this *LedgerStoreImp) saveBlock(block *types.Block, stateMerkleRoot common
 	return this.submitBlock(block, result)
 }
 
-func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cache *storage.CacheDB, gasTable map[string]uint64, block *types.Block, tx *types.Transaction) (*event.ExecuteNotify, error) {
+//handleTransaction execute transaction on overlay, ledger is the ledger store passed to smart contracts
+func (this *LedgerStoreImp) handleTransaction(ledger store.LedgerStore, overlay *overlaydb.OverlayDB, cache *storage.CacheDB, gasTable map[string]uint64, block *types.Block, tx *types.Transaction) (*event.ExecuteNotify, error) {
 	txHash := tx.Hash()
 	notify := &event.ExecuteNotify{TxHash: txHash, State: event.CONTRACT_STATE_FAIL}
 	switch tx.TxType {
 	case types.Deploy:
-		err := this.stateStore.HandleDeployTransaction(this, overlay, gasTable, cache, tx, block, notify)
+		err := this.stateStore.HandleDeployTransaction(ledger, overlay, gasTable, cache, tx, block, notify)
 		if overlay.Error() != nil {
 			return nil, fmt.Errorf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
 		if err != nil {
 			log.Debugf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
+	case types.Bookkeeper:
+		if config.DefConfig.GetBookkeeperEpochPeriod(block.Header.Height) != 0 {
+			notify.State = event.CONTRACT_STATE_SUCCESS
+		}
 	case types.InvokeNeo, types.InvokeWasm:
-		err := this.stateStore.HandleInvokeTransaction(this, overlay, gasTable, cache, tx, block, notify)
+		err := this.stateStore.HandleInvokeTransaction(ledger, overlay, gasTable, cache, tx, block, notify)
 		if overlay.Error() != nil {
 			return nil, fmt.Errorf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
@@ -981,6 +1230,8 @@ func (this *LedgerStoreImp) GetBookkeeperState() (*states.BookkeeperState, error
 
 //GetMerkleProof return the block merkle proof. Wrap function of StateStore.GetMerkleProof
 func (this *LedgerStoreImp) GetMerkleProof(proofHeight, rootHeight uint32) ([]common.Uint256, error) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
 	return this.stateStore.GetMerkleProof(proofHeight, rootHeight)
 }
 
@@ -994,14 +1245,55 @@ func (this *LedgerStoreImp) GetStorageItem(key *states.StorageKey) (*states.Stor
 	return this.stateStore.GetStorageState(key)
 }
 
+//GetStorageUsage return the storage usage of smart contract. Wrap function of StateStore.GetStorageUsage
+func (this *LedgerStoreImp) GetStorageUsage(contractAddress common.Address) (*states.StorageUsage, error) {
+	return this.stateStore.GetStorageUsage(contractAddress)
+}
+
 //GetEventNotifyByTx return the events notify gen by executing of smart contract.  Wrap function of EventStore.GetEventNotifyByTx
 func (this *LedgerStoreImp) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
-	return this.eventStore.GetEventNotifyByTx(tx)
+	notify, err := this.eventStore.GetEventNotifyByTx(tx)
+	if err == scom.ErrNotFound && this.GetPrunedHeight() > 0 {
+		if _, _, txErr := this.blockStore.GetTransaction(tx); txErr == scom.ErrPruned {
+			return nil, scom.ErrPruned
+		}
+	}
+	return notify, err
 }
 
 //GetEventNotifyByBlock return the transaction hash which have event notice after execution of smart contract. Wrap function of EventStore.GetEventNotifyByBlock
 func (this *LedgerStoreImp) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
-	return this.eventStore.GetEventNotifyByBlock(height)
+	notifies, err := this.eventStore.GetEventNotifyByBlock(height)
+	if err == scom.ErrNotFound && height != 0 && height <= this.GetPrunedHeight() {
+		return nil, scom.ErrPruned
+	}
+	return notifies, err
+}
+
+//FindEvents return the event notifies of contract with topic in blocks from height to height, empty topic matches
+//any topic. Wrap function of EventStore.FindEventNotify
+func (this *LedgerStoreImp) FindEvents(contract common.Address, topic string, fromHeight,
+	toHeight uint32) ([]*event.ExecuteNotify, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("invalid height range %d-%d", fromHeight, toHeight)
+	}
+	if currHeight := this.GetCurrentBlockHeight(); toHeight > currHeight {
+		toHeight = currHeight
+	}
+	if prunedHeight := this.GetPrunedHeight(); prunedHeight > 0 && fromHeight <= prunedHeight {
+		return nil, scom.ErrPruned
+	}
+	return this.eventStore.FindEventNotify(contract, topic, fromHeight, toHeight)
+}
+
+//GetBlockLimit return the gas and size limit of next block from global params
+func (this *LedgerStoreImp) GetBlockLimit() (*types.BlockLimit, error) {
+	config := &smartcontract.Config{
+		Time:   uint32(time.Now().Unix()),
+		Height: this.GetCurrentBlockHeight() + 1,
+		Tx:     &types.Transaction{},
+	}
+	return getBlockLimit(config, storage.NewCacheDB(this.stateStore.NewOverlayDB()), this)
 }
 
 //PreExecuteContract return the result of smart contract execution without commit to store
@@ -1056,6 +1348,11 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 		if gasCost < mixGas {
 			gasCost = mixGas
 		}
+		storageGas, err := checkStorageQuota(cache, gasTable)
+		if err != nil {
+			return stf, err
+		}
+		gasCost += storageGas
 
 		var cv interface{}
 		if tx.TxType == types.InvokeNeo { //neovm
@@ -1102,6 +1399,7 @@ func (this *LedgerStoreImp) Close() error {
 	defer this.releaseSavingBlockLock()
 
 	this.closing = true
+	close(this.pruneExit)
 
 	err := this.blockStore.Close()
 	if err != nil {
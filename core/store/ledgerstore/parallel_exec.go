@@ -0,0 +1,265 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/states"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/store/overlaydb"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/smartcontract/event"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/native/tst"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/native/utils"
+	"github.com/TesraSupernet/Tesra/smartcontract/storage"
+)
+
+const PARALLEL_EXEC_MIN_TXS = 4 //Blocks with fewer transactions are executed sequentially
+
+//feeAccrualKey is the TSG balance key of governance contract, which every charged transaction pays gas fee to
+var feeAccrualKey = string(append([]byte{byte(scom.ST_STORAGE)},
+	tst.GenBalanceKey(utils.TsgContractAddress, utils.GovernanceContractAddress)...))
+
+func getExecWorkers() int {
+	workers := int(config.DefConfig.Common.ExecWorkers)
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	return workers
+}
+
+//txReadSet is the read only view of block overlay used as the store of a transaction overlay.
+//It records the keys and prefixes read by the transaction. The governance balance read while charging gas fee
+//is recorded apart, so transactions paying fee do not conflict with each other
+type txReadSet struct {
+	scom.PersistStore
+	overlay    *overlaydb.OverlayDB
+	keys       map[string]bool
+	prefixes   []string
+	charging   bool
+	feeCharged bool
+	feeRead    []byte
+}
+
+func newTxReadSet(store scom.PersistStore, overlay *overlaydb.OverlayDB) *txReadSet {
+	return &txReadSet{
+		PersistStore: store,
+		overlay:      overlay,
+		keys:         make(map[string]bool),
+	}
+}
+
+//Get read key from the write set of block overlay first, and then from store.
+//Block overlay is not modified while transactions are executed in parallel
+func (self *txReadSet) Get(key []byte) ([]byte, error) {
+	if self.charging && string(key) == feeAccrualKey {
+		value, err := self.get(key)
+		if !self.feeCharged && (err == nil || err == scom.ErrNotFound) {
+			self.feeCharged = true
+			self.feeRead = value
+		}
+		return value, err
+	}
+	self.keys[string(key)] = true
+	return self.get(key)
+}
+
+func (self *txReadSet) get(key []byte) ([]byte, error) {
+	value, unknown := self.overlay.GetWriteSet().Get(key)
+	if unknown {
+		return self.PersistStore.Get(key)
+	}
+	if len(value) == 0 {
+		return nil, scom.ErrNotFound
+	}
+	return value, nil
+}
+
+func (self *txReadSet) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	if err == scom.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (self *txReadSet) NewIterator(prefix []byte) scom.StoreIterator {
+	self.prefixes = append(self.prefixes, string(prefix))
+	return self.overlay.NewIterator(prefix)
+}
+
+//conflict return whether the transaction read any of keys written by the previous transactions
+func (self *txReadSet) conflict(written map[string]bool) bool {
+	for key := range self.keys {
+		if written[key] {
+			return true
+		}
+	}
+	for _, prefix := range self.prefixes {
+		for key := range written {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//accrueFee return the governance balance written by the transaction rebased on the balance of block overlay, which
+//may have got the fees of previous transactions since the transaction read it
+func (self *txReadSet) accrueFee(overlay *overlaydb.OverlayDB, written []byte) ([]byte, error) {
+	current, err := overlay.Get([]byte(feeAccrualKey))
+	if err != nil {
+		return nil, err
+	}
+	if string(current) == string(self.feeRead) {
+		return written, nil
+	}
+	balances := make([]uint64, 0, 3)
+	for _, raw := range [][]byte{current, written, self.feeRead} {
+		balance, err := decodeBalance(raw)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	balance := balances[0] + balances[1] - balances[2]
+	if balance == 0 {
+		return nil, nil
+	}
+	return utils.GenUInt64StorageItem(balance).ToArray(), nil
+}
+
+func decodeBalance(raw []byte) (uint64, error) {
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	value, err := states.GetValueFromRawStorageItem(raw)
+	if err != nil {
+		return 0, err
+	}
+	balance, eof := common.NewZeroCopySource(value).NextUint64()
+	if eof {
+		return 0, fmt.Errorf("invalid balance %x", value)
+	}
+	return balance, nil
+}
+
+//txLedgerStore is the ledger store passed to smart contracts of a transaction executed in parallel
+type txLedgerStore struct {
+	*LedgerStoreImp
+	reads *txReadSet
+}
+
+func (self *txLedgerStore) beginFeeCharge() {
+	self.reads.charging = true
+}
+
+func (self *txLedgerStore) endFeeCharge() {
+	self.reads.charging = false
+}
+
+type txExecution struct {
+	overlay *overlaydb.OverlayDB
+	reads   *txReadSet
+	notify  *event.ExecuteNotify
+	err     error
+}
+
+//executeTransaction execute transaction on its own overlay over the block overlay
+func (this *LedgerStoreImp) executeTransaction(overlay *overlaydb.OverlayDB, gasTable map[string]uint64,
+	block *types.Block, tx *types.Transaction) *txExecution {
+	reads := newTxReadSet(this.stateStore.store, overlay)
+	exec := &txExecution{overlay: overlaydb.NewOverlayDB(reads), reads: reads}
+	ledger := &txLedgerStore{LedgerStoreImp: this, reads: reads}
+	exec.notify, exec.err = this.handleTransaction(ledger, exec.overlay, storage.NewCacheDB(exec.overlay), gasTable, block, tx)
+	return exec
+}
+
+//executeTransactionsParallel execute transactions of block optimistically in parallel, each on its own overlay.
+//Then the write sets are applied to block overlay in order, and the transactions which read keys written by
+//previous transactions are re-executed, so the result is the same as sequential execution. Gas fees paid to
+//governance are added to its balance in block overlay. Return the notifies and count of re-executed transactions
+func (this *LedgerStoreImp) executeTransactionsParallel(overlay *overlaydb.OverlayDB, gasTable map[string]uint64,
+	block *types.Block) ([]*event.ExecuteNotify, int, error) {
+	txs := block.Transactions
+	execs := make([]*txExecution, len(txs))
+	workers := this.execWorkers
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	next := int32(-1)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				index := int(atomic.AddInt32(&next, 1))
+				if index >= len(txs) {
+					return
+				}
+				execs[index] = this.executeTransaction(overlay, gasTable, block, txs[index])
+			}
+		}()
+	}
+	wg.Wait()
+
+	notifies := make([]*event.ExecuteNotify, 0, len(txs))
+	written := make(map[string]bool)
+	reExecuted := 0
+	for i, tx := range txs {
+		exec := execs[i]
+		if exec.err != nil || exec.overlay.Error() != nil || exec.reads.conflict(written) {
+			reExecuted++
+			exec = this.executeTransaction(overlay, gasTable, block, tx)
+			if exec.err != nil {
+				return nil, reExecuted, exec.err
+			}
+		}
+		var err error
+		exec.overlay.GetWriteSet().ForEach(func(key, val []byte) {
+			if exec.reads.feeCharged && string(key) == feeAccrualKey {
+				if val, err = exec.reads.accrueFee(overlay, val); err != nil {
+					return
+				}
+			}
+			written[string(key)] = true
+			if len(val) == 0 {
+				overlay.Delete(key)
+			} else {
+				overlay.Put(key, val)
+			}
+		})
+		if err != nil {
+			return nil, reExecuted, err
+		}
+		notifies = append(notifies, exec.notify)
+	}
+	log.Debugf("execute block %d, %d transactions, %d re-executed", block.Header.Height, len(txs), reExecuted)
+	return notifies, reExecuted, nil
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/genesis"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/store/leveldbstore"
+	"github.com/TesraSupernet/Tesra/core/store/overlaydb"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/core/utils"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/native/tst"
+	nutils "github.com/TesraSupernet/Tesra/smartcontract/service/native/utils"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/neovm"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxReadSetConflict(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	overlay := overlaydb.NewOverlayDB(store)
+	overlay.Put([]byte("k1"), []byte("v1"))
+
+	reads := newTxReadSet(store, overlay)
+	value, err := reads.Get([]byte("k1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	iter := reads.NewIterator([]byte("p"))
+	iter.Release()
+
+	assert.False(t, reads.conflict(map[string]bool{"k2": true}))
+	assert.True(t, reads.conflict(map[string]bool{"k1": true}))
+	assert.True(t, reads.conflict(map[string]bool{"p1": true}))
+}
+
+func TestExecuteTransactionsParallel(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	ledgerStore, err := NewLedgerStore("test/parallel", 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+
+	ledgerStore.execWorkers = 1
+	sequential, err := ledgerStore.executeBlock(block)
+	assert.Nil(t, err)
+	ledgerStore.execWorkers = 4
+	parallel, err := ledgerStore.executeBlock(block)
+	assert.Nil(t, err)
+
+	assert.Equal(t, sequential.Hash, parallel.Hash)
+	assert.Equal(t, sequential.Notify, parallel.Notify)
+	assert.Equal(t, sequential.WriteSet.Len(), parallel.WriteSet.Len())
+}
+
+func newTransferTx(t *testing.T, from *account.Account, to common.Address) *types.Transaction {
+	transfers := &tst.Transfers{States: []tst.State{{From: from.Address, To: to, Value: 1}}}
+	mutable := utils.BuildNativeTransaction(nutils.TsgContractAddress, tst.TRANSFER_NAME, common.SerializeToBytes(transfers))
+	mutable.GasPrice = 1
+	mutable.GasLimit = 30000
+	mutable.Payer = from.Address
+	hash := mutable.Hash()
+	sig, err := signature.Sign(from, hash[:])
+	assert.Nil(t, err)
+	mutable.Sigs = []types.Sig{{PubKeys: []keypair.PublicKey{from.PublicKey}, M: 1, SigData: [][]byte{sig}}}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	return tx
+}
+
+func TestExecuteFeeChargedTransactionsParallel(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	ledgerStore, err := NewLedgerStore("test/parallel_fee", 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	err = ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers)
+	assert.Nil(t, err)
+
+	// independent transfers of funded accounts, all paying gas fee to governance
+	ledgerStore.stateStore.NewBatch()
+	txs := make([]*types.Transaction, 0)
+	for i := 0; i < 8; i++ {
+		from := account.NewAccount("")
+		key := append([]byte{byte(scom.ST_STORAGE)}, tst.GenBalanceKey(nutils.TsgContractAddress, from.Address)...)
+		ledgerStore.stateStore.BatchPutRawKeyVal(key, nutils.GenUInt64StorageItem(1000000).ToArray())
+		txs = append(txs, newTransferTx(t, from, account.NewAccount("").Address))
+	}
+	assert.Nil(t, ledgerStore.stateStore.CommitTo())
+
+	header := &types.Header{
+		Height:        1,
+		PrevBlockHash: genesisBlock.Hash(),
+		Timestamp:     genesisBlock.Header.Timestamp + 1,
+	}
+	block := &types.Block{Header: header, Transactions: txs}
+
+	ledgerStore.execWorkers = 1
+	sequential, err := ledgerStore.executeBlock(block)
+	assert.Nil(t, err)
+	ledgerStore.execWorkers = 4
+	parallel, err := ledgerStore.executeBlock(block)
+	assert.Nil(t, err)
+
+	assert.Equal(t, sequential.Hash, parallel.Hash)
+	assert.Equal(t, sequential.Notify, parallel.Notify)
+	for _, notify := range parallel.Notify {
+		assert.Equal(t, uint64(20000), notify.GasConsumed)
+	}
+
+	gasTable := make(map[string]uint64)
+	neovm.GAS_TABLE.Range(func(k, value interface{}) bool {
+		gasTable[k.(string)] = value.(uint64)
+		return true
+	})
+	_, reExecuted, err := ledgerStore.executeTransactionsParallel(ledgerStore.stateStore.NewOverlayDB(), gasTable, block)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, reExecuted)
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+)
+
+const (
+	PRUNE_INTERVAL       = time.Minute    //Interval of checking blocks out of retention window
+	PRUNE_BATCH_SIZE     = uint32(500)    //Count of blocks pruned in one batch
+	PRUNE_COMPACT_BLOCKS = uint32(100000) //Compact stores after pruning this count of blocks
+)
+
+//GetPrunedHeight return the height up to which block bodies and events have been pruned
+func (this *BlockStore) GetPrunedHeight() (uint32, error) {
+	value, err := this.store.Get(this.getPrunedHeightKey())
+	if err != nil {
+		return 0, err
+	}
+	height, eof := common.NewZeroCopySource(value).NextUint32()
+	if eof {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return height, nil
+}
+
+//SavePrunedHeight persist pruned height to store in batch
+func (this *BlockStore) SavePrunedHeight(height uint32) {
+	value := common.NewZeroCopySink(nil)
+	value.WriteUint32(height)
+	this.store.BatchPut(this.getPrunedHeightKey(), value.Bytes())
+}
+
+//PruneBlock replace transactions of block with their height in batch, and return the transaction hashes.
+//The header and transaction hash list of block are kept
+func (this *BlockStore) PruneBlock(blockHash common.Uint256) ([]common.Uint256, error) {
+	header, txHashes, err := this.loadHeaderWithTx(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	value := common.NewZeroCopySink(nil)
+	value.WriteUint32(header.Height)
+	for _, txHash := range txHashes {
+		this.store.BatchPut(this.getTransactionKey(txHash), value.Bytes())
+	}
+	return txHashes, nil
+}
+
+//Compact block store to reclaim space of pruned data
+func (this *BlockStore) Compact() error {
+	return this.store.Compact()
+}
+
+func (this *BlockStore) getPrunedHeightKey() []byte {
+	return []byte{byte(scom.SYS_PRUNED_HEIGHT)}
+}
+
+//PruneEventNotify delete event notifies of block in batch
+func (this *EventStore) PruneEventNotify(height uint32, txHashes []common.Uint256) {
+	this.store.BatchDelete(genEventNotifyByBlockKey(height))
+	this.store.BatchDelete(genEventBloomKey(height))
+	for _, txHash := range txHashes {
+		this.store.BatchDelete(genEventNotifyByTxKey(txHash))
+	}
+}
+
+//BatchDeleteReverseWriteSet delete reverse write set of pruned block in batch, it can not be rolled back any more
+func (self *StateStore) BatchDeleteReverseWriteSet(height uint32) {
+	self.store.BatchDelete(self.genReverseWriteSetKey(height))
+}
+
+//Compact event store to reclaim space of pruned data
+func (this *EventStore) Compact() error {
+	return this.store.Compact()
+}
+
+//GetPrunedHeight return the height up to which block bodies and events have been pruned
+func (this *LedgerStoreImp) GetPrunedHeight() uint32 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.prunedHeight
+}
+
+func (this *LedgerStoreImp) loadPrunedHeight() error {
+	height, err := this.blockStore.GetPrunedHeight()
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	this.lock.Lock()
+	this.prunedHeight = height
+	this.lock.Unlock()
+	return nil
+}
+
+//startPruning run the background task pruning blocks out of retention window
+func (this *LedgerStoreImp) startPruning() {
+	if this.pruneRetention == 0 {
+		return
+	}
+	log.Infof("ledger pruning enabled, keep recent %d blocks", this.pruneRetention)
+	go func() {
+		ticker := time.NewTicker(PRUNE_INTERVAL)
+		defer ticker.Stop()
+		pruned := uint32(0)
+		for {
+			select {
+			case <-ticker.C:
+				count, err := this.pruneBlocks()
+				if err != nil {
+					log.Errorf("prune blocks error:%s", err)
+				}
+				pruned += count
+				if pruned < PRUNE_COMPACT_BLOCKS {
+					continue
+				}
+				pruned = 0
+				if err = this.blockStore.Compact(); err != nil {
+					log.Warnf("compact block store error:%s", err)
+				}
+				if err = this.eventStore.Compact(); err != nil {
+					log.Warnf("compact event store error:%s", err)
+				}
+			case <-this.pruneExit:
+				return
+			}
+		}
+	}()
+}
+
+//pruneBlocks prune the blocks out of retention window, return the count of pruned blocks
+func (this *LedgerStoreImp) pruneBlocks() (uint32, error) {
+	count := uint32(0)
+	for {
+		currHeight := this.GetCurrentBlockHeight()
+		prunedHeight := this.GetPrunedHeight()
+		if currHeight <= this.pruneRetention || prunedHeight >= currHeight-this.pruneRetention {
+			return count, nil
+		}
+		end := currHeight - this.pruneRetention
+		if end-prunedHeight > PRUNE_BATCH_SIZE {
+			end = prunedHeight + PRUNE_BATCH_SIZE
+		}
+		err := this.pruneBlockRange(prunedHeight+1, end)
+		if err != nil {
+			return count, err
+		}
+		count += end - prunedHeight
+	}
+}
+
+//pruneBlockRange prune blocks in [start, end], the genesis block and blocks needed by consensus are kept
+func (this *LedgerStoreImp) pruneBlockRange(start, end uint32) error {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	if this.closing {
+		return nil
+	}
+	header, err := this.GetHeaderByHash(this.GetCurrentBlockHash())
+	if err != nil {
+		return err
+	}
+	anchors, err := this.getAnchorBlocks(header)
+	if err != nil {
+		return err
+	}
+	keep := make(map[uint32]bool, len(anchors))
+	for _, height := range anchors {
+		keep[height] = true
+	}
+
+	this.blockStore.NewBatch()
+	this.eventStore.NewBatch()
+	this.stateStore.NewBatch()
+	for height := start; height <= end; height++ {
+		this.stateStore.BatchDeleteReverseWriteSet(height)
+		if keep[height] {
+			continue
+		}
+		txHashes, err := this.blockStore.PruneBlock(this.getHeaderIndex(height))
+		if err != nil {
+			return fmt.Errorf("prune block height:%d error %s", height, err)
+		}
+		this.eventStore.PruneEventNotify(height, txHashes)
+	}
+	this.blockStore.SavePrunedHeight(end)
+	err = this.stateStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("stateStore.CommitTo error %s", err)
+	}
+	err = this.eventStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("eventStore.CommitTo error %s", err)
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	this.lock.Lock()
+	this.prunedHeight = end
+	this.lock.Unlock()
+	log.Debugf("pruned blocks from height %d to %d", start, end)
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneBlock(t *testing.T) {
+	acc1 := account.NewAccount("")
+	acc2 := account.NewAccount("")
+	tx, err := transferTx(acc1.Address, acc2.Address, 100)
+	assert.Nil(t, err)
+	block := &types.Block{
+		Header:       &types.Header{Height: 3, NextBookkeeper: acc1.Address},
+		Transactions: []*types.Transaction{tx},
+	}
+	blockHash := block.Hash()
+	txHash := tx.Hash()
+
+	testBlockStore.NewBatch()
+	assert.Nil(t, testBlockStore.SaveBlock(block))
+	assert.Nil(t, testBlockStore.CommitTo())
+
+	_, err = testBlockStore.GetPrunedHeight()
+	assert.Equal(t, scom.ErrNotFound, err)
+
+	testBlockStore.NewBatch()
+	txHashes, err := testBlockStore.PruneBlock(blockHash)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Uint256{txHash}, txHashes)
+	testBlockStore.SavePrunedHeight(3)
+	assert.Nil(t, testBlockStore.CommitTo())
+
+	prunedHeight, err := testBlockStore.GetPrunedHeight()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), prunedHeight)
+
+	_, height, err := testBlockStore.GetTransaction(txHash)
+	assert.Equal(t, scom.ErrPruned, err)
+	assert.Equal(t, uint32(3), height)
+	exist, err := testBlockStore.ContainTransaction(txHash)
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	_, err = testBlockStore.GetBlock(blockHash)
+	assert.Equal(t, scom.ErrPruned, err)
+	header, err := testBlockStore.GetHeader(blockHash)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), header.Height)
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/errors"
+	"github.com/TesraSupernet/Tesra/events"
+	"github.com/TesraSupernet/Tesra/events/message"
+)
+
+var ErrReadOnlyLedger = errors.NewErr("ledger is opened read only")
+
+//NewReadOnlyLedgerStore open the ledger in data dir used by another process read only. Checkpoints of stores
+//are made in checkpoint dir, and refreshed every refresh interval to catch up with the blocks committed by
+//the other process, 0 interval means no refreshing
+func NewReadOnlyLedgerStore(dataDir, checkpointDir string, stateHashHeight uint32,
+	refreshInterval time.Duration) (*LedgerStoreImp, error) {
+	ledgerStore := &LedgerStoreImp{
+		headerIndex:          make(map[uint32]common.Uint256),
+		headerCache:          make(map[common.Uint256]*types.Header, 0),
+		vbftPeerInfoheader:   make(map[string]uint32),
+		vbftPeerInfoblock:    make(map[string]uint32),
+		savingBlockSemaphore: make(chan bool, 1),
+		stateHashCheckHeight: stateHashHeight,
+		pruneExit:            make(chan bool),
+		readOnly:             true,
+	}
+	//blocks are committed to block store first and state store last, so opening stores in reverse order makes
+	//sure the block and event stores are not behind state store
+	for _, dir := range []string{DBDirState, DBDirEvent, DBDirBlock} {
+		store, err := OpenSecondaryStore(filepath.Join(dataDir, dir), filepath.Join(checkpointDir, dir))
+		if err != nil {
+			ledgerStore.closeSecondaryStores()
+			return nil, fmt.Errorf("open %s store error %s", dir, err)
+		}
+		ledgerStore.secondaryStores = append(ledgerStore.secondaryStores, store)
+	}
+	ledgerStore.stateStore = &StateStore{
+		dbDir:                filepath.Join(dataDir, DBDirState),
+		store:                ledgerStore.secondaryStores[0],
+		merklePath:           filepath.Join(dataDir, MerkleTreeStorePath),
+		stateHashCheckHeight: stateHashHeight,
+		readOnly:             true,
+	}
+	ledgerStore.eventStore = &EventStore{
+		dbDir: filepath.Join(dataDir, DBDirEvent),
+		store: ledgerStore.secondaryStores[1],
+	}
+	ledgerStore.blockStore = &BlockStore{
+		dbDir: filepath.Join(dataDir, DBDirBlock),
+		store: ledgerStore.secondaryStores[2],
+	}
+
+	version, err := ledgerStore.blockStore.GetVersion()
+	if err == nil && version != SYSTEM_VERSION {
+		err = fmt.Errorf("unsupported version %d", version)
+	}
+	if err == nil {
+		_, err = ledgerStore.loadReadOnlyLedger()
+	}
+	if err != nil {
+		ledgerStore.closeSecondaryStores()
+		return nil, fmt.Errorf("load ledger error %s", err)
+	}
+	log.Infof("ledger opened read only, current block height %d", ledgerStore.GetCurrentBlockHeight())
+	ledgerStore.startRefreshing(refreshInterval)
+	return ledgerStore, nil
+}
+
+//IsReadOnly return whether the ledger is opened read only
+func (this *LedgerStoreImp) IsReadOnly() bool {
+	return this.readOnly
+}
+
+//Refresh catch up with the blocks committed by the process using the ledger, and publish the new blocks
+func (this *LedgerStoreImp) Refresh() error {
+	if !this.readOnly {
+		return nil
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	if this.closing {
+		return nil
+	}
+	for _, store := range this.secondaryStores {
+		err := store.Refresh()
+		if err != nil {
+			return err
+		}
+	}
+	start, err := this.loadReadOnlyLedger()
+	if err != nil {
+		return err
+	}
+	if events.DefActorPublisher == nil {
+		return nil
+	}
+	end := this.GetCurrentBlockHeight()
+	for height := start; height <= end; height++ {
+		block, err := this.blockStore.GetBlock(this.getHeaderIndex(height))
+		if err != nil {
+			return fmt.Errorf("get block height:%d error %s", height, err)
+		}
+		events.DefActorPublisher.Publish(message.TOPIC_SAVE_BLOCK_COMPLETE, &message.SaveBlockCompleteMsg{Block: block})
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) startRefreshing(interval time.Duration) {
+	if interval == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := this.Refresh(); err != nil {
+					log.Warnf("refresh read only ledger error:%s", err)
+				}
+			case <-this.pruneExit:
+				return
+			}
+		}
+	}()
+}
+
+//loadReadOnlyLedger load the header index up to the current block of state store and the block merkle tree,
+//return the height of first new block
+func (this *LedgerStoreImp) loadReadOnlyLedger() (uint32, error) {
+	_, height, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return 0, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	prunedHeight, err := this.blockStore.GetPrunedHeight()
+	if err != nil && err != scom.ErrNotFound {
+		return 0, fmt.Errorf("GetPrunedHeight error %s", err)
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	start := this.currBlockHeight + 1
+	if len(this.headerIndex) == 0 {
+		start = 0
+	} else {
+		//the ledger has been rolled back by the other process
+		reset := height < this.currBlockHeight
+		if !reset {
+			blockHash, err := this.blockStore.GetBlockHash(this.currBlockHeight)
+			reset = err != nil || blockHash != this.currBlockHash
+		}
+		if reset {
+			this.headerIndex = make(map[uint32]common.Uint256)
+		}
+	}
+	if len(this.headerIndex) == 0 {
+		headerIndex, err := this.blockStore.GetHeaderIndexList()
+		if err != nil {
+			return 0, fmt.Errorf("GetHeaderIndexList error %s", err)
+		}
+		for h := range headerIndex {
+			if h > height {
+				delete(headerIndex, h)
+			}
+		}
+		this.headerIndex = headerIndex
+	}
+	for h := uint32(len(this.headerIndex)); h <= height; h++ {
+		blockHash, err := this.blockStore.GetBlockHash(h)
+		if err != nil {
+			return 0, fmt.Errorf("LoadBlockHash height %d error %s", h, err)
+		}
+		if blockHash == common.UINT256_EMPTY {
+			return 0, fmt.Errorf("LoadBlockHash height %d hash nil", h)
+		}
+		this.headerIndex[h] = blockHash
+	}
+	this.storedIndexCount = uint32(len(this.headerIndex))
+	this.currBlockHeight = height
+	this.currBlockHash = this.headerIndex[height]
+	this.prunedHeight = prunedHeight
+
+	if this.stateStore.merkleHashStore != nil {
+		this.stateStore.merkleHashStore.Close()
+	}
+	err = this.stateStore.init(height)
+	if err != nil {
+		return 0, fmt.Errorf("init state store error %s", err)
+	}
+	return start, nil
+}
+
+func (this *LedgerStoreImp) closeSecondaryStores() {
+	for _, store := range this.secondaryStores {
+		store.Close()
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"os"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/genesis"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyLedgerStore(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	primary, err := NewLedgerStore("test/readonly", 0)
+	assert.Nil(t, err)
+	defer primary.Close()
+	assert.Nil(t, primary.InitLedgerStoreWithGenesisBlock(block, bookkeepers))
+
+	ledgerStore, err := NewReadOnlyLedgerStore("test/readonly", "test/readonly_checkpoint", 0, 0)
+	assert.Nil(t, err)
+	assert.True(t, ledgerStore.IsReadOnly())
+	assert.Equal(t, uint32(0), ledgerStore.GetCurrentBlockHeight())
+	assert.Equal(t, block.Hash(), ledgerStore.GetCurrentBlockHash())
+	header, err := ledgerStore.GetHeaderByHeight(0)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), header.Hash())
+	assert.Equal(t, ErrReadOnlyLedger, ledgerStore.AddBlock(block, common.UINT256_EMPTY))
+
+	key := []byte{byte(scom.ST_STORAGE), 1}
+	primary.stateStore.NewBatch()
+	primary.stateStore.BatchPutRawKeyVal(key, []byte{1})
+	assert.Nil(t, primary.stateStore.CommitTo())
+	_, err = ledgerStore.stateStore.store.Get(key)
+	assert.Equal(t, scom.ErrNotFound, err)
+	assert.Nil(t, ledgerStore.Refresh())
+	value, err := ledgerStore.stateStore.store.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1}, value)
+	assert.Equal(t, uint32(0), ledgerStore.GetCurrentBlockHeight())
+
+	assert.Nil(t, ledgerStore.Close())
+	_, err = os.Stat("test/readonly_checkpoint/" + DBDirState)
+	assert.True(t, os.IsNotExist(err))
+}
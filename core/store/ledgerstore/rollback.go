@@ -0,0 +1,293 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/store/overlaydb"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/merkle"
+)
+
+//MAX_ROLLBACK_BLOCKS is the count of recent blocks whose reverse write sets are kept, only these blocks can be rolled back
+const MAX_ROLLBACK_BLOCKS = uint32(10000)
+
+//RollbackTo revert the ledger to the block at height, the blocks above height are removed.
+//Blocks are rolled back with the reverse write sets saved along with them, so only the last MAX_ROLLBACK_BLOCKS
+//blocks can be rolled back, and blocks pruned or saved by an old version without reverse write set cannot be rolled back
+func (this *LedgerStoreImp) RollbackTo(height uint32) error {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	if this.closing {
+		return fmt.Errorf("rollback error: ledger is closing")
+	}
+	currHeight := this.GetCurrentBlockHeight()
+	if height >= currHeight {
+		return fmt.Errorf("rollback height %d should be lower than current block height %d", height, currHeight)
+	}
+	if currHeight-height > this.stateStore.rollbackRetention {
+		return fmt.Errorf("rollback height %d is out of the last %d blocks", height, this.stateStore.rollbackRetention)
+	}
+	if height < this.GetPrunedHeight() {
+		return fmt.Errorf("rollback height %d is lower than pruned height %d", height, this.GetPrunedHeight())
+	}
+	for h := height + 1; h <= currHeight; h++ {
+		has, err := this.stateStore.HasReverseWriteSet(h)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("reverse write set of block %d not found, cannot rollback to height %d", h, height)
+		}
+	}
+	blockHash := this.getHeaderIndex(height)
+
+	// state store is committed first. If interrupted, the state is recovered by replaying blocks at next start
+	// and rollback can be run again
+	this.stateStore.NewBatch()
+	for h := currHeight; h > height; h-- {
+		err := this.stateStore.BatchRollbackBlock(h)
+		if err != nil {
+			return fmt.Errorf("rollback state of block %d error %s", h, err)
+		}
+	}
+	err := this.stateStore.BatchRollbackMerkleTrees(height)
+	if err != nil {
+		return fmt.Errorf("rollback merkle trees error %s", err)
+	}
+	err = this.stateStore.SaveCurrentBlock(height, blockHash)
+	if err != nil {
+		return fmt.Errorf("SaveCurrentBlock error %s", err)
+	}
+	err = this.stateStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("stateStore.CommitTo error %s", err)
+	}
+
+	this.blockStore.NewBatch()
+	this.eventStore.NewBatch()
+	for h := currHeight; h > height; h-- {
+		txHashes, err := this.blockStore.BatchDeleteBlock(this.getHeaderIndex(h), h)
+		if err != nil {
+			return fmt.Errorf("delete block %d error %s", h, err)
+		}
+		this.eventStore.PruneEventNotify(h, txHashes)
+	}
+	storedIndexCount, err := this.blockStore.BatchDeleteHeaderIndexList(height)
+	if err != nil {
+		return fmt.Errorf("delete header index list error %s", err)
+	}
+	err = this.blockStore.SaveCurrentBlock(height, blockHash)
+	if err != nil {
+		return fmt.Errorf("SaveCurrentBlock error %s", err)
+	}
+	this.eventStore.SaveCurrentBlock(height, blockHash)
+	err = this.eventStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("eventStore.CommitTo error %s", err)
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+
+	this.lock.Lock()
+	for h := range this.headerIndex {
+		if h > height {
+			delete(this.headerIndex, h)
+		}
+	}
+	this.storedIndexCount = storedIndexCount
+	this.headerCache = make(map[common.Uint256]*types.Header, 0)
+	this.lock.Unlock()
+	this.setCurrentBlock(height, blockHash)
+	err = this.loadVbftPeerInfo()
+	if err != nil {
+		return fmt.Errorf("loadVbftPeerInfo error %s", err)
+	}
+	log.Infof("ledger rolled back from height %d to %d, block hash %s", currHeight, height, blockHash.ToHexString())
+	return nil
+}
+
+//BatchDeleteBlock delete block, its transactions and height index in batch, return the transaction hashes of block
+func (this *BlockStore) BatchDeleteBlock(blockHash common.Uint256, height uint32) ([]common.Uint256, error) {
+	_, txHashes, err := this.loadHeaderWithTx(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, txHash := range txHashes {
+		this.store.BatchDelete(this.getTransactionKey(txHash))
+		if this.enableCache {
+			this.cache.RemoveTransaction(txHash)
+		}
+	}
+	this.store.BatchDelete(this.getHeaderKey(blockHash))
+	this.store.BatchDelete(this.getBlockHashKey(height))
+	if this.enableCache {
+		this.cache.RemoveBlock(blockHash)
+	}
+	return txHashes, nil
+}
+
+//BatchDeleteHeaderIndexList delete the header index lists not below height in batch, return the count of heights
+//still saved in header index lists
+func (this *BlockStore) BatchDeleteHeaderIndexList(height uint32) (uint32, error) {
+	iter := this.store.NewIterator([]byte{byte(scom.IX_HEADER_HASH_LIST)})
+	defer iter.Release()
+	storedCount := uint32(0)
+	for iter.Next() {
+		startHeight, err := this.getStartHeightByHeaderIndexKey(iter.Key())
+		if err != nil {
+			return 0, err
+		}
+		// same as saveHeaderIndexList, a list is only saved when current height is not below its end
+		if startHeight+HEADER_INDEX_BATCH_SIZE > height {
+			this.store.BatchDelete(iter.Key())
+		} else if startHeight+HEADER_INDEX_BATCH_SIZE > storedCount {
+			storedCount = startHeight + HEADER_INDEX_BATCH_SIZE
+		}
+	}
+	return storedCount, iter.Error()
+}
+
+//BatchSaveReverseWriteSet save the previous values of keys written by block in batch, so that the block
+//can be rolled back. The reverse write set of the block out of retention window is deleted at the same time
+func (self *StateStore) BatchSaveReverseWriteSet(height uint32, writeSet *overlaydb.MemDB) error {
+	bookkeeperKey, err := self.getBookkeeperKey()
+	if err != nil {
+		return err
+	}
+	keys := [][]byte{bookkeeperKey}
+	writeSet.ForEach(func(key, val []byte) {
+		keys = append(keys, append([]byte{}, key...))
+	})
+	keys = append(keys, storageUsageKeys(writeSet)...)
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(uint32(len(keys)))
+	for _, key := range keys {
+		value, err := self.store.Get(key)
+		if err != nil && err != scom.ErrNotFound {
+			return err
+		}
+		sink.WriteVarBytes(key)
+		sink.WriteBool(err == nil)
+		sink.WriteVarBytes(value)
+	}
+	self.store.BatchPut(self.genReverseWriteSetKey(height), sink.Bytes())
+	if height >= self.rollbackRetention {
+		self.store.BatchDelete(self.genReverseWriteSetKey(height - self.rollbackRetention))
+	}
+	return nil
+}
+
+//HasReverseWriteSet return whether the reverse write set of block is saved
+func (self *StateStore) HasReverseWriteSet(height uint32) (bool, error) {
+	return self.store.Has(self.genReverseWriteSetKey(height))
+}
+
+//BatchRollbackBlock restore the values of keys written by block in batch. Blocks should be rolled back
+//from the highest one
+func (self *StateStore) BatchRollbackBlock(height uint32) error {
+	key := self.genReverseWriteSetKey(height)
+	data, err := self.store.Get(key)
+	if err != nil {
+		return err
+	}
+	source := common.NewZeroCopySource(data)
+	count, eof := source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	for i := uint32(0); i < count; i++ {
+		k, _, irregular, eof := source.NextVarBytes()
+		if irregular || eof {
+			return io.ErrUnexpectedEOF
+		}
+		exist, irregular, eof := source.NextBool()
+		if irregular || eof {
+			return io.ErrUnexpectedEOF
+		}
+		v, _, irregular, eof := source.NextVarBytes()
+		if irregular || eof {
+			return io.ErrUnexpectedEOF
+		}
+		if exist {
+			self.store.BatchPut(k, v)
+		} else {
+			self.store.BatchDelete(k)
+		}
+	}
+	self.store.BatchDelete(key)
+	self.store.BatchDelete(self.genStateMerkleRootKey(height))
+	return nil
+}
+
+//BatchRollbackMerkleTrees roll back block merkle tree and state merkle tree to height in batch
+func (self *StateStore) BatchRollbackMerkleTrees(height uint32) error {
+	err := self.merkleTree.Truncate(height + 1)
+	if err != nil {
+		return err
+	}
+	self.batchSaveMerkleTree(self.genBlockMerkleTreeKey(), self.merkleTree)
+
+	if height < self.stateHashCheckHeight {
+		self.deltaMerkleTree = merkle.NewTree(0, nil, nil)
+		self.store.BatchDelete(self.genStateMerkleTreeKey())
+		return nil
+	}
+	// state merkle tree has no hash store, rebuild it with the write set hashes of blocks
+	deltaMerkleTree := merkle.NewTree(0, nil, nil)
+	for h := self.stateHashCheckHeight; h <= height; h++ {
+		value, err := self.store.Get(self.genStateMerkleRootKey(h))
+		if err != nil {
+			return fmt.Errorf("get state merkle root of block %d error %s", h, err)
+		}
+		writeSetHash, eof := common.NewZeroCopySource(value).NextHash()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		deltaMerkleTree.AppendHash(writeSetHash)
+	}
+	self.deltaMerkleTree = deltaMerkleTree
+	self.batchSaveMerkleTree(self.genStateMerkleTreeKey(), self.deltaMerkleTree)
+	return nil
+}
+
+func (self *StateStore) batchSaveMerkleTree(key []byte, tree *merkle.CompactMerkleTree) {
+	hashes := tree.Hashes()
+	value := common.NewZeroCopySink(make([]byte, 0, 4+len(hashes)*common.UINT256_SIZE))
+	value.WriteUint32(tree.TreeSize())
+	for _, hash := range hashes {
+		value.WriteHash(hash)
+	}
+	self.store.BatchPut(key, value.Bytes())
+}
+
+func (self *StateStore) genReverseWriteSetKey(height uint32) []byte {
+	key := make([]byte, 5, 5)
+	key[0] = byte(scom.DATA_REVERSE_WRITE_SET)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
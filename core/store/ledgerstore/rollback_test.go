@@ -0,0 +1,127 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackStateStore(t *testing.T) {
+	stateStore, err := NewStateStore("test/rollback_state", "test/rollback_"+MerkleTreeStorePath, 3)
+	assert.Nil(t, err)
+	defer stateStore.Close()
+
+	type snapshot struct {
+		values    map[string][]byte
+		blockRoot common.Uint256
+		stateRoot common.Uint256
+	}
+	keys := [][]byte{{byte(scom.ST_STORAGE), 1}, {byte(scom.ST_STORAGE), 2}, {byte(scom.ST_STORAGE), 3}}
+	snapshots := make([]*snapshot, 0)
+	for height := uint32(0); height < 10; height++ {
+		overlay := stateStore.NewOverlayDB()
+		key := keys[height%3]
+		if height%4 == 3 {
+			overlay.Delete(key)
+		} else {
+			overlay.Put(key, []byte{byte(height)})
+		}
+		stateStore.NewBatch()
+		assert.Nil(t, stateStore.AddStateMerkleTreeRoot(height, overlay.ChangeHash()))
+		assert.Nil(t, stateStore.AddBlockMerkleTreeRoot(common.Uint256{byte(height)}))
+		assert.Nil(t, stateStore.SaveCurrentBlock(height, common.Uint256{byte(height)}))
+		assert.Nil(t, stateStore.BatchSaveReverseWriteSet(height, overlay.GetWriteSet()))
+		overlay.CommitTo()
+		assert.Nil(t, stateStore.CommitTo())
+
+		snap := &snapshot{values: make(map[string][]byte)}
+		for _, k := range keys {
+			value, err := stateStore.store.Get(k)
+			if err == nil {
+				snap.values[string(k)] = value
+			}
+		}
+		snap.blockRoot = stateStore.merkleTree.Root()
+		if height >= 3 {
+			snap.stateRoot = stateStore.deltaMerkleTree.Root()
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	for _, height := range []uint32{7, 4, 1} {
+		has, err := stateStore.HasReverseWriteSet(height + 1)
+		assert.Nil(t, err)
+		assert.True(t, has)
+		_, currHeight, err := stateStore.GetCurrentBlock()
+		assert.Nil(t, err)
+		stateStore.NewBatch()
+		for h := currHeight; h > height; h-- {
+			assert.Nil(t, stateStore.BatchRollbackBlock(h))
+		}
+		assert.Nil(t, stateStore.BatchRollbackMerkleTrees(height))
+		assert.Nil(t, stateStore.SaveCurrentBlock(height, common.Uint256{byte(height)}))
+		assert.Nil(t, stateStore.CommitTo())
+
+		for _, k := range keys {
+			value, err := stateStore.store.Get(k)
+			expected, ok := snapshots[height].values[string(k)]
+			if ok {
+				assert.Equal(t, expected, value)
+			} else {
+				assert.Equal(t, scom.ErrNotFound, err)
+			}
+		}
+		assert.Equal(t, snapshots[height].blockRoot, stateStore.merkleTree.Root())
+		if height >= 3 {
+			assert.Equal(t, snapshots[height].stateRoot, stateStore.deltaMerkleTree.Root())
+		}
+		has, err = stateStore.HasReverseWriteSet(height + 1)
+		assert.Nil(t, err)
+		assert.False(t, has)
+	}
+
+	treeSize, _, err := stateStore.GetBlockMerkleTree()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), treeSize)
+	_, err = stateStore.GetStateMerkleRoot(3)
+	assert.Equal(t, scom.ErrNotFound, err)
+}
+
+func TestReverseWriteSetRetention(t *testing.T) {
+	stateStore := NewMemStateStore(0)
+	stateStore.rollbackRetention = 3
+	for height := uint32(0); height < 8; height++ {
+		overlay := stateStore.NewOverlayDB()
+		overlay.Put([]byte{byte(scom.ST_STORAGE), byte(height)}, []byte{byte(height)})
+		stateStore.NewBatch()
+		assert.Nil(t, stateStore.BatchSaveReverseWriteSet(height, overlay.GetWriteSet()))
+		overlay.CommitTo()
+		assert.Nil(t, stateStore.CommitTo())
+	}
+	for height := uint32(0); height < 8; height++ {
+		has, err := stateStore.HasReverseWriteSet(height)
+		assert.Nil(t, err)
+		// only the reverse write sets of the last 3 blocks are kept
+		assert.Equal(t, height >= 5, has, "height %d", height)
+	}
+}
@@ -0,0 +1,653 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/common/serialization"
+	"github.com/TesraSupernet/Tesra/consensus/vbft/config"
+	"github.com/TesraSupernet/Tesra/core/store"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/merkle"
+)
+
+const (
+	STATE_SNAPSHOT_VERSION    = byte(3)          //Version of state snapshot file
+	STATE_SNAPSHOT_BATCH_SIZE = 10000            //Count of state records committed in one batch when importing
+	STATE_SNAPSHOT_HASH_BATCH = 4096             //Count of merkle hashes appended to hash store in one write
+	STATE_SNAPSHOT_MAX_BLOCK  = 64 * 1024 * 1024 //Max size of a block in snapshot
+	STATE_SNAPSHOT_MAX_HEADER = 1024 * 1024      //Max size of an unsigned header in snapshot
+)
+
+var STATE_SNAPSHOT_MAGIC = []byte("TSTSNAP")
+
+//State snapshot file layout, all sections are covered by the sha256 digest in trailer:
+//  magic, version, height, block hash, block root, state merkle root
+//  unsigned header of every height from 0 to height
+//  anchor blocks: genesis block, block at height, and the last vbft config block
+//  hashes of block merkle tree hash store
+//  state db key value pairs, ended with an empty key
+//  trailer: count of state records, state hash, sha256 digest
+//Block hashes below height are trusted only through the prev block hash chain of the headers up to the checkpoint
+//block hash, and the block merkle tree through the BlockRoot of the checkpoint header. Block headers of this chain
+//commit to no state root, so state hash, the sha256 of all state db key value pairs, must be got from a trusted node
+//together with the block hash, and the imported states are checked against it
+
+//ExportStateSnapshot dump the full state db at current block height, together with the blocks needed
+//to continue syncing from that height
+func (this *LedgerStoreImp) ExportStateSnapshot(w io.Writer) (*store.StateSnapshotInfo, error) {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+
+	height, blockHash := this.GetCurrentBlock()
+	header, err := this.GetHeaderByHash(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetHeaderByHash height:%d error %s", height, err)
+	}
+	stateMerkleRoot, err := this.GetStateMerkleRoot(height)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateMerkleRoot height:%d error %s", height, err)
+	}
+	info := &store.StateSnapshotInfo{
+		Height:          height,
+		BlockHash:       blockHash,
+		BlockRoot:       header.BlockRoot,
+		StateMerkleRoot: stateMerkleRoot,
+	}
+	anchors, err := this.getAnchorBlocks(header)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.New()
+	bw := bufio.NewWriter(w)
+	writer := io.MultiWriter(bw, digest)
+	err = writeSnapshotHeader(writer, info)
+	if err != nil {
+		return nil, fmt.Errorf("write snapshot header error %s", err)
+	}
+	for i := uint32(0); i <= height; i++ {
+		header, err := this.GetHeaderByHeight(i)
+		if err != nil {
+			return nil, fmt.Errorf("GetHeaderByHeight height:%d error %s", i, err)
+		}
+		if err = serialization.WriteVarBytes(writer, header.GetMessage()); err != nil {
+			return nil, fmt.Errorf("write header error %s", err)
+		}
+	}
+	if err = serialization.WriteUint32(writer, uint32(len(anchors))); err != nil {
+		return nil, fmt.Errorf("write anchor blocks error %s", err)
+	}
+	for _, h := range anchors {
+		block, err := this.GetBlockByHeight(h)
+		if err != nil {
+			return nil, fmt.Errorf("GetBlockByHeight height:%d error %s", h, err)
+		}
+		if err = serialization.WriteVarBytes(writer, block.ToArray()); err != nil {
+			return nil, fmt.Errorf("write anchor block error %s", err)
+		}
+	}
+	err = this.stateStore.exportMerkleHashes(writer)
+	if err != nil {
+		return nil, fmt.Errorf("export merkle hashes error %s", err)
+	}
+	info.StateCount, info.StateHash, err = this.stateStore.exportStates(writer)
+	if err != nil {
+		return nil, fmt.Errorf("export states error %s", err)
+	}
+
+	if err = writeSnapshotTrailer(bw, info, digest.Sum(nil)); err != nil {
+		return nil, err
+	}
+	if err = bw.Flush(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+//getAnchorBlocks return the heights of blocks needed to start node at header height:
+//genesis block, block of header and the last vbft config block
+func (this *LedgerStoreImp) getAnchorBlocks(header *types.Header) ([]uint32, error) {
+	anchors := []uint32{0}
+	if header.Height != 0 {
+		anchors = append(anchors, header.Height)
+	}
+	if config.DefConfig.Genesis.GetConsensusType(header.Height+1) != config.CONSENSUS_TYPE_VBFT {
+		return anchors, nil
+	}
+	blkInfo, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return nil, err
+	}
+	cfgHeight := blkInfo.LastConfigBlockNum
+	if blkInfo.NewChainConfig == nil && cfgHeight != 0 && cfgHeight != header.Height {
+		anchors = append(anchors, cfgHeight)
+	}
+	return anchors, nil
+}
+
+//ImportStateSnapshot load a state snapshot taken at the trusted checkpoint into an empty ledger store. The ledger
+//can continue syncing blocks from snapshot height after InitLedgerStoreWithGenesisBlock
+func (this *LedgerStoreImp) ImportStateSnapshot(r io.ReadSeeker, checkpoint *store.StateSnapshotCheckpoint) (*store.StateSnapshotInfo, error) {
+	if checkpoint == nil {
+		return nil, fmt.Errorf("trusted checkpoint of state snapshot is required")
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+
+	hasInit, err := this.hasAlreadyInitGenesisBlock()
+	if err != nil {
+		return nil, err
+	}
+	if hasInit {
+		return nil, fmt.Errorf("ledger already initialized, state snapshot can only be imported to an empty ledger")
+	}
+	// the whole snapshot is checked against checkpoint before anything is written to ledger
+	err = scanStateSnapshot(r, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	for _, clear := range []func() error{this.blockStore.ClearAll, this.stateStore.ClearAll, this.eventStore.ClearAll} {
+		if err = clear(); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := this.importStateSnapshot(r, checkpoint)
+	if err != nil {
+		this.lock.Lock()
+		this.headerIndex = make(map[uint32]common.Uint256)
+		this.lock.Unlock()
+		this.blockStore.ClearAll()
+		this.stateStore.ClearAll()
+		this.eventStore.ClearAll()
+		return nil, err
+	}
+	err = this.initGenesisBlock()
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+//scanStateSnapshot read through a snapshot and check it against the trusted checkpoint, nothing is written to ledger
+func scanStateSnapshot(r io.Reader, checkpoint *store.StateSnapshotCheckpoint) error {
+	digest := sha256.New()
+	br := bufio.NewReader(r)
+	reader := io.TeeReader(br, digest)
+	info, err := readSnapshotHeader(reader)
+	if err != nil {
+		return fmt.Errorf("read snapshot header error %s", err)
+	}
+	if err = checkSnapshotCheckpoint(info, checkpoint); err != nil {
+		return err
+	}
+	if err = readSnapshotHeaders(reader, info, nil); err != nil {
+		return err
+	}
+	anchorCount, err := serialization.ReadUint32(reader)
+	if err != nil {
+		return fmt.Errorf("read anchor blocks error %s", err)
+	}
+	var current *types.Block
+	for i := uint32(0); i < anchorCount; i++ {
+		block, err := readAnchorBlock(reader)
+		if err != nil {
+			return err
+		}
+		if block.Header.Height == info.Height {
+			if block.Hash() != info.BlockHash {
+				return fmt.Errorf("anchor block of height:%d mismatch", info.Height)
+			}
+			current = block
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("missing block of height:%d", info.Height)
+	}
+	if info.Height > 0 && current.Header.BlockRoot != info.BlockRoot {
+		return fmt.Errorf("block root mismatch with header of height:%d", info.Height)
+	}
+	hashCount, err := serialization.ReadUint32(reader)
+	if err != nil {
+		return fmt.Errorf("read merkle hashes error %s", err)
+	}
+	if _, err = io.CopyN(ioutil.Discard, reader, int64(hashCount)*common.UINT256_SIZE); err != nil {
+		return fmt.Errorf("read merkle hashes error %s", err)
+	}
+	stateDigest := sha256.New()
+	for {
+		key, _, err := readStateRecord(reader, stateDigest)
+		if err != nil {
+			return fmt.Errorf("read states error %s", err)
+		}
+		if key == nil {
+			break
+		}
+		info.StateCount++
+	}
+	copy(info.StateHash[:], stateDigest.Sum(nil))
+	err = readSnapshotTrailer(br, info, digest.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if info.StateHash != checkpoint.StateHash {
+		return fmt.Errorf("state hash mismatch with trusted checkpoint")
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) importStateSnapshot(r io.Reader, checkpoint *store.StateSnapshotCheckpoint) (*store.StateSnapshotInfo, error) {
+	digest := sha256.New()
+	br := bufio.NewReader(r)
+	reader := io.TeeReader(br, digest)
+	info, err := readSnapshotHeader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot header error %s", err)
+	}
+	if err = checkSnapshotCheckpoint(info, checkpoint); err != nil {
+		return nil, err
+	}
+
+	this.blockStore.NewBatch()
+	hashes := make([]common.Uint256, 0, HEADER_INDEX_BATCH_SIZE)
+	// the batch is only committed after the header chain reached the checkpoint block hash
+	err = readSnapshotHeaders(reader, info, func(height uint32, hash common.Uint256) {
+		this.blockStore.SaveBlockHash(height, hash)
+		this.setHeaderIndex(height, hash)
+		hashes = append(hashes, hash)
+		if uint32(len(hashes)) == HEADER_INDEX_BATCH_SIZE && height < info.Height {
+			this.blockStore.SaveHeaderIndexList(height+1-HEADER_INDEX_BATCH_SIZE, hashes)
+			hashes = hashes[:0]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	anchorCount, err := serialization.ReadUint32(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read anchor blocks error %s", err)
+	}
+	var current *types.Block
+	for i := uint32(0); i < anchorCount; i++ {
+		block, err := readAnchorBlock(reader)
+		if err != nil {
+			return nil, err
+		}
+		height := block.Header.Height
+		if height > info.Height || this.getHeaderIndex(height) != block.Hash() {
+			return nil, fmt.Errorf("anchor block of height:%d mismatch", height)
+		}
+		if err = this.blockStore.SaveBlock(block); err != nil {
+			return nil, err
+		}
+		if height == info.Height {
+			current = block
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("missing block of height:%d", info.Height)
+	}
+	if info.Height > 0 && current.Header.BlockRoot != info.BlockRoot {
+		return nil, fmt.Errorf("block root mismatch with header of height:%d", info.Height)
+	}
+	this.blockStore.SaveCurrentBlock(info.Height, info.BlockHash)
+	// blocks below snapshot height are not available, same as pruned ones
+	this.blockStore.SavePrunedHeight(info.Height)
+
+	err = this.stateStore.importMerkleHashes(reader)
+	if err != nil {
+		return nil, fmt.Errorf("import merkle hashes error %s", err)
+	}
+	info.StateCount, info.StateHash, err = this.stateStore.importStates(reader)
+	if err != nil {
+		return nil, fmt.Errorf("import states error %s", err)
+	}
+	err = readSnapshotTrailer(br, info, digest.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	// snapshot file may be changed after scanning
+	if info.StateHash != checkpoint.StateHash {
+		return nil, fmt.Errorf("state hash mismatch with trusted checkpoint")
+	}
+
+	err = this.stateStore.verifySnapshot(info)
+	if err != nil {
+		return nil, err
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return nil, err
+	}
+	this.eventStore.NewBatch()
+	this.eventStore.SaveCurrentBlock(info.Height, info.BlockHash)
+	err = this.eventStore.CommitTo()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("import state snapshot height:%d hash:%s states:%d", info.Height, info.BlockHash.ToHexString(), info.StateCount)
+	return info, nil
+}
+
+func checkSnapshotCheckpoint(info *store.StateSnapshotInfo, checkpoint *store.StateSnapshotCheckpoint) error {
+	if info.Height != checkpoint.Height || info.BlockHash != checkpoint.BlockHash {
+		return fmt.Errorf("snapshot at height:%d hash:%s mismatch with trusted checkpoint height:%d hash:%s",
+			info.Height, info.BlockHash.ToHexString(), checkpoint.Height, checkpoint.BlockHash.ToHexString())
+	}
+	return nil
+}
+
+//readSnapshotHeaders read the unsigned headers from genesis to info.Height, and check every header is chained to the
+//previous one by prev block hash and the last one is the block of info, which is checked against trusted checkpoint.
+//onHash is called with the block hash of every height in order, the caller must discard them if error returned
+func readSnapshotHeaders(r io.Reader, info *store.StateSnapshotInfo, onHash func(height uint32, hash common.Uint256)) error {
+	var prevHash common.Uint256
+	for i := uint32(0); i <= info.Height; i++ {
+		data, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("read header of height:%d error %s", i, err)
+		}
+		if len(data) > STATE_SNAPSHOT_MAX_HEADER {
+			return fmt.Errorf("header of height:%d too large", i)
+		}
+		header, err := types.HeaderFromUnsignedBytes(data)
+		if err != nil {
+			return fmt.Errorf("header of height:%d deserialize error %s", i, err)
+		}
+		if header.Height != i || (i > 0 && header.PrevBlockHash != prevHash) {
+			return fmt.Errorf("header of height:%d is not chained to previous header", i)
+		}
+		prevHash = header.Hash()
+		if onHash != nil {
+			onHash(i, prevHash)
+		}
+	}
+	if prevHash != info.BlockHash {
+		return fmt.Errorf("block hash of height:%d mismatch", info.Height)
+	}
+	return nil
+}
+
+func readAnchorBlock(r io.Reader) (*types.Block, error) {
+	data, err := serialization.ReadVarBytes(r)
+	if err != nil {
+		return nil, fmt.Errorf("read anchor block error %s", err)
+	}
+	if len(data) > STATE_SNAPSHOT_MAX_BLOCK {
+		return nil, fmt.Errorf("anchor block too large")
+	}
+	block, err := types.BlockFromRawBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("anchor block deserialize error %s", err)
+	}
+	return block, nil
+}
+
+//readStateRecord read a key value pair of state db and add it to state hash, nil key at the end of states
+func readStateRecord(r io.Reader, stateDigest hash.Hash) ([]byte, []byte, error) {
+	key, err := serialization.ReadVarBytes(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(key) == 0 {
+		return nil, nil, nil
+	}
+	value, err := serialization.ReadVarBytes(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialization.WriteVarBytes(stateDigest, key)
+	serialization.WriteVarBytes(stateDigest, value)
+	return key, value, nil
+}
+
+func writeSnapshotTrailer(w io.Writer, info *store.StateSnapshotInfo, digest []byte) error {
+	if err := serialization.WriteUint64(w, info.StateCount); err != nil {
+		return err
+	}
+	if _, err := w.Write(info.StateHash[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(digest)
+	return err
+}
+
+//readSnapshotTrailer check the trailer against the state records read and the digest of snapshot
+func readSnapshotTrailer(r io.Reader, info *store.StateSnapshotInfo, digest []byte) error {
+	stateCount, err := serialization.ReadUint64(r)
+	if err != nil {
+		return fmt.Errorf("read snapshot trailer error %s", err)
+	}
+	var stateHash common.Uint256
+	if _, err = io.ReadFull(r, stateHash[:]); err != nil {
+		return fmt.Errorf("read snapshot trailer error %s", err)
+	}
+	expected := make([]byte, sha256.Size)
+	if _, err = io.ReadFull(r, expected); err != nil {
+		return fmt.Errorf("read snapshot trailer error %s", err)
+	}
+	if stateCount != info.StateCount || stateHash != info.StateHash || !bytes.Equal(expected, digest) {
+		return fmt.Errorf("snapshot digest mismatch, file is corrupted")
+	}
+	return nil
+}
+
+func writeSnapshotHeader(w io.Writer, info *store.StateSnapshotInfo) error {
+	if _, err := w.Write(STATE_SNAPSHOT_MAGIC); err != nil {
+		return err
+	}
+	if err := serialization.WriteByte(w, STATE_SNAPSHOT_VERSION); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, info.Height); err != nil {
+		return err
+	}
+	for _, hash := range []common.Uint256{info.BlockHash, info.BlockRoot, info.StateMerkleRoot} {
+		if _, err := w.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSnapshotHeader(r io.Reader) (*store.StateSnapshotInfo, error) {
+	magic := make([]byte, len(STATE_SNAPSHOT_MAGIC))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != string(STATE_SNAPSHOT_MAGIC) {
+		return nil, fmt.Errorf("not a state snapshot file")
+	}
+	version, err := serialization.ReadByte(r)
+	if err != nil {
+		return nil, err
+	}
+	if version != STATE_SNAPSHOT_VERSION {
+		return nil, fmt.Errorf("unsupported snapshot version:%d", version)
+	}
+	info := &store.StateSnapshotInfo{}
+	info.Height, err = serialization.ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range []*common.Uint256{&info.BlockHash, &info.BlockRoot, &info.StateMerkleRoot} {
+		if _, err = io.ReadFull(r, hash[:]); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+//exportMerkleHashes write the hashes persisted in block merkle tree hash store
+func (self *StateStore) exportMerkleHashes(w io.Writer) error {
+	treeSize := self.merkleTree.TreeSize()
+	count := uint32(merkle.GetStoredHashNum(treeSize))
+	if self.merkleHashStore == nil {
+		count = 0
+	}
+	if err := serialization.WriteUint32(w, count); err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		hash, err := self.merkleHashStore.GetHash(i)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *StateStore) importMerkleHashes(r io.Reader) error {
+	count, err := serialization.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	if count > 0 && self.merkleHashStore == nil {
+		return fmt.Errorf("merkle hash store is not available")
+	}
+	batch := make([]common.Uint256, 0, STATE_SNAPSHOT_HASH_BATCH)
+	for i := uint32(0); i < count; i++ {
+		var hash common.Uint256
+		if _, err = io.ReadFull(r, hash[:]); err != nil {
+			return err
+		}
+		batch = append(batch, hash)
+		if len(batch) == STATE_SNAPSHOT_HASH_BATCH || i == count-1 {
+			if err = self.merkleHashStore.Append(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if count > 0 {
+		return self.merkleHashStore.Flush()
+	}
+	return nil
+}
+
+//exportStates write all key value pairs of state db, return the count and hash of pairs
+func (self *StateStore) exportStates(w io.Writer) (uint64, common.Uint256, error) {
+	iter := self.store.NewIterator(nil)
+	defer iter.Release()
+	stateDigest := sha256.New()
+	writer := io.MultiWriter(w, stateDigest)
+	count := uint64(0)
+	for iter.Next() {
+		if iter.Key()[0] == byte(scom.DATA_REVERSE_WRITE_SET) {
+			// blocks before snapshot height can not be rolled back by importer
+			continue
+		}
+		if err := serialization.WriteVarBytes(writer, iter.Key()); err != nil {
+			return 0, common.UINT256_EMPTY, err
+		}
+		if err := serialization.WriteVarBytes(writer, iter.Value()); err != nil {
+			return 0, common.UINT256_EMPTY, err
+		}
+		count++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, common.UINT256_EMPTY, err
+	}
+	var stateHash common.Uint256
+	copy(stateHash[:], stateDigest.Sum(nil))
+	return count, stateHash, serialization.WriteVarBytes(w, nil)
+}
+
+func (self *StateStore) importStates(r io.Reader) (uint64, common.Uint256, error) {
+	stateDigest := sha256.New()
+	count := uint64(0)
+	self.store.NewBatch()
+	for {
+		key, value, err := readStateRecord(r, stateDigest)
+		if err != nil {
+			return 0, common.UINT256_EMPTY, err
+		}
+		if key == nil {
+			break
+		}
+		self.store.BatchPut(key, value)
+		count++
+		if count%STATE_SNAPSHOT_BATCH_SIZE == 0 {
+			if err = self.store.BatchCommit(); err != nil {
+				return 0, common.UINT256_EMPTY, err
+			}
+			self.store.NewBatch()
+		}
+	}
+	var stateHash common.Uint256
+	copy(stateHash[:], stateDigest.Sum(nil))
+	return count, stateHash, self.store.BatchCommit()
+}
+
+//verifySnapshot check the imported state db is at the snapshot height, and its merkle trees match the snapshot roots
+func (self *StateStore) verifySnapshot(info *store.StateSnapshotInfo) error {
+	blockHash, height, err := self.GetCurrentBlock()
+	if err != nil {
+		return fmt.Errorf("GetCurrentBlock error %s", err)
+	}
+	if height != info.Height || blockHash != info.BlockHash {
+		return fmt.Errorf("state db is at height:%d, expected:%d", height, info.Height)
+	}
+	treeSize, hashes, err := self.GetBlockMerkleTree()
+	if err != nil {
+		return fmt.Errorf("GetBlockMerkleTree error %s", err)
+	}
+	if treeSize != info.Height+1 {
+		return fmt.Errorf("block merkle tree size %d inconsistent with height:%d", treeSize, info.Height)
+	}
+	self.merkleTree = merkle.NewTree(treeSize, hashes, self.merkleHashStore)
+	if info.Height > 0 && self.merkleTree.Root() != info.BlockRoot {
+		return fmt.Errorf("block merkle root mismatch")
+	}
+
+	if info.Height < self.stateHashCheckHeight {
+		return nil
+	}
+	stateRoot, err := self.GetStateMerkleRoot(info.Height)
+	if err != nil {
+		return fmt.Errorf("GetStateMerkleRoot error %s", err)
+	}
+	treeSize, hashes, err = self.GetStateMerkleTree()
+	if err != nil && err != scom.ErrNotFound {
+		return fmt.Errorf("GetStateMerkleTree error %s", err)
+	}
+	self.deltaMerkleTree = merkle.NewTree(treeSize, hashes, nil)
+	if stateRoot != info.StateMerkleRoot || self.deltaMerkleTree.Root() != info.StateMerkleRoot {
+		return fmt.Errorf("state merkle root mismatch")
+	}
+	return nil
+}
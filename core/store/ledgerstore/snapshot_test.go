@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/serialization"
+	"github.com/TesraSupernet/Tesra/core/store"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateSnapshot(t *testing.T) {
+	genesisBlock, err := testLedgerStore.GetBlockByHeight(0)
+	assert.Nil(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	info, err := testLedgerStore.ExportStateSnapshot(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, testLedgerStore.GetCurrentBlockHeight(), info.Height)
+	assert.Equal(t, testLedgerStore.GetCurrentBlockHash(), info.BlockHash)
+	assert.True(t, info.StateCount > 0)
+	data := buf.Bytes()
+	checkpoint := &store.StateSnapshotCheckpoint{Height: info.Height, BlockHash: info.BlockHash, StateHash: info.StateHash}
+
+	corrupted := make([]byte, len(data))
+	copy(corrupted, data)
+	corrupted[len(corrupted)/2] ^= 0xff
+	// last state value changed, with digest of file recomputed
+	const trailerSize = 8 + common.UINT256_SIZE + sha256.Size
+	forged := make([]byte, len(data))
+	copy(forged, data)
+	forged[len(forged)-trailerSize-2] ^= 0xff
+	digest := sha256.Sum256(forged[:len(forged)-sha256.Size])
+	copy(forged[len(forged)-sha256.Size:], digest[:])
+
+	badStore, err := NewLedgerStore("test/snapshot_bad", 0)
+	assert.Nil(t, err)
+	for _, c := range []struct {
+		data       []byte
+		checkpoint *store.StateSnapshotCheckpoint
+	}{
+		{corrupted, checkpoint},
+		{forged, checkpoint},
+		{data, nil},
+		{data, &store.StateSnapshotCheckpoint{Height: info.Height, BlockHash: common.Uint256{1}, StateHash: info.StateHash}},
+		{data, &store.StateSnapshotCheckpoint{Height: info.Height, BlockHash: info.BlockHash, StateHash: common.Uint256{1}}},
+	} {
+		_, err = badStore.ImportStateSnapshot(bytes.NewReader(c.data), c.checkpoint)
+		assert.NotNil(t, err)
+		hasInit, err := badStore.hasAlreadyInitGenesisBlock()
+		assert.Nil(t, err)
+		assert.False(t, hasInit)
+		_, height, err := badStore.stateStore.GetCurrentBlock()
+		assert.NotNil(t, err)
+		assert.Equal(t, uint32(0), height)
+	}
+	assert.Nil(t, badStore.Close())
+
+	newStore, err := NewLedgerStore("test/snapshot", 0)
+	assert.Nil(t, err)
+	defer newStore.Close()
+	imported, err := newStore.ImportStateSnapshot(bytes.NewReader(data), checkpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, info, imported)
+	err = newStore.InitLedgerStoreWithGenesisBlock(genesisBlock, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, info.Height, newStore.GetCurrentBlockHeight())
+	assert.Equal(t, info.BlockHash, newStore.GetCurrentBlockHash())
+
+	expected, err := testLedgerStore.GetBookkeeperState()
+	assert.Nil(t, err)
+	bookkeepers, err := newStore.GetBookkeeperState()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, bookkeepers)
+
+	_, err = newStore.ImportStateSnapshot(bytes.NewReader(data), checkpoint)
+	assert.NotNil(t, err)
+}
+
+func TestReadSnapshotHeaders(t *testing.T) {
+	headers := make([]*types.Header, 0)
+	prevHash := common.UINT256_EMPTY
+	for i := uint32(0); i < 5; i++ {
+		header := &types.Header{Height: i, PrevBlockHash: prevHash, Timestamp: i}
+		headers = append(headers, header)
+		prevHash = header.Hash()
+	}
+	info := &store.StateSnapshotInfo{Height: 4, BlockHash: prevHash}
+	encode := func(headers []*types.Header) *bytes.Buffer {
+		buf := bytes.NewBuffer(nil)
+		for _, header := range headers {
+			serialization.WriteVarBytes(buf, header.GetMessage())
+		}
+		return buf
+	}
+
+	hashes := make([]common.Uint256, 0)
+	err := readSnapshotHeaders(encode(headers), info, func(height uint32, hash common.Uint256) {
+		assert.Equal(t, uint32(len(hashes)), height)
+		hashes = append(hashes, hash)
+	})
+	assert.Nil(t, err)
+	for i, header := range headers {
+		assert.Equal(t, header.Hash(), hashes[i])
+	}
+
+	// a forged header below checkpoint breaks the prev block hash chain
+	forged := make([]*types.Header, len(headers))
+	copy(forged, headers)
+	forged[2] = &types.Header{Height: 2, PrevBlockHash: headers[1].Hash(), Timestamp: 100}
+	assert.NotNil(t, readSnapshotHeaders(encode(forged), info, nil))
+
+	// chained headers not ending at checkpoint
+	assert.NotNil(t, readSnapshotHeaders(encode(headers[:4]), &store.StateSnapshotInfo{Height: 3, BlockHash: prevHash}, nil))
+}
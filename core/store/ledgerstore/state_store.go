@@ -51,12 +51,14 @@ type StateStore struct {
 	deltaMerkleTree      *merkle.CompactMerkleTree //Merkle tree of delta state root
 	merkleHashStore      merkle.HashStore
 	stateHashCheckHeight uint32
+	readOnly             bool   //Merkle tree store is appended by another process
+	rollbackRetention    uint32 //Count of recent blocks whose reverse write sets are kept
 }
 
 //NewStateStore return state store instance
 func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*StateStore, error) {
 	var err error
-	store, err := leveldbstore.NewLevelDBStore(dbDir)
+	store, err := NewPersistStore(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +67,7 @@ func NewStateStore(dbDir, merklePath string, stateHashCheckHeight uint32) (*Stat
 		store:                store,
 		merklePath:           merklePath,
 		stateHashCheckHeight: stateHashCheckHeight,
+		rollbackRetention:    MAX_ROLLBACK_BLOCKS,
 	}
 	_, height, err := stateStore.GetCurrentBlock()
 	if err != nil && err != scom.ErrNotFound {
@@ -85,6 +88,7 @@ func NewMemStateStore(stateHashHeight uint32) *StateStore {
 		merkleTree:           merkle.NewTree(0, nil, nil),
 		deltaMerkleTree:      merkle.NewTree(0, nil, nil),
 		stateHashCheckHeight: stateHashHeight,
+		rollbackRetention:    MAX_ROLLBACK_BLOCKS,
 	}
 
 	return stateStore
@@ -111,7 +115,11 @@ func (self *StateStore) init(currBlockHeight uint32) error {
 	if treeSize > 0 && treeSize != currBlockHeight+1 {
 		return fmt.Errorf("merkle tree size is inconsistent with blockheight: %d", currBlockHeight+1)
 	}
-	self.merkleHashStore, err = merkle.NewFileHashStore(self.merklePath, treeSize)
+	if self.readOnly {
+		self.merkleHashStore, err = merkle.NewReadOnlyFileHashStore(self.merklePath, treeSize)
+	} else {
+		self.merkleHashStore, err = merkle.NewFileHashStore(self.merklePath, treeSize)
+	}
 	if err != nil {
 		log.Warn("merkle store is inconsistent with ChainStore. persistence will be disabled")
 	}
@@ -292,6 +300,15 @@ func (self *StateStore) SaveBookkeeperState(bookkeeperState *states.BookkeeperSt
 	return self.store.Put(key, value.Bytes())
 }
 
+//BatchSaveBookkeeperState persist book keeper state to store in batch
+func (self *StateStore) BatchSaveBookkeeperState(bookkeeperState *states.BookkeeperState) {
+	key, _ := self.getBookkeeperKey()
+	value := common.NewZeroCopySink(nil)
+	bookkeeperState.Serialization(value)
+
+	self.store.BatchPut(key, value.Bytes())
+}
+
 //GetStorageItem return the storage value of the key in smart contract.
 func (self *StateStore) GetStorageState(key *states.StorageKey) (*states.StorageItem, error) {
 	storeKey, err := self.getStorageKey(key)
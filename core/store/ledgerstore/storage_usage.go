@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"fmt"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/states"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/core/store/overlaydb"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/native"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/neovm"
+	"github.com/TesraSupernet/Tesra/smartcontract/storage"
+)
+
+//GetStorageUsage return the bytes and count of storage items of contract.
+//Storage usage is updated by transactions in the write set of block while storage quota or rent is enabled, so
+//it is covered by state hash, otherwise updated after the write set of block
+func (self *StateStore) GetStorageUsage(address common.Address) (*states.StorageUsage, error) {
+	usage := &states.StorageUsage{}
+	value, err := self.store.Get(genStorageUsageKey(address))
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return usage, nil
+		}
+		return nil, err
+	}
+	err = usage.Deserialization(common.NewZeroCopySource(value))
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+//BatchSaveStorageUsage update the storage usage of contracts written by block in batch, except the ones already
+//updated in write set
+func (self *StateStore) BatchSaveStorageUsage(writeSet *overlaydb.MemDB) error {
+	deltas := make(map[common.Address]*storage.StorageUsageDelta)
+	var err error
+	writeSet.ForEach(func(key, val []byte) {
+		if err != nil || key[0] != byte(scom.ST_STORAGE) {
+			return
+		}
+		old, e := self.store.Get(key)
+		if e != nil && e != scom.ErrNotFound {
+			err = e
+			return
+		}
+		storage.AccumulateStorageUsage(deltas, key, old, val)
+	})
+	if err != nil {
+		return err
+	}
+	for address, delta := range deltas {
+		if delta.Bytes == 0 && delta.Keys == 0 {
+			continue
+		}
+		if _, unknown := writeSet.Get(genStorageUsageKey(address)); !unknown {
+			continue
+		}
+		usage, err := self.GetStorageUsage(address)
+		if err != nil {
+			return err
+		}
+		usage.Add(delta.Bytes, delta.Keys)
+		self.store.BatchPut(genStorageUsageKey(address), common.SerializeToBytes(usage))
+	}
+	return nil
+}
+
+//CheckStorageUsage build the storage usage of contracts from their storage items, if the ledger was created
+//before storage usage accounting
+func (self *StateStore) CheckStorageUsage() error {
+	built, err := self.store.Has(self.getStorageUsageBuiltKey())
+	if err != nil || built {
+		return err
+	}
+	log.Infof("building storage usage of contracts")
+	deltas := make(map[common.Address]*storage.StorageUsageDelta)
+	iter := self.store.NewIterator([]byte{byte(scom.ST_STORAGE)})
+	for iter.Next() {
+		storage.AccumulateStorageUsage(deltas, iter.Key(), nil, iter.Value())
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	self.store.NewBatch()
+	iter = self.store.NewIterator([]byte{byte(scom.IX_STORAGE_USAGE)})
+	for iter.Next() {
+		self.store.BatchDelete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	for address, delta := range deltas {
+		usage := &states.StorageUsage{}
+		usage.Add(delta.Bytes, delta.Keys)
+		self.store.BatchPut(genStorageUsageKey(address), common.SerializeToBytes(usage))
+	}
+	self.store.BatchPut(self.getStorageUsageBuiltKey(), []byte{1})
+	err = self.store.BatchCommit()
+	if err != nil {
+		return err
+	}
+	log.Infof("storage usage of %d contracts built", len(deltas))
+	return nil
+}
+
+//storageUsageKeys return the storage usage keys of contracts written in write set, except the ones in write set
+func storageUsageKeys(writeSet *overlaydb.MemDB) [][]byte {
+	keys := make([][]byte, 0)
+	written := make(map[common.Address]bool)
+	writeSet.ForEach(func(key, val []byte) {
+		if len(key) < 1+common.ADDR_LEN || key[0] != byte(scom.ST_STORAGE) {
+			return
+		}
+		var address common.Address
+		copy(address[:], key[1:])
+		if written[address] {
+			return
+		}
+		written[address] = true
+		usageKey := genStorageUsageKey(address)
+		if _, unknown := writeSet.Get(usageKey); unknown {
+			keys = append(keys, usageKey)
+		}
+	})
+	return keys
+}
+
+func genStorageUsageKey(address common.Address) []byte {
+	return append([]byte{byte(scom.IX_STORAGE_USAGE)}, address[:]...)
+}
+
+func (self *StateStore) getStorageUsageBuiltKey() []byte {
+	return []byte{byte(scom.IX_STORAGE_USAGE)}
+}
+
+//checkStorageQuota check the storage quota of contracts written by transaction and return the gas of storage
+//rent for the bytes added, both are set by global params and disabled by default. Native contracts are exempted.
+//While enabled, the usage of contracts written is updated in cache, so the quota is checked with the transactions
+//before in block, and the usage is covered by state hash
+func checkStorageQuota(cache *storage.CacheDB, gasTable map[string]uint64) (uint64, error) {
+	quota := gasTable[neovm.STORAGE_QUOTA_NAME]
+	byteGas := gasTable[neovm.STORAGE_BYTE_GAS_NAME]
+	if quota == 0 && byteGas == 0 {
+		return 0, nil
+	}
+	deltas, err := cache.StorageUsageDeltas()
+	if err != nil {
+		return 0, err
+	}
+	gas := uint64(0)
+	for address, delta := range deltas {
+		if (delta.Bytes == 0 && delta.Keys == 0) || native.Contracts[address] != nil {
+			continue
+		}
+		usage, err := cache.GetStorageUsage(address)
+		if err != nil {
+			return 0, err
+		}
+		usage.Add(delta.Bytes, delta.Keys)
+		if delta.Bytes > 0 {
+			gas += uint64(delta.Bytes) * byteGas
+			if quota != 0 && usage.Bytes > quota {
+				return 0, fmt.Errorf("storage of contract %s exceeds quota %d bytes", address.ToHexString(), quota)
+			}
+		}
+		cache.PutStorageUsage(address, usage)
+	}
+	return gas, nil
+}
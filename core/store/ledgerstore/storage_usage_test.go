@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/states"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/neovm"
+	"github.com/TesraSupernet/Tesra/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageUsage(t *testing.T) {
+	stateStore, err := NewStateStore("test/storage_usage", "test/storage_usage_"+MerkleTreeStorePath, 0)
+	assert.Nil(t, err)
+	defer stateStore.Close()
+	assert.Nil(t, stateStore.CheckStorageUsage())
+
+	address := common.Address{1}
+	genKey := func(key byte) []byte {
+		return append(append([]byte{byte(scom.ST_STORAGE)}, address[:]...), key)
+	}
+	saveBlock := func(height uint32, update func(put func(key, value []byte), del func(key []byte))) {
+		overlay := stateStore.NewOverlayDB()
+		update(overlay.Put, overlay.Delete)
+		stateStore.NewBatch()
+		assert.Nil(t, stateStore.SaveCurrentBlock(height, common.Uint256{byte(height)}))
+		assert.Nil(t, stateStore.BatchSaveReverseWriteSet(height, overlay.GetWriteSet()))
+		assert.Nil(t, stateStore.BatchSaveStorageUsage(overlay.GetWriteSet()))
+		overlay.CommitTo()
+		assert.Nil(t, stateStore.CommitTo())
+	}
+
+	saveBlock(0, func(put func(key, value []byte), del func(key []byte)) {
+		put(genKey(1), []byte{1, 2})
+		put(genKey(2), []byte{1})
+	})
+	usage, err := stateStore.GetStorageUsage(address)
+	assert.Nil(t, err)
+	assert.Equal(t, &states.StorageUsage{Bytes: 2*21 + 3, Keys: 2}, usage)
+
+	saveBlock(1, func(put func(key, value []byte), del func(key []byte)) {
+		put(genKey(1), []byte{1, 2, 3, 4})
+		del(genKey(2))
+		del(genKey(3))
+	})
+	usage, err = stateStore.GetStorageUsage(address)
+	assert.Nil(t, err)
+	assert.Equal(t, &states.StorageUsage{Bytes: 21 + 4, Keys: 1}, usage)
+
+	stateStore.NewBatch()
+	assert.Nil(t, stateStore.BatchRollbackBlock(1))
+	assert.Nil(t, stateStore.CommitTo())
+	usage, err = stateStore.GetStorageUsage(address)
+	assert.Nil(t, err)
+	assert.Equal(t, &states.StorageUsage{Bytes: 2*21 + 3, Keys: 2}, usage)
+
+	stateStore.store.Put(genStorageUsageKey(address), common.SerializeToBytes(&states.StorageUsage{}))
+	assert.Nil(t, stateStore.CheckStorageUsage())
+	usage, err = stateStore.GetStorageUsage(address)
+	assert.Nil(t, err)
+	assert.Equal(t, &states.StorageUsage{}, usage)
+	stateStore.store.Delete(stateStore.getStorageUsageBuiltKey())
+	assert.Nil(t, stateStore.CheckStorageUsage())
+	usage, err = stateStore.GetStorageUsage(address)
+	assert.Nil(t, err)
+	assert.Equal(t, &states.StorageUsage{Bytes: 2*21 + 3, Keys: 2}, usage)
+}
+
+func TestStorageQuotaInBlock(t *testing.T) {
+	stateStore, err := NewStateStore("test/storage_quota", "test/storage_quota_"+MerkleTreeStorePath, 0)
+	assert.Nil(t, err)
+	defer stateStore.Close()
+
+	address := common.Address{1}
+	gasTable := map[string]uint64{neovm.STORAGE_QUOTA_NAME: 100}
+	overlay := stateStore.NewOverlayDB()
+	cache := storage.NewCacheDB(overlay)
+
+	//each transaction is within the quota, but not the two in one block
+	cache.Put(append(address[:], 1), make([]byte, 60))
+	_, err = checkStorageQuota(cache, gasTable)
+	assert.Nil(t, err)
+	cache.Commit()
+	cache.Reset()
+	cache.Put(append(address[:], 2), make([]byte, 60))
+	_, err = checkStorageQuota(cache, gasTable)
+	assert.NotNil(t, err)
+	cache.Reset()
+
+	//the usage is updated in write set, and not counted again when saved
+	_, unknown := overlay.GetWriteSet().Get(genStorageUsageKey(address))
+	assert.False(t, unknown)
+	stateStore.NewBatch()
+	assert.Nil(t, stateStore.BatchSaveStorageUsage(overlay.GetWriteSet()))
+	overlay.CommitTo()
+	assert.Nil(t, stateStore.CommitTo())
+	usage, err := stateStore.GetStorageUsage(address)
+	assert.Nil(t, err)
+	assert.Equal(t, &states.StorageUsage{Bytes: 21 + 60, Keys: 1}, usage)
+}
@@ -225,6 +225,23 @@ func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay
 		return err
 	}
 
+	if !sysTransFlag {
+		storageGas, err := checkStorageQuota(sc.CacheDB, gasTable)
+		if err == nil && costGasLimit+storageGas > availableGasLimit {
+			err = fmt.Errorf("gas insufficient for storage rent: need %d actual:%d", costGasLimit+storageGas, availableGasLimit)
+		}
+		if err != nil {
+			if isCharge {
+				if err := costInvalidGas(tx.Payer, costGas, config, overlay, store, notify); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+		costGasLimit += storageGas
+		costGas = costGasLimit * tx.GasPrice
+	}
+
 	var notifies []*event.NotifyEventInfo
 	if isCharge {
 		newBalance, err = getBalanceFromNative(config, cache, store, tx.Payer)
@@ -281,8 +298,19 @@ func isBalanceSufficient(payer common.Address, cache *storage.CacheDB, config *s
 	return balance, nil
 }
 
+//feeAccrual is implemented by the ledger store of a transaction executed in parallel. Its read of governance balance
+//while charging gas is not a conflict, the fee is added to the balance of block as a delta
+type feeAccrual interface {
+	beginFeeCharge()
+	endFeeCharge()
+}
+
 func chargeCostGas(payer common.Address, gas uint64, config *smartcontract.Config,
 	cache *storage.CacheDB, store store.LedgerStore) ([]*event.NotifyEventInfo, error) {
+	if accrual, ok := store.(feeAccrual); ok {
+		accrual.beginFeeCharge()
+		defer accrual.endFeeCharge()
+	}
 
 	params := genNativeTransferCode(payer, utils.GovernanceContractAddress, gas)
 
@@ -339,6 +367,44 @@ func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, st
 	return nil
 }
 
+func getBlockLimit(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) (*types.BlockLimit, error) {
+	keys := []string{types.BLOCK_GAS_LIMIT_PARAM, types.BLOCK_SIZE_LIMIT_PARAM}
+	sink := common.NewZeroCopySink(nil)
+	utils.EncodeVarUint(sink, uint64(len(keys)))
+	for _, key := range keys {
+		sink.WriteString(key)
+	}
+
+	sc := smartcontract.SmartContract{
+		Config:  config,
+		CacheDB: cache,
+		Store:   store,
+		Gas:     math.MaxUint64,
+	}
+
+	service, _ := sc.NewNativeService()
+	result, err := service.NativeCall(utils.ParamContractAddress, "getGlobalParam", sink.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	params := new(global_params.Params)
+	if err := params.Deserialization(common.NewZeroCopySource(result.([]byte))); err != nil {
+		return nil, fmt.Errorf("deserialize global params error:%s", err)
+	}
+	limits := make([]uint64, len(keys))
+	for i, key := range keys {
+		_, param := params.GetParam(key)
+		if param.Value == "" {
+			continue
+		}
+		limits[i], err = strconv.ParseUint(param.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse global param %s error:%s", key, err)
+		}
+	}
+	return &types.BlockLimit{GasLimit: limits[0], SizeLimit: limits[1]}, nil
+}
+
 func getBalanceFromNative(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore, address common.Address) (uint64, error) {
 	bf := common.NewZeroCopySink(nil)
 	utils.EncodeAddress(bf, address)
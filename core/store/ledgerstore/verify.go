@@ -0,0 +1,290 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/common/serialization"
+	scom "github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/TesraSupernet/Tesra/merkle"
+)
+
+//LedgerVerifyError report the first inconsistent height found by VerifyLedger
+type LedgerVerifyError struct {
+	Height uint32
+	Err    error
+}
+
+func (this *LedgerVerifyError) Error() string {
+	return fmt.Sprintf("inconsistent at height %d: %s", this.Height, this.Err)
+}
+
+//VerifyLedger walk all blocks of a ledger store opened by NewLedgerStore and not initialized, check the header
+//hash links, transaction roots, block merkle tree, state merkle root chain and event store. If replay is not nil,
+//which is a ledger store initialized with the same genesis block, blocks are re-executed on it to recompute
+//the write set hashes. progress is called after each block is verified
+func (this *LedgerStoreImp) VerifyLedger(replay *LedgerStoreImp, progress func(height uint32)) error {
+	err := this.loadCurrentBlock()
+	if err != nil {
+		return err
+	}
+	err = this.loadHeaderIndexList()
+	if err != nil {
+		return err
+	}
+	err = this.loadPrunedHeight()
+	if err != nil {
+		return err
+	}
+	currHeight, currHash := this.GetCurrentBlock()
+	prunedHeight := this.GetPrunedHeight()
+	if replay != nil && prunedHeight > 0 {
+		log.Warnf("blocks below height %d are pruned, skip replay", prunedHeight)
+		replay = nil
+	}
+	checkHeight := this.stateStore.stateHashCheckHeight
+
+	blockTree := merkle.NewTree(0, nil, nil)
+	stateTree := merkle.NewTree(0, nil, nil)
+	prevHash := common.UINT256_EMPTY
+	partial := false
+	for height := uint32(0); height <= currHeight; height++ {
+		fail := func(format string, a ...interface{}) error {
+			return &LedgerVerifyError{Height: height, Err: fmt.Errorf(format, a...)}
+		}
+		blockHash, err := this.blockStore.GetBlockHash(height)
+		if err != nil {
+			return fail("get block hash error %s", err)
+		}
+		if blockHash != this.getHeaderIndex(height) {
+			return fail("block hash %s mismatch with header index", blockHash.ToHexString())
+		}
+		header, txHashes, err := this.blockStore.loadHeaderWithTx(blockHash)
+		if err == scom.ErrNotFound && height < prunedHeight {
+			// blocks below the height of imported state snapshot are not available
+			partial = true
+			prevHash = blockHash
+			err = this.verifyStateMerkleRoot(stateTree, height)
+			if err != nil {
+				return fail("%s", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fail("load header error %s", err)
+		}
+		if header.Height != height || header.Hash() != blockHash {
+			return fail("header mismatch with block hash %s", blockHash.ToHexString())
+		}
+		if height > 0 && header.PrevBlockHash != prevHash {
+			return fail("prev block hash %s mismatch", header.PrevBlockHash.ToHexString())
+		}
+		//ComputeMerkleRoot overwrites the hashes passed in
+		if common.ComputeMerkleRoot(append([]common.Uint256(nil), txHashes...)) != header.TransactionsRoot {
+			return fail("transactions root mismatch")
+		}
+		for _, txHash := range txHashes {
+			tx, txHeight, err := this.blockStore.loadTransaction(txHash)
+			if err == scom.ErrPruned && height <= prunedHeight {
+				err = nil
+			} else if err == nil && tx.Hash() != txHash {
+				err = fmt.Errorf("hash mismatch")
+			}
+			if err == nil && txHeight != height {
+				err = fmt.Errorf("height %d mismatch", txHeight)
+			}
+			if err != nil {
+				return fail("transaction %s error %s", txHash.ToHexString(), err)
+			}
+		}
+		if !partial {
+			if height > 0 && blockTree.GetRootWithNewLeaf(header.TransactionsRoot) != header.BlockRoot {
+				return fail("block root mismatch with block merkle tree")
+			}
+			blockTree.AppendHash(header.TransactionsRoot)
+		}
+		err = this.verifyStateMerkleRoot(stateTree, height)
+		if err != nil {
+			return fail("%s", err)
+		}
+		if height > prunedHeight && len(txHashes) > 0 {
+			err = this.verifyEventNotify(height, txHashes)
+			if err != nil {
+				return fail("event store error %s", err)
+			}
+		}
+		if replay != nil && height == 0 && replay.GetCurrentBlockHash() != blockHash {
+			return fail("genesis block mismatch with replay ledger")
+		}
+		if replay != nil && height > 0 {
+			err = this.verifyByReplay(replay, blockHash, height)
+			if err != nil {
+				return fail("replay error %s", err)
+			}
+		}
+		prevHash = blockHash
+		if progress != nil {
+			progress(height)
+		}
+	}
+
+	fail := func(format string, a ...interface{}) error {
+		return &LedgerVerifyError{Height: currHeight, Err: fmt.Errorf(format, a...)}
+	}
+	treeSize, hashes, err := this.stateStore.GetBlockMerkleTree()
+	if err != nil {
+		return fail("get block merkle tree error %s", err)
+	}
+	if treeSize != currHeight+1 || (!partial && merkle.NewTree(treeSize, hashes, nil).Root() != blockTree.Root()) {
+		return fail("stored block merkle tree mismatch")
+	}
+	if currHeight >= checkHeight {
+		treeSize, hashes, err = this.stateStore.GetStateMerkleTree()
+		if err != nil {
+			return fail("get state merkle tree error %s", err)
+		}
+		if treeSize != stateTree.TreeSize() || merkle.NewTree(treeSize, hashes, nil).Root() != stateTree.Root() {
+			return fail("stored state merkle tree mismatch")
+		}
+	}
+	stateHash, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return fail("get state store current block error %s", err)
+	}
+	if stateHeight != currHeight || stateHash != currHash {
+		return fail("state store current block %d mismatch", stateHeight)
+	}
+	eventHash, eventHeight, err := this.eventStore.GetCurrentBlock()
+	if err != nil {
+		return fail("get event store current block error %s", err)
+	}
+	if eventHeight != currHeight || eventHash != currHash {
+		return fail("event store current block %d mismatch", eventHeight)
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) verifyEventNotify(height uint32, txHashes []common.Uint256) error {
+	eventTxHashes, err := this.eventStore.getEventTxHashesByBlock(height)
+	if err != nil {
+		return err
+	}
+	if len(eventTxHashes) != len(txHashes) {
+		return fmt.Errorf("event transaction count %d mismatch", len(eventTxHashes))
+	}
+	for i, txHash := range txHashes {
+		if eventTxHashes[i] != txHash {
+			return fmt.Errorf("event transaction %s mismatch", eventTxHashes[i].ToHexString())
+		}
+		if !config.DefConfig.Common.EnableEventLog {
+			continue
+		}
+		_, err = this.eventStore.GetEventNotifyByTx(txHash)
+		if err != nil {
+			return fmt.Errorf("event notify of transaction %s error %s", txHash.ToHexString(), err)
+		}
+	}
+	return nil
+}
+
+//verifyStateMerkleRoot append the saved write set hash of block to state merkle tree, and check the tree root
+//is the same as the saved state merkle root
+func (this *LedgerStoreImp) verifyStateMerkleRoot(stateTree *merkle.CompactMerkleTree, height uint32) error {
+	if height < this.stateStore.stateHashCheckHeight {
+		return nil
+	}
+	writeSetHash, err := this.stateStore.getWriteSetHash(height)
+	if err != nil {
+		return fmt.Errorf("get write set hash error %s", err)
+	}
+	stateRoot, err := this.stateStore.GetStateMerkleRoot(height)
+	if err != nil {
+		return fmt.Errorf("get state merkle root error %s", err)
+	}
+	stateTree.AppendHash(writeSetHash)
+	if stateTree.Root() != stateRoot {
+		return fmt.Errorf("state merkle root mismatch with write set hashes")
+	}
+	return nil
+}
+
+//verifyByReplay execute block on replay ledger, and check the recomputed write set hash is the same as the saved one
+func (this *LedgerStoreImp) verifyByReplay(replay *LedgerStoreImp, blockHash common.Uint256, height uint32) error {
+	block, err := this.blockStore.GetBlock(blockHash)
+	if err != nil {
+		return err
+	}
+	result, err := replay.ExecuteBlock(block)
+	if err != nil {
+		return err
+	}
+	if height >= this.stateStore.stateHashCheckHeight {
+		writeSetHash, err := this.stateStore.getWriteSetHash(height)
+		if err != nil {
+			return err
+		}
+		if result.Hash != writeSetHash {
+			return fmt.Errorf("recomputed write set hash %s mismatch with saved %s", result.Hash.ToHexString(),
+				writeSetHash.ToHexString())
+		}
+	}
+	return replay.SubmitBlock(block, result)
+}
+
+//getWriteSetHash return the write set hash of block saved with state merkle root
+func (self *StateStore) getWriteSetHash(height uint32) (common.Uint256, error) {
+	value, err := self.store.Get(self.genStateMerkleRootKey(height))
+	if err != nil {
+		return common.UINT256_EMPTY, err
+	}
+	hash, eof := common.NewZeroCopySource(value).NextHash()
+	if eof {
+		return common.UINT256_EMPTY, io.ErrUnexpectedEOF
+	}
+	return hash, nil
+}
+
+//getEventTxHashesByBlock return the hashes of transactions saved in event store for block
+func (this *EventStore) getEventTxHashesByBlock(height uint32) ([]common.Uint256, error) {
+	data, err := this.store.Get(genEventNotifyByBlockKey(height))
+	if err != nil {
+		return nil, err
+	}
+	reader := bytes.NewBuffer(data)
+	size, err := serialization.ReadUint32(reader)
+	if err != nil {
+		return nil, err
+	}
+	txHashes := make([]common.Uint256, 0, size)
+	for i := uint32(0); i < size; i++ {
+		var txHash common.Uint256
+		err = txHash.Deserialize(reader)
+		if err != nil {
+			return nil, err
+		}
+		txHashes = append(txHashes, txHash)
+	}
+	return txHashes, nil
+}
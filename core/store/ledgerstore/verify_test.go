@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/genesis"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyLedger(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	ledgerStore, err := NewLedgerStore("test/verify", 0)
+	assert.Nil(t, err)
+	assert.Nil(t, ledgerStore.InitLedgerStoreWithGenesisBlock(block, bookkeepers))
+	assert.Nil(t, ledgerStore.Close())
+
+	ledgerStore, err = NewLedgerStore("test/verify", 0)
+	assert.Nil(t, err)
+	defer ledgerStore.Close()
+	verified := make([]uint32, 0)
+	err = ledgerStore.VerifyLedger(nil, func(height uint32) {
+		verified = append(verified, height)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{0}, verified)
+
+	ledgerStore.stateStore.NewBatch()
+	ledgerStore.stateStore.store.BatchDelete(ledgerStore.stateStore.genStateMerkleRootKey(0))
+	assert.Nil(t, ledgerStore.stateStore.CommitTo())
+	err = ledgerStore.VerifyLedger(nil, nil)
+	verifyErr, ok := err.(*LedgerVerifyError)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(0), verifyErr.Height)
+	}
+}
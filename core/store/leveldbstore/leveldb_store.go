@@ -41,7 +41,36 @@ const BITSPERKEY = 10
 
 //NewLevelDBStore return LevelDBStore instance
 func NewLevelDBStore(file string) (*LevelDBStore, error) {
+	return NewLevelDBStoreWithOptions(file, nil)
+}
+
+//NewLevelDBStoreWithOptions return LevelDBStore instance tuned by options, nil options means default
+func NewLevelDBStoreWithOptions(file string, options *common.PersistStoreOptions) (*LevelDBStore, error) {
+	o := newLevelDBOptions(options)
+	db, err := leveldb.OpenFile(file, o)
+
+	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+		db, err = leveldb.RecoverFile(file, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDBStore{
+		db:    db,
+		batch: nil,
+	}, nil
+}
+
+func newLevelDBOptions(options *common.PersistStoreOptions) *opt.Options {
+	if options == nil {
+		options = &common.PersistStoreOptions{}
+	}
 	openFileCache := opt.DefaultOpenFilesCacheCapacity
+	if options.OpenFiles > 0 {
+		openFileCache = options.OpenFiles
+	}
 	maxOpenFiles, err := fdlimit.Current()
 	if err == nil && maxOpenFiles < openFileCache*5 {
 		openFileCache = maxOpenFiles / 5
@@ -52,26 +81,21 @@ func NewLevelDBStore(file string) (*LevelDBStore, error) {
 	}
 
 	// default Options
-	o := opt.Options{
+	o := &opt.Options{
 		NoSync:                 false,
 		OpenFilesCacheCapacity: openFileCache,
 		Filter:                 filter.NewBloomFilter(BITSPERKEY),
 	}
-
-	db, err := leveldb.OpenFile(file, &o)
-
-	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
-		db, err = leveldb.RecoverFile(file, nil)
+	if options.CacheSize > 0 {
+		o.BlockCacheCapacity = options.CacheSize * opt.MiB
 	}
-
-	if err != nil {
-		return nil, err
+	if options.WriteBuffer > 0 {
+		o.WriteBuffer = options.WriteBuffer * opt.MiB
 	}
-
-	return &LevelDBStore{
-		db:    db,
-		batch: nil,
-	}, nil
+	if options.DisableCompression {
+		o.Compression = opt.NoCompression
+	}
+	return o
 }
 
 func NewMemLevelDBStore() (*LevelDBStore, error) {
@@ -144,6 +168,11 @@ func (self *LevelDBStore) BatchCommit() error {
 	return nil
 }
 
+//Compact the whole leveldb, reclaim the space of deleted keys
+func (self *LevelDBStore) Compact() error {
+	return self.db.CompactRange(util.Range{})
+}
+
 //Close leveldb
 func (self *LevelDBStore) Close() error {
 	err := self.db.Close()
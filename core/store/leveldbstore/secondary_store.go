@@ -0,0 +1,287 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package leveldbstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/TesraSupernet/Tesra/core/store/common"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const CHECKPOINT_RETRY_TIMES = 10 //Retry times of making checkpoint while primary is changing the manifest
+
+var ErrReadOnlyStore = errors.New("store is read only")
+
+//SecondaryLevelDBStore is a read only store over the checkpoint of a leveldb used by another process.
+//The leveldb of primary is locked, so its table files are hard linked and the manifest and journals are
+//copied to checkpoint dir, which is opened read only. Refresh make a new checkpoint to catch up with primary
+type SecondaryLevelDBStore struct {
+	primaryDir    string
+	checkpointDir string
+	options       *common.PersistStoreOptions
+	lock          sync.RWMutex
+	current       *checkpointDB
+	previous      *checkpointDB //Kept open until next refresh for the iterators created before refresh
+	seq           uint64
+}
+
+type checkpointDB struct {
+	dir string
+	db  *leveldb.DB
+}
+
+func (self *checkpointDB) close() {
+	self.db.Close()
+	os.RemoveAll(self.dir)
+}
+
+//NewSecondaryLevelDBStore return SecondaryLevelDBStore instance of primary dir, checkpoints are made in checkpoint dir
+func NewSecondaryLevelDBStore(primaryDir, checkpointDir string, options *common.PersistStoreOptions) (*SecondaryLevelDBStore, error) {
+	if _, err := os.Stat(filepath.Join(primaryDir, "CURRENT")); err != nil {
+		return nil, fmt.Errorf("leveldb %s not found:%s", primaryDir, err)
+	}
+	err := os.RemoveAll(checkpointDir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(checkpointDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	store := &SecondaryLevelDBStore{
+		primaryDir:    primaryDir,
+		checkpointDir: checkpointDir,
+		options:       options,
+	}
+	err = store.Refresh()
+	if err != nil {
+		os.RemoveAll(checkpointDir)
+		return nil, err
+	}
+	return store, nil
+}
+
+//Refresh open a new checkpoint of primary, the store sees the data committed by primary before refresh
+func (self *SecondaryLevelDBStore) Refresh() error {
+	self.seq++
+	dir := filepath.Join(self.checkpointDir, fmt.Sprintf("%d", self.seq))
+	err := makeCheckpoint(self.primaryDir, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("make checkpoint of %s error:%s", self.primaryDir, err)
+	}
+	o := newLevelDBOptions(self.options)
+	o.ReadOnly = true
+	db, err := leveldb.OpenFile(dir, o)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("open checkpoint of %s error:%s", self.primaryDir, err)
+	}
+
+	self.lock.Lock()
+	previous := self.previous
+	self.previous = self.current
+	self.current = &checkpointDB{dir: dir, db: db}
+	self.lock.Unlock()
+	if previous != nil {
+		previous.close()
+	}
+	return nil
+}
+
+//makeCheckpoint link tables and copy manifest and journals of primary to dir. The manifest is recorded after
+//tables are created and before they are deleted, so the checkpoint is consistent if the manifest is not changed
+//while tables are linked
+func makeCheckpoint(primaryDir, dir string) error {
+	for i := 0; i < CHECKPOINT_RETRY_TIMES; i++ {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		current, manifest, err := readManifest(primaryDir)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, "CURRENT"), current, 0644)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, strings.TrimSpace(string(current))), manifest, 0644)
+		if err != nil {
+			return err
+		}
+		err = linkTables(primaryDir, dir)
+		if err != nil {
+			return err
+		}
+		currentAfter, manifestAfter, err := readManifest(primaryDir)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(current, currentAfter) && len(manifest) == len(manifestAfter) {
+			return nil
+		}
+	}
+	return fmt.Errorf("manifest keeps changing in %d times", CHECKPOINT_RETRY_TIMES)
+}
+
+func readManifest(primaryDir string) ([]byte, []byte, error) {
+	current, err := ioutil.ReadFile(filepath.Join(primaryDir, "CURRENT"))
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, err := ioutil.ReadFile(filepath.Join(primaryDir, strings.TrimSpace(string(current))))
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, manifest, nil
+}
+
+//linkTables hard link table files and copy journal files of primary to dir
+func linkTables(primaryDir, dir string) error {
+	files, err := ioutil.ReadDir(primaryDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		src := filepath.Join(primaryDir, name)
+		dst := filepath.Join(dir, name)
+		switch filepath.Ext(name) {
+		case ".ldb", ".sst":
+			err = os.Link(src, dst)
+			if err != nil && !os.IsNotExist(err) {
+				//checkpoint dir on another device
+				err = copyFile(src, dst)
+			}
+		case ".log":
+			err = copyFile(src, dst)
+		default:
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (self *SecondaryLevelDBStore) db() *leveldb.DB {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.current.db
+}
+
+//Put is refused by read only store
+func (self *SecondaryLevelDBStore) Put(key []byte, value []byte) error {
+	return ErrReadOnlyStore
+}
+
+//Get the value of a key from checkpoint
+func (self *SecondaryLevelDBStore) Get(key []byte) ([]byte, error) {
+	dat, err := self.db().Get(key, nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, common.ErrNotFound
+		}
+		return nil, err
+	}
+	return dat, nil
+}
+
+//Has return whether the key is exist in checkpoint
+func (self *SecondaryLevelDBStore) Has(key []byte) (bool, error) {
+	return self.db().Has(key, nil)
+}
+
+//Delete is refused by read only store
+func (self *SecondaryLevelDBStore) Delete(key []byte) error {
+	return ErrReadOnlyStore
+}
+
+func (self *SecondaryLevelDBStore) NewBatch() {
+}
+
+func (self *SecondaryLevelDBStore) BatchPut(key []byte, value []byte) {
+}
+
+func (self *SecondaryLevelDBStore) BatchDelete(key []byte) {
+}
+
+//BatchCommit is refused by read only store
+func (self *SecondaryLevelDBStore) BatchCommit() error {
+	return ErrReadOnlyStore
+}
+
+//Compact is refused by read only store
+func (self *SecondaryLevelDBStore) Compact() error {
+	return ErrReadOnlyStore
+}
+
+//Close the checkpoints and remove checkpoint dir
+func (self *SecondaryLevelDBStore) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.previous != nil {
+		self.previous.close()
+		self.previous = nil
+	}
+	if self.current != nil {
+		self.current.close()
+		self.current = nil
+	}
+	return os.RemoveAll(self.checkpointDir)
+}
+
+//NewIterator return a iterator of checkpoint with the key prefix
+func (self *SecondaryLevelDBStore) NewIterator(prefix []byte) common.StoreIterator {
+	return self.db().NewIterator(util.BytesPrefix(prefix), nil)
+}
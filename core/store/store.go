@@ -19,6 +19,8 @@
 package store
 
 import (
+	"io"
+
 	"github.com/TesraSupernet/tesracrypto/keypair"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/core/payload"
@@ -36,11 +38,30 @@ type ExecuteResult struct {
 	Notify     []*event.ExecuteNotify
 }
 
+//StateSnapshotInfo describe the ledger state a state snapshot is taken at
+type StateSnapshotInfo struct {
+	Height          uint32
+	BlockHash       common.Uint256
+	BlockRoot       common.Uint256 //merkle root of blocks, equal to the BlockRoot of header at Height
+	StateMerkleRoot common.Uint256 //state merkle root at Height, empty before state hash check height
+	StateHash       common.Uint256 //sha256 of all key value pairs in state db
+	StateCount      uint64         //count of key value pairs in state db
+}
+
+//StateSnapshotCheckpoint is the trusted position of a state snapshot, got from a trusted node which exported it.
+//The state db is not covered by block headers, so both block hash and state hash must be trusted
+type StateSnapshotCheckpoint struct {
+	Height    uint32
+	BlockHash common.Uint256
+	StateHash common.Uint256
+}
+
 // LedgerStore provides func with store package.
 type LedgerStore interface {
 	InitLedgerStoreWithGenesisBlock(genesisblock *types.Block, defaultBookkeeper []keypair.PublicKey) error
 	Close() error
 	AddHeaders(headers []*types.Header) error
+	AddCheckpointHeaders(headers []*types.Header, checkpoint common.Uint256) error
 	AddBlock(block *types.Block, stateMerkleRoot common.Uint256) error
 	ExecuteBlock(b *types.Block) (ExecuteResult, error)   // called by consensus
 	SubmitBlock(b *types.Block, exec ExecuteResult) error // called by consensus
@@ -63,7 +84,14 @@ type LedgerStore interface {
 	GetContractState(contractHash common.Address) (*payload.DeployCode, error)
 	GetBookkeeperState() (*states.BookkeeperState, error)
 	GetStorageItem(key *states.StorageKey) (*states.StorageItem, error)
+	GetStorageUsage(contractAddress common.Address) (*states.StorageUsage, error)
 	PreExecuteContract(tx *types.Transaction) (*cstates.PreExecResult, error)
+	GetBlockLimit() (*types.BlockLimit, error)
 	GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error)
 	GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error)
+	FindEvents(contract common.Address, topic string, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error)
+	GetPrunedHeight() uint32
+	ExportStateSnapshot(w io.Writer) (*StateSnapshotInfo, error)
+	ImportStateSnapshot(r io.ReadSeeker, checkpoint *StateSnapshotCheckpoint) (*StateSnapshotInfo, error)
+	RollbackTo(height uint32) error
 }
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package types
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/TesraSupernet/Tesra/common"
+)
+
+//global params name of block limits
+const (
+	BLOCK_GAS_LIMIT_PARAM  = "blockGasLimit"
+	BLOCK_SIZE_LIMIT_PARAM = "blockSizeLimit"
+)
+
+//BlockLimit limits the total gas limit and raw bytes of transactions in a block, 0 means no limit
+type BlockLimit struct {
+	GasLimit  uint64
+	SizeLimit uint64
+}
+
+func txSize(tx *Transaction) uint64 {
+	return uint64(len(tx.Raw))
+}
+
+func addGas(gas, txGas uint64) uint64 {
+	total, overflow := common.SafeAdd(gas, txGas)
+	if overflow {
+		return math.MaxUint64
+	}
+	return total
+}
+
+func (self *BlockLimit) fit(gas, size uint64) bool {
+	if self.GasLimit != 0 && gas > self.GasLimit {
+		return false
+	}
+	if self.SizeLimit != 0 && size > self.SizeLimit {
+		return false
+	}
+	return true
+}
+
+//SelectTransactions picks txs in order which fit in the block after the reserved txs
+func (self *BlockLimit) SelectTransactions(reserved, txs []*Transaction) []*Transaction {
+	var gas, size uint64
+	for _, tx := range reserved {
+		gas = addGas(gas, tx.GasLimit)
+		size += txSize(tx)
+	}
+	selected := make([]*Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if !self.fit(addGas(gas, tx.GasLimit), size+txSize(tx)) {
+			continue
+		}
+		gas = addGas(gas, tx.GasLimit)
+		size += txSize(tx)
+		selected = append(selected, tx)
+	}
+	return selected
+}
+
+//CheckTransactions checks the txs of a block not exceed the limit
+func (self *BlockLimit) CheckTransactions(txs []*Transaction) error {
+	var gas, size uint64
+	for _, tx := range txs {
+		gas = addGas(gas, tx.GasLimit)
+		size += txSize(tx)
+	}
+	if self.GasLimit != 0 && gas > self.GasLimit {
+		return fmt.Errorf("block gas limit exceeded, %d > %d", gas, self.GasLimit)
+	}
+	if self.SizeLimit != 0 && size > self.SizeLimit {
+		return fmt.Errorf("block size limit exceeded, %d > %d", size, self.SizeLimit)
+	}
+	return nil
+}
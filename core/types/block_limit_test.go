@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package types
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockLimit(t *testing.T) {
+	txs := []*Transaction{
+		{GasLimit: 20000, Raw: make([]byte, 100)},
+		{GasLimit: 50000, Raw: make([]byte, 100)},
+		{GasLimit: 20000, Raw: make([]byte, 300)},
+		{GasLimit: 10000, Raw: make([]byte, 100)},
+	}
+
+	limit := &BlockLimit{}
+	assert.Equal(t, txs, limit.SelectTransactions(nil, txs))
+	assert.Nil(t, limit.CheckTransactions(txs))
+
+	limit = &BlockLimit{GasLimit: 60000}
+	selected := limit.SelectTransactions(nil, txs)
+	assert.Equal(t, []*Transaction{txs[0], txs[2], txs[3]}, selected)
+	assert.Nil(t, limit.CheckTransactions(selected))
+	assert.NotNil(t, limit.CheckTransactions(txs))
+
+	limit = &BlockLimit{SizeLimit: 400}
+	selected = limit.SelectTransactions(txs[:1], txs[1:])
+	assert.Equal(t, []*Transaction{txs[1], txs[3]}, selected)
+	assert.NotNil(t, limit.CheckTransactions(txs))
+
+	limit = &BlockLimit{GasLimit: 60000}
+	overflow := []*Transaction{{GasLimit: math.MaxUint64}, {GasLimit: 1}}
+	assert.NotNil(t, limit.CheckTransactions(overflow))
+	assert.Equal(t, 0, len(limit.SelectTransactions(nil, overflow[:1])))
+}
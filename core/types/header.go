@@ -20,6 +20,7 @@ package types
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"io"
 
 	"github.com/TesraSupernet/tesracrypto/keypair"
@@ -169,6 +170,20 @@ func HeaderFromRawBytes(raw []byte) (*Header, error) {
 	return header, nil
 
 }
+//HeaderFromUnsignedBytes deserialize a header without bookkeepers and signatures, which are not covered by its hash
+func HeaderFromUnsignedBytes(raw []byte) (*Header, error) {
+	source := common.NewZeroCopySource(raw)
+	header := &Header{}
+	err := header.deserializationUnsigned(source)
+	if err != nil {
+		return nil, err
+	}
+	if source.Len() != 0 {
+		return nil, fmt.Errorf("unexpected %d bytes after unsigned header", source.Len())
+	}
+	return header, nil
+}
+
 func (bd *Header) Deserialization(source *common.ZeroCopySource) error {
 	err := bd.deserializationUnsigned(source)
 	if err != nil {
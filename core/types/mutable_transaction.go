@@ -110,6 +110,8 @@ func (tx *MutableTransaction) serializeUnsigned(sink *common.ZeroCopySink) error
 		pl.Serialization(sink)
 	case *payload.InvokeCode:
 		pl.Serialization(sink)
+	case *payload.Bookkeeper:
+		pl.Serialization(sink)
 	default:
 		return errors.New("wrong transaction payload type")
 	}
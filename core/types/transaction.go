@@ -170,6 +170,13 @@ func (tx *Transaction) deserializationUnsigned(source *common.ZeroCopySource) er
 			return err
 		}
 		tx.Payload = pl
+	case Bookkeeper:
+		pl := new(payload.Bookkeeper)
+		err := pl.Deserialization(source)
+		if err != nil {
+			return err
+		}
+		tx.Payload = pl
 	default:
 		return fmt.Errorf("unsupported tx type %v", tx.Type())
 	}
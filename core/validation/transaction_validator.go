@@ -125,6 +125,11 @@ func checkTransactionPayload(tx *types.Transaction) error {
 		return nil
 	case *payload.InvokeCode:
 		return nil
+	case *payload.Bookkeeper:
+		if pld.Action != payload.BookkeeperAction_ADD && pld.Action != payload.BookkeeperAction_SUB {
+			return fmt.Errorf("[txValidator], unknown bookkeeper action %d", pld.Action)
+		}
+		return nil
 	default:
 		return errors.New(fmt.Sprint("[txValidator], unimplemented transaction payload type.", pld))
 	}
@@ -19,12 +19,147 @@
 package vote
 
 import (
-	"github.com/TesraSupernet/tesracrypto/keypair"
-	"github.com/TesraSupernet/Tesra/core/genesis"
+	"fmt"
+
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/payload"
+	"github.com/TesraSupernet/Tesra/core/states"
 	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/tesracrypto/keypair"
 )
 
-func GetValidators(txs []*types.Transaction) ([]keypair.PublicKey, error) {
-	// TODO implement vote
-	return genesis.GenesisBookkeepers, nil
+//GetValidators return the bookkeepers of the block following the block at height,
+//state is the bookkeeper state before the block at height, txs are the transactions of the block
+func GetValidators(state *states.BookkeeperState, height uint32, txs []*types.Transaction) ([]keypair.PublicKey, error) {
+	next, err := GetBookkeeperState(state, height, txs)
+	if err != nil {
+		return nil, err
+	}
+	return next.CurrBookkeeper, nil
+}
+
+//GetBookkeeperState return the bookkeeper state after the block at height with transactions txs.
+//Bookkeeper transactions only change the pending bookkeepers, which take effect at the end of an epoch
+func GetBookkeeperState(state *states.BookkeeperState, height uint32, txs []*types.Transaction) (*states.BookkeeperState, error) {
+	if state == nil {
+		return nil, fmt.Errorf("bookkeeper state is nil")
+	}
+	period := config.DefConfig.GetBookkeeperEpochPeriod(height)
+	if period == 0 {
+		return state, nil
+	}
+	next := make([]keypair.PublicKey, len(state.NextBookkeeper))
+	copy(next, state.NextBookkeeper)
+	for _, tx := range txs {
+		if tx.TxType != types.Bookkeeper {
+			continue
+		}
+		bk, ok := tx.Payload.(*payload.Bookkeeper)
+		if !ok {
+			continue
+		}
+		if err := VerifyBookkeeperTx(state, height, tx); err != nil {
+			log.Warnf("[GetBookkeeperState] ignore bookkeeper tx %x: %s", tx.Hash(), err)
+			continue
+		}
+		next = applyBookkeeperAction(next, bk, height)
+	}
+	result := &states.BookkeeperState{
+		StateBase:      state.StateBase,
+		CurrBookkeeper: state.CurrBookkeeper,
+		NextBookkeeper: keypair.SortPublicKeys(next),
+	}
+	if height%period == 0 {
+		result.CurrBookkeeper = result.NextBookkeeper
+	}
+	return result, nil
+}
+
+//VerifyBookkeeperTx checks the bookkeeper transaction in the block at height is issued by a current bookkeeper
+//and signed by a 2/3+1 quorum of the current bookkeepers
+func VerifyBookkeeperTx(state *states.BookkeeperState, height uint32, tx *types.Transaction) error {
+	bk, ok := tx.Payload.(*payload.Bookkeeper)
+	if !ok {
+		return fmt.Errorf("not a bookkeeper transaction")
+	}
+	if config.DefConfig.GetBookkeeperEpochPeriod(height) == 0 {
+		return fmt.Errorf("bookkeeper change is not enabled")
+	}
+	if bk.Action != payload.BookkeeperAction_ADD && bk.Action != payload.BookkeeperAction_SUB {
+		return fmt.Errorf("unknown bookkeeper action %d", bk.Action)
+	}
+	if isSolo(height) && bk.Action == payload.BookkeeperAction_SUB {
+		return fmt.Errorf("solo bookkeeper can only be replaced by adding another one")
+	}
+	if indexOf(state.CurrBookkeeper, bk.Issuer) < 0 {
+		return fmt.Errorf("issuer is not a current bookkeeper")
+	}
+	return verifyBookkeeperSigners(state.CurrBookkeeper, tx)
+}
+
+//verifyBookkeeperSigners checks the transaction carries a multi-signature of at least 2/3+1 distinct current bookkeepers.
+//The signatures themselves are verified by the stateless validator
+func verifyBookkeeperSigners(bookkeepers []keypair.PublicKey, tx *types.Transaction) error {
+	quorum := len(bookkeepers)*2/3 + 1
+	for _, raw := range tx.Sigs {
+		sig, err := raw.GetSig()
+		if err != nil {
+			return err
+		}
+		if int(sig.M) < quorum || !isDistinctBookkeepers(bookkeepers, sig.PubKeys) {
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("bookkeeper transaction requires a %d of %d multi-signature of current bookkeepers", quorum, len(bookkeepers))
+}
+
+func isDistinctBookkeepers(bookkeepers, keys []keypair.PublicKey) bool {
+	for i, key := range keys {
+		if indexOf(bookkeepers, key) < 0 || indexOf(keys[:i], key) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//applyBookkeeperAction return the pending bookkeepers changed by bk. Solo has only one bookkeeper producing blocks,
+//so adding a bookkeeper replaces it
+func applyBookkeeperAction(bookkeepers []keypair.PublicKey, bk *payload.Bookkeeper, height uint32) []keypair.PublicKey {
+	index := indexOf(bookkeepers, bk.PubKey)
+	switch bk.Action {
+	case payload.BookkeeperAction_ADD:
+		if isSolo(height) {
+			return []keypair.PublicKey{bk.PubKey}
+		}
+		if index < 0 {
+			bookkeepers = append(bookkeepers, bk.PubKey)
+		}
+	case payload.BookkeeperAction_SUB:
+		if index >= 0 && len(bookkeepers) > minBookkeeperNum(height) {
+			bookkeepers = append(bookkeepers[:index], bookkeepers[index+1:]...)
+		}
+	}
+	return bookkeepers
+}
+
+func minBookkeeperNum(height uint32) int {
+	if isSolo(height) {
+		return config.SOLO_MIN_NODE_NUM
+	}
+	return config.DBFT_MIN_NODE_NUM
+}
+
+func isSolo(height uint32) bool {
+	return config.DefConfig.Genesis.GetConsensusType(height) == config.CONSENSUS_TYPE_SOLO
+}
+
+func indexOf(bookkeepers []keypair.PublicKey, key keypair.PublicKey) int {
+	for i, k := range bookkeepers {
+		if keypair.ComparePublicKey(k, key) {
+			return i
+		}
+	}
+	return -1
 }
@@ -0,0 +1,184 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vote
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/payload"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	"github.com/TesraSupernet/Tesra/core/states"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBookkeeperTx(t *testing.T, issuer *account.Account, pubKey keypair.PublicKey, action payload.BookkeeperAction) *types.Transaction {
+	return newMultiSigBookkeeperTx(t, issuer, pubKey, action, 1, []*account.Account{issuer})
+}
+
+func newMultiSigBookkeeperTx(t *testing.T, issuer *account.Account, pubKey keypair.PublicKey, action payload.BookkeeperAction,
+	m uint16, signers []*account.Account) *types.Transaction {
+	mutable := &types.MutableTransaction{
+		TxType: types.Bookkeeper,
+		Payer:  issuer.Address,
+		Payload: &payload.Bookkeeper{
+			PubKey: pubKey,
+			Action: action,
+			Issuer: issuer.PublicKey,
+		},
+	}
+	hash := mutable.Hash()
+	sig := types.Sig{M: m}
+	for _, signer := range signers {
+		data, err := signature.Sign(signer, hash[:])
+		assert.Nil(t, err)
+		sig.PubKeys = append(sig.PubKeys, signer.PublicKey)
+		sig.SigData = append(sig.SigData, data)
+	}
+	mutable.Sigs = []types.Sig{sig}
+	tx, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+	return tx
+}
+
+func TestGetBookkeeperState(t *testing.T) {
+	genesis := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesis }()
+	config.DefConfig.Genesis = config.NewGenesisConfig()
+	config.DefConfig.Genesis.DBFT.EpochPeriod = 10
+
+	accounts := make([]*account.Account, 0)
+	bookkeepers := make([]keypair.PublicKey, 0)
+	for i := 0; i < 5; i++ {
+		acc := account.NewAccount("")
+		accounts = append(accounts, acc)
+		if i < 4 {
+			bookkeepers = append(bookkeepers, acc.PublicKey)
+		}
+	}
+	bookkeepers = keypair.SortPublicKeys(bookkeepers)
+	state := &states.BookkeeperState{CurrBookkeeper: bookkeepers, NextBookkeeper: bookkeepers}
+
+	addTx := newMultiSigBookkeeperTx(t, accounts[0], accounts[4].PublicKey, payload.BookkeeperAction_ADD, 3, accounts[:3])
+	state, err := GetBookkeeperState(state, 5, []*types.Transaction{addTx})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(state.CurrBookkeeper))
+	assert.Equal(t, 5, len(state.NextBookkeeper))
+
+	// not issued by a current bookkeeper
+	subTx := newMultiSigBookkeeperTx(t, accounts[4], accounts[0].PublicKey, payload.BookkeeperAction_SUB, 3, accounts[2:])
+	state, err = GetBookkeeperState(state, 6, []*types.Transaction{subTx})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(state.NextBookkeeper))
+
+	validators, err := GetValidators(state, 10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(validators))
+
+	config.DefConfig.Genesis.DBFT.EpochPeriod = 0
+	validators, err = GetValidators(state, 10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(validators))
+}
+
+func TestSoloBookkeeperReplace(t *testing.T) {
+	genesis := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesis }()
+	config.DefConfig.Genesis = config.NewGenesisConfig()
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SOLO
+	config.DefConfig.Genesis.SOLO.EpochPeriod = 10
+
+	solo, next := account.NewAccount(""), account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{solo.PublicKey}
+	state := &states.BookkeeperState{CurrBookkeeper: bookkeepers, NextBookkeeper: bookkeepers}
+
+	subTx := newBookkeeperTx(t, solo, solo.PublicKey, payload.BookkeeperAction_SUB)
+	assert.NotNil(t, VerifyBookkeeperTx(state, 5, subTx))
+
+	addTx := newBookkeeperTx(t, solo, next.PublicKey, payload.BookkeeperAction_ADD)
+	state, err := GetBookkeeperState(state, 5, []*types.Transaction{addTx})
+	assert.Nil(t, err)
+	assert.Equal(t, []keypair.PublicKey{next.PublicKey}, state.NextBookkeeper)
+
+	validators, err := GetValidators(state, 10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []keypair.PublicKey{next.PublicKey}, validators)
+}
+
+func TestBookkeeperTxQuorum(t *testing.T) {
+	genesis := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesis }()
+	config.DefConfig.Genesis = config.NewGenesisConfig()
+	config.DefConfig.Genesis.DBFT.EpochPeriod = 10
+
+	accounts := make([]*account.Account, 0)
+	bookkeepers := make([]keypair.PublicKey, 0)
+	for i := 0; i < 5; i++ {
+		acc := account.NewAccount("")
+		accounts = append(accounts, acc)
+		if i < 4 {
+			bookkeepers = append(bookkeepers, acc.PublicKey)
+		}
+	}
+	state := &states.BookkeeperState{CurrBookkeeper: bookkeepers, NextBookkeeper: bookkeepers}
+	newKey := account.NewAccount("").PublicKey
+
+	// a single current bookkeeper can not change the bookkeepers
+	addTx := newBookkeeperTx(t, accounts[0], newKey, payload.BookkeeperAction_ADD)
+	assert.NotNil(t, VerifyBookkeeperTx(state, 5, addTx))
+	next, err := GetBookkeeperState(state, 5, []*types.Transaction{addTx})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(next.NextBookkeeper))
+
+	// below the 2/3+1 quorum
+	addTx = newMultiSigBookkeeperTx(t, accounts[0], newKey, payload.BookkeeperAction_ADD, 2, accounts[:2])
+	assert.NotNil(t, VerifyBookkeeperTx(state, 5, addTx))
+
+	// signer not a current bookkeeper
+	addTx = newMultiSigBookkeeperTx(t, accounts[0], newKey, payload.BookkeeperAction_ADD, 3, []*account.Account{accounts[0], accounts[1], accounts[4]})
+	assert.NotNil(t, VerifyBookkeeperTx(state, 5, addTx))
+
+	// duplicated signer
+	addTx = newMultiSigBookkeeperTx(t, accounts[0], newKey, payload.BookkeeperAction_ADD, 3, []*account.Account{accounts[0], accounts[1], accounts[1]})
+	assert.NotNil(t, VerifyBookkeeperTx(state, 5, addTx))
+
+	addTx = newMultiSigBookkeeperTx(t, accounts[0], newKey, payload.BookkeeperAction_ADD, 3, accounts[1:4])
+	assert.Nil(t, VerifyBookkeeperTx(state, 5, addTx))
+}
+
+func TestBookkeeperTxDisabled(t *testing.T) {
+	genesis := config.DefConfig.Genesis
+	defer func() { config.DefConfig.Genesis = genesis }()
+	config.DefConfig.Genesis = config.NewGenesisConfig()
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_VBFT
+	config.DefConfig.Genesis.DBFT.EpochPeriod = 10
+
+	// decoded regardless of consensus, and refused by vbft
+	issuer, next := account.NewAccount(""), account.NewAccount("")
+	state := &states.BookkeeperState{CurrBookkeeper: []keypair.PublicKey{issuer.PublicKey}}
+	addTx := newBookkeeperTx(t, issuer, next.PublicKey, payload.BookkeeperAction_ADD)
+	assert.NotNil(t, VerifyBookkeeperTx(state, 50, addTx))
+
+	config.DefConfig.Genesis.ConsensusForks = []*config.ConsensusForkConfig{{Height: 100, ConsensusType: config.CONSENSUS_TYPE_DBFT}}
+	assert.NotNil(t, VerifyBookkeeperTx(state, 50, addTx))
+	assert.Nil(t, VerifyBookkeeperTx(state, 150, addTx))
+}
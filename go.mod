@@ -1,26 +1,62 @@
 module github.com/TesraSupernet/Tesra
 
-go 1.12
+go 1.21
 
 require (
 	github.com/JohnCGriffin/overflow v0.0.0-20170615021017-4d914c927216
 	github.com/TesraSupernet/tesracrypto v0.0.1
 	github.com/TesraSupernet/tesraevent v0.0.1
+	github.com/dgraph-io/badger/v2 v2.2007.4
 	github.com/ethereum/go-ethereum v1.9.9
 	github.com/go-interpreter/wagon v0.6.0
+	github.com/golang/snappy v0.0.3
 	github.com/gorilla/websocket v1.4.1
-	github.com/gosuri/uilive v0.0.3 // indirect
 	github.com/gosuri/uiprogress v0.0.1
 	github.com/hashicorp/golang-lru v0.5.3
 	github.com/howeyc/gopass v0.0.0-20190910152052-7cb4b85ec19c
 	github.com/itchyny/base58-go v0.1.0
 	github.com/pborman/uuid v1.2.0
-	github.com/stretchr/testify v1.4.0
+	github.com/quic-go/quic-go v0.42.0
+	github.com/stretchr/testify v1.6.1
 	github.com/syndtr/goleveldb v1.0.1-0.20190923125748-758128399b1d
 	github.com/urfave/cli v1.22.1
 	github.com/valyala/bytebufferpool v1.0.0
-	golang.org/x/crypto v0.0.0-20191219195013-becbf705a915
-	golang.org/x/net v0.0.0-20191209160850-c0dbc17a3553
+	golang.org/x/crypto v0.4.0
+	golang.org/x/net v0.10.0
+)
+
+require (
+	github.com/Workiva/go-datastructures v1.0.50 // indirect
+	github.com/btcsuite/btcd v0.20.1-beta // indirect
+	github.com/cespare/xxhash v1.1.0 // indirect
+	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dgraph-io/ristretto v0.0.3-0.20200630154024-f66de99634de // indirect
+	github.com/dgryski/go-farm v0.0.0-20190423205320-6a90982ecee2 // indirect
+	github.com/dustin/go-humanize v1.0.0 // indirect
+	github.com/emirpasic/gods v1.12.0 // indirect
+	github.com/go-task/slim-sprig v0.0.0-20230315185526-52ccab3ef572 // indirect
+	github.com/gogo/protobuf v1.3.1 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/google/pprof v0.0.0-20210407192527-94a9f03dee38 // indirect
+	github.com/google/uuid v1.0.0 // indirect
+	github.com/gosuri/uilive v0.0.3 // indirect
+	github.com/klauspost/compress v1.12.3 // indirect
+	github.com/mattn/go-isatty v0.0.5-0.20180830101745-3fb116b82035 // indirect
+	github.com/onsi/ginkgo/v2 v2.9.5 // indirect
+	github.com/orcaman/concurrent-map v0.0.0-20190826125027-8c72a8bb44f6 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/russross/blackfriday/v2 v2.0.1 // indirect
+	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
+	go.uber.org/mock v0.4.0 // indirect
+	golang.org/x/exp v0.0.0-20221205204356-47842c84f3db // indirect
+	golang.org/x/mod v0.11.0 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/term v0.8.0 // indirect
+	golang.org/x/tools v0.9.1 // indirect
+	google.golang.org/protobuf v1.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace (
@@ -28,8 +64,4 @@ replace (
 	github.com/TesraSupernet/tesracrypto v0.0.1 => github.com/user00000001/tesracrypto v0.0.0-20191225075117-65443069d854
 	github.com/TesraSupernet/tesraevent v0.0.1 => github.com/user00000001/tesraevent v0.0.0-20191230030134-e9672c983098
 	github.com/go-interpreter/wagon => github.com/user00000001/wagon v0.6.1-0.20191230091825-31cd91d9e5db
-	golang.org/x/crypto => github.com/golang/crypto v0.0.0-20191029031824-8986dd9e96cf
-	golang.org/x/net => github.com/golang/net v0.0.0-20191028085509-fe3aa8a45271
-	golang.org/x/sys => github.com/golang/sys v0.0.0-20190412213103-97732733099d
-	golang.org/x/text => github.com/golang/text v0.3.0
 )
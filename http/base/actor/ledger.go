@@ -22,6 +22,7 @@ import (
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/payload"
+	"github.com/TesraSupernet/Tesra/core/states"
 	"github.com/TesraSupernet/Tesra/core/types"
 	"github.com/TesraSupernet/Tesra/smartcontract/event"
 	cstate "github.com/TesraSupernet/Tesra/smartcontract/states"
@@ -67,6 +68,11 @@ func GetTransaction(hash common.Uint256) (*types.Transaction, error) {
 	return ledger.DefLedger.GetTransaction(hash)
 }
 
+//GetStorageUsage of contract from ledger
+func GetStorageUsage(address common.Address) (*states.StorageUsage, error) {
+	return ledger.DefLedger.GetStorageUsage(address)
+}
+
 //GetStorageItem from ledger
 func GetStorageItem(address common.Address, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetStorageItem(address, key)
@@ -99,6 +105,11 @@ func GetEventNotifyByHeight(height uint32) ([]*event.ExecuteNotify, error) {
 	return ledger.DefLedger.GetEventNotifyByBlock(height)
 }
 
+//FindEvents of contract with topic in height range from ledger
+func FindEvents(contract common.Address, topic string, fromHeight, toHeight uint32) ([]*event.ExecuteNotify, error) {
+	return ledger.DefLedger.FindEvents(contract, topic, fromHeight, toHeight)
+}
+
 //GetMerkleProof from ledger
 func GetMerkleProof(proofHeight uint32, rootHeight uint32) ([]common.Uint256, error) {
 	return ledger.DefLedger.GetMerkleProof(proofHeight, rootHeight)
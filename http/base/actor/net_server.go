@@ -26,6 +26,7 @@ import (
 	"github.com/TesraSupernet/Tesra/common/log"
 	ac "github.com/TesraSupernet/Tesra/p2pserver/actor/server"
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 var netServerPid *actor.PID
@@ -204,3 +205,89 @@ func GetNodeType() (uint64, error) {
 	}
 	return r.NodeType, nil
 }
+
+//GetPeerScores from netSever actor
+func GetPeerScores() ([]reputation.PeerScore, error) {
+	if netServerPid == nil {
+		return []reputation.PeerScore{}, nil
+	}
+	future := netServerPid.RequestFuture(&ac.GetPeerScoresReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*ac.GetPeerScoresRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.Peers, nil
+}
+
+//GetPeerInfos from netSever actor
+func GetPeerInfos() ([]common.PeerInfo, error) {
+	if netServerPid == nil {
+		return []common.PeerInfo{}, nil
+	}
+	future := netServerPid.RequestFuture(&ac.GetPeerInfosReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*ac.GetPeerInfosRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.Peers, nil
+}
+
+//GetBanList from netSever actor
+func GetBanList() ([]reputation.BanEntry, error) {
+	if netServerPid == nil {
+		return []reputation.BanEntry{}, nil
+	}
+	future := netServerPid.RequestFuture(&ac.GetBanListReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*ac.GetBanListRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.Bans, nil
+}
+
+//BanPeer by netSever actor
+func BanPeer(host string, duration time.Duration) error {
+	if netServerPid == nil {
+		return errors.New("net server not started")
+	}
+	future := netServerPid.RequestFuture(&ac.BanPeerReq{Host: host, Duration: duration}, REQ_TIMEOUT*time.Second)
+	_, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return err
+	}
+	return nil
+}
+
+//UnbanPeer by netSever actor, return false if the host is not banned
+func UnbanPeer(host string) (bool, error) {
+	if netServerPid == nil {
+		return false, errors.New("net server not started")
+	}
+	future := netServerPid.RequestFuture(&ac.UnbanPeerReq{Host: host}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return false, err
+	}
+	r, ok := result.(*ac.BanPeerRsp)
+	if !ok {
+		return false, errors.New("fail")
+	}
+	return r.Ok, nil
+}
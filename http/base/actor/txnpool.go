@@ -35,6 +35,9 @@ var txnPid *actor.PID
 var txnPoolPid *actor.PID
 var DisableSyncVerifyTx = false
 
+//ErrTxPoolUnavailable is returned when txpool is not started, like a read only node
+var ErrTxPoolUnavailable = errors.New("transaction pool is not available")
+
 func SetTxPid(actr *actor.PID) {
 	txnPid = actr
 }
@@ -44,8 +47,11 @@ func SetTxnPoolPid(actr *actor.PID) {
 
 //append transaction to pool to txpool actor
 func AppendTxToPool(txn *types.Transaction) (tstErrors.ErrCode, string) {
+	if txnPid == nil {
+		return tstErrors.ErrUnknown, ErrTxPoolUnavailable.Error()
+	}
 	if DisableSyncVerifyTx {
-		txReq := &tcomn.TxReq{txn, tcomn.HttpSender, nil}
+		txReq := &tcomn.TxReq{Tx: txn, Sender: tcomn.HttpSender}
 		txnPid.Tell(txReq)
 		return tstErrors.ErrNoError, ""
 	}
@@ -55,7 +61,7 @@ func AppendTxToPool(txn *types.Transaction) (tstErrors.ErrCode, string) {
 		return tstErrors.ErrUnknown, err.Error()
 	}
 	ch := make(chan *tcomn.TxResult, 1)
-	txReq := &tcomn.TxReq{txn, tcomn.HttpSender, ch}
+	txReq := &tcomn.TxReq{Tx: txn, Sender: tcomn.HttpSender, TxResultCh: ch}
 	txnPid.Tell(txReq)
 	if msg, ok := <-ch; ok {
 		return msg.Err, msg.Desc
@@ -65,6 +71,9 @@ func AppendTxToPool(txn *types.Transaction) (tstErrors.ErrCode, string) {
 
 //GetTxsFromPool from txpool actor
 func GetTxsFromPool(byCount bool) map[common.Uint256]*types.Transaction {
+	if txnPoolPid == nil {
+		return nil
+	}
 	future := txnPoolPid.RequestFuture(&tcomn.GetTxnPoolReq{ByCount: byCount}, REQ_TIMEOUT*time.Second)
 	result, err := future.Result()
 	if err != nil {
@@ -85,8 +94,11 @@ func GetTxsFromPool(byCount bool) map[common.Uint256]*types.Transaction {
 
 //GetTxFromPool from txpool actor
 func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
+	if txnPid == nil {
+		return tcomn.TXEntry{}, ErrTxPoolUnavailable
+	}
 
-	future := txnPid.RequestFuture(&tcomn.GetTxnReq{hash}, REQ_TIMEOUT*time.Second)
+	future := txnPid.RequestFuture(&tcomn.GetTxnReq{Hash: hash}, REQ_TIMEOUT*time.Second)
 	result, err := future.Result()
 	if err != nil {
 		log.Errorf(ERR_ACTOR_COMM, err)
@@ -100,7 +112,7 @@ func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
 		return tcomn.TXEntry{}, errors.New("fail")
 	}
 
-	future = txnPid.RequestFuture(&tcomn.GetTxnStatusReq{hash}, REQ_TIMEOUT*time.Second)
+	future = txnPid.RequestFuture(&tcomn.GetTxnStatusReq{Hash: hash}, REQ_TIMEOUT*time.Second)
 	result, err = future.Result()
 	if err != nil {
 		log.Errorf(ERR_ACTOR_COMM, err)
@@ -110,12 +122,15 @@ func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
 	if !ok {
 		return tcomn.TXEntry{}, errors.New("fail")
 	}
-	txnEntry := tcomn.TXEntry{rsp.Txn, txStatus.TxStatus}
+	txnEntry := tcomn.TXEntry{Tx: rsp.Txn, Attrs: txStatus.TxStatus}
 	return txnEntry, nil
 }
 
 //GetTxnCount from txpool actor
 func GetTxnCount() ([]uint32, error) {
+	if txnPid == nil {
+		return []uint32{}, ErrTxPoolUnavailable
+	}
 	future := txnPid.RequestFuture(&tcomn.GetTxnCountReq{}, REQ_TIMEOUT*time.Second)
 	result, err := future.Result()
 	if err != nil {
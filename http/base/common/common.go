@@ -44,6 +44,7 @@ import (
 )
 
 const MAX_SEARCH_HEIGHT uint32 = 100
+const MAX_FIND_EVENTS_RANGE uint32 = 100000
 const MAX_REQUEST_BODY_SIZE = 1 << 20
 
 type BalanceOfRsp struct {
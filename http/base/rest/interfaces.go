@@ -93,6 +93,9 @@ func GetBlockHash(cmd map[string]interface{}) map[string]interface{} {
 
 func getBlock(hash common.Uint256, getTxBytes bool) (interface{}, int64) {
 	block, err := bactor.GetBlockFromStore(hash)
+	if err == scom.ErrPruned {
+		return nil, berr.DATA_PRUNED
+	}
 	if err != nil {
 		return nil, berr.UNKNOWN_BLOCK
 	}
@@ -140,10 +143,10 @@ func GetBlockHeightByTxHash(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
+	if err != nil && err != scom.ErrPruned {
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
-	if tx == nil {
+	if tx == nil && err == nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	resp["Result"] = height
@@ -168,6 +171,9 @@ func GetBlockTxsByHeight(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	block, err := bactor.GetBlockFromStore(hash)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.DATA_PRUNED)
+	}
 	if err != nil {
 		return ResponsePack(berr.UNKNOWN_BLOCK)
 	}
@@ -196,6 +202,9 @@ func GetBlockByHeight(cmd map[string]interface{}) map[string]interface{} {
 	}
 	index := uint32(height)
 	block, err := bactor.GetBlockByHeight(index)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.DATA_PRUNED)
+	}
 	if err != nil || block == nil {
 		return ResponsePack(berr.UNKNOWN_BLOCK)
 	}
@@ -220,6 +229,9 @@ func GetTransactionByHash(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.DATA_PRUNED)
+	}
 	if tx == nil {
 		return ResponsePack(berr.UNKNOWN_TRANSACTION)
 	}
@@ -305,6 +317,9 @@ func GetSmartCodeEventTxsByHeight(cmd map[string]interface{}) map[string]interfa
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.DATA_PRUNED)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
@@ -337,6 +352,9 @@ func GetSmartCodeEventByTxHash(cmd map[string]interface{}) map[string]interface{
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.DATA_PRUNED)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	if eventInfo == nil {
@@ -402,6 +420,25 @@ func GetStorage(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+//get storage usage of contract
+func GetStorageUsage(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	usage, err := bactor.GetStorageUsage(address)
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp["Result"] = usage
+	return resp
+}
+
 //get balance of address
 func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -433,10 +470,10 @@ func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
+	if err != nil && err != scom.ErrPruned {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	if tx == nil {
+	if tx == nil && err == nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	header, err := bactor.GetHeaderByHeight(height)
@@ -457,8 +494,8 @@ func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
 	for _, v := range proof {
 		hashes = append(hashes, v.ToHexString())
 	}
-	resp["Result"] = bcomn.MerkleProof{"MerkleProof", header.TransactionsRoot.ToHexString(), height,
-		curHeader.BlockRoot.ToHexString(), curHeight, hashes}
+	resp["Result"] = bcomn.MerkleProof{Type: "MerkleProof", TransactionsRoot: header.TransactionsRoot.ToHexString(),
+		BlockHeight: height, CurBlockRoot: curHeader.BlockRoot.ToHexString(), CurBlockHeight: curHeight, TargetHashes: hashes}
 	return resp
 }
 
@@ -572,8 +609,8 @@ func GetMemPoolTxState(cmd map[string]interface{}) map[string]interface{} {
 	}
 	attrs := []bcomn.TXNAttrInfo{}
 	for _, t := range txEntry.Attrs {
-		attrs = append(attrs, bcomn.TXNAttrInfo{t.Height, int(t.Type), int(t.ErrCode)})
+		attrs = append(attrs, bcomn.TXNAttrInfo{Height: t.Height, Type: int(t.Type), ErrCode: int(t.ErrCode)})
 	}
-	resp["Result"] = bcomn.TXNEntryInfo{attrs}
+	resp["Result"] = bcomn.TXNEntryInfo{State: attrs}
 	return resp
 }
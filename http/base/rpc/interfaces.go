@@ -20,6 +20,8 @@ package rpc
 
 import (
 	"encoding/hex"
+	"fmt"
+	"math"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
@@ -68,6 +70,9 @@ func GetBlock(params []interface{}) map[string]interface{} {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	block, err := bactor.GetBlockFromStore(hash)
+	if err == scom.ErrPruned {
+		return responsePack(berr.DATA_PRUNED, "block pruned")
+	}
 	if err != nil {
 		return responsePack(berr.UNKNOWN_BLOCK, "unknown block")
 	}
@@ -160,9 +165,9 @@ func GetMemPoolTxState(params []interface{}) map[string]interface{} {
 		}
 		attrs := []bcomn.TXNAttrInfo{}
 		for _, t := range txEntry.Attrs {
-			attrs = append(attrs, bcomn.TXNAttrInfo{t.Height, int(t.Type), int(t.ErrCode)})
+			attrs = append(attrs, bcomn.TXNAttrInfo{Height: t.Height, Type: int(t.Type), ErrCode: int(t.ErrCode)})
 		}
-		info := bcomn.TXNEntryInfo{attrs}
+		info := bcomn.TXNEntryInfo{State: attrs}
 		return responseSuccess(info)
 	default:
 		return responsePack(berr.INVALID_PARAMS, "")
@@ -186,6 +191,9 @@ func GetRawTransaction(params []interface{}) map[string]interface{} {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		h, t, err := bactor.GetTxnWithHeightByTxHash(hash)
+		if err == scom.ErrPruned {
+			return responsePack(berr.DATA_PRUNED, "transaction pruned")
+		}
 		if err != nil {
 			return responsePack(berr.UNKNOWN_TRANSACTION, "unknown transaction")
 		}
@@ -254,6 +262,27 @@ func GetStorage(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(value))
 }
 
+//get storage usage of contract
+//   {"jsonrpc": "2.0", "method": "getstorageusage", "params": ["code hash"], "id": 0}
+func GetStorageUsage(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	usage, err := bactor.GetStorageUsage(address)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(usage)
+}
+
 //send raw transaction
 // A JSON example for sendrawtransaction method as following:
 //   {"jsonrpc": "2.0", "method": "sendrawtransaction", "params": ["raw transactioin in hex"], "id": 0}
@@ -366,6 +395,9 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if err == scom.ErrNotFound {
 				return responseSuccess(nil)
 			}
+			if err == scom.ErrPruned {
+				return responsePack(berr.DATA_PRUNED, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
@@ -386,6 +418,9 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if scom.ErrNotFound == err {
 				return responseSuccess(nil)
 			}
+			if scom.ErrPruned == err {
+				return responsePack(berr.DATA_PRUNED, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		_, notify := bcomn.GetExecuteNotify(eventInfo)
@@ -396,6 +431,62 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 	return responsePack(berr.INVALID_PARAMS, "")
 }
 
+//find event notifies of contract with topic in blocks of height range, empty topic matches any topic
+//   {"jsonrpc": "2.0", "method": "findevents", "params": ["contract address", "topic", fromHeight, toHeight], "id": 0}
+func FindEvents(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return responsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 4 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	contract, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	topic, ok := params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	fromHeight, ok := heightParam(params[2])
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	toHeight, ok := heightParam(params[3])
+	if !ok || toHeight < fromHeight {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if toHeight-fromHeight >= bcomn.MAX_FIND_EVENTS_RANGE {
+		return responsePack(berr.INVALID_PARAMS, fmt.Sprintf("height range exceeds %d", bcomn.MAX_FIND_EVENTS_RANGE))
+	}
+	eventInfos, err := bactor.FindEvents(contract, topic, fromHeight, toHeight)
+	if err != nil {
+		if err == scom.ErrPruned {
+			return responsePack(berr.DATA_PRUNED, "event pruned")
+		}
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
+	for _, eventInfo := range eventInfos {
+		_, notify := bcomn.GetExecuteNotify(eventInfo)
+		eInfos = append(eInfos, &notify)
+	}
+	return responseSuccess(eInfos)
+}
+
+//heightParam return the block height of json number, which must be an integer in the range of uint32
+func heightParam(param interface{}) (uint32, bool) {
+	height, ok := param.(float64)
+	if !ok || height < 0 || height > math.MaxUint32 || height != math.Trunc(height) {
+		return 0, false
+	}
+	return uint32(height), true
+}
+
 //get block height by transaction hash
 func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -410,8 +501,9 @@ func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 		if err != nil {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
+		// height of pruned transaction is still kept
 		height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-		if err != nil {
+		if err != nil && err != scom.ErrPruned {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		return responseSuccess(height)
@@ -487,7 +579,7 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
+	if err != nil && err != scom.ErrPruned {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	header, err := bactor.GetHeaderByHeight(height)
@@ -508,8 +600,8 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 	for _, v := range proof {
 		hashes = append(hashes, v.ToHexString())
 	}
-	return responseSuccess(bcomn.MerkleProof{"MerkleProof", header.TransactionsRoot.ToHexString(), height,
-		curHeader.BlockRoot.ToHexString(), curHeight, hashes})
+	return responseSuccess(bcomn.MerkleProof{Type: "MerkleProof", TransactionsRoot: header.TransactionsRoot.ToHexString(),
+		BlockHeight: height, CurBlockRoot: curHeader.BlockRoot.ToHexString(), CurBlockHeight: curHeight, TargetHashes: hashes})
 }
 
 //get block transactions by height
@@ -525,6 +617,9 @@ func GetBlockTxsByHeight(params []interface{}) map[string]interface{} {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		block, err := bactor.GetBlockFromStore(hash)
+		if err == scom.ErrPruned {
+			return responsePack(berr.DATA_PRUNED, "block pruned")
+		}
 		if err != nil {
 			return responsePack(berr.UNKNOWN_BLOCK, "")
 		}
@@ -21,11 +21,13 @@ package rpc
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/TesraSupernet/Tesra/common/log"
 	bactor "github.com/TesraSupernet/Tesra/http/base/actor"
 	"github.com/TesraSupernet/Tesra/http/base/common"
 	berr "github.com/TesraSupernet/Tesra/http/base/error"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 const (
@@ -122,3 +124,69 @@ func SetDebugInfo(params []interface{}) map[string]interface{} {
 	}
 	return responsePack(berr.SUCCESS, true)
 }
+
+//ListPeers return the neighbors with their misbehavior scores
+func ListPeers(params []interface{}) map[string]interface{} {
+	peers, err := bactor.GetPeerScores()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responseSuccess(peers)
+}
+
+//GetPeerInfos return the detail of neighbors, including traffic, latency and sync role
+func GetPeerInfos(params []interface{}) map[string]interface{} {
+	peers, err := bactor.GetPeerInfos()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responseSuccess(peers)
+}
+
+//ListBannedPeers return the banned hosts
+func ListBannedPeers(params []interface{}) map[string]interface{} {
+	bans, err := bactor.GetBanList()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responseSuccess(bans)
+}
+
+//BanPeer ban the host of ip or ip:port for the seconds, default one day
+func BanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	host, ok := params[0].(string)
+	if !ok || host == "" {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	duration := time.Duration(reputation.DEFAULT_BAN) * time.Second
+	if len(params) > 1 {
+		seconds, ok := params[1].(float64)
+		if !ok || seconds <= 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	if err := bactor.BanPeer(host, duration); err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//UnbanPeer remove the ban of host
+func UnbanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	host, ok := params[0].(string)
+	if !ok || host == "" {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	ok, err := bactor.UnbanPeer(reputation.Host(host))
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responseSuccess(ok)
+}
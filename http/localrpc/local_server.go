@@ -43,6 +43,11 @@ func StartLocalServer() error {
 	rpc.HandleFunc("startconsensus", rpc.StartConsensus)
 	rpc.HandleFunc("stopconsensus", rpc.StopConsensus)
 	rpc.HandleFunc("setdebuginfo", rpc.SetDebugInfo)
+	rpc.HandleFunc("listpeers", rpc.ListPeers)
+	rpc.HandleFunc("getpeerinfos", rpc.GetPeerInfos)
+	rpc.HandleFunc("listbannedpeers", rpc.ListBannedPeers)
+	rpc.HandleFunc("banpeer", rpc.BanPeer)
+	rpc.HandleFunc("unbanpeer", rpc.UnbanPeer)
 
 	// TODO: only listen to local host
 	err := http.ListenAndServe(LOCAL_HOST+":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpLocalPort)), nil)
@@ -116,5 +116,6 @@ func StartServer(n p2p.P2P) {
 	node = n
 	port := int(config.DefConfig.P2PNode.HttpInfoPort)
 	http.HandleFunc("/info", viewHandler)
+	http.HandleFunc("/neighbors", neighborsHandler)
 	http.ListenAndServe(":"+strconv.Itoa(port), nil)
 }
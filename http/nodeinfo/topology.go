@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nodeinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/ledger"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//Topology is the node with its neighbors served at /neighbors, by which the network is crawled from node to node
+type Topology struct {
+	Id        string             `json:"id"`
+	Version   string             `json:"version"`
+	Height    uint32             `json:"height"`
+	Neighbors []TopologyNeighbor `json:"neighbors"`
+}
+
+//TopologyNeighbor is an established neighbor of node
+type TopologyNeighbor struct {
+	Id           string `json:"id"`
+	Addr         string `json:"addr"`           //address for other nodes to connect
+	Direction    string `json:"direction"`      //direction of the link seen by node
+	Height       uint64 `json:"height"`         //block height last reported
+	HttpInfoAddr string `json:"http_info_addr"` //empty if http info server of neighbor not started
+}
+
+//newTopology return the node with its established neighbors
+func newTopology() *Topology {
+	topology := &Topology{
+		Id:        fmt.Sprintf("0x%x", node.GetID()),
+		Version:   config.Version,
+		Height:    ledger.DefLedger.GetCurrentBlockHeight(),
+		Neighbors: make([]TopologyNeighbor, 0),
+	}
+	for _, p := range node.GetNeighbors() {
+		if p.GetState() != common.ESTABLISH {
+			continue
+		}
+		ngb := TopologyNeighbor{
+			Id:        fmt.Sprintf("0x%x", p.GetID()),
+			Addr:      p.GetNodeAddr(),
+			Direction: common.DIRECTION_OUTBOUND,
+			Height:    p.GetHeight(),
+		}
+		if p.Link.IsInbound() {
+			ngb.Direction = common.DIRECTION_INBOUND
+		}
+		if ip, err := common.ParseIPAddr(p.GetAddr()); err == nil && p.GetHttpInfoState() {
+			ngb.HttpInfoAddr = ip + ":" + strconv.Itoa(int(p.GetHttpInfoPort()))
+		}
+		topology.Neighbors = append(topology.Neighbors, ngb)
+	}
+	return topology
+}
+
+func neighborsHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(newTopology())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
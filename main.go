@@ -23,7 +23,9 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -36,6 +38,7 @@ import (
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/consensus"
+	"github.com/TesraSupernet/Tesra/consensus/signer"
 	"github.com/TesraSupernet/Tesra/core/genesis"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/events"
@@ -74,6 +77,11 @@ func setupAPP() *cli.App {
 		cmd.ContractCommand,
 		cmd.ImportCommand,
 		cmd.ExportCommand,
+		cmd.MigrateDBCommand,
+		cmd.RollbackCommand,
+		cmd.LedgerCommand,
+		cmd.NetworkCommand,
+		cmd.SoftTokenCommand,
 		cmd.TxCommond,
 		cmd.SigTxCommand,
 		cmd.MultiSigAddrCommand,
@@ -88,6 +96,15 @@ func setupAPP() *cli.App {
 		utils.DisableLogFileFlag,
 		utils.DisableEventLogFlag,
 		utils.DataDirFlag,
+		utils.PruneRetentionFlag,
+		utils.DBBackendFlag,
+		utils.DBCacheSizeFlag,
+		utils.DBWriteBufferFlag,
+		utils.DBOpenFilesFlag,
+		utils.DBCompressionFlag,
+		utils.ExecWorkersFlag,
+		utils.ReadOnlyFlag,
+		utils.ReadOnlyRefreshFlag,
 		//account setting
 		utils.WalletFileFlag,
 		utils.AccountAddressFlag,
@@ -95,6 +112,8 @@ func setupAPP() *cli.App {
 		//consensus setting
 		utils.EnableConsensusFlag,
 		utils.MaxTxInBlockFlag,
+		utils.ConsensusSignerFlag,
+		utils.ConsensusSignerAddressFlag,
 		//txpool setting
 		utils.GasPriceFlag,
 		utils.GasLimitFlag,
@@ -106,10 +125,21 @@ func setupAPP() *cli.App {
 		utils.ReservedPeersFileFlag,
 		utils.NetworkIdFlag,
 		utils.NodePortFlag,
+		utils.ConsensusPortFlag,
+		utils.TransportFlag,
+		utils.ConsensusTransportFlag,
 		utils.HttpInfoPortFlag,
 		utils.MaxConnInBoundFlag,
 		utils.MaxConnOutBoundFlag,
 		utils.MaxConnInBoundForSingleIPFlag,
+		utils.P2PAuthFlag,
+		utils.NodeKeyFileFlag,
+		utils.DisableDiscoveryFlag,
+		utils.DisableCompressionFlag,
+		utils.NATFlag,
+		utils.PublicAddressFlag,
+		utils.CheckpointsFlag,
+		utils.CheckpointSyncFlag,
 		//test mode setting
 		utils.EnableTestModeFlag,
 		utils.TestModeGenBlockTimeFlag,
@@ -152,9 +182,13 @@ func startTesranode(ctx *cli.Context) {
 		log.Errorf("initConfig error: %s", err)
 		return
 	}
-	acc, err := initAccount(ctx)
+	if ctx.GlobalBool(utils.GetFlagName(utils.ReadOnlyFlag)) {
+		startReadOnlyNode(ctx, cfg)
+		return
+	}
+	consensusSigner, err := initSigner(ctx)
 	if err != nil {
-		log.Errorf("initWallet error: %s", err)
+		log.Errorf("initSigner error: %s", err)
 		return
 	}
 	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
@@ -168,12 +202,12 @@ func startTesranode(ctx *cli.Context) {
 		log.Errorf("initTxPool error: %s", err)
 		return
 	}
-	p2pSvr, p2pPid, err := initP2PNode(ctx, txpool)
+	p2pSvr, p2pPid, err := initP2PNode(ctx, txpool, consensusSigner)
 	if err != nil {
 		log.Errorf("initP2PNode error: %s", err)
 		return
 	}
-	_, err = initConsensus(ctx, p2pPid, txpool, acc)
+	_, err = initConsensus(ctx, p2pPid, txpool, consensusSigner)
 	if err != nil {
 		log.Errorf("initConsensus error: %s", err)
 		return
@@ -196,6 +230,34 @@ func startTesranode(ctx *cli.Context) {
 	waitToExit(ldg)
 }
 
+//startReadOnlyNode serve rpc, restful and websocket from the ledger used by another node
+func startReadOnlyNode(ctx *cli.Context, cfg *config.TesranodeConfig) {
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	ldg, checkpointDir, err := initReadOnlyLedger(ctx, stateHashHeight)
+	if err != nil {
+		log.Errorf("%s", err)
+		return
+	}
+	defer os.RemoveAll(checkpointDir)
+	err = initRpc(ctx)
+	if err != nil {
+		log.Errorf("initRpc error: %s", err)
+		ldg.Close()
+		return
+	}
+	err = initLocalRpc(ctx)
+	if err != nil {
+		log.Errorf("initLocalRpc error: %s", err)
+		ldg.Close()
+		return
+	}
+	initRestful(ctx)
+	initWs(ctx)
+
+	go logCurrBlockHeight()
+	waitToExit(ldg)
+}
+
 func initLog(ctx *cli.Context) {
 	//init log module
 	logLevel := ctx.GlobalInt(utils.GetFlagName(utils.LogLevelFlag))
@@ -219,10 +281,85 @@ func initConfig(ctx *cli.Context) (*config.TesranodeConfig, error) {
 	return cfg, nil
 }
 
-func initAccount(ctx *cli.Context) (*account.Account, error) {
+func initSigner(ctx *cli.Context) (*signer.ConsensusSigner, error) {
 	if !config.DefConfig.Consensus.EnableConsensus {
 		return nil, nil
 	}
+	var bkSigner signer.Signer
+	var err error
+	switch config.DefConfig.Consensus.SignerType {
+	case signer.SIGNER_TYPE_LOCAL, "":
+		var acc *account.Account
+		acc, err = initAccount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		bkSigner, err = signer.NewLocalSigner(acc)
+	case signer.SIGNER_TYPE_SIGSVR:
+		bkSigner, err = initRemoteSigner(ctx)
+	case signer.SIGNER_TYPE_SOFTTOKEN:
+		bkSigner, err = initSoftTokenSigner(ctx)
+	default:
+		return nil, fmt.Errorf("unknown consensus signer: %s", config.DefConfig.Consensus.SignerType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO && len(config.DefConfig.Genesis.SOLO.Bookkeepers) == 0 {
+		curPk := hex.EncodeToString(keypair.SerializePublicKey(bkSigner.PublicKey()))
+		config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
+	}
+
+	stateFile := filepath.Join(utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName), config.DEFAULT_SIGN_STATE_FILE)
+	consensusSigner, err := signer.NewConsensusSigner(bkSigner, stateFile)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Consensus signer init success, type: %s", config.DefConfig.Consensus.SignerType)
+	return consensusSigner, nil
+}
+
+func initRemoteSigner(ctx *cli.Context) (signer.Signer, error) {
+	address := config.DefConfig.Consensus.SignerAddress
+	if address == "" {
+		return nil, fmt.Errorf("Please config sig server address using --%s flag", utils.GetFlagName(utils.ConsensusSignerAddressFlag))
+	}
+	accAddr := ctx.String(utils.GetFlagName(utils.AccountAddressFlag))
+	if accAddr == "" {
+		return nil, fmt.Errorf("Please config account address using --%s flag", utils.GetFlagName(utils.AccountAddressFlag))
+	}
+	passwd, err := cmdcom.GetPasswd(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remoteSigner, err := signer.NewRemoteSigner(address, accAddr, passwd)
+	if err != nil {
+		return nil, fmt.Errorf("connect sig server %s error: %s", address, err)
+	}
+	log.Infof("Using remote signer: %s, account: %s", address, accAddr)
+	return remoteSigner, nil
+}
+
+func initSoftTokenSigner(ctx *cli.Context) (signer.Signer, error) {
+	address := config.DefConfig.Consensus.SignerAddress
+	if address == "" {
+		return nil, fmt.Errorf("Please config token address using --%s flag", utils.GetFlagName(utils.ConsensusSignerAddressFlag))
+	}
+	pin, err := cmdcom.GetPasswd(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cmdcom.ClearPasswd(pin)
+	tokenSigner, err := signer.NewSoftTokenSigner(address, ctx.String(utils.GetFlagName(utils.AccountAddressFlag)), pin)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Using softtoken signer: %s", address)
+	return tokenSigner, nil
+}
+
+func initAccount(ctx *cli.Context) (*account.Account, error) {
 	walletFile := ctx.GlobalString(utils.GetFlagName(utils.WalletFileFlag))
 	if walletFile == "" {
 		return nil, fmt.Errorf("Please config wallet file using --wallet flag")
@@ -237,11 +374,6 @@ func initAccount(ctx *cli.Context) (*account.Account, error) {
 	}
 	log.Infof("Using account: %s", acc.Address.ToBase58())
 
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
-		curPk := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
-		config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
-	}
-
 	log.Infof("Account init success")
 	return acc, nil
 }
@@ -273,6 +405,22 @@ func initLedger(ctx *cli.Context, stateHashHeight uint32) (*ledger.Ledger, error
 	return ledger.DefLedger, nil
 }
 
+func initReadOnlyLedger(ctx *cli.Context, stateHashHeight uint32) (*ledger.Ledger, string, error) {
+	events.Init() //Init event hub
+
+	var err error
+	dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+	checkpointDir := filepath.Join(config.DefConfig.Common.DataDir, config.READONLY_CHECKPOINT_DIR, strconv.Itoa(os.Getpid()))
+	refreshInterval := time.Duration(ctx.GlobalUint(utils.GetFlagName(utils.ReadOnlyRefreshFlag))) * time.Second
+	ledger.DefLedger, err = ledger.NewReadOnlyLedger(dbDir, checkpointDir, stateHashHeight, refreshInterval)
+	if err != nil {
+		os.RemoveAll(checkpointDir)
+		return nil, "", fmt.Errorf("NewReadOnlyLedger error: %s", err)
+	}
+	log.Infof("Read only ledger init success")
+	return ledger.DefLedger, checkpointDir, nil
+}
+
 func initTxPool(ctx *cli.Context) (*proc.TXPoolServer, error) {
 	disablePreExec := ctx.GlobalBool(utils.GetFlagName(utils.TxpoolPreExecDisableFlag))
 	bactor.DisableSyncVerifyTx = ctx.GlobalBool(utils.GetFlagName(utils.DisableSyncVerifyTxFlag))
@@ -295,8 +443,8 @@ func initTxPool(ctx *cli.Context) (*proc.TXPoolServer, error) {
 	return txPoolServer, nil
 }
 
-func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer) (*p2pserver.P2PServer, *actor.PID, error) {
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer, consensusSigner *signer.ConsensusSigner) (*p2pserver.P2PServer, *actor.PID, error) {
+	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO && len(config.DefConfig.Genesis.ConsensusForks) == 0 {
 		return nil, nil, nil
 	}
 	p2p := p2pserver.NewServer()
@@ -307,6 +455,9 @@ func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer) (*p2pserver.P2P
 		return nil, nil, fmt.Errorf("p2pActor init error %s", err)
 	}
 	p2p.SetPID(p2pPID)
+	if consensusSigner != nil {
+		p2p.SetValidatorSigner(consensusSigner)
+	}
 	err = p2p.Start()
 	if err != nil {
 		return nil, nil, fmt.Errorf("p2p service start error %s", err)
@@ -319,14 +470,14 @@ func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer) (*p2pserver.P2P
 	return p2p, p2pPID, nil
 }
 
-func initConsensus(ctx *cli.Context, p2pPid *actor.PID, txpoolSvr *proc.TXPoolServer, acc *account.Account) (consensus.ConsensusService, error) {
+func initConsensus(ctx *cli.Context, p2pPid *actor.PID, txpoolSvr *proc.TXPoolServer, consensusSigner *signer.ConsensusSigner) (consensus.ConsensusService, error) {
 	if !config.DefConfig.Consensus.EnableConsensus {
 		return nil, nil
 	}
 	pool := txpoolSvr.GetPID(tc.TxPoolActor)
 
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
-	consensusService, err := consensus.NewConsensusService(consensusType, acc, pool, nil, p2pPid)
+	consensusService, err := consensus.NewConsensusService(consensusType, consensusSigner, pool, nil, p2pPid)
 	if err != nil {
 		return nil, fmt.Errorf("NewConsensusService %s error: %s", consensusType, err)
 	}
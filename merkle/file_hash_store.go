@@ -29,6 +29,7 @@ import (
 // HashStore is an interface for persist hash
 type HashStore interface {
 	Append(hash []common.Uint256) error
+	Truncate(tree_size uint32) error
 	Flush() error
 	Close()
 	GetHash(pos uint32) (common.Uint256, error)
@@ -55,7 +56,7 @@ func NewFileHashStore(name string, tree_size uint32) (HashStore, error) {
 		return nil, err
 	}
 
-	num_hashes := getStoredHashNum(tree_size)
+	num_hashes := GetStoredHashNum(tree_size)
 	size := int64(num_hashes) * int64(common.UINT256_SIZE)
 
 	_, err = store.file.Seek(size, io.SeekStart)
@@ -65,7 +66,27 @@ func NewFileHashStore(name string, tree_size uint32) (HashStore, error) {
 	return store, nil
 }
 
-func getStoredHashNum(tree_size uint32) int64 {
+// NewReadOnlyFileHashStore returns a HashStore reading the hashes of a file appended by another process.
+// Hashes of tree_size leaves should have been stored, and Append and Truncate are refused
+func NewReadOnlyFileHashStore(name string, tree_size uint32) (HashStore, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	store := &fileHashStore{
+		file_name: name,
+		file:      f,
+	}
+	err = store.checkConsistence(tree_size)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &readOnlyHashStore{store}, nil
+}
+
+// GetStoredHashNum returns the count of hashes persisted for a tree of tree_size
+func GetStoredHashNum(tree_size uint32) int64 {
 	subtreesize := getSubTreeSize(tree_size)
 	sum := int64(0)
 	for _, v := range subtreesize {
@@ -76,7 +97,7 @@ func getStoredHashNum(tree_size uint32) int64 {
 }
 
 func (self *fileHashStore) checkConsistence(tree_size uint32) error {
-	num_hashes := getStoredHashNum(tree_size)
+	num_hashes := GetStoredHashNum(tree_size)
 
 	stat, err := self.file.Stat()
 	if err != nil {
@@ -100,6 +121,17 @@ func (self *fileHashStore) Append(hash []common.Uint256) error {
 	return err
 }
 
+// Truncate drops the hashes appended after the tree had tree_size leaves. The file is not shrunk, so it stays
+// consistent with a persisted larger tree size until the following appends overwrite the dropped hashes
+func (self *fileHashStore) Truncate(tree_size uint32) error {
+	if self == nil {
+		return nil
+	}
+	size := GetStoredHashNum(tree_size) * int64(common.UINT256_SIZE)
+	_, err := self.file.Seek(size, io.SeekStart)
+	return err
+}
+
 func (self *fileHashStore) Flush() error {
 	if self == nil {
 		return nil
@@ -127,6 +159,20 @@ func (self *fileHashStore) GetHash(pos uint32) (common.Uint256, error) {
 	return hash, nil
 }
 
+type readOnlyHashStore struct {
+	*fileHashStore
+}
+
+var errReadOnlyHashStore = errors.New("hash store is read only")
+
+func (self *readOnlyHashStore) Append(hash []common.Uint256) error {
+	return errReadOnlyHashStore
+}
+
+func (self *readOnlyHashStore) Truncate(tree_size uint32) error {
+	return errReadOnlyHashStore
+}
+
 type memHashStore struct {
 	hashes []common.Uint256
 }
@@ -141,6 +187,11 @@ func (self *memHashStore) Append(hash []common.Uint256) error {
 	return nil
 }
 
+func (self *memHashStore) Truncate(tree_size uint32) error {
+	self.hashes = self.hashes[:GetStoredHashNum(tree_size)]
+	return nil
+}
+
 func (self *memHashStore) GetHash(pos uint32) (common.Uint256, error) {
 	return self.hashes[pos], nil
 }
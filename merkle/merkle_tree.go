@@ -184,6 +184,33 @@ func (self *CompactMerkleTree) AppendHash(leaf common.Uint256) []common.Uint256
 	return auditPath
 }
 
+// Truncate rolls the merkle tree back to tree_size leaves, the dropped hashes are removed from hash store
+func (self *CompactMerkleTree) Truncate(tree_size uint32) error {
+	if tree_size > self.treeSize {
+		return errors.New("truncate size is larger than tree size")
+	}
+	if tree_size == self.treeSize {
+		return nil
+	}
+	if self.hashStore == nil {
+		return errors.New("truncate merkle tree without hash store")
+	}
+	hashes := make([]common.Uint256, 0, countBit(tree_size))
+	for _, pos := range getSubTreePos(tree_size) {
+		hash, err := self.hashStore.GetHash(pos - 1)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	err := self.hashStore.Truncate(tree_size)
+	if err != nil {
+		return err
+	}
+	self._update(tree_size, hashes)
+	return nil
+}
+
 func (self *CompactMerkleTree) DumpStatus() {
 	log.Errorf("tree root: %x \n", self.rootHash)
 	log.Errorf("tree size: %d \n", self.treeSize)
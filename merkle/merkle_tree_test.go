@@ -78,6 +78,30 @@ func TestCompactMerkleTree_GetRootWithNewLeaves(t *testing.T) {
 	}
 }
 
+func TestCompactMerkleTree_Truncate(t *testing.T) {
+	N := 100
+	tree := NewTree(0, nil, NewMemHashStore())
+	roots := make([]common.Uint256, N+1)
+	roots[0] = tree.Root()
+	for i := 0; i < N; i++ {
+		var leaf common.Uint256
+		leaf[0] = byte(i)
+		tree.AppendHash(leaf)
+		roots[i+1] = tree.Root()
+	}
+	for size := N; size >= 0; size -= 7 {
+		assert.Nil(t, tree.Truncate(uint32(size)))
+		assert.Equal(t, uint32(size), tree.TreeSize())
+		assert.Equal(t, roots[size], tree.Root())
+	}
+	assert.Nil(t, tree.Truncate(1))
+	var leaf common.Uint256
+	leaf[0] = 1
+	tree.AppendHash(leaf)
+	assert.Equal(t, roots[2], tree.Root())
+	assert.NotNil(t, tree.Truncate(3))
+}
+
 func TestMerkle(t *testing.T) {
 	hasher := TreeHasher{}
 	leafs := []common.Uint256{hasher.hash_leaf([]byte{1}),
@@ -64,5 +64,20 @@ func GetTransaction(hash common.Uint256) (*types.Transaction, error) {
 		log.Warnf("[p2p]net_server GetTransaction error: %v\n", err)
 		return nil, err
 	}
-	return result.(tc.GetTxnRsp).Txn, nil
+	return result.(*tc.GetTxnRsp).Txn, nil
+}
+
+//get the verified txns of the short ids in txnpool
+func GetTransactionsByShortID(ids []uint64) (map[uint64][]*types.Transaction, error) {
+	if txnPoolPid == nil {
+		log.Warn("[p2p]net_server tx pool pid is nil")
+		return nil, errors.NewErr("[p2p]net_server tx pool pid is nil")
+	}
+	future := txnPoolPid.RequestFuture(&tc.GetTxnsByShortIDReq{ShortIDs: ids}, txnPoolReqTimeout)
+	result, err := future.Result()
+	if err != nil {
+		log.Warnf("[p2p]net_server GetTransactionsByShortID error: %v\n", err)
+		return nil, err
+	}
+	return result.(*tc.GetTxnsByShortIDRsp).Txs, nil
 }
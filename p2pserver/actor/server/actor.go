@@ -82,12 +82,26 @@ func (this *P2PActor) Receive(ctx actor.Context) {
 		this.handleGetNodeTypeReq(ctx, msg)
 	case *TransmitConsensusMsgReq:
 		this.handleTransmitConsensusMsgReq(ctx, msg)
+	case *SetValidatorsReq:
+		this.server.GetNetWork().SetValidators(msg.Validators)
+	case *GetPeerScoresReq:
+		this.handleGetPeerScoresReq(ctx, msg)
+	case *GetPeerInfosReq:
+		this.handleGetPeerInfosReq(ctx, msg)
+	case *GetBanListReq:
+		this.handleGetBanListReq(ctx, msg)
+	case *BanPeerReq:
+		this.handleBanPeerReq(ctx, msg)
+	case *UnbanPeerReq:
+		this.handleUnbanPeerReq(ctx, msg)
 	case *common.AppendPeerID:
 		this.server.OnAddNode(msg.ID)
 	case *common.RemovePeerID:
 		this.server.OnDelNode(msg.ID)
 	case *common.AppendHeaders:
 		this.server.OnHeaderReceive(msg.FromID, msg.Headers)
+	case *common.AppendSkeleton:
+		this.server.OnSkeletonReceive(msg.FromID, msg.Headers)
 	case *common.AppendBlock:
 		this.server.OnBlockReceive(msg.FromID, msg.BlockSize, msg.Block, msg.MerkleRoot)
 	default:
@@ -207,10 +221,56 @@ func (this *P2PActor) handleGetNodeTypeReq(ctx actor.Context, req *GetNodeTypeRe
 }
 
 func (this *P2PActor) handleTransmitConsensusMsgReq(ctx actor.Context, req *TransmitConsensusMsgReq) {
-	peer := this.server.GetNetWork().GetPeer(req.Target)
-	if peer != nil {
-		this.server.Send(peer, req.Msg, true)
-	} else {
-		log.Warnf("[p2p]can`t transmit consensus msg:no valid neighbor peer: %d\n", req.Target)
+	if err := this.server.GetNetWork().SendConsensus(req.Target, req.Msg); err != nil {
+		log.Warnf("[p2p]can`t transmit consensus msg:%s", err)
+	}
+}
+
+//nbr peers with misbehavior scores handler
+func (this *P2PActor) handleGetPeerScoresReq(ctx actor.Context, req *GetPeerScoresReq) {
+	peers := this.server.GetPeerScores()
+	if ctx.Sender() != nil {
+		resp := &GetPeerScoresRsp{
+			Peers: peers,
+		}
+		ctx.Sender().Request(resp, ctx.Self())
+	}
+}
+
+//detail of nbr peers handler
+func (this *P2PActor) handleGetPeerInfosReq(ctx actor.Context, req *GetPeerInfosReq) {
+	peers := this.server.GetPeerInfos()
+	if ctx.Sender() != nil {
+		resp := &GetPeerInfosRsp{
+			Peers: peers,
+		}
+		ctx.Sender().Request(resp, ctx.Self())
+	}
+}
+
+//banned peers handler
+func (this *P2PActor) handleGetBanListReq(ctx actor.Context, req *GetBanListReq) {
+	bans := this.server.GetNetWork().GetReputation().BanList()
+	if ctx.Sender() != nil {
+		resp := &GetBanListRsp{
+			Bans: bans,
+		}
+		ctx.Sender().Request(resp, ctx.Self())
+	}
+}
+
+//ban peer handler
+func (this *P2PActor) handleBanPeerReq(ctx actor.Context, req *BanPeerReq) {
+	this.server.BanPeer(req.Host, req.Duration)
+	if ctx.Sender() != nil {
+		ctx.Sender().Request(&BanPeerRsp{Ok: true}, ctx.Self())
+	}
+}
+
+//unban peer handler
+func (this *P2PActor) handleUnbanPeerReq(ctx actor.Context, req *UnbanPeerReq) {
+	ok := this.server.GetNetWork().GetReputation().Unban(req.Host)
+	if ctx.Sender() != nil {
+		ctx.Sender().Request(&BanPeerRsp{Ok: ok}, ctx.Self())
 	}
 }
@@ -19,8 +19,11 @@
 package server
 
 import (
+	"time"
+
 	types "github.com/TesraSupernet/Tesra/p2pserver/common"
 	ptypes "github.com/TesraSupernet/Tesra/p2pserver/message/types"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 //stop net server
@@ -113,3 +116,51 @@ type TransmitConsensusMsgReq struct {
 	Target uint64
 	Msg    ptypes.Message
 }
+
+//update current validators allowed in consensus overlay, by public key ids
+type SetValidatorsReq struct {
+	Validators []string
+}
+
+//get nbr peers with misbehavior scores request
+type GetPeerScoresReq struct {
+}
+
+//response of nbr peers with misbehavior scores
+type GetPeerScoresRsp struct {
+	Peers []reputation.PeerScore
+}
+
+//get detail of nbr peers request
+type GetPeerInfosReq struct {
+}
+
+//response of detail of nbr peers
+type GetPeerInfosRsp struct {
+	Peers []types.PeerInfo
+}
+
+//get banned peers request
+type GetBanListReq struct {
+}
+
+//response of banned peers
+type GetBanListRsp struct {
+	Bans []reputation.BanEntry
+}
+
+//ban peer request
+type BanPeerReq struct {
+	Host     string
+	Duration time.Duration
+}
+
+//unban peer request
+type UnbanPeerReq struct {
+	Host string
+}
+
+//response of ban or unban peer request
+type BanPeerRsp struct {
+	Ok bool
+}
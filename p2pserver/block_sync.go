@@ -25,12 +25,15 @@ import (
 	"time"
 
 	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/types"
 	p2pComm "github.com/TesraSupernet/Tesra/p2pserver/common"
 	msgpack "github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
+	"github.com/TesraSupernet/Tesra/p2pserver/message/utils"
 	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 const (
@@ -233,6 +236,8 @@ type BlockSyncMgr struct {
 	ledger         *ledger.Ledger                       //ledger
 	lock           sync.RWMutex                         //lock
 	nodeWeights    map[uint64]*NodeWeight               //Map NodeID => NodeStatus, using for getNextNode
+	checkpoints    []*config.Checkpoint                 //Configured checkpoints sorted by height
+	skeleton       *skeletonRound                       //Current round of skeleton header sync
 }
 
 //NewBlockSyncMgr return a BlockSyncMgr instance
@@ -245,6 +250,7 @@ func NewBlockSyncMgr(server *P2PServer) *BlockSyncMgr {
 		ledger:        server.ledger,
 		exitCh:        make(chan interface{}, 1),
 		nodeWeights:   make(map[uint64]*NodeWeight, 0),
+		checkpoints:   config.GetCheckpoints(),
 	}
 }
 
@@ -357,6 +363,7 @@ func (this *BlockSyncMgr) checkTimeout() {
 		}
 	}
 	this.lock.RUnlock()
+	this.checkSkeletonTimeout()
 
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
 	curBlockHeight := this.ledger.GetCurrentBlockHeight()
@@ -427,6 +434,9 @@ func (this *BlockSyncMgr) syncHeader() {
 	}
 	defer this.releaseSyncHeaderLock()
 
+	if this.syncSkeleton() {
+		return
+	}
 	if this.getFlightHeaderCount() >= SYNC_MAX_FLIGHT_HEADER_SIZE {
 		return
 	}
@@ -532,6 +542,9 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID uint64, headers []*types.Header
 	if len(headers) == 0 {
 		return
 	}
+	if this.onSegmentReceive(fromID, headers) {
+		return
+	}
 	log.Infof("Header receive height:%d - %d", headers[0].Height, headers[len(headers)-1].Height)
 	height := headers[0].Height
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
@@ -543,7 +556,10 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID uint64, headers []*types.Header
 	if !this.isHeaderOnFlight(height) {
 		return
 	}
-	err := this.ledger.AddHeaders(headers)
+	err := this.checkCheckpoints(headers)
+	if err == nil {
+		err = this.ledger.AddHeaders(headers)
+	}
 	this.delFlightHeader(height)
 	if err != nil {
 		this.addErrorRespCnt(fromID)
@@ -554,10 +570,17 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID uint64, headers []*types.Header
 		log.Warnf("[p2p]OnHeaderReceive AddHeaders error:%s", err)
 		return
 	}
+	this.addEmptyBlocks(fromID, headers)
+	go this.saveBlock()
+	this.syncHeader()
+}
+
+//addEmptyBlocks add the blocks of headers without transactions to cache
+func (this *BlockSyncMgr) addEmptyBlocks(fromID uint64, headers []*types.Header) {
 	sort.Slice(headers, func(i, j int) bool {
 		return headers[i].Height < headers[j].Height
 	})
-	curHeaderHeight = this.ledger.GetCurrentHeaderHeight()
+	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
 	curBlockHeight := this.ledger.GetCurrentBlockHeight()
 	for _, header := range headers {
 		//handle empty block
@@ -579,8 +602,6 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID uint64, headers []*types.Header
 			this.addBlockCache(fromID, block, common.UINT256_EMPTY)
 		}
 	}
-	go this.saveBlock()
-	this.syncHeader()
 }
 
 // OnBlockReceive receive block from net
@@ -932,12 +953,67 @@ func (this *BlockSyncMgr) getAllNodeWeights() NodeWeights {
 	return weights
 }
 
+//SyncStatuses return the roles of sync nodes with their requests in flight, by node id
+func (this *BlockSyncMgr) SyncStatuses() map[uint64]p2pComm.SyncStatus {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	statuses := make(map[uint64]*p2pComm.SyncStatus)
+	status := func(nodeId uint64) *p2pComm.SyncStatus {
+		s, ok := statuses[nodeId]
+		if !ok {
+			s = &p2pComm.SyncStatus{Role: p2pComm.SYNC_ROLE_IDLE}
+			statuses[nodeId] = s
+		}
+		return s
+	}
+	for id, w := range this.nodeWeights {
+		s := status(id)
+		s.Timeouts = w.timeoutCnt
+		s.ErrorResps = w.errorRespCnt
+	}
+	for _, info := range this.flightHeaders {
+		status(info.GetNodeId()).HeadersInFlight++
+	}
+	for _, infos := range this.flightBlocks {
+		for _, info := range infos {
+			status(info.GetNodeId()).BlocksInFlight++
+		}
+	}
+	skeletonNode := uint64(0)
+	if round := this.skeleton; round != nil {
+		if round.segments == nil {
+			skeletonNode = round.nodeId
+			status(skeletonNode)
+		}
+		for _, segment := range round.segments {
+			if segment.headers == nil && segment.nodeId != 0 {
+				status(segment.nodeId).HeadersInFlight++
+			}
+		}
+	}
+
+	result := make(map[uint64]p2pComm.SyncStatus, len(statuses))
+	for id, s := range statuses {
+		switch {
+		case skeletonNode != 0 && id == skeletonNode:
+			s.Role = p2pComm.SYNC_ROLE_SKELETON
+		case s.HeadersInFlight > 0:
+			s.Role = p2pComm.SYNC_ROLE_HEADERS
+		case s.BlocksInFlight > 0:
+			s.Role = p2pComm.SYNC_ROLE_BLOCKS
+		}
+		result[id] = *s
+	}
+	return result
+}
+
 //addTimeoutCnt incre a node's timeout count
 func (this *BlockSyncMgr) addTimeoutCnt(nodeId uint64) {
 	n := this.getNodeWeight(nodeId)
 	if n != nil {
 		n.AddTimeoutCnt()
 	}
+	utils.Misbehave(this.server.network, nodeId, "", reputation.SCORE_TIMEOUT, "sync request timeout")
 }
 
 //addErrorRespCnt incre a node's error resp count
@@ -946,6 +1022,7 @@ func (this *BlockSyncMgr) addErrorRespCnt(nodeId uint64) {
 	if n != nil {
 		n.AddErrorRespCnt()
 	}
+	utils.Misbehave(this.server.network, nodeId, "", reputation.SCORE_ERROR_RESP, "sync error response")
 }
 
 //appendReqTime append a node's request time
@@ -0,0 +1,49 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	p2pComm "github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncStatuses(t *testing.T) {
+	syncMgr := &BlockSyncMgr{
+		flightBlocks:  make(map[common.Uint256][]*SyncFlightInfo),
+		flightHeaders: make(map[uint32]*SyncFlightInfo),
+		nodeWeights:   make(map[uint64]*NodeWeight),
+	}
+	for id := uint64(1); id <= 4; id++ {
+		syncMgr.OnAddNode(id)
+	}
+	syncMgr.getNodeWeight(2).AddTimeoutCnt()
+	syncMgr.addFlightHeader(2, 100)
+	syncMgr.addFlightBlock(3, 90, common.Uint256{1})
+	syncMgr.addFlightBlock(3, 91, common.Uint256{2})
+	syncMgr.skeleton = &skeletonRound{nodeId: 4}
+
+	statuses := syncMgr.SyncStatuses()
+	assert.Equal(t, p2pComm.SyncStatus{Role: p2pComm.SYNC_ROLE_IDLE}, statuses[1])
+	assert.Equal(t, p2pComm.SyncStatus{Role: p2pComm.SYNC_ROLE_HEADERS, HeadersInFlight: 1, Timeouts: 1}, statuses[2])
+	assert.Equal(t, p2pComm.SyncStatus{Role: p2pComm.SYNC_ROLE_BLOCKS, BlocksInFlight: 2}, statuses[3])
+	assert.Equal(t, p2pComm.SYNC_ROLE_SKELETON, statuses[4].Role)
+}
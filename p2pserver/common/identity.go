@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"golang.org/x/crypto/ed25519"
+)
+
+//NodeKey is the keypair of p2p identity, the id of node is derived from the public key
+type NodeKey struct {
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+//NewNodeKey return a random node key
+func NewNodeKey() (*NodeKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeKey{PrivateKey: priv, PublicKey: pub}, nil
+}
+
+//LoadNodeKey load node key from file which saves the hex seed of private key, the file is created with a new node
+//key if not exist. Empty path means a new node key not saved
+func LoadNodeKey(path string) (*NodeKey, error) {
+	if path == "" {
+		return NewNodeKey()
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		key, err := NewNodeKey()
+		if err != nil {
+			return nil, err
+		}
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return nil, err
+		}
+		err = ioutil.WriteFile(path, []byte(hex.EncodeToString(key.PrivateKey.Seed())), 0600)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid node key file %s", path)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	return &NodeKey{PrivateKey: priv, PublicKey: priv.Public().(ed25519.PublicKey)}, nil
+}
+
+//ValidatorSigner sign with the consensus key of validator, which proves the validator identity in consensus overlay
+type ValidatorSigner interface {
+	PublicKey() keypair.PublicKey
+	Sign(data []byte) ([]byte, error)
+}
+
+//ID return the peer id of node key
+func (this *NodeKey) ID() uint64 {
+	return PubKeyToID(this.PublicKey)
+}
+
+//PubKeyToID return the peer id derived from public key of node
+func PubKeyToID(pubKey []byte) uint64 {
+	hash := sha256.Sum256(pubKey)
+	return binary.LittleEndian.Uint64(hash[:8])
+}
+
+//ReservedPeer is an entry of reserved peers, in the format of "address prefix", "public key@address prefix" which pins
+//the public key of peers with the address, or "public key@" which allows the public key from any address
+type ReservedPeer struct {
+	Addr   string
+	PubKey []byte
+}
+
+//ParseReservedPeer parse reserved peer entry, public key is in hex
+func ParseReservedPeer(entry string) (*ReservedPeer, error) {
+	i := strings.Index(entry, "@")
+	if i < 0 {
+		return &ReservedPeer{Addr: entry}, nil
+	}
+	pubKey, err := hex.DecodeString(entry[:i])
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key of reserved peer %s", entry)
+	}
+	return &ReservedPeer{Addr: entry[i+1:], PubKey: pubKey}, nil
+}
+
+//CheckReservedPeer check the address and public key of peer with reserved peers. Peers with address pinned must have
+//one of the pinned public keys. Other peers are allowed if matching any entry, or reserved only is not required. Nil
+//public key means the key is not known yet, and only address is checked
+func CheckReservedPeer(reserved []string, reservedOnly bool, addr string, pubKey []byte) bool {
+	var allowed, pinned, pinMatched bool
+	for _, entry := range reserved {
+		peer, err := ParseReservedPeer(entry)
+		if err != nil || !strings.HasPrefix(addr, peer.Addr) {
+			continue
+		}
+		keyMatched := peer.PubKey == nil || pubKey == nil || bytes.Equal(pubKey, peer.PubKey)
+		if peer.PubKey != nil && peer.Addr != "" {
+			pinned = true
+			pinMatched = pinMatched || keyMatched
+		}
+		allowed = allowed || keyMatched
+	}
+	if pinned {
+		return pinMatched
+	}
+	return allowed || !reservedOnly
+}
+
+//HasPinnedKeys return whether any reserved peer pins public key, which requires peers authenticated
+func HasPinnedKeys(reserved []string) bool {
+	for _, entry := range reserved {
+		peer, err := ParseReservedPeer(entry)
+		if err == nil && peer.PubKey != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadNodeKey(t *testing.T) {
+	path := "test_nodekey/nodekey"
+	defer os.RemoveAll("test_nodekey")
+	key, err := LoadNodeKey(path)
+	assert.Nil(t, err)
+	loaded, err := LoadNodeKey(path)
+	assert.Nil(t, err)
+	assert.Equal(t, key.PublicKey, loaded.PublicKey)
+	assert.Equal(t, key.ID(), loaded.ID())
+
+	//a broken key file is not replaced by a new key
+	err = ioutil.WriteFile(path, []byte("broken"), 0600)
+	assert.Nil(t, err)
+	_, err = LoadNodeKey(path)
+	assert.NotNil(t, err)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "broken", string(data))
+}
+
+func TestCheckReservedPeer(t *testing.T) {
+	key, _ := NewNodeKey()
+	other, _ := NewNodeKey()
+	pinned := hex.EncodeToString(key.PublicKey)
+	reserved := []string{"10.0.0.1", pinned + "@10.0.0.2", hex.EncodeToString(other.PublicKey) + "@"}
+
+	assert.True(t, CheckReservedPeer(reserved, true, "10.0.0.1:20338", nil))
+	assert.True(t, CheckReservedPeer(reserved, true, "10.0.0.2:20338", nil))
+	assert.True(t, CheckReservedPeer(reserved, true, "10.0.0.2:20338", key.PublicKey))
+	assert.False(t, CheckReservedPeer(reserved, true, "10.0.0.2:20338", other.PublicKey))
+	assert.False(t, CheckReservedPeer(reserved, false, "10.0.0.2:20338", other.PublicKey))
+	assert.True(t, CheckReservedPeer(reserved, true, "10.0.0.3:20338", other.PublicKey))
+	assert.False(t, CheckReservedPeer(reserved, true, "10.0.0.3:20338", key.PublicKey))
+	assert.True(t, CheckReservedPeer(reserved, false, "10.0.0.3:20338", key.PublicKey))
+	assert.True(t, HasPinnedKeys(reserved))
+	assert.False(t, HasPinnedKeys(reserved[:1]))
+}
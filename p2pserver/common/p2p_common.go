@@ -61,11 +61,12 @@ const (
 const (
 	MAX_ADDR_NODE_CNT = 64 //the maximum peer address from msg
 	MAX_INV_BLK_CNT   = 64 //the maximum blk hash cnt of inv msg
+	MAX_SKELETON_CNT  = 64 //the maximum hdr cnt of skeleton msg
 )
 
 //info update const
 const (
-	PROTOCOL_VERSION      = 0     //protocol version
+	PROTOCOL_VERSION      = 3     //protocol version
 	UPDATE_RATE_PER_BLOCK = 2     //info update rate in one generate block period
 	KEEPALIVE_TIMEOUT     = 15    //contact timeout in sec
 	DIAL_TIMEOUT          = 6     //connect timeout in sec
@@ -88,7 +89,8 @@ const (
 
 //cap flag
 const (
-	HTTP_INFO_FLAG = 0 //peer`s http info bit in cap field
+	HTTP_INFO_FLAG   = 0 //peer`s http info bit in cap field
+	COMPRESSION_FLAG = 1 //peer`s support of compressed messages in cap field
 )
 
 //actor const
@@ -103,6 +105,58 @@ const (
 	RECENT_LIMIT     = 10 //recent contact list limit
 )
 
+//discovery const
+const (
+	DISCOVERY_INTERVAL    = 60 //time to refresh routing table in sec
+	DISCOVERY_MIN_VERSION = 1  //min protocol version of peer supporting findnode
+)
+
+//compact relay const
+const (
+	COMPACT_RELAY_MIN_VERSION = 2  //min protocol version of peer supporting compact block and tx inv
+	TX_REQ_TIMEOUT            = 5  //time to request an announced tx from another peer in sec
+	MAX_TX_ANNOUNCERS         = 8  //max peers recorded to request an announced tx from in turn
+	MAX_PENDING_CMPCT         = 16 //max compact blocks waiting for the missing txs
+)
+
+//skeleton sync const
+const (
+	SKELETON_SYNC_MIN_VERSION = 3 //min protocol version of peer supporting getskel
+)
+
+//consensus overlay const
+const (
+	OVERLAY_CHECK_INTERVAL = 10       //time to connect validators not in consensus overlay in sec
+	OVERLAY_RETRY_INTERVAL = 60       //time to retry a failed overlay connection to a peer in sec
+	OVERLAY_QUEUE_SIZE     = 1024     //the maximum pending messages of each overlay send queue
+	OVERLAY_MAX_HANDSHAKES = 16       //the maximum inbound overlay connections in handshake
+	OVERLAY_SMALL_MSG_LEN  = 4 * 1024 //messages up to the len such as votes are sent ahead of block proposals
+)
+
+//external address const
+const (
+	ADDR_SOURCE_OBSERVED      = 1  //public address agreed by the observations of peers
+	ADDR_SOURCE_NAT           = 2  //public address mapped on NAT gateway
+	ADDR_SOURCE_CONFIG        = 3  //public address set by config, never replaced
+	OBSERVED_ADDR_MIN_REPORTS = 3  //min peer networks agreeing on the observed address of self
+	MAX_OBSERVED_REPORTS      = 64 //the maximum peer networks whose observations are kept
+)
+
+//compression const
+const (
+	COMPRESS_SNAPPY = 1 //snappy compression algorithm of compressed msg
+)
+
+//transport const
+const (
+	TRANSPORT_TCP       = "tcp"       //tcp links, encrypted by tls if enabled
+	TRANSPORT_QUIC      = "quic"      //quic links with a stream for each class of messages
+	QUIC_ALPN           = "tesra-p2p" //application protocol negotiated by quic handshake
+	QUIC_IDLE_TIMEOUT   = 60          //time to close a quic connection without any packet in sec
+	QUIC_KEEPALIVE      = 15          //interval of quic keep-alive packets in sec
+	STREAM_OPEN_TIMEOUT = 6           //timeout of accepting the control stream of quic connection in sec
+)
+
 //PeerAddr represent peer`s net information
 type PeerAddr struct {
 	Time     int64    //latest timestamp
@@ -132,6 +186,14 @@ const (
 	GET_BLOCKS_TYPE  = "getblocks"  //req blks from peer
 	NOT_FOUND_TYPE   = "notfound"   //peer can`t find blk according to the hash
 	DISCONNECT_TYPE  = "disconnect" //peer disconnect info raise by link
+	FINDNODE_TYPE    = "findnode"   //req nodes closest to target id
+	NEIGHBORS_TYPE   = "neighbors"  //nodes closest to target id
+	CMPCT_BLOCK_TYPE = "cmpctblock" //blk hdr with short tx ids
+	GET_SKEL_TYPE    = "getskel"    //req blk hdrs at heights
+	SKELETON_TYPE    = "skeleton"   //blk hdrs at heights
+	COMPRESSED_TYPE  = "compressed" //msg with compressed payload
+	GET_BLKTXN_TYPE  = "getblktxn"  //req missing txs of compact blk
+	BLKTXN_TYPE      = "blktxn"     //missing txs of compact blk
 )
 
 type AppendPeerID struct {
@@ -147,6 +209,11 @@ type AppendHeaders struct {
 	Headers []*types.Header // Headers to be added to the ledger
 }
 
+type AppendSkeleton struct {
+	FromID  uint64          // The peer id
+	Headers []*types.Header // Skeleton headers of header sync
+}
+
 type AppendBlock struct {
 	FromID     uint64       // The peer id
 	BlockSize  uint32       // Block size
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+//direction of the sync link, by which side dialed
+const (
+	DIRECTION_INBOUND  = "inbound"
+	DIRECTION_OUTBOUND = "outbound"
+)
+
+//role of peer in block sync
+const (
+	SYNC_ROLE_NONE     = "none"     //not a sync node, e.g. handshake not finished
+	SYNC_ROLE_IDLE     = "idle"     //sync node without request in flight
+	SYNC_ROLE_SKELETON = "skeleton" //serving the skeleton of current round
+	SYNC_ROLE_HEADERS  = "headers"  //serving headers
+	SYNC_ROLE_BLOCKS   = "blocks"   //serving blocks
+)
+
+//MsgTraffic count messages and bytes of a message type transferred with peer. Bytes are counted as sent on wire,
+//so compressed messages are counted with compressed size under the type carried
+type MsgTraffic struct {
+	MsgsIn   uint64 `json:"msgs_in"`
+	BytesIn  uint64 `json:"bytes_in"`
+	MsgsOut  uint64 `json:"msgs_out"`
+	BytesOut uint64 `json:"bytes_out"`
+}
+
+//SyncStatus represent the role of peer in block sync
+type SyncStatus struct {
+	Role            string `json:"role"`
+	HeadersInFlight int    `json:"headers_in_flight"`
+	BlocksInFlight  int    `json:"blocks_in_flight"`
+	Timeouts        int    `json:"timeouts"`
+	ErrorResps      int    `json:"error_resps"`
+}
+
+//PeerInfo represent the detail of an established nbr peer for inspection
+type PeerInfo struct {
+	ID             uint64                `json:"id"`
+	Addr           string                `json:"addr"`
+	NodeAddr       string                `json:"node_addr"`
+	Direction      string                `json:"direction"`
+	ConnectedSince int64                 `json:"connected_since"`
+	Version        uint32                `json:"version"`
+	SoftVersion    string                `json:"soft_version"`
+	Height         uint64                `json:"height"`
+	Latency        float64               `json:"latency_ms"` //round trip time of last ping, 0 if not measured
+	Score          int                   `json:"score"`
+	Sync           SyncStatus            `json:"sync"`
+	Traffic        map[string]MsgTraffic `json:"traffic"`
+}
@@ -0,0 +1,354 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//Package dht provides a kademlia routing table of p2p nodes
+package dht
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	BUCKET_SIZE     = 16      //max nodes of a bucket, the k of kademlia
+	BUCKET_COUNT    = 64      //bits of node id
+	ALPHA           = 3       //nodes queried once of a lookup
+	MAX_NODE_FAILS  = 3       //node is removed after continuous failed contacts
+	NODE_EXPIRE     = 60 * 60 //node not seen in expire seconds is not alive
+	FINDNODE_EXPIRE = 30      //seconds to wait for the neighbors reply of a findnode request
+)
+
+//Node is the record of a node in routing table
+type Node struct {
+	ID       uint64 `json:"id"`
+	Addr     string `json:"addr"`      //ip:sync port
+	LastSeen int64  `json:"last_seen"` //unix time when node was last seen alive, 0 if never
+	Fails    int    `json:"fails"`     //continuous failed contacts
+}
+
+//alive return whether the node was seen recently and has no failed contacts since then
+func (this *Node) alive(now int64) bool {
+	return this.Fails == 0 && this.LastSeen+NODE_EXPIRE > now
+}
+
+//RoutingTable keeps the nodes in buckets by the xor distance to self. Nodes of a bucket are ordered by last
+//seen time, least recently seen first. Only the nodes contacted successfully are kept in buckets. Alive old
+//nodes are not replaced by new nodes when bucket is full, the new nodes and the nodes learned from other nodes
+//are kept in replacements, and the contacted ones fill the bucket when nodes are removed
+type RoutingTable struct {
+	lock         sync.RWMutex
+	self         uint64
+	buckets      [BUCKET_COUNT][]*Node
+	replacements [BUCKET_COUNT][]*Node
+	findNodes    map[uint64]map[uint64]int64 //outstanding findnode requests, peer id to target and sent time
+}
+
+//NewRoutingTable return a routing table of self id
+func NewRoutingTable(self uint64) *RoutingTable {
+	return &RoutingTable{
+		self:      self,
+		findNodes: make(map[uint64]map[uint64]int64),
+	}
+}
+
+//Distance return the xor distance of two node ids
+func Distance(a, b uint64) uint64 {
+	return a ^ b
+}
+
+//bucketIndex return bucket of id, -1 means self
+func (this *RoutingTable) bucketIndex(id uint64) int {
+	return bits.Len64(Distance(this.self, id)) - 1
+}
+
+//RandomTarget return a random id in bucket i, lookup of it refreshes the bucket
+func (this *RoutingTable) RandomTarget(i int) uint64 {
+	if i < 0 || i >= BUCKET_COUNT {
+		return this.self
+	}
+	mask := uint64(1)<<uint(i) - 1
+	return this.self ^ (uint64(1)<<uint(i) | rand.Uint64()&mask)
+}
+
+//Add a node learned from other nodes to replacements, it is moved to bucket when contacted successfully.
+//Existing node is not changed
+func (this *RoutingTable) Add(id uint64, addr string) {
+	i := this.bucketIndex(id)
+	if i < 0 || addr == "" {
+		return
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if indexOf(this.buckets[i], id) >= 0 || indexOf(this.replacements[i], id) >= 0 {
+		return
+	}
+	this.addReplacement(i, &Node{ID: id, Addr: addr})
+}
+
+//FindNodeSent record the findnode request of target sent to peer, only the neighbors replied to outstanding
+//requests are accepted
+func (this *RoutingTable) FindNodeSent(peer, target uint64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	now := time.Now().Unix()
+	for id, targets := range this.findNodes {
+		for t, sent := range targets {
+			if sent+FINDNODE_EXPIRE <= now {
+				delete(targets, t)
+			}
+		}
+		if len(targets) == 0 {
+			delete(this.findNodes, id)
+		}
+	}
+	targets, ok := this.findNodes[peer]
+	if !ok {
+		targets = make(map[uint64]int64)
+		this.findNodes[peer] = targets
+	}
+	targets[target] = now
+}
+
+//NeighborsReceived return whether the neighbors of target from peer reply an outstanding findnode request,
+//the request is finished by the reply
+func (this *RoutingTable) NeighborsReceived(peer, target uint64) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	targets := this.findNodes[peer]
+	sent, ok := targets[target]
+	if !ok {
+		return false
+	}
+	delete(targets, target)
+	if len(targets) == 0 {
+		delete(this.findNodes, peer)
+	}
+	return sent+FINDNODE_EXPIRE > time.Now().Unix()
+}
+
+//Seen update the node which is contacted successfully as the most recently seen node
+func (this *RoutingTable) Seen(id uint64, addr string) {
+	i := this.bucketIndex(id)
+	if i < 0 {
+		return
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	now := time.Now().Unix()
+	bucket := this.buckets[i]
+	node := &Node{ID: id, Addr: addr}
+	if pos := indexOf(bucket, id); pos >= 0 {
+		node = bucket[pos]
+		bucket = append(bucket[:pos], bucket[pos+1:]...)
+	} else if pos := indexOf(this.replacements[i], id); pos >= 0 {
+		node = this.replacements[i][pos]
+		this.replacements[i] = append(this.replacements[i][:pos], this.replacements[i][pos+1:]...)
+	}
+	if addr != "" {
+		node.Addr = addr
+	}
+	node.LastSeen = now
+	node.Fails = 0
+	if len(bucket) >= BUCKET_SIZE {
+		//prefer the alive old nodes, evict the least recently seen one only if it is not alive
+		if bucket[0].alive(now) {
+			this.buckets[i] = bucket
+			this.addReplacement(i, node)
+			return
+		}
+		bucket = bucket[1:]
+	}
+	this.buckets[i] = append(bucket, node)
+}
+
+//Failed record a failed contact of node, the node is removed after MAX_NODE_FAILS continuous failures
+func (this *RoutingTable) Failed(id uint64) {
+	i := this.bucketIndex(id)
+	if i < 0 {
+		return
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if pos := indexOf(this.replacements[i], id); pos >= 0 {
+		this.replacements[i] = append(this.replacements[i][:pos], this.replacements[i][pos+1:]...)
+		return
+	}
+	bucket := this.buckets[i]
+	pos := indexOf(bucket, id)
+	if pos < 0 {
+		return
+	}
+	bucket[pos].Fails++
+	if bucket[pos].Fails < MAX_NODE_FAILS {
+		return
+	}
+	bucket = append(bucket[:pos], bucket[pos+1:]...)
+	//fill the bucket with the most recent replacement which was contacted
+	replacements := this.replacements[i]
+	for j := len(replacements) - 1; j >= 0; j-- {
+		if replacements[j].LastSeen != 0 {
+			bucket = append([]*Node{replacements[j]}, bucket...)
+			this.replacements[i] = append(replacements[:j], replacements[j+1:]...)
+			break
+		}
+	}
+	this.buckets[i] = bucket
+}
+
+func (this *RoutingTable) addReplacement(i int, node *Node) {
+	replacements := append(this.replacements[i], node)
+	if len(replacements) > BUCKET_SIZE {
+		//the oldest never seen node is evicted first
+		pos := 0
+		for j, n := range replacements {
+			if n.LastSeen == 0 {
+				pos = j
+				break
+			}
+		}
+		replacements = append(replacements[:pos], replacements[pos+1:]...)
+	}
+	this.replacements[i] = replacements
+}
+
+//Get return the node of id
+func (this *RoutingTable) Get(id uint64) (Node, bool) {
+	i := this.bucketIndex(id)
+	if i < 0 {
+		return Node{}, false
+	}
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	if pos := indexOf(this.buckets[i], id); pos >= 0 {
+		return *this.buckets[i][pos], true
+	}
+	return Node{}, false
+}
+
+//Closest return at most count nodes closest to target, which have been contacted successfully
+func (this *RoutingTable) Closest(target uint64, count int) []Node {
+	nodes := make([]Node, 0)
+	for _, node := range this.Nodes() {
+		if node.LastSeen != 0 {
+			nodes = append(nodes, node)
+		}
+	}
+	return closest(nodes, target, count)
+}
+
+//Candidates return at most count nodes closest to target in buckets and replacements to be connected,
+//including the nodes not contacted yet
+func (this *RoutingTable) Candidates(target uint64, count int) []Node {
+	this.lock.RLock()
+	nodes := make([]Node, 0)
+	for i := range this.buckets {
+		for _, node := range this.buckets[i] {
+			nodes = append(nodes, *node)
+		}
+		for _, node := range this.replacements[i] {
+			nodes = append(nodes, *node)
+		}
+	}
+	this.lock.RUnlock()
+	return closest(nodes, target, count)
+}
+
+func closest(nodes []Node, target uint64, count int) []Node {
+	sort.Slice(nodes, func(i, j int) bool {
+		return Distance(nodes[i].ID, target) < Distance(nodes[j].ID, target)
+	})
+	if len(nodes) > count {
+		nodes = nodes[:count]
+	}
+	return nodes
+}
+
+//Nodes return all nodes in buckets
+func (this *RoutingTable) Nodes() []Node {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	nodes := make([]Node, 0)
+	for _, bucket := range this.buckets {
+		for _, node := range bucket {
+			nodes = append(nodes, *node)
+		}
+	}
+	return nodes
+}
+
+//Len return the count of nodes in buckets
+func (this *RoutingTable) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	count := 0
+	for _, bucket := range this.buckets {
+		count += len(bucket)
+	}
+	return count
+}
+
+//Save persist the nodes in buckets to file
+func (this *RoutingTable) Save(path string) error {
+	buf, err := json.Marshal(this.Nodes())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf, 0644)
+}
+
+//Load add the nodes persisted in file, the records are kept so alive nodes are still preferred
+func (this *RoutingTable) Load(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	nodes := make([]Node, 0)
+	err = json.Unmarshal(buf, &nodes)
+	if err != nil {
+		return err
+	}
+	//most recently seen nodes are kept if bucket is full
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].LastSeen > nodes[j].LastSeen
+	})
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, node := range nodes {
+		i := this.bucketIndex(node.ID)
+		if i < 0 || node.Addr == "" || len(this.buckets[i]) >= BUCKET_SIZE || indexOf(this.buckets[i], node.ID) >= 0 {
+			continue
+		}
+		n := node
+		this.buckets[i] = append([]*Node{&n}, this.buckets[i]...)
+	}
+	return nil
+}
+
+func indexOf(nodes []*Node, id uint64) int {
+	for i, node := range nodes {
+		if node.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dht
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutingTableBuckets(t *testing.T) {
+	table := NewRoutingTable(0)
+	assert.Equal(t, -1, table.bucketIndex(0))
+	assert.Equal(t, 0, table.bucketIndex(1))
+	assert.Equal(t, 63, table.bucketIndex(1<<63))
+	for i := 0; i < BUCKET_COUNT; i++ {
+		assert.Equal(t, i, table.bucketIndex(table.RandomTarget(i)))
+	}
+
+	table.Add(0, "127.0.0.1:20338")
+	assert.Equal(t, 0, table.Len())
+	//bucket 63 holds ids with the highest bit set
+	for i := uint64(0); i < BUCKET_SIZE; i++ {
+		table.Seen(1<<63|i, "127.0.0.1:20338")
+	}
+	table.Seen(1<<63|100, "127.0.0.1:20339")
+	_, ok := table.Get(1<<63 | 100)
+	assert.False(t, ok, "alive old nodes are preferred")
+	assert.Equal(t, BUCKET_SIZE, table.Len())
+
+	for i := 0; i < MAX_NODE_FAILS; i++ {
+		table.Failed(1 << 63)
+	}
+	_, ok = table.Get(1 << 63)
+	assert.False(t, ok)
+	node, ok := table.Get(1<<63 | 100)
+	assert.True(t, ok, "replacement fills the bucket")
+	assert.Equal(t, "127.0.0.1:20339", node.Addr)
+
+	closest := table.Closest(1<<63|3, 2)
+	assert.Equal(t, 2, len(closest))
+	assert.Equal(t, uint64(1<<63|3), closest[0].ID)
+	assert.Equal(t, uint64(1<<63|2), closest[1].ID)
+}
+
+func TestRoutingTablePersist(t *testing.T) {
+	path := "nodes.table.test"
+	defer os.Remove(path)
+	table := NewRoutingTable(1)
+	table.Seen(2, "127.0.0.1:20338")
+	table.Add(3, "127.0.0.1:20339")
+	assert.Nil(t, table.Save(path))
+
+	loaded := NewRoutingTable(1)
+	assert.Nil(t, loaded.Load(path))
+	assert.Equal(t, table.Nodes(), loaded.Nodes())
+}
+
+func TestRoutingTableUnverified(t *testing.T) {
+	table := NewRoutingTable(0)
+	table.Add(1<<63|1, "127.0.0.1:20338")
+	_, ok := table.Get(1<<63 | 1)
+	assert.False(t, ok, "learned node is kept in replacements")
+	assert.Equal(t, 0, len(table.Closest(1<<63, BUCKET_SIZE)))
+	candidates := table.Candidates(1<<63, BUCKET_SIZE)
+	assert.Equal(t, 1, len(candidates))
+
+	table.Seen(1<<63|1, "")
+	assert.Equal(t, 1, len(table.Closest(1<<63, BUCKET_SIZE)))
+
+	//learned nodes do not evict the contacted replacements
+	for i := uint64(0); i < BUCKET_SIZE; i++ {
+		table.Seen(1<<62|i, "127.0.0.1:20338")
+	}
+	table.Seen(1<<62|100, "127.0.0.1:20339")
+	for i := uint64(0); i < 2*BUCKET_SIZE; i++ {
+		table.Add(1<<62|200+i, "127.0.0.1:20340")
+	}
+	for i := 0; i < MAX_NODE_FAILS; i++ {
+		table.Failed(1 << 62)
+	}
+	_, ok = table.Get(1<<62 | 100)
+	assert.True(t, ok, "contacted replacement fills the bucket")
+	for _, node := range table.Closest(1<<62, 2*BUCKET_SIZE) {
+		assert.NotEqual(t, int64(0), node.LastSeen)
+	}
+}
+
+func TestRoutingTableFindNode(t *testing.T) {
+	table := NewRoutingTable(0)
+	assert.False(t, table.NeighborsReceived(1, 2), "unsolicited neighbors")
+	table.FindNodeSent(1, 2)
+	assert.False(t, table.NeighborsReceived(3, 2))
+	assert.False(t, table.NeighborsReceived(1, 3))
+	assert.True(t, table.NeighborsReceived(1, 2))
+	assert.False(t, table.NeighborsReceived(1, 2), "request is finished by the reply")
+
+	table.FindNodeSent(1, 2)
+	table.findNodes[1][2] -= FINDNODE_EXPIRE
+	assert.False(t, table.NeighborsReceived(1, 2), "expired request")
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/dht"
+	msgpack "github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
+	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+)
+
+//discoveryEnabled return whether nodes are found by routing table, reserved only node never connects other nodes
+func discoveryEnabled() bool {
+	return config.DefConfig.P2PNode.EnableDiscovery && !config.DefConfig.P2PNode.ReservedPeersOnly
+}
+
+//loadRoutingTable load the persisted routing table when service start, the nodes in it are connected as seeds
+func (this *P2PServer) loadRoutingTable() {
+	path := config.DefConfig.P2PNode.NodeTablePath
+	if path == "" || !comm.FileExisted(path) {
+		return
+	}
+	table := this.network.GetRoutingTable()
+	err := table.Load(path)
+	if err != nil {
+		log.Warnf("[p2p]load routing table %s fail:%s", path, err)
+		return
+	}
+	log.Infof("[p2p]load %d nodes from routing table", table.Len())
+}
+
+//saveRoutingTable persist the routing table
+func (this *P2PServer) saveRoutingTable() {
+	path := config.DefConfig.P2PNode.NodeTablePath
+	if path == "" {
+		return
+	}
+	err := this.network.GetRoutingTable().Save(path)
+	if err != nil {
+		log.Warnf("[p2p]save routing table %s fail:%s", path, err)
+	}
+}
+
+//discoveryService lookup nodes and connect the closest ones periodically, quickly before enough peers to query
+func (this *P2PServer) discoveryService() {
+	t := time.NewTimer(time.Second * common.CONN_MONITOR)
+	for round := 0; ; round++ {
+		select {
+		case <-t.C:
+			this.discover(round)
+			t.Stop()
+			if this.GetConnectionCnt() < dht.ALPHA {
+				t.Reset(time.Second * common.CONN_MONITOR)
+			} else {
+				t.Reset(time.Second * common.DISCOVERY_INTERVAL)
+			}
+		case <-this.quitDiscovery:
+			t.Stop()
+			this.saveRoutingTable()
+			return
+		}
+	}
+}
+
+//discover refresh the liveness of established peers, query the peers closest to target for closer nodes and
+//connect the closest nodes found by previous queries. The lookup of self finds the neighborhood, and the
+//lookups of random ids refresh the far buckets
+func (this *P2PServer) discover(round int) {
+	table := this.network.GetRoutingTable()
+	target := this.GetID()
+	if round%2 == 1 {
+		target = table.RandomTarget(rand.Intn(dht.BUCKET_COUNT))
+	}
+
+	peers := make([]*peer.Peer, 0)
+	for _, p := range this.network.GetNeighbors() {
+		if p.GetState() != common.ESTABLISH {
+			continue
+		}
+		if node, ok := table.Get(p.GetID()); ok {
+			table.Seen(p.GetID(), node.Addr)
+		}
+		if p.GetVersion() >= common.DISCOVERY_MIN_VERSION {
+			peers = append(peers, p)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return dht.Distance(peers[i].GetID(), target) < dht.Distance(peers[j].GetID(), target)
+	})
+	if len(peers) > dht.ALPHA {
+		peers = peers[:dht.ALPHA]
+	}
+	for _, p := range peers {
+		table.FindNodeSent(p.GetID(), target)
+		go this.Send(p, msgpack.NewFindNode(target), false)
+	}
+
+	this.connectClosestNodes(target)
+	this.saveRoutingTable()
+}
+
+//connectClosestNodes connect at most ALPHA nodes closest to target, the failed nodes are removed from routing
+//table after continuous failures
+func (this *P2PServer) connectClosestNodes(target uint64) {
+	if uint(this.network.GetOutConnRecordLen()) >= config.DefConfig.P2PNode.MaxConnOutBound {
+		return
+	}
+	table := this.network.GetRoutingTable()
+	count := 0
+	for _, node := range table.Candidates(target, dht.BUCKET_SIZE) {
+		if count >= dht.ALPHA {
+			break
+		}
+		if this.network.NodeEstablished(node.ID) || this.network.GetPeerFromAddr(node.Addr) != nil ||
+			this.network.IsAddrFromConnecting(node.Addr) || this.network.IsOwnAddress(node.Addr) {
+			continue
+		}
+		count++
+		log.Debugf("[p2p]connect node %d found by discovery:%s", node.ID, node.Addr)
+		go func(node dht.Node) {
+			if err := this.network.Connect(node.Addr); err != nil {
+				table.Failed(node.ID)
+			}
+		}(node)
+	}
+}
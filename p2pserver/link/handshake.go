@@ -0,0 +1,224 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	HANDSHAKE_TIMEOUT = 10        //timeout of authenticated handshake in sec
+	MAX_FRAME_LEN     = 64 * 1024 //the maximum plain text length of an encrypted frame
+	HELLO_LEN         = 4 + ed25519.PublicKeySize + 32
+)
+
+var handshakeLabel = []byte("tesra p2p handshake")
+
+//Handshake run the authenticated key exchange with remote node over conn. Both nodes send their node public keys and
+//ephemeral curve25519 keys, derive session keys from the shared secret, and sign the network magic with both node
+//keys and ephemeral keys over the encrypted session. Return the encrypted conn and the authenticated public key of remote node
+func Handshake(conn net.Conn, key *common.NodeKey, magic uint32) (net.Conn, ed25519.PublicKey, error) {
+	conn.SetDeadline(time.Now().Add(HANDSHAKE_TIMEOUT * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	var ephPriv, ephPub, remoteEph, shared [32]byte
+	if _, err := io.ReadFull(rand.Reader, ephPriv[:]); err != nil {
+		return nil, nil, err
+	}
+	curve25519.ScalarBaseMult(&ephPub, &ephPriv)
+
+	hello := make([]byte, HELLO_LEN)
+	binary.LittleEndian.PutUint32(hello, magic)
+	copy(hello[4:], key.PublicKey)
+	copy(hello[4+ed25519.PublicKeySize:], ephPub[:])
+	if _, err := conn.Write(hello); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		return nil, nil, err
+	}
+	if binary.LittleEndian.Uint32(hello) != magic {
+		return nil, nil, fmt.Errorf("network magic %d mismatch", binary.LittleEndian.Uint32(hello))
+	}
+	remoteKey := ed25519.PublicKey(append([]byte{}, hello[4:4+ed25519.PublicKeySize]...))
+	copy(remoteEph[:], hello[4+ed25519.PublicKeySize:])
+
+	curve25519.ScalarMult(&shared, &ephPriv, &remoteEph)
+	if shared == [32]byte{} {
+		return nil, nil, errors.New("invalid ephemeral key")
+	}
+	secure, err := newSecureConn(conn, deriveKey(shared, ephPub, remoteEph), deriveKey(shared, remoteEph, ephPub))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sig := ed25519.Sign(key.PrivateKey, handshakeTranscript(magic, key.PublicKey, ephPub, remoteKey, remoteEph))
+	if _, err := secure.Write(sig); err != nil {
+		return nil, nil, err
+	}
+	remoteSig := make([]byte, ed25519.SignatureSize)
+	if _, err := io.ReadFull(secure, remoteSig); err != nil {
+		return nil, nil, err
+	}
+	if !ed25519.Verify(remoteKey, handshakeTranscript(magic, remoteKey, remoteEph, key.PublicKey, ephPub),
+		remoteSig) {
+		return nil, nil, errors.New("invalid handshake signature")
+	}
+	return secure, remoteKey, nil
+}
+
+func deriveKey(shared, from, to [32]byte) []byte {
+	hasher := sha256.New()
+	hasher.Write(shared[:])
+	hasher.Write(from[:])
+	hasher.Write(to[:])
+	return hasher.Sum(nil)
+}
+
+//handshakeTranscript return the data signed by node key, binding the network magic, and the node keys and ephemeral
+//keys of signer and the other side
+func handshakeTranscript(magic uint32, signerKey ed25519.PublicKey, signerEph [32]byte, otherKey ed25519.PublicKey,
+	otherEph [32]byte) []byte {
+	data := make([]byte, len(handshakeLabel)+4, len(handshakeLabel)+4+2*(ed25519.PublicKeySize+32))
+	copy(data, handshakeLabel)
+	binary.LittleEndian.PutUint32(data[len(handshakeLabel):], magic)
+	data = append(data, signerKey...)
+	data = append(data, signerEph[:]...)
+	data = append(data, otherKey...)
+	return append(data, otherEph[:]...)
+}
+
+//secureConn encrypt data sent over conn in frames of AES-GCM, each direction has its own key and nonce counter
+type secureConn struct {
+	net.Conn
+	sendKey   []byte
+	recvKey   []byte
+	sendLock  sync.Mutex
+	sendAEAD  cipher.AEAD
+	sendNonce uint64
+	recvAEAD  cipher.AEAD
+	recvNonce uint64
+	recvBuf   []byte
+}
+
+func newSecureConn(conn net.Conn, sendKey, recvKey []byte) (*secureConn, error) {
+	sendAEAD, err := newAEAD(sendKey)
+	if err != nil {
+		return nil, err
+	}
+	recvAEAD, err := newAEAD(recvKey)
+	if err != nil {
+		return nil, err
+	}
+	return &secureConn{Conn: conn, sendKey: sendKey, recvKey: recvKey, sendAEAD: sendAEAD, recvAEAD: recvAEAD}, nil
+}
+
+//secureStream encrypt the stream of multiplexed connection if its control stream is encrypted by handshake. Each
+//stream has its own keys derived from the session keys and stream class
+func secureStream(control net.Conn, stream net.Conn, class StreamClass) (net.Conn, error) {
+	secure, ok := control.(*secureConn)
+	if !ok {
+		return stream, nil
+	}
+	return newSecureConn(stream, streamKey(secure.sendKey, class), streamKey(secure.recvKey, class))
+}
+
+func streamKey(key []byte, class StreamClass) []byte {
+	hasher := sha256.New()
+	hasher.Write(key)
+	hasher.Write([]byte{byte(class)})
+	return hasher.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func frameNonce(aead cipher.AEAD, counter uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], counter)
+	return nonce
+}
+
+//Write encrypt data in frames of length prefixed cipher text
+func (this *secureConn) Write(data []byte) (int, error) {
+	this.sendLock.Lock()
+	defer this.sendLock.Unlock()
+	written := 0
+	for written < len(data) {
+		end := written + MAX_FRAME_LEN
+		if end > len(data) {
+			end = len(data)
+		}
+		frame := make([]byte, 4, 4+end-written+this.sendAEAD.Overhead())
+		frame = this.sendAEAD.Seal(frame, frameNonce(this.sendAEAD, this.sendNonce), data[written:end], nil)
+		this.sendNonce++
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+		if _, err := this.Conn.Write(frame); err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+//Read decrypt the next frame if no decrypted data left
+func (this *secureConn) Read(data []byte) (int, error) {
+	if len(this.recvBuf) == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(this.Conn, header[:]); err != nil {
+			return 0, err
+		}
+		length := binary.BigEndian.Uint32(header[:])
+		if length > MAX_FRAME_LEN+uint32(this.recvAEAD.Overhead()) {
+			return 0, fmt.Errorf("encrypted frame length %d exceeds limit", length)
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(this.Conn, frame); err != nil {
+			return 0, err
+		}
+		plain, err := this.recvAEAD.Open(frame[:0], frameNonce(this.recvAEAD, this.recvNonce), frame, nil)
+		if err != nil {
+			return 0, err
+		}
+		this.recvNonce++
+		this.recvBuf = plain
+	}
+	n := copy(data, this.recvBuf)
+	this.recvBuf = this.recvBuf[n:]
+	return n, nil
+}
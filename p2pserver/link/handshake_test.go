@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestHandshake(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	cliKey, _ := common.NewNodeKey()
+	serKey, _ := common.NewNodeKey()
+
+	type result struct {
+		conn   net.Conn
+		pubKey []byte
+		err    error
+	}
+	serResult := make(chan *result, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serResult <- &result{err: err}
+			return
+		}
+		secure, pubKey, err := Handshake(conn, serKey, 1)
+		serResult <- &result{conn: secure, pubKey: pubKey, err: err}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	cliConn, pubKey, err := Handshake(conn, cliKey, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(serKey.PublicKey), []byte(pubKey))
+	ser := <-serResult
+	assert.Nil(t, ser.err)
+	assert.Equal(t, []byte(cliKey.PublicKey), ser.pubKey)
+
+	data := bytes.Repeat([]byte{1, 2, 3}, MAX_FRAME_LEN)
+	go cliConn.Write(data)
+	buf := make([]byte, len(data))
+	_, err = io.ReadFull(ser.conn, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, data, buf)
+	cliConn.Close()
+	ser.conn.Close()
+}
+
+func TestHandshakeMagicMismatch(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	cliKey, _ := common.NewNodeKey()
+	serKey, _ := common.NewNodeKey()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			Handshake(conn, serKey, 2)
+			conn.Close()
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, _, err = Handshake(conn, cliKey, 1)
+	assert.NotNil(t, err)
+}
+
+func TestHandshakeTranscript(t *testing.T) {
+	key, _ := common.NewNodeKey()
+	other, _ := common.NewNodeKey()
+	relay, _ := common.NewNodeKey()
+	eph, otherEph := [32]byte{1}, [32]byte{2}
+
+	sig := ed25519.Sign(key.PrivateKey, handshakeTranscript(1, key.PublicKey, eph, other.PublicKey, otherEph))
+	assert.True(t, ed25519.Verify(key.PublicKey,
+		handshakeTranscript(1, key.PublicKey, eph, other.PublicKey, otherEph), sig))
+	//the signature can't be replayed to another node or network
+	assert.False(t, ed25519.Verify(key.PublicKey,
+		handshakeTranscript(1, key.PublicKey, eph, relay.PublicKey, otherEph), sig))
+	assert.False(t, ed25519.Verify(key.PublicKey,
+		handshakeTranscript(2, key.PublicKey, eph, other.PublicKey, otherEph), sig))
+}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	comm "github.com/TesraSupernet/Tesra/common"
@@ -40,11 +41,25 @@ type Link struct {
 	time      time.Time              // The latest time the node activity
 	recvChan  chan *types.MsgPayload //msgpayload channel
 	reqRecord map[string]int64       //Map RequestId to Timestamp, using for rejecting duplicate request in specific time
+	pubKey    []byte                 //Authenticated node public key of the peer, nil if not authenticated
+	reqLock   sync.Mutex             //lock of reqRecord, which is accessed by the reading goroutine of each stream
+	timeLock  sync.RWMutex           //lock of time, which is updated by the reading goroutine of each stream
+
+	streams     StreamConn                 //multiplexed connection, nil if the transport has no streams
+	streamLock  sync.Mutex                 //lock of sendStreams and streamError
+	sendStreams [STREAM_CLASS_NUM]net.Conn //streams opened to send messages of each class
+	streamError error                      //malformed message error of a stream other than control
+
+	inbound     bool                          //whether the link is accepted from the peer
+	connTime    time.Time                     //The time the link connected
+	traffic     map[string]*common.MsgTraffic //Map message type to the traffic with the peer
+	trafficLock sync.Mutex                    //lock of traffic
 }
 
 func NewLink() *Link {
 	link := &Link{
 		reqRecord: make(map[string]int64, 0),
+		traffic:   make(map[string]*common.MsgTraffic),
 	}
 	return link
 }
@@ -59,6 +74,16 @@ func (this *Link) GetID() uint64 {
 	return this.id
 }
 
+//SetPublicKey set the authenticated node public key of peer
+func (this *Link) SetPublicKey(pubKey []byte) {
+	this.pubKey = pubKey
+}
+
+//GetPublicKey return the authenticated node public key of peer, nil if not authenticated
+func (this *Link) GetPublicKey() []byte {
+	return this.pubKey
+}
+
 //If there is connection return true
 func (this *Link) Valid() bool {
 	return this.conn != nil
@@ -97,15 +122,70 @@ func (this *Link) GetConn() net.Conn {
 //set connection
 func (this *Link) SetConn(conn net.Conn) {
 	this.conn = conn
+	this.connTime = time.Now()
+}
+
+//GetConnTime return the time the link connected
+func (this *Link) GetConnTime() time.Time {
+	return this.connTime
+}
+
+//SetInbound set whether the link is accepted from the peer
+func (this *Link) SetInbound(inbound bool) {
+	this.inbound = inbound
+}
+
+//IsInbound return whether the link is accepted from the peer, false if dialed to the peer
+func (this *Link) IsInbound() bool {
+	return this.inbound
+}
+
+//GetTraffic return the copy of traffic with the peer by message type
+func (this *Link) GetTraffic() map[string]common.MsgTraffic {
+	this.trafficLock.Lock()
+	defer this.trafficLock.Unlock()
+	traffic := make(map[string]common.MsgTraffic, len(this.traffic))
+	for cmdType, t := range this.traffic {
+		traffic[cmdType] = *t
+	}
+	return traffic
+}
+
+//addTraffic count a message of cmdType transferred in or out
+func (this *Link) addTraffic(cmdType string, in bool, size int) {
+	this.trafficLock.Lock()
+	defer this.trafficLock.Unlock()
+	t, ok := this.traffic[cmdType]
+	if !ok {
+		t = &common.MsgTraffic{}
+		this.traffic[cmdType] = t
+	}
+	if in {
+		t.MsgsIn++
+		t.BytesIn += uint64(size)
+	} else {
+		t.MsgsOut++
+		t.BytesOut += uint64(size)
+	}
+}
+
+//SetStreams set the multiplexed connection, whose control stream is the conn of link, and the other streams are
+//opened on demand for each class of messages
+func (this *Link) SetStreams(streams StreamConn) {
+	this.streams = streams
 }
 
 //record latest message time
 func (this *Link) UpdateRXTime(t time.Time) {
+	this.timeLock.Lock()
+	defer this.timeLock.Unlock()
 	this.time = t
 }
 
 //GetRXTime return the latest message time
 func (this *Link) GetRXTime() time.Time {
+	this.timeLock.RLock()
+	defer this.timeLock.RUnlock()
 	return this.time
 }
 
@@ -114,18 +194,61 @@ func (this *Link) Rx() {
 	if conn == nil {
 		return
 	}
+	if this.streams != nil {
+		go this.acceptStreams(this.streams, conn)
+	}
+
+	malformed := this.read(conn)
+	if malformed == nil {
+		this.streamLock.Lock()
+		malformed = this.streamError
+		this.streamLock.Unlock()
+	}
+	this.notifyDisconnect(malformed)
+}
+
+//acceptStreams read messages from the streams opened by remote node. A malformed message of any stream closes the
+//connection, which stops reading the control stream and notifies the disconnection
+func (this *Link) acceptStreams(streams StreamConn, control net.Conn) {
+	for {
+		class, stream, err := streams.AcceptStream()
+		if err != nil {
+			return
+		}
+		stream, err = secureStream(control, stream, class)
+		if err != nil {
+			log.Warnf("[p2p]secure stream of %s error:%s", this.GetAddr(), err)
+			streams.Close()
+			return
+		}
+		go func() {
+			if malformed := this.read(stream); malformed != nil {
+				this.streamLock.Lock()
+				this.streamError = malformed
+				this.streamLock.Unlock()
+				streams.Close()
+			}
+		}()
+	}
+}
 
+//read push messages read from conn to channel until error, return the error if a message is malformed
+func (this *Link) read(conn net.Conn) error {
 	reader := bufio.NewReaderSize(conn, common.MAX_BUF_LEN)
 
 	for {
 		msg, payloadSize, err := types.ReadMessage(reader)
 		if err != nil {
 			log.Infof("[p2p]error read from %s :%s", this.GetAddr(), err.Error())
-			break
+			if _, ok := err.(*types.MalformedMsgError); ok {
+				return err
+			}
+			return nil
 		}
 
 		t := time.Now()
 		this.UpdateRXTime(t)
+		this.addTraffic(msg.CmdType(), true, int(payloadSize)+common.MSG_HDR_LEN)
 
 		if !this.needSendMsg(msg) {
 			log.Debugf("skip handle msgType:%s from:%d", msg.CmdType(), this.id)
@@ -141,20 +264,22 @@ func (this *Link) Rx() {
 		}
 
 	}
-
-	this.disconnectNotify()
 }
 
 //disconnectNotify push disconnect msg to channel
 func (this *Link) disconnectNotify() {
+	this.notifyDisconnect(nil)
+}
+
+//notifyDisconnect push disconnect msg with the malformed message error if any to channel
+func (this *Link) notifyDisconnect(malformed error) {
 	log.Debugf("[p2p]call disconnectNotify for %s", this.GetAddr())
 	this.CloseConn()
 
-	msg, _ := types.MakeEmptyMessage(common.DISCONNECT_TYPE)
 	discMsg := &types.MsgPayload{
 		Id:      this.id,
 		Addr:    this.addr,
-		Payload: msg,
+		Payload: &types.Disconnected{Malformed: malformed},
 	}
 	this.recvChan <- discMsg
 }
@@ -171,14 +296,24 @@ func (this *Link) Send(msg types.Message) error {
 	sink := comm.NewZeroCopySink(nil)
 	types.WriteMessage(sink, msg)
 
-	return this.SendRaw(sink.Bytes())
+	return this.SendRaw(StreamOf(msg), sink.Bytes())
 }
 
-func (this *Link) SendRaw(rawPacket []byte) error {
+//SendRaw send the raw message over the stream of its class, or the conn if transport has no streams
+func (this *Link) SendRaw(class StreamClass, rawPacket []byte) error {
 	conn := this.conn
 	if conn == nil {
 		return errors.New("[p2p]tx link invalid")
 	}
+	if this.streams != nil && class != STREAM_CONTROL {
+		stream, err := this.sendStream(conn, class)
+		if err != nil {
+			log.Infof("[p2p]error opening stream to %s :%s", this.GetAddr(), err)
+			this.disconnectNotify()
+			return err
+		}
+		conn = stream
+	}
 
 	nByteCnt := len(rawPacket)
 	log.Tracef("[p2p]TX buf length: %d\n", nByteCnt)
@@ -194,10 +329,31 @@ func (this *Link) SendRaw(rawPacket []byte) error {
 		this.disconnectNotify()
 		return err
 	}
+	this.addTraffic(types.CmdTypeOf(rawPacket), false, nByteCnt)
 
 	return nil
 }
 
+//sendStream return the stream to send messages of class, which is opened on first use
+func (this *Link) sendStream(control net.Conn, class StreamClass) (net.Conn, error) {
+	this.streamLock.Lock()
+	defer this.streamLock.Unlock()
+	if stream := this.sendStreams[class]; stream != nil {
+		return stream, nil
+	}
+	stream, err := this.streams.OpenStream(class)
+	if err != nil {
+		return nil, err
+	}
+	secure, err := secureStream(control, stream, class)
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	this.sendStreams[class] = secure
+	return secure, nil
+}
+
 //needSendMsg check whether the msg is needed to push to channel
 func (this *Link) needSendMsg(msg types.Message) bool {
 	if msg.CmdType() != common.GET_DATA_TYPE {
@@ -207,6 +363,8 @@ func (this *Link) needSendMsg(msg types.Message) bool {
 	reqID := fmt.Sprintf("%x%s", dataReq.DataType, dataReq.Hash.ToHexString())
 	now := time.Now().Unix()
 
+	this.reqLock.Lock()
+	defer this.reqLock.Unlock()
 	if t, ok := this.reqRecord[reqID]; ok {
 		if int(now-t) < common.REQ_INTERVAL {
 			return false
@@ -221,6 +379,8 @@ func (this *Link) addReqRecord(msg types.Message) {
 		return
 	}
 	now := time.Now().Unix()
+	this.reqLock.Lock()
+	defer this.reqLock.Unlock()
 	if len(this.reqRecord) >= common.MAX_REQ_RECORD_SIZE-1 {
 		for id := range this.reqRecord {
 			t := this.reqRecord[id]
@@ -20,6 +20,7 @@ package link
 
 import (
 	"math/rand"
+	"net"
 	"testing"
 	"time"
 
@@ -196,3 +197,32 @@ func TestUnpackBufNode(t *testing.T) {
 	sink := comm.NewZeroCopySink(nil)
 	mt.WriteMessage(sink, msg)
 }
+
+func TestLinkTraffic(t *testing.T) {
+	local, remote := net.Pipe()
+	sender, receiver := NewLink(), NewLink()
+	sender.SetConn(local)
+	receiver.SetConn(remote)
+	recvChan := make(chan *mt.MsgPayload, 10)
+	receiver.SetChan(recvChan)
+	go receiver.Rx()
+	defer local.Close()
+
+	sink := comm.NewZeroCopySink(nil)
+	mt.WriteMessage(sink, mt.NewCompressed(&mt.Ping{Height: 1}))
+	for i := 0; i < 2; i++ {
+		if err := sender.SendRaw(STREAM_CONTROL, sink.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		<-recvChan
+	}
+
+	out := sender.GetTraffic()[common.PING_TYPE]
+	in := receiver.GetTraffic()[common.PING_TYPE]
+	if out.MsgsOut != 2 || out.BytesOut != uint64(2*sink.Size()) {
+		t.Errorf("unexpected traffic out %+v", out)
+	}
+	if in.MsgsIn != 2 || in.BytesIn != out.BytesOut {
+		t.Errorf("unexpected traffic in %+v", in)
+	}
+}
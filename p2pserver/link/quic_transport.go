@@ -0,0 +1,273 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/quic-go/quic-go"
+)
+
+//quicTransport connect links by quic, each class of messages is sent over its own stream of the connection
+type quicTransport struct {
+	clientConf *tls.Config
+	serverConf *tls.Config
+	quicConf   *quic.Config
+}
+
+//NewQUICTransport return the quic transport. Nodes are verified by the tls configs if not nil, otherwise connections
+//are encrypted by a temporary self signed certificate, and nodes are left to be authenticated by node keys
+func NewQUICTransport(clientConf, serverConf *tls.Config) (Transport, error) {
+	if clientConf == nil || serverConf == nil {
+		cert, err := selfSignedCert()
+		if err != nil {
+			return nil, fmt.Errorf("generate quic certificate error:%s", err)
+		}
+		clientConf = &tls.Config{InsecureSkipVerify: true}
+		serverConf = &tls.Config{Certificates: []tls.Certificate{cert}}
+	} else {
+		clientConf = clientConf.Clone()
+		serverConf = serverConf.Clone()
+	}
+	clientConf.NextProtos = []string{common.QUIC_ALPN}
+	serverConf.NextProtos = []string{common.QUIC_ALPN}
+
+	return &quicTransport{
+		clientConf: clientConf,
+		serverConf: serverConf,
+		quicConf: &quic.Config{
+			HandshakeIdleTimeout:  time.Second * common.DIAL_TIMEOUT,
+			MaxIdleTimeout:        time.Second * common.QUIC_IDLE_TIMEOUT,
+			KeepAlivePeriod:       time.Second * common.QUIC_KEEPALIVE,
+			MaxIncomingStreams:    int64(STREAM_CLASS_NUM),
+			MaxIncomingUniStreams: -1,
+		},
+	}, nil
+}
+
+func (this *quicTransport) Name() string {
+	return common.TRANSPORT_QUIC
+}
+
+//Dial connect the address and open the control stream
+func (this *quicTransport) Dial(addr string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*common.DIAL_TIMEOUT)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, addr, this.clientConf, this.quicConf)
+	if err != nil {
+		return nil, err
+	}
+	control, err := openStream(conn, STREAM_CONTROL)
+	if err != nil {
+		conn.CloseWithError(0, "")
+		return nil, err
+	}
+	return &quicConn{quicStream: control}, nil
+}
+
+//Listen accept connections by a transport owning the udp port, so closing the listener keeps accepted connections
+func (this *quicTransport) Listen(port uint16) (net.Listener, error) {
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: int(port)})
+	if err != nil {
+		return nil, err
+	}
+	transport := &quic.Transport{Conn: udpConn}
+	listener, err := transport.Listen(this.serverConf, this.quicConf)
+	if err != nil {
+		udpConn.Close()
+		return nil, err
+	}
+	l := &quicListener{
+		listener: listener,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go l.loop()
+	return l, nil
+}
+
+//quicListener accept quic connections whose control streams are opened
+type quicListener struct {
+	listener *quic.Listener
+	conns    chan net.Conn
+	done     chan struct{} //closed when the listener stopped
+	err      error         //the error stopped the listener
+}
+
+//loop accept quic connections until the listener is closed
+func (this *quicListener) loop() {
+	for {
+		conn, err := this.listener.Accept(context.Background())
+		if err != nil {
+			this.err = err
+			close(this.done)
+			return
+		}
+		go this.setup(conn)
+	}
+}
+
+//setup wait for the control stream of connection, so a slow node never blocks accepting the others
+func (this *quicListener) setup(conn quic.Connection) {
+	ctx, cancel := context.WithTimeout(conn.Context(), time.Second*common.STREAM_OPEN_TIMEOUT)
+	defer cancel()
+	class, control, err := acceptStream(ctx, conn)
+	if err == nil && class != STREAM_CONTROL {
+		err = fmt.Errorf("first stream of class %d", class)
+	}
+	if err != nil {
+		log.Debugf("[p2p]quic connection from %s refused:%s", conn.RemoteAddr(), err)
+		conn.CloseWithError(0, "")
+		return
+	}
+	select {
+	case this.conns <- &quicConn{quicStream: control}:
+	case <-this.done:
+		conn.CloseWithError(0, "")
+	}
+}
+
+func (this *quicListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-this.conns:
+		return conn, nil
+	case <-this.done:
+		return nil, this.err
+	}
+}
+
+func (this *quicListener) Close() error {
+	return this.listener.Close()
+}
+
+func (this *quicListener) Addr() net.Addr {
+	return this.listener.Addr()
+}
+
+//quicStream is a stream of quic connection as net.Conn
+type quicStream struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+func (this *quicStream) LocalAddr() net.Addr {
+	return this.conn.LocalAddr()
+}
+
+func (this *quicStream) RemoteAddr() net.Addr {
+	return this.conn.RemoteAddr()
+}
+
+//Close close both directions of the stream
+func (this *quicStream) Close() error {
+	this.Stream.CancelRead(0)
+	return this.Stream.Close()
+}
+
+//quicConn is the quic connection, read and written by its control stream
+type quicConn struct {
+	*quicStream
+}
+
+//Close close the connection with all its streams
+func (this *quicConn) Close() error {
+	return this.conn.CloseWithError(0, "")
+}
+
+func (this *quicConn) OpenStream(class StreamClass) (net.Conn, error) {
+	return openStream(this.conn, class)
+}
+
+func (this *quicConn) AcceptStream() (StreamClass, net.Conn, error) {
+	return acceptStream(this.conn.Context(), this.conn)
+}
+
+//openStream open a stream and write its class, which also makes the stream visible to remote node
+func openStream(conn quic.Connection, class StreamClass) (*quicStream, error) {
+	stream, err := conn.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	stream.SetWriteDeadline(time.Now().Add(time.Second * common.WRITE_DEADLINE))
+	if _, err := stream.Write([]byte{byte(class)}); err != nil {
+		stream.CancelWrite(0)
+		return nil, err
+	}
+	stream.SetWriteDeadline(time.Time{})
+	return &quicStream{Stream: stream, conn: conn}, nil
+}
+
+//acceptStream accept the next stream and read its class
+func acceptStream(ctx context.Context, conn quic.Connection) (StreamClass, *quicStream, error) {
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	var class [1]byte
+	stream.SetReadDeadline(time.Now().Add(time.Second * common.STREAM_OPEN_TIMEOUT))
+	if _, err := io.ReadFull(stream, class[:]); err != nil {
+		stream.CancelRead(0)
+		return 0, nil, err
+	}
+	stream.SetReadDeadline(time.Time{})
+	if StreamClass(class[0]) >= STREAM_CLASS_NUM {
+		stream.CancelRead(0)
+		return 0, nil, errors.New("unknown stream class")
+	}
+	return StreamClass(class[0]), &quicStream{Stream: stream, conn: conn}, nil
+}
+
+//selfSignedCert generate a temporary certificate of a new key
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: common.QUIC_ALPN},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//SendQueue write messages to connection in its own goroutine, so senders are never blocked by a slow connection.
+//Small messages such as votes are sent ahead of the large ones such as block proposals
+type SendQueue struct {
+	conn    net.Conn
+	high    chan []byte   //messages up to OVERLAY_SMALL_MSG_LEN
+	low     chan []byte   //larger messages
+	quit    chan struct{} //closed to stop the queue
+	once    sync.Once
+	onClose func()
+}
+
+//NewSendQueue return a send queue of conn, onClose is called once when the queue is closed
+func NewSendQueue(conn net.Conn, onClose func()) *SendQueue {
+	return &SendQueue{
+		conn:    conn,
+		high:    make(chan []byte, common.OVERLAY_QUEUE_SIZE),
+		low:     make(chan []byte, common.OVERLAY_QUEUE_SIZE),
+		quit:    make(chan struct{}),
+		onClose: onClose,
+	}
+}
+
+//Start start writing queued messages to connection
+func (this *SendQueue) Start() {
+	go this.loop()
+}
+
+//Push queue the raw message, return error if the queue is closed or full
+func (this *SendQueue) Push(rawPacket []byte) error {
+	queue := this.low
+	if len(rawPacket) <= common.OVERLAY_SMALL_MSG_LEN {
+		queue = this.high
+	}
+	select {
+	case <-this.quit:
+		return errors.New("[p2p]send queue closed")
+	default:
+	}
+	select {
+	case queue <- rawPacket:
+		return nil
+	default:
+		return errors.New("[p2p]send queue full")
+	}
+}
+
+//Close stop the queue and close connection
+func (this *SendQueue) Close() {
+	this.once.Do(func() {
+		close(this.quit)
+		this.conn.Close()
+		if this.onClose != nil {
+			this.onClose()
+		}
+	})
+}
+
+//loop write messages of high priority first until the queue is closed
+func (this *SendQueue) loop() {
+	for {
+		var rawPacket []byte
+		select {
+		case rawPacket = <-this.high:
+		default:
+			select {
+			case rawPacket = <-this.high:
+			case rawPacket = <-this.low:
+			case <-this.quit:
+				return
+			}
+		}
+		if err := this.write(rawPacket); err != nil {
+			log.Infof("[p2p]error sending messge to %s :%s", this.conn.RemoteAddr(), err)
+			this.Close()
+			return
+		}
+	}
+}
+
+//write write the raw message with the deadline of its length
+func (this *SendQueue) write(rawPacket []byte) error {
+	nCount := len(rawPacket) / common.PER_SEND_LEN
+	if nCount == 0 {
+		nCount = 1
+	}
+	this.conn.SetWriteDeadline(time.Now().Add(time.Duration(nCount*common.WRITE_DEADLINE) * time.Second))
+	_, err := this.conn.Write(rawPacket)
+	return err
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendQueuePriority(t *testing.T) {
+	local, remote := net.Pipe()
+	closed := make(chan struct{})
+	queue := NewSendQueue(local, func() {
+		close(closed)
+	})
+
+	proposal := bytes.Repeat([]byte{1}, common.OVERLAY_SMALL_MSG_LEN+1)
+	vote := []byte{2, 2, 2}
+	assert.Nil(t, queue.Push(proposal))
+	assert.Nil(t, queue.Push(vote))
+	queue.Start()
+
+	buf := make([]byte, len(vote))
+	_, err := io.ReadFull(remote, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, vote, buf)
+	buf = make([]byte, len(proposal))
+	_, err = io.ReadFull(remote, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, proposal, buf)
+
+	remote.Close()
+	assert.Nil(t, queue.Push(vote))
+	<-closed
+	assert.NotNil(t, queue.Push(vote))
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//tcpTransport connect links by tcp, or tls over tcp if configured
+type tcpTransport struct {
+	clientConf *tls.Config
+	serverConf *tls.Config
+}
+
+//NewTCPTransport return the tcp transport, which use tls if the configs are not nil
+func NewTCPTransport(clientConf, serverConf *tls.Config) Transport {
+	return &tcpTransport{clientConf: clientConf, serverConf: serverConf}
+}
+
+func (this *tcpTransport) Name() string {
+	return common.TRANSPORT_TCP
+}
+
+func (this *tcpTransport) Dial(addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: time.Second * common.DIAL_TIMEOUT}
+	if this.clientConf == nil {
+		return dialer.Dial("tcp", addr)
+	}
+	return tls.DialWithDialer(dialer, "tcp", addr, this.clientConf)
+}
+
+func (this *tcpTransport) Listen(port uint16) (net.Listener, error) {
+	addr := ":" + strconv.Itoa(int(port))
+	if this.serverConf == nil {
+		return net.Listen("tcp", addr)
+	}
+	return tls.Listen("tcp", addr, this.serverConf)
+}
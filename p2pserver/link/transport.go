@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
+)
+
+//StreamClass is the class of messages, each class has its own stream over multiplexed transports
+type StreamClass byte
+
+const (
+	STREAM_CONTROL   StreamClass = iota //handshakes, pings, addresses and messages of no other class
+	STREAM_CONSENSUS                    //consensus messages
+	STREAM_BLOCK                        //block sync and relay
+	STREAM_TX                           //transaction gossip
+	STREAM_CLASS_NUM
+)
+
+//Transport dial and listen the connections of links
+type Transport interface {
+	//Name return the transport name in config
+	Name() string
+	//Dial connect the address
+	Dial(addr string) (net.Conn, error)
+	//Listen listen on the port of all interfaces
+	Listen(port uint16) (net.Listener, error)
+}
+
+//StreamConn is the connection of multiplexed transports. The conn itself is the control stream, messages of the other
+//classes are sent over the streams opened on demand, so they are never blocked by each other
+type StreamConn interface {
+	net.Conn
+	//OpenStream open a stream to send messages of the class
+	OpenStream(class StreamClass) (net.Conn, error)
+	//AcceptStream wait for the next stream opened by remote node
+	AcceptStream() (StreamClass, net.Conn, error)
+}
+
+//CheckTransport return error if the transport name is unknown
+func CheckTransport(name string) error {
+	switch name {
+	case common.TRANSPORT_TCP, common.TRANSPORT_QUIC:
+		return nil
+	}
+	return fmt.Errorf("unknown transport %s, expect %s or %s", name, common.TRANSPORT_TCP, common.TRANSPORT_QUIC)
+}
+
+//NewTransport return the transport of name. Connections are encrypted by tls if the configs are not nil, quic
+//transport always encrypts its connections, by a temporary self signed certificate without config
+func NewTransport(name string, clientConf, serverConf *tls.Config) (Transport, error) {
+	switch name {
+	case common.TRANSPORT_TCP:
+		return NewTCPTransport(clientConf, serverConf), nil
+	case common.TRANSPORT_QUIC:
+		return NewQUICTransport(clientConf, serverConf)
+	}
+	return nil, CheckTransport(name)
+}
+
+//StreamOf return the class of message. Inventories and data requests follow the class of their data, so fetching
+//blocks is not delayed by transaction gossip
+func StreamOf(msg types.Message) StreamClass {
+	switch msg.CmdType() {
+	case common.CONSENSUS_TYPE:
+		return STREAM_CONSENSUS
+	case common.TX_TYPE:
+		return STREAM_TX
+	case common.GET_HEADERS_TYPE, common.HEADERS_TYPE, common.GET_BLOCKS_TYPE, common.BLOCK_TYPE,
+		common.CMPCT_BLOCK_TYPE, common.GET_SKEL_TYPE, common.SKELETON_TYPE, common.NOT_FOUND_TYPE,
+		common.GET_BLKTXN_TYPE, common.BLKTXN_TYPE:
+		return STREAM_BLOCK
+	case common.INV_TYPE:
+		if inv, ok := msg.(*types.Inv); ok {
+			return streamOfInventory(inv.P.InvType)
+		}
+	case common.GET_DATA_TYPE:
+		if req, ok := msg.(*types.DataReq); ok {
+			return streamOfInventory(req.DataType)
+		}
+	}
+	return STREAM_CONTROL
+}
+
+func streamOfInventory(invType comm.InventoryType) StreamClass {
+	switch invType {
+	case comm.TRANSACTION:
+		return STREAM_TX
+	case comm.CONSENSUS:
+		return STREAM_CONSENSUS
+	}
+	return STREAM_BLOCK
+}
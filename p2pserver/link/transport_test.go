@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	mt "github.com/TesraSupernet/Tesra/p2pserver/message/types"
+	"github.com/stretchr/testify/assert"
+)
+
+//transportPipe return both ends of a connection of transport
+func transportPipe(t *testing.T, transport Transport) (net.Conn, net.Conn) {
+	listener, err := transport.Listen(0)
+	assert.Nil(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	port := listener.Addr().(*net.UDPAddr).Port
+	conn, err := transport.Dial("127.0.0.1:" + strconv.Itoa(port))
+	assert.Nil(t, err)
+	return conn, <-accepted
+}
+
+func TestStreamOf(t *testing.T) {
+	assert.Equal(t, STREAM_CONTROL, StreamOf(&mt.Ping{}))
+	assert.Equal(t, STREAM_CONSENSUS, StreamOf(&mt.Consensus{}))
+	assert.Equal(t, STREAM_TX, StreamOf(&mt.Trn{}))
+	assert.Equal(t, STREAM_BLOCK, StreamOf(&mt.BlkHeader{}))
+	assert.Equal(t, STREAM_TX, StreamOf(&mt.Inv{P: mt.InvPayload{InvType: comm.TRANSACTION}}))
+	assert.Equal(t, STREAM_BLOCK, StreamOf(&mt.Inv{P: mt.InvPayload{InvType: comm.BLOCK}}))
+	assert.Equal(t, STREAM_TX, StreamOf(&mt.DataReq{DataType: comm.TRANSACTION}))
+	assert.Equal(t, STREAM_BLOCK, StreamOf(&mt.DataReq{DataType: comm.COMPACT_BLOCK}))
+}
+
+func TestQUICLinkStreams(t *testing.T) {
+	transport, err := NewQUICTransport(nil, nil)
+	assert.Nil(t, err)
+	cliConn, srvConn := transportPipe(t, transport)
+
+	cliKey, _ := common.NewNodeKey()
+	srvKey, _ := common.NewNodeKey()
+	done := make(chan net.Conn, 1)
+	go func() {
+		secure, _, err := Handshake(srvConn, srvKey, 1)
+		assert.Nil(t, err)
+		done <- secure
+	}()
+	cliSecure, _, err := Handshake(cliConn, cliKey, 1)
+	assert.Nil(t, err)
+	srvSecure := <-done
+
+	recv := make(chan *mt.MsgPayload, 10)
+	cli, srv := NewLink(), NewLink()
+	cli.SetConn(cliSecure)
+	cli.SetStreams(cliConn.(StreamConn))
+	cli.SetChan(make(chan *mt.MsgPayload, 10))
+	srv.SetConn(srvSecure)
+	srv.SetStreams(srvConn.(StreamConn))
+	srv.SetChan(recv)
+	go cli.Rx()
+	go srv.Rx()
+
+	msgs := []mt.Message{
+		&mt.Ping{Height: 1},
+		&mt.NotFound{Hash: comm.UINT256_EMPTY},
+		&mt.Inv{P: mt.InvPayload{InvType: comm.TRANSACTION, Blk: []comm.Uint256{comm.UINT256_EMPTY}}},
+	}
+	for _, msg := range msgs {
+		assert.Nil(t, cli.Send(msg))
+	}
+	received := make(map[string]bool)
+	for range msgs {
+		select {
+		case payload := <-recv:
+			received[payload.Payload.CmdType()] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("message not received over quic streams")
+		}
+	}
+	assert.Equal(t, map[string]bool{common.PING_TYPE: true, common.NOT_FOUND_TYPE: true, common.INV_TYPE: true},
+		received)
+	assert.NotNil(t, cli.sendStreams[STREAM_BLOCK])
+	assert.NotNil(t, cli.sendStreams[STREAM_TX])
+	assert.Nil(t, cli.sendStreams[STREAM_CONSENSUS])
+
+	cliConn.Close()
+	select {
+	case payload := <-recv:
+		assert.Equal(t, common.DISCONNECT_TYPE, payload.Payload.CmdType())
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnection not notified")
+	}
+}
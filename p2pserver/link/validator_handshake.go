@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+const MAX_VALIDATOR_HELLO_LEN = 1024 //the maximum length of validator hello
+
+var validatorLabel = []byte("tesra consensus overlay")
+
+//ValidatorHandshake prove the validator identities of both nodes over the session authenticated by Handshake. Each
+//node signs both node public keys with its validator key, so the proof can't be replayed over another session. The
+//dialer proves first, and the acceptor sends its proof only after accept passes the validator of dialer, so nodes
+//not validators can't collect the proofs of validators by connecting them. Return the validator public key of remote
+//node
+func ValidatorHandshake(conn net.Conn, signer common.ValidatorSigner, localKey, remoteKey []byte, dialer bool,
+	accept func(keypair.PublicKey) error) (keypair.PublicKey, error) {
+	conn.SetDeadline(time.Now().Add(HANDSHAKE_TIMEOUT * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	if dialer {
+		if err := writeValidatorHello(conn, signer, localKey, remoteKey); err != nil {
+			return nil, err
+		}
+	}
+	pubKey, err := readValidatorHello(conn, localKey, remoteKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := accept(pubKey); err != nil {
+		return nil, err
+	}
+	if !dialer {
+		if err := writeValidatorHello(conn, signer, localKey, remoteKey); err != nil {
+			return nil, err
+		}
+	}
+	return pubKey, nil
+}
+
+func writeValidatorHello(conn net.Conn, signer common.ValidatorSigner, localKey, remoteKey []byte) error {
+	sig, err := signer.Sign(validatorTranscript(localKey, remoteKey))
+	if err != nil {
+		return fmt.Errorf("sign validator hello:%s", err)
+	}
+	sink := comm.NewZeroCopySink(nil)
+	sink.WriteVarBytes(keypair.SerializePublicKey(signer.PublicKey()))
+	sink.WriteVarBytes(sig)
+	hello := make([]byte, 2, 2+len(sink.Bytes()))
+	binary.LittleEndian.PutUint16(hello, uint16(len(sink.Bytes())))
+	_, err = conn.Write(append(hello, sink.Bytes()...))
+	return err
+}
+
+func readValidatorHello(conn net.Conn, localKey, remoteKey []byte) (keypair.PublicKey, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint16(header[:])
+	if length > MAX_VALIDATOR_HELLO_LEN {
+		return nil, fmt.Errorf("validator hello length %d exceeds limit", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	source := comm.NewZeroCopySource(buf)
+	pkBuf, _, irregular, eof := source.NextVarBytes()
+	remoteSig, _, irr, eof2 := source.NextVarBytes()
+	if irregular || irr || eof || eof2 {
+		return nil, errors.New("invalid validator hello")
+	}
+	pubKey, err := keypair.DeserializePublicKey(pkBuf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validator public key:%s", err)
+	}
+	if err := signature.Verify(pubKey, validatorTranscript(remoteKey, localKey), remoteSig); err != nil {
+		return nil, fmt.Errorf("invalid validator hello signature:%s", err)
+	}
+	return pubKey, nil
+}
+
+//validatorTranscript return the data signed by validator, binding the node keys of signer and the other side
+func validatorTranscript(signer, other []byte) []byte {
+	data := make([]byte, 0, len(validatorLabel)+len(signer)+len(other))
+	data = append(data, validatorLabel...)
+	data = append(data, signer...)
+	return append(data, other...)
+}
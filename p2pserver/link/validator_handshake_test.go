@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	"github.com/TesraSupernet/Tesra/account"
+	"github.com/TesraSupernet/Tesra/core/signature"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSigner struct {
+	acc *account.Account
+}
+
+func (self *testSigner) PublicKey() keypair.PublicKey {
+	return self.acc.PublicKey
+}
+
+func (self *testSigner) Sign(data []byte) ([]byte, error) {
+	return signature.Sign(self.acc, data)
+}
+
+//tcpPipe return both ends of a tcp connection, which buffers the hello written before reading
+func tcpPipe(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	return conn, <-accepted
+}
+
+func acceptAny(keypair.PublicKey) error {
+	return nil
+}
+
+func TestValidatorHandshake(t *testing.T) {
+	cliKey, _ := common.NewNodeKey()
+	serKey, _ := common.NewNodeKey()
+	cliSigner := &testSigner{acc: account.NewAccount("")}
+	serSigner := &testSigner{acc: account.NewAccount("")}
+
+	cliConn, serConn := tcpPipe(t)
+	defer cliConn.Close()
+	defer serConn.Close()
+	errs := make(chan error, 1)
+	go func() {
+		pubKey, err := ValidatorHandshake(serConn, serSigner, serKey.PublicKey, cliKey.PublicKey, false, acceptAny)
+		if err == nil {
+			assert.Equal(t, keypair.SerializePublicKey(cliSigner.PublicKey()), keypair.SerializePublicKey(pubKey))
+		}
+		errs <- err
+	}()
+	pubKey, err := ValidatorHandshake(cliConn, cliSigner, cliKey.PublicKey, serKey.PublicKey, true, acceptAny)
+	assert.Nil(t, err)
+	assert.Equal(t, keypair.SerializePublicKey(serSigner.PublicKey()), keypair.SerializePublicKey(pubKey))
+	assert.Nil(t, <-errs)
+
+	//the proof signed for another node key is refused
+	otherKey, _ := common.NewNodeKey()
+	cliConn2, serConn2 := tcpPipe(t)
+	defer cliConn2.Close()
+	defer serConn2.Close()
+	go func() {
+		_, err := ValidatorHandshake(serConn2, serSigner, serKey.PublicKey, cliKey.PublicKey, false, acceptAny)
+		serConn2.Close()
+		errs <- err
+	}()
+	_, err = ValidatorHandshake(cliConn2, cliSigner, otherKey.PublicKey, serKey.PublicKey, true, acceptAny)
+	assert.NotNil(t, err)
+	assert.NotNil(t, <-errs)
+
+	//the acceptor never sends its proof to a dialer not accepted
+	cliConn3, serConn3 := tcpPipe(t)
+	defer cliConn3.Close()
+	defer serConn3.Close()
+	go func() {
+		_, err := ValidatorHandshake(serConn3, serSigner, serKey.PublicKey, cliKey.PublicKey, false,
+			func(keypair.PublicKey) error { return errors.New("not a validator") })
+		serConn3.Close()
+		errs <- err
+	}()
+	_, err = ValidatorHandshake(cliConn3, cliSigner, cliKey.PublicKey, serKey.PublicKey, true, acceptAny)
+	assert.NotNil(t, err)
+	assert.NotNil(t, <-errs)
+}
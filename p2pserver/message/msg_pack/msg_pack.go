@@ -19,6 +19,8 @@
 package msgpack
 
 import (
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/TesraSupernet/Tesra/common"
@@ -39,6 +41,25 @@ func NewAddrs(nodeAddrs []msgCommon.PeerAddr) mt.Message {
 	return &addr
 }
 
+//Find node request package
+func NewFindNode(target uint64) mt.Message {
+	log.Trace()
+	var msg mt.FindNode
+	msg.Target = target
+
+	return &msg
+}
+
+//Nodes closest to target package
+func NewNeighbors(target uint64, nodeAddrs []msgCommon.PeerAddr) mt.Message {
+	log.Trace()
+	var msg mt.Neighbors
+	msg.Target = target
+	msg.NodeAddrs = nodeAddrs
+
+	return &msg
+}
+
 //Peer address request package
 func NewAddrReq() mt.Message {
 	log.Trace()
@@ -56,6 +77,20 @@ func NewBlock(bk *ct.Block, merkleRoot common.Uint256) mt.Message {
 	return &blk
 }
 
+//compact block package
+func NewCompactBlock(bk *ct.Block, merkleRoot common.Uint256) mt.Message {
+	log.Trace()
+	var cmpct mt.CompactBlock
+	cmpct.Header = bk.Header
+	cmpct.ShortIDs = make([]uint64, 0, len(bk.Transactions))
+	for _, txn := range bk.Transactions {
+		cmpct.ShortIDs = append(cmpct.ShortIDs, mt.ShortTxID(txn.Hash()))
+	}
+	cmpct.MerkleRoot = merkleRoot
+
+	return &cmpct
+}
+
 //blk hdr package
 func NewHeaders(headers []*ct.RawHeader) mt.Message {
 	log.Trace()
@@ -75,6 +110,35 @@ func NewHeadersReq(curHdrHash common.Uint256) mt.Message {
 	return &h
 }
 
+//blk hdr req package of the hdrs after start hash up to stop hash
+func NewHeadersRangeReq(startHash, stopHash common.Uint256) mt.Message {
+	log.Trace()
+	var h mt.HeadersReq
+	h.Len = 1
+	h.HashStart = stopHash
+	h.HashEnd = startHash
+
+	return &h
+}
+
+//skeleton req package
+func NewSkeletonReq(heights []uint32) mt.Message {
+	log.Trace()
+	var req mt.SkeletonReq
+	req.Heights = heights
+
+	return &req
+}
+
+//skeleton package
+func NewSkeleton(headers []*ct.Header) mt.Message {
+	log.Trace()
+	var skeleton mt.Skeleton
+	skeleton.Headers = headers
+
+	return &skeleton
+}
+
 ////Consensus info package
 func NewConsensus(cp *mt.ConsensusPayload) mt.Message {
 	log.Trace()
@@ -112,6 +176,26 @@ func NewNotFound(hash common.Uint256) mt.Message {
 	return &notFound
 }
 
+//block txn req package
+func NewBlockTxnReq(hash common.Uint256, indexes []uint32) mt.Message {
+	log.Trace()
+	var req mt.BlockTxnReq
+	req.BlockHash = hash
+	req.Indexes = indexes
+
+	return &req
+}
+
+//block txn package
+func NewBlockTxn(hash common.Uint256, txs []*ct.Transaction) mt.Message {
+	log.Trace()
+	var blkTxn mt.BlockTxn
+	blkTxn.BlockHash = hash
+	blkTxn.Txs = txs
+
+	return &blkTxn
+}
+
 //ping msg package
 func NewPingMsg(height uint64) *mt.Ping {
 	log.Trace()
@@ -148,19 +232,28 @@ func NewVerAck() mt.Message {
 }
 
 //Version package
-func NewVersion(n p2pnet.P2P, height uint32) mt.Message {
+func NewVersion(n p2pnet.P2P, height uint32, remoteAddr string) mt.Message {
 	log.Trace()
 	var version mt.Version
 	version.P = mt.VersionPayload{
 		Version:      n.GetVersion(),
 		Services:     n.GetServices(),
 		SyncPort:     n.GetPort(),
+		ConsPort:     n.GetConsPort(),
 		Nonce:        n.GetID(),
 		IsConsensus:  false,
 		HttpInfoPort: n.GetHttpInfoPort(),
 		StartHeight:  uint64(height),
 		TimeStamp:    time.Now().UnixNano(),
 		SoftVersion:  config.Version,
+		PublicAddr:   n.GetPublicAddress(),
+		ObservedAddr: remoteAddr,
+	}
+	//legacy nodes take the sync port as the port to connect, which may be mapped to another on NAT gateway
+	if _, port, err := net.SplitHostPort(version.P.PublicAddr); err == nil {
+		if portNum, err := strconv.Atoi(port); err == nil {
+			version.P.SyncPort = uint16(portNum)
+		}
 	}
 
 	if n.GetRelay() {
@@ -173,6 +266,9 @@ func NewVersion(n p2pnet.P2P, height uint32) mt.Message {
 	} else {
 		version.P.Cap[msgCommon.HTTP_INFO_FLAG] = 0x00
 	}
+	if config.DefConfig.P2PNode.EnableCompression {
+		version.P.Cap[msgCommon.COMPRESSION_FLAG] = 0x01
+	}
 	return &version
 }
 
@@ -196,6 +292,16 @@ func NewBlkDataReq(hash common.Uint256) mt.Message {
 	return &dataReq
 }
 
+//compact block request package
+func NewCompactBlkDataReq(hash common.Uint256) mt.Message {
+	log.Trace()
+	var dataReq mt.DataReq
+	dataReq.DataType = common.COMPACT_BLOCK
+	dataReq.Hash = hash
+
+	return &dataReq
+}
+
 //consensus request package
 func NewConsensusDataReq(hash common.Uint256) mt.Message {
 	log.Trace()
@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//BlockTxnReq requests the transactions of compact block missing in txnpool by their indexes in block
+type BlockTxnReq struct {
+	BlockHash common.Uint256
+	Indexes   []uint32
+}
+
+//Serialize message payload
+func (this *BlockTxnReq) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteHash(this.BlockHash)
+	sink.WriteUint32(uint32(len(this.Indexes)))
+	for _, index := range this.Indexes {
+		sink.WriteUint32(index)
+	}
+}
+
+func (this *BlockTxnReq) CmdType() string {
+	return comm.GET_BLKTXN_TYPE
+}
+
+//Deserialize message payload
+func (this *BlockTxnReq) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	count, eof := source.NextUint32()
+	if eof || uint64(count)*4 > source.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	this.Indexes = make([]uint32, 0, count)
+	for i := uint32(0); i < count; i++ {
+		index, _ := source.NextUint32()
+		this.Indexes = append(this.Indexes, index)
+	}
+
+	return nil
+}
+
+//BlockTxn is the transactions requested by BlockTxnReq, in the order of the requested indexes
+type BlockTxn struct {
+	BlockHash common.Uint256
+	Txs       []*types.Transaction
+}
+
+//Serialize message payload
+func (this *BlockTxn) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteHash(this.BlockHash)
+	sink.WriteUint32(uint32(len(this.Txs)))
+	for _, tx := range this.Txs {
+		tx.Serialization(sink)
+	}
+}
+
+func (this *BlockTxn) CmdType() string {
+	return comm.BLKTXN_TYPE
+}
+
+//Deserialize message payload
+func (this *BlockTxn) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	count, eof := source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if uint64(count) > source.Len() {
+		return errors.New("transaction count exceeds payload")
+	}
+	for i := uint32(0); i < count; i++ {
+		tx := new(types.Transaction)
+		err := tx.Deserialization(source)
+		if err != nil {
+			return fmt.Errorf("read transaction error. err:%v", err)
+		}
+		this.Txs = append(this.Txs, tx)
+	}
+
+	return nil
+}
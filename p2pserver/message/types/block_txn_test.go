@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/core/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockTxn(t *testing.T) {
+	txs := make([]*types.Transaction, 0)
+	hashes := make([]common.Uint256, 0)
+	shortIDs := make([]uint64, 0)
+	for i := 0; i < 3; i++ {
+		mutable := utils.NewInvokeTransaction([]byte{byte(i)})
+		mutable.Nonce = uint32(i)
+		txn, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, txn)
+		hashes = append(hashes, txn.Hash())
+		shortIDs = append(shortIDs, ShortTxID(txn.Hash()))
+	}
+	cmpct := &CompactBlock{
+		Header:   &types.Header{Height: 1, TransactionsRoot: common.ComputeMerkleRoot(hashes)},
+		ShortIDs: shortIDs,
+	}
+	hash := cmpct.Header.Hash()
+
+	sink := common.NewZeroCopySink(nil)
+	WriteMessage(sink, &BlockTxnReq{BlockHash: hash, Indexes: []uint32{0, 2}})
+	demsg, _, err := ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+	req := demsg.(*BlockTxnReq)
+	assert.Equal(t, hash, req.BlockHash)
+	assert.Equal(t, []uint32{0, 2}, req.Indexes)
+
+	sink = common.NewZeroCopySink(nil)
+	WriteMessage(sink, &BlockTxn{BlockHash: hash, Txs: []*types.Transaction{txs[0], txs[2]}})
+	demsg, _, err = ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+	blkTxn := demsg.(*BlockTxn)
+	assert.Equal(t, hash, blkTxn.BlockHash)
+	assert.Equal(t, 2, len(blkTxn.Txs))
+
+	matched, missing, err := cmpct.MatchTransactions(map[uint64][]*types.Transaction{shortIDs[1]: {txs[1]}})
+	assert.Nil(t, err)
+	assert.Equal(t, req.Indexes, missing)
+	_, err = cmpct.Complete(matched, missing, blkTxn.Txs[:1])
+	assert.Equal(t, ErrMismatchedBlockTxn, err)
+	_, err = cmpct.Complete(matched, missing, []*types.Transaction{txs[2], txs[0]})
+	assert.Equal(t, ErrMismatchedBlockTxn, err)
+	block, err := cmpct.Complete(matched, missing, blkTxn.Txs)
+	assert.Nil(t, err)
+	assert.Equal(t, txs, block.Transactions)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+	tc "github.com/TesraSupernet/Tesra/txnpool/common"
+)
+
+//ErrMissingTransactions means some transactions of compact block are not in txnpool
+var ErrMissingTransactions = errors.New("missing transactions of compact block")
+
+//ErrShortIDCollision means the transactions of compact block can not be decided by short id
+var ErrShortIDCollision = errors.New("short id collides in compact block")
+
+//CompactBlock is a block with short ids of transactions instead of the transactions, peer reconstructs the
+//block from the transactions in its txnpool
+type CompactBlock struct {
+	Header     *types.Header
+	ShortIDs   []uint64
+	MerkleRoot common.Uint256
+}
+
+//ShortTxID return the short id of transaction in compact block
+func ShortTxID(hash common.Uint256) uint64 {
+	return tc.ShortTxID(hash)
+}
+
+//ErrMismatchedBlockTxn means the transactions replied for the missing ones of compact block are not the requested
+var ErrMismatchedBlockTxn = errors.New("mismatched transactions of compact block")
+
+//Reconstruct the block by the transactions indexed by short id. Short ids are not salted and may collide, so
+//the full block should be requested whenever it fails
+func (this *CompactBlock) Reconstruct(txs map[uint64][]*types.Transaction) (*types.Block, error) {
+	matched, missing, err := this.MatchTransactions(txs)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, ErrMissingTransactions
+	}
+	return this.Complete(matched, nil, nil)
+}
+
+//MatchTransactions return the transactions of block matched by short id, nil for the ones not in txs, and the
+//indexes of the missing ones to request by BlockTxnReq
+func (this *CompactBlock) MatchTransactions(txs map[uint64][]*types.Transaction) ([]*types.Transaction,
+	[]uint32, error) {
+	ids := make(map[uint64]bool, len(this.ShortIDs))
+	for _, id := range this.ShortIDs {
+		if ids[id] {
+			return nil, nil, ErrShortIDCollision
+		}
+		ids[id] = true
+	}
+	matched := make([]*types.Transaction, len(this.ShortIDs))
+	var missing []uint32
+	for i, id := range this.ShortIDs {
+		switch len(txs[id]) {
+		case 0:
+			missing = append(missing, uint32(i))
+		case 1:
+			matched[i] = txs[id][0]
+		default:
+			return nil, nil, ErrShortIDCollision
+		}
+	}
+	return matched, missing, nil
+}
+
+//Complete fill the transactions replied for the missing indexes into the matched ones and return the block
+func (this *CompactBlock) Complete(matched []*types.Transaction, missing []uint32,
+	txs []*types.Transaction) (*types.Block, error) {
+	if len(matched) != len(this.ShortIDs) || len(missing) != len(txs) {
+		return nil, ErrMismatchedBlockTxn
+	}
+	block := &types.Block{
+		Header:       this.Header,
+		Transactions: make([]*types.Transaction, len(matched)),
+	}
+	copy(block.Transactions, matched)
+	for i, index := range missing {
+		if index >= uint32(len(matched)) || ShortTxID(txs[i].Hash()) != this.ShortIDs[index] {
+			return nil, ErrMismatchedBlockTxn
+		}
+		block.Transactions[index] = txs[i]
+	}
+
+	hashes := make([]common.Uint256, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			return nil, ErrMissingTransactions
+		}
+		hashes = append(hashes, tx.Hash())
+	}
+	//short ids collide with the transactions not in txnpool
+	if common.ComputeMerkleRoot(hashes) != this.Header.TransactionsRoot {
+		return nil, errors.New("mismatched transaction root")
+	}
+	return block, nil
+}
+
+//Serialize message payload
+func (this *CompactBlock) Serialization(sink *common.ZeroCopySink) {
+	this.Header.Serialization(sink)
+	sink.WriteUint32(uint32(len(this.ShortIDs)))
+	for _, id := range this.ShortIDs {
+		sink.WriteUint64(id)
+	}
+	sink.WriteHash(this.MerkleRoot)
+}
+
+func (this *CompactBlock) CmdType() string {
+	return comm.CMPCT_BLOCK_TYPE
+}
+
+//Deserialize message payload
+func (this *CompactBlock) Deserialization(source *common.ZeroCopySource) error {
+	this.Header = new(types.Header)
+	err := this.Header.Deserialization(source)
+	if err != nil {
+		return fmt.Errorf("read header error. err:%v", err)
+	}
+
+	count, eof := source.NextUint32()
+	if eof || uint64(count)*8 > source.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	this.ShortIDs = make([]uint64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		id, _ := source.NextUint64()
+		this.ShortIDs = append(this.ShortIDs, id)
+	}
+	this.MerkleRoot, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/core/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactBlock(t *testing.T) {
+	txs := make([]*types.Transaction, 0)
+	hashes := make([]common.Uint256, 0)
+	shortIDs := make([]uint64, 0)
+	for i := 0; i < 3; i++ {
+		mutable := utils.NewInvokeTransaction([]byte{byte(i)})
+		mutable.Nonce = uint32(i)
+		txn, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, txn)
+		hashes = append(hashes, txn.Hash())
+		shortIDs = append(shortIDs, ShortTxID(txn.Hash()))
+	}
+	header := &types.Header{
+		Height:           1,
+		TransactionsRoot: common.ComputeMerkleRoot(hashes),
+	}
+	msg := &CompactBlock{
+		Header:     header,
+		ShortIDs:   shortIDs,
+		MerkleRoot: common.Uint256{1},
+	}
+
+	sink := common.NewZeroCopySink(nil)
+	WriteMessage(sink, msg)
+	demsg, _, err := ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+	cmpct := demsg.(*CompactBlock)
+	assert.Equal(t, header.Hash(), cmpct.Header.Hash())
+	assert.Equal(t, msg.ShortIDs, cmpct.ShortIDs)
+	assert.Equal(t, msg.MerkleRoot, cmpct.MerkleRoot)
+
+	pool := map[uint64][]*types.Transaction{shortIDs[0]: {txs[0]}, shortIDs[2]: {txs[2]}}
+	_, err = cmpct.Reconstruct(pool)
+	assert.Equal(t, ErrMissingTransactions, err)
+	pool[shortIDs[1]] = []*types.Transaction{txs[0]}
+	_, err = cmpct.Reconstruct(pool)
+	assert.NotNil(t, err)
+	pool[shortIDs[1]] = []*types.Transaction{txs[1]}
+	block, err := cmpct.Reconstruct(pool)
+	assert.Nil(t, err)
+	assert.Equal(t, txs, block.Transactions)
+
+	dup := &CompactBlock{
+		Header:   header,
+		ShortIDs: []uint64{shortIDs[0], shortIDs[0], shortIDs[1]},
+	}
+	_, err = dup.Reconstruct(pool)
+	assert.Equal(t, ErrShortIDCollision, err)
+}
+
+func TestCompactBlockCollision(t *testing.T) {
+	txs := make([]*types.Transaction, 0)
+	for i := 0; i < 2; i++ {
+		mutable := utils.NewInvokeTransaction([]byte{byte(i)})
+		mutable.Nonce = uint32(i)
+		txn, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, txn)
+	}
+	id := ShortTxID(txs[0].Hash())
+	cmpct := &CompactBlock{
+		Header: &types.Header{
+			Height:           1,
+			TransactionsRoot: common.ComputeMerkleRoot([]common.Uint256{txs[0].Hash()}),
+		},
+		ShortIDs: []uint64{id},
+	}
+	//the tx of block and another tx share the short id in txnpool
+	_, err := cmpct.Reconstruct(map[uint64][]*types.Transaction{id: {txs[1], txs[0]}})
+	assert.Equal(t, ErrShortIDCollision, err)
+	//only the colliding tx is in txnpool
+	_, err = cmpct.Reconstruct(map[uint64][]*types.Transaction{id: {txs[1]}})
+	assert.NotNil(t, err)
+	block, err := cmpct.Reconstruct(map[uint64][]*types.Transaction{id: {txs[0]}})
+	assert.Nil(t, err)
+	assert.Equal(t, txs[:1], block.Transactions)
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/golang/snappy"
+)
+
+//capabilities required by message types. Such messages are sent only to the peers advertising the cap flags in
+//version, so a new message type is introduced with its cap flag and never sent to the peers unaware of it
+var capabilities = map[string]int{
+	comm.COMPRESSED_TYPE: comm.COMPRESSION_FLAG,
+}
+
+//RequiredCapability return the cap flag required by the message type, false if all peers accept the type
+func RequiredCapability(cmdType string) (int, bool) {
+	flag, ok := capabilities[cmdType]
+	return flag, ok
+}
+
+//IsCompressible return whether the message type carries blocks or headers, whose payloads are worth compressing
+func IsCompressible(cmdType string) bool {
+	switch cmdType {
+	case comm.BLOCK_TYPE, comm.HEADERS_TYPE, comm.CMPCT_BLOCK_TYPE, comm.SKELETON_TYPE, comm.BLKTXN_TYPE:
+		return true
+	}
+	return false
+}
+
+//CmdTypeOf return the type of the serialized message, which is the type carried if the message is compressed
+func CmdTypeOf(raw []byte) string {
+	if len(raw) < comm.MSG_HDR_LEN {
+		return ""
+	}
+	cmdType := cmdTypeAt(raw, comm.CMD_OFFSET)
+	if cmdType == comm.COMPRESSED_TYPE && len(raw) >= comm.MSG_HDR_LEN+comm.MSG_CMD_LEN {
+		return cmdTypeAt(raw, comm.MSG_HDR_LEN)
+	}
+	return cmdType
+}
+
+func cmdTypeAt(raw []byte, offset int) string {
+	return string(bytes.TrimRight(raw[offset:offset+comm.MSG_CMD_LEN], "\x00"))
+}
+
+//Compressed carry another message with compressed payload. It's read as the message carried, so handlers never
+//see the compressed message
+type Compressed struct {
+	Algorithm byte
+	Msg       Message
+}
+
+//NewCompressed return the message compressed by snappy
+func NewCompressed(msg Message) *Compressed {
+	return &Compressed{Algorithm: comm.COMPRESS_SNAPPY, Msg: msg}
+}
+
+//Serialize message payload
+func (this *Compressed) Serialization(sink *common.ZeroCopySink) {
+	var cmd [comm.MSG_CMD_LEN]byte
+	copy(cmd[:], this.Msg.CmdType())
+	payload := common.NewZeroCopySink(nil)
+	this.Msg.Serialization(payload)
+
+	sink.WriteBytes(cmd[:])
+	sink.WriteByte(this.Algorithm)
+	sink.WriteBytes(snappy.Encode(nil, payload.Bytes()))
+}
+
+func (this *Compressed) CmdType() string {
+	return comm.COMPRESSED_TYPE
+}
+
+//Deserialize message payload. Unknown message types are malformed here, since peers only compress the types known
+//by both nodes
+func (this *Compressed) Deserialization(source *common.ZeroCopySource) error {
+	cmd, eof := source.NextBytes(comm.MSG_CMD_LEN)
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.Algorithm, eof = source.NextByte()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if this.Algorithm != comm.COMPRESS_SNAPPY {
+		return fmt.Errorf("unknown compression algorithm %d", this.Algorithm)
+	}
+	data, _ := source.NextBytes(source.Len())
+	length, err := snappy.DecodedLen(data)
+	if err != nil {
+		return err
+	}
+	if length > comm.MAX_PAYLOAD_LEN {
+		return fmt.Errorf("decompressed length %d exceeds max payload size %d", length, comm.MAX_PAYLOAD_LEN)
+	}
+	payload, err := snappy.Decode(nil, data)
+	if err != nil {
+		return err
+	}
+
+	cmdType := string(bytes.TrimRight(cmd, "\x00"))
+	if cmdType == comm.COMPRESSED_TYPE {
+		return errors.New("nested compressed message")
+	}
+	msg, err := MakeEmptyMessage(cmdType)
+	if err != nil {
+		return err
+	}
+	if err := msg.Deserialization(common.NewZeroCopySource(payload)); err != nil {
+		return fmt.Errorf("deserialize compressed %s error:%s", cmdType, err)
+	}
+	this.Msg = msg
+	return nil
+}
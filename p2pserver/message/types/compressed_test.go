@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	ct "github.com/TesraSupernet/Tesra/core/types"
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressed(t *testing.T) {
+	msg := &Skeleton{}
+	for i := uint32(0); i < comm.MAX_SKELETON_CNT; i++ {
+		msg.Headers = append(msg.Headers, &ct.Header{Height: i * 1000})
+	}
+	plain := common.NewZeroCopySink(nil)
+	WriteMessage(plain, msg)
+	sink := common.NewZeroCopySink(nil)
+	WriteMessage(sink, NewCompressed(msg))
+	assert.True(t, sink.Size() < plain.Size())
+
+	demsg, length, err := ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(sink.Size()-comm.MSG_HDR_LEN), length)
+	skeleton, ok := demsg.(*Skeleton)
+	assert.True(t, ok)
+	assert.Equal(t, len(msg.Headers), len(skeleton.Headers))
+	assert.Equal(t, msg.Headers[10].Hash(), skeleton.Headers[10].Hash())
+}
+
+func TestCompressedMalformed(t *testing.T) {
+	nested := common.NewZeroCopySink(nil)
+	WriteMessage(nested, NewCompressed(NewCompressed(&Ping{Height: 1})))
+	_, _, err := ReadMessage(bytes.NewBuffer(nested.Bytes()))
+	_, ok := err.(*MalformedMsgError)
+	assert.True(t, ok)
+
+	var cmd [comm.MSG_CMD_LEN]byte
+	copy(cmd[:], comm.BLOCK_TYPE)
+	payload := common.NewZeroCopySink(nil)
+	payload.WriteBytes(cmd[:])
+	payload.WriteByte(comm.COMPRESS_SNAPPY)
+	payload.WriteBytes(snappy.Encode(nil, make([]byte, comm.MAX_PAYLOAD_LEN+1)))
+	err = (&Compressed{}).Deserialization(common.NewZeroCopySource(payload.Bytes()))
+	assert.NotNil(t, err)
+}
+
+func TestRequiredCapability(t *testing.T) {
+	flag, ok := RequiredCapability(comm.COMPRESSED_TYPE)
+	assert.True(t, ok)
+	assert.Equal(t, comm.COMPRESSION_FLAG, flag)
+	_, ok = RequiredCapability(comm.BLOCK_TYPE)
+	assert.False(t, ok)
+}
+
+func TestCmdTypeOf(t *testing.T) {
+	plain := common.NewZeroCopySink(nil)
+	WriteMessage(plain, &Skeleton{})
+	assert.Equal(t, comm.SKELETON_TYPE, CmdTypeOf(plain.Bytes()))
+	compressed := common.NewZeroCopySink(nil)
+	WriteMessage(compressed, NewCompressed(&Skeleton{}))
+	assert.Equal(t, comm.SKELETON_TYPE, CmdTypeOf(compressed.Bytes()))
+	assert.Equal(t, "", CmdTypeOf(plain.Bytes()[:comm.MSG_HDR_LEN-1]))
+}
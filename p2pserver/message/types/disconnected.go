@@ -23,7 +23,9 @@ import (
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
 )
 
-type Disconnected struct{}
+type Disconnected struct {
+	Malformed error //the malformed message read from link, nil if link is broken
+}
 
 //Serialize message payload
 func (this Disconnected) Serialization(sink *comm.ZeroCopySink) {
@@ -0,0 +1,51 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"io"
+
+	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//FindNode request the nodes closest to target id in routing table of peer
+type FindNode struct {
+	Target uint64
+}
+
+//Serialize message payload
+func (this FindNode) Serialization(sink *comm.ZeroCopySink) {
+	sink.WriteUint64(this.Target)
+}
+
+func (this *FindNode) CmdType() string {
+	return common.FINDNODE_TYPE
+}
+
+//Deserialize message payload
+func (this *FindNode) Deserialization(source *comm.ZeroCopySource) error {
+	var eof bool
+	this.Target, eof = source.NextUint64()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
@@ -102,6 +102,16 @@ func WriteMessage(sink *comm.ZeroCopySink, msg Message) {
 	sink.NextBytes(payLen)
 }
 
+//MalformedMsgError is returned by ReadMessage when the message read is invalid, the unsupported cmd type is not
+//malformed for the compatibility with newer versions
+type MalformedMsgError struct {
+	Err error
+}
+
+func (this *MalformedMsgError) Error() string {
+	return this.Err.Error()
+}
+
 func ReadMessage(reader io.Reader) (Message, uint32, error) {
 	hdr, err := readMessageHeader(reader)
 	if err != nil {
@@ -110,12 +120,12 @@ func ReadMessage(reader io.Reader) (Message, uint32, error) {
 
 	magic := config.DefConfig.P2PNode.NetworkMagic
 	if hdr.Magic != magic {
-		return nil, 0, fmt.Errorf("unmatched magic number %d, expected %d", hdr.Magic, magic)
+		return nil, 0, &MalformedMsgError{fmt.Errorf("unmatched magic number %d, expected %d", hdr.Magic, magic)}
 	}
 
 	if hdr.Length > common.MAX_PAYLOAD_LEN {
-		return nil, 0, fmt.Errorf("msg payload length:%d exceed max payload size: %d",
-			hdr.Length, common.MAX_PAYLOAD_LEN)
+		return nil, 0, &MalformedMsgError{fmt.Errorf("msg payload length:%d exceed max payload size: %d",
+			hdr.Length, common.MAX_PAYLOAD_LEN)}
 	}
 
 	buf := make([]byte, hdr.Length)
@@ -126,10 +136,10 @@ func ReadMessage(reader io.Reader) (Message, uint32, error) {
 
 	checksum := common.Checksum(buf)
 	if checksum != hdr.Checksum {
-		return nil, 0, fmt.Errorf("message checksum mismatch: %x != %x ", hdr.Checksum, checksum)
+		return nil, 0, &MalformedMsgError{fmt.Errorf("message checksum mismatch: %x != %x ", hdr.Checksum, checksum)}
 	}
 
-	cmdType := string(bytes.TrimRight(hdr.CMD[:], string(0)))
+	cmdType := string(bytes.TrimRight(hdr.CMD[:], "\x00"))
 	msg, err := MakeEmptyMessage(cmdType)
 	if err != nil {
 		return nil, 0, err
@@ -139,7 +149,10 @@ func ReadMessage(reader io.Reader) (Message, uint32, error) {
 	source := comm.NewZeroCopySource(buf)
 	err = msg.Deserialization(source)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, &MalformedMsgError{fmt.Errorf("deserialize %s error:%s", cmdType, err)}
+	}
+	if compressed, ok := msg.(*Compressed); ok {
+		msg = compressed.Msg
 	}
 
 	return msg, hdr.Length, nil
@@ -179,6 +192,22 @@ func MakeEmptyMessage(cmdType string) (Message, error) {
 		return &Disconnected{}, nil
 	case common.GET_BLOCKS_TYPE:
 		return &BlocksReq{}, nil
+	case common.FINDNODE_TYPE:
+		return &FindNode{}, nil
+	case common.NEIGHBORS_TYPE:
+		return &Neighbors{}, nil
+	case common.GET_SKEL_TYPE:
+		return &SkeletonReq{}, nil
+	case common.SKELETON_TYPE:
+		return &Skeleton{}, nil
+	case common.CMPCT_BLOCK_TYPE:
+		return &CompactBlock{}, nil
+	case common.GET_BLKTXN_TYPE:
+		return &BlockTxnReq{}, nil
+	case common.BLKTXN_TYPE:
+		return &BlockTxn{}, nil
+	case common.COMPRESSED_TYPE:
+		return &Compressed{}, nil
 	default:
 		return nil, errors.New("unsupported cmd type:" + cmdType)
 	}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//Neighbors response the nodes closest to target id of FindNode
+type Neighbors struct {
+	Target    uint64
+	NodeAddrs []comm.PeerAddr
+}
+
+//Serialize message payload
+func (this Neighbors) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Target)
+	Addr{NodeAddrs: this.NodeAddrs}.Serialization(sink)
+}
+
+func (this *Neighbors) CmdType() string {
+	return comm.NEIGHBORS_TYPE
+}
+
+//Deserialize message payload
+func (this *Neighbors) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Target, eof = source.NextUint64()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	var addr Addr
+	err := addr.Deserialization(source)
+	if err != nil {
+		return err
+	}
+	this.NodeAddrs = addr.NodeAddrs
+
+	return nil
+}
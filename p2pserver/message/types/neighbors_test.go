@@ -0,0 +1,47 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"net"
+	"testing"
+
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+func TestFindNodeSerializationDeserialization(t *testing.T) {
+	var msg FindNode
+	msg.Target = 987654321
+
+	MessageTest(t, &msg)
+}
+
+func TestNeighborsSerializationDeserialization(t *testing.T) {
+	var msg Neighbors
+	msg.Target = 987654321
+	var addr [16]byte
+	copy(addr[:], net.ParseIP("192.168.0.1").To16())
+	msg.NodeAddrs = append(msg.NodeAddrs, comm.PeerAddr{
+		IpAddr: addr,
+		Port:   20338,
+		ID:     987654320,
+	})
+
+	MessageTest(t, &msg)
+}
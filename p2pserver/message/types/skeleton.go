@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TesraSupernet/Tesra/common"
+	ct "github.com/TesraSupernet/Tesra/core/types"
+	comm "github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//SkeletonReq request the headers at heights, which split the headers to sync into segments
+type SkeletonReq struct {
+	Heights []uint32
+}
+
+//Serialize message payload
+func (this *SkeletonReq) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint32(uint32(len(this.Heights)))
+	for _, height := range this.Heights {
+		sink.WriteUint32(height)
+	}
+}
+
+func (this *SkeletonReq) CmdType() string {
+	return comm.GET_SKEL_TYPE
+}
+
+//Deserialize message payload
+func (this *SkeletonReq) Deserialization(source *common.ZeroCopySource) error {
+	count, eof := source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if count > comm.MAX_SKELETON_CNT {
+		return errors.New("skeleton heights exceed max count")
+	}
+	for i := uint32(0); i < count; i++ {
+		height, eof := source.NextUint32()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		this.Heights = append(this.Heights, height)
+	}
+	return nil
+}
+
+//Skeleton response the headers at heights of SkeletonReq
+type Skeleton struct {
+	Headers []*ct.Header
+}
+
+//Serialize message payload
+func (this *Skeleton) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint32(uint32(len(this.Headers)))
+	for _, header := range this.Headers {
+		header.Serialization(sink)
+	}
+}
+
+func (this *Skeleton) CmdType() string {
+	return comm.SKELETON_TYPE
+}
+
+//Deserialize message payload
+func (this *Skeleton) Deserialization(source *common.ZeroCopySource) error {
+	count, eof := source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if count > comm.MAX_SKELETON_CNT {
+		return errors.New("skeleton headers exceed max count")
+	}
+	for i := uint32(0); i < count; i++ {
+		header := &ct.Header{}
+		err := header.Deserialization(source)
+		if err != nil {
+			return fmt.Errorf("deserialze Skeleton error: %v", err)
+		}
+		this.Headers = append(this.Headers, header)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkeleton(t *testing.T) {
+	req := &SkeletonReq{Heights: []uint32{500, 1000, 1500}}
+	sink := common.NewZeroCopySink(nil)
+	WriteMessage(sink, req)
+	demsg, _, err := ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, req.Heights, demsg.(*SkeletonReq).Heights)
+
+	headers := []*types.Header{{Height: 500}, {Height: 1000}}
+	sink = common.NewZeroCopySink(nil)
+	WriteMessage(sink, &Skeleton{Headers: headers})
+	demsg, _, err = ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+	skeleton := demsg.(*Skeleton)
+	assert.Equal(t, 2, len(skeleton.Headers))
+	for i, header := range skeleton.Headers {
+		assert.Equal(t, headers[i].Hash(), header.Hash())
+	}
+
+	req = &SkeletonReq{Heights: make([]uint32, 65)}
+	sink = common.NewZeroCopySink(nil)
+	WriteMessage(sink, req)
+	_, _, err = ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.NotNil(t, err)
+}
@@ -31,14 +31,16 @@ type VersionPayload struct {
 	TimeStamp    int64
 	SyncPort     uint16
 	HttpInfoPort uint16
-	//TODO remove this legecy field
-	ConsPort    uint16
-	Cap         [32]byte
-	Nonce       uint64
-	StartHeight uint64
-	Relay       uint8
-	IsConsensus bool
-	SoftVersion string
+	ConsPort     uint16
+	Cap          [32]byte
+	Nonce        uint64
+	StartHeight  uint64
+	Relay        uint8
+	IsConsensus  bool
+	SoftVersion  string
+	//the optional fields not sent by legacy nodes
+	PublicAddr   string //public ip:port of sender, empty if unknown
+	ObservedAddr string //address of receiver observed by sender
 }
 
 type Version struct {
@@ -59,6 +61,8 @@ func (this *Version) Serialization(sink *comm.ZeroCopySink) {
 	sink.WriteUint8(this.P.Relay)
 	sink.WriteBool(this.P.IsConsensus)
 	sink.WriteString(this.P.SoftVersion)
+	sink.WriteString(this.P.PublicAddr)
+	sink.WriteString(this.P.ObservedAddr)
 }
 
 func (this *Version) CmdType() string {
@@ -93,6 +97,14 @@ func (this *Version) Deserialization(source *comm.ZeroCopySource) error {
 	if eof || irregular {
 		this.P.SoftVersion = ""
 	}
+	this.P.PublicAddr, _, irregular, eof = source.NextString()
+	if eof || irregular {
+		this.P.PublicAddr = ""
+	}
+	this.P.ObservedAddr, _, irregular, eof = source.NextString()
+	if eof || irregular {
+		this.P.ObservedAddr = ""
+	}
 
 	return nil
 }
@@ -23,7 +23,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru"
@@ -35,9 +35,12 @@ import (
 	"github.com/TesraSupernet/Tesra/core/types"
 	actor "github.com/TesraSupernet/Tesra/p2pserver/actor/req"
 	msgCommon "github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/dht"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
 	msgTypes "github.com/TesraSupernet/Tesra/p2pserver/message/types"
 	"github.com/TesraSupernet/Tesra/p2pserver/net/protocol"
+	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 //respCache cache for some response data
@@ -47,6 +50,30 @@ var respCache *lru.ARCCache
 // thread safe
 var txCache, _ = lru.NewARC(msgCommon.MAX_TX_CACHE_SIZE)
 
+//Store the request of announced txHash, using for not requesting the tx from every peer announcing it
+var txReqCache, _ = lru.NewARC(msgCommon.MAX_TX_CACHE_SIZE)
+var txReqLock sync.Mutex
+
+//Store the compact blocks waiting for the missing txs requested from the peer sent them
+var cmpctCache, _ = lru.NewARC(msgCommon.MAX_PENDING_CMPCT)
+
+//txRequest is an announced tx requested from one peer, the other announcers are requested in turn if it is not
+//received in time or not found
+type txRequest struct {
+	peerId     uint64
+	announcers []uint64
+	timer      *time.Timer
+}
+
+//pendingCmpct is a compact block waiting for the txs missing in txnpool
+type pendingCmpct struct {
+	peerId     uint64
+	cmpct      *msgTypes.CompactBlock
+	matched    []*types.Transaction
+	missing    []uint32
+	payloadLen uint32
+}
+
 // AddrReqHandle handles the neighbor address request from peer
 func AddrReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Trace("[p2p]receive addr request message", data.Addr, data.Id)
@@ -122,6 +149,49 @@ func HeadersReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID,
 	}
 }
 
+//SkeletonReqHandle handles the skeleton req of header sync from peer
+func SkeletonReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive skeleton request message", data.Addr, data.Id)
+
+	req := data.Payload.(*msgTypes.SkeletonReq)
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debugf("[p2p]remotePeer invalid in SkeletonReqHandle, peer id: %d", data.Id)
+		return
+	}
+	curHeight := ledger.DefLedger.GetCurrentHeaderHeight()
+	headers := make([]*types.Header, 0, len(req.Heights))
+	for _, height := range req.Heights {
+		if height > curHeight {
+			break
+		}
+		header, err := ledger.DefLedger.GetHeaderByHash(ledger.DefLedger.GetBlockHash(height))
+		if err != nil || header == nil {
+			break
+		}
+		headers = append(headers, header)
+	}
+	msg := msgpack.NewSkeleton(headers)
+	err := p2p.Send(remotePeer, msg)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+}
+
+//SkeletonHandle handles the skeleton of header sync from peer
+func SkeletonHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive skeleton message", data.Addr, data.Id)
+	if pid != nil {
+		var skeleton = data.Payload.(*msgTypes.Skeleton)
+		input := &msgCommon.AppendSkeleton{
+			FromID:  data.Id,
+			Headers: skeleton.Headers,
+		}
+		pid.Tell(input)
+	}
+}
+
 //PingHandle handle ping msg from peer
 func PingHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Trace("[p2p]receive ping message", data.Addr, data.Id)
@@ -156,6 +226,7 @@ func PongHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args
 		return
 	}
 	remotePeer.SetHeight(pong.Height)
+	remotePeer.OnPong()
 }
 
 // BlkHeaderHandle handles the sync headers from peer
@@ -180,6 +251,7 @@ func BlockHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args
 		stateHashHeight := config.GetStateHashCheckHeight(config.DefConfig.P2PNode.NetworkId)
 		if block.Blk.Header.Height >= stateHashHeight && block.MerkleRoot == common.UINT256_EMPTY {
 			log.Info("received block msg with empty merkle root")
+			Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_INVALID_BLOCK, "block with empty merkle root")
 			remotePeer := p2p.GetPeer(data.Id)
 			if remotePeer != nil {
 				remotePeer.Close()
@@ -198,6 +270,193 @@ func BlockHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args
 	}
 }
 
+//Misbehave add the misbehavior score to peer, and close the peer if it is banned. The address of peer is
+//looked up by id if empty
+func Misbehave(p2p p2p.P2P, id uint64, addr string, score int, reason string) {
+	remotePeer := p2p.GetPeer(id)
+	if addr == "" {
+		if remotePeer == nil {
+			return
+		}
+		addr = remotePeer.Link.GetAddr()
+	}
+	if p2p.GetReputation().Misbehave(id, addr, score, reason) && remotePeer != nil {
+		remotePeer.Close()
+	}
+}
+
+//AddKnownTxn record the tx relayed by self, so it is not requested when announced back by peers
+func AddKnownTxn(hash common.Uint256) {
+	if !txCache.Contains(hash) {
+		txCache.Add(hash, nil)
+	}
+}
+
+// CompactBlockHandle reconstructs the block from txnpool, and requests the full block if failed
+func CompactBlockHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive compact block message from ", data.Addr, data.Id)
+
+	if pid == nil {
+		return
+	}
+	var cmpct = data.Payload.(*msgTypes.CompactBlock)
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in CompactBlockHandle")
+		return
+	}
+	stateHashHeight := config.GetStateHashCheckHeight(config.DefConfig.P2PNode.NetworkId)
+	if cmpct.Header.Height >= stateHashHeight && cmpct.MerkleRoot == common.UINT256_EMPTY {
+		log.Info("received compact block msg with empty merkle root")
+		Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_INVALID_BLOCK, "compact block with empty merkle root")
+		remotePeer.Close()
+		return
+	}
+
+	if cmpct.Header.Height <= ledger.DefLedger.GetCurrentBlockHeight() {
+		return
+	}
+
+	txs := make(map[uint64][]*types.Transaction)
+	if len(cmpct.ShortIDs) > 0 {
+		var err error
+		txs, err = actor.GetTransactionsByShortID(cmpct.ShortIDs)
+		if err != nil {
+			log.Warn(err)
+			txs = make(map[uint64][]*types.Transaction)
+		}
+	}
+	hash := cmpct.Header.Hash()
+	matched, missing, err := cmpct.MatchTransactions(txs)
+	if err == nil && len(missing) > 0 {
+		log.Debugf("[p2p]compact block %x misses %d txs, request them", hash, len(missing))
+		cmpctCache.Add(hash, &pendingCmpct{
+			peerId:     data.Id,
+			cmpct:      cmpct,
+			matched:    matched,
+			missing:    missing,
+			payloadLen: data.PayloadSize,
+		})
+		err = p2p.Send(remotePeer, msgpack.NewBlockTxnReq(hash, missing))
+		if err != nil {
+			log.Warn(err)
+		}
+		return
+	}
+	var block *types.Block
+	if err == nil {
+		block, err = cmpct.Complete(matched, nil, nil)
+	}
+	//short ids may collide, the peer is penalized by block sync only if the full block fails to verify
+	if err != nil {
+		log.Debugf("[p2p]reconstruct compact block %x error:%s, request full block", hash, err)
+		requestFullBlock(p2p, remotePeer, hash)
+		return
+	}
+
+	input := &msgCommon.AppendBlock{
+		FromID:     data.Id,
+		BlockSize:  data.PayloadSize,
+		Block:      block,
+		MerkleRoot: cmpct.MerkleRoot,
+	}
+	pid.Tell(input)
+}
+
+// BlockTxnReqHandle replies the txs of block requested by index for the compact block sent to peer
+func BlockTxnReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive block txn request message from ", data.Addr, data.Id)
+
+	var req = data.Payload.(*msgTypes.BlockTxnReq)
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in BlockTxnReqHandle")
+		return
+	}
+
+	block, err := ledger.DefLedger.GetBlockByHash(req.BlockHash)
+	if err != nil || block == nil {
+		log.Debugf("[p2p]can't get block by hash %x for block txn request", req.BlockHash)
+		err = p2p.Send(remotePeer, msgpack.NewNotFound(req.BlockHash))
+		if err != nil {
+			log.Warn(err)
+		}
+		return
+	}
+	if len(req.Indexes) > len(block.Transactions) {
+		Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_MALFORMED_MSG, "too many block txn indexes")
+		return
+	}
+	txs := make([]*types.Transaction, 0, len(req.Indexes))
+	for _, index := range req.Indexes {
+		if index >= uint32(len(block.Transactions)) {
+			Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_MALFORMED_MSG, "block txn index out of range")
+			return
+		}
+		txs = append(txs, block.Transactions[index])
+	}
+	err = p2p.Send(remotePeer, msgpack.NewBlockTxn(req.BlockHash, txs))
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
+// BlockTxnHandle completes the compact block waiting for the txs replied by peer
+func BlockTxnHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive block txn message from ", data.Addr, data.Id)
+
+	if pid == nil {
+		return
+	}
+	var blkTxn = data.Payload.(*msgTypes.BlockTxn)
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in BlockTxnHandle")
+		return
+	}
+	pending := takePendingCmpct(blkTxn.BlockHash, data.Id)
+	if pending == nil {
+		log.Debugf("[p2p]receive unrequested block txn of %x", blkTxn.BlockHash)
+		return
+	}
+
+	block, err := pending.cmpct.Complete(pending.matched, pending.missing, blkTxn.Txs)
+	if err != nil {
+		log.Debugf("[p2p]complete compact block %x error:%s, request full block", blkTxn.BlockHash, err)
+		if err == msgTypes.ErrMismatchedBlockTxn {
+			Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_INVALID_BLOCK, "mismatched block txn")
+		}
+		requestFullBlock(p2p, remotePeer, blkTxn.BlockHash)
+		return
+	}
+
+	input := &msgCommon.AppendBlock{
+		FromID:     data.Id,
+		BlockSize:  pending.payloadLen + data.PayloadSize,
+		Block:      block,
+		MerkleRoot: pending.cmpct.MerkleRoot,
+	}
+	pid.Tell(input)
+}
+
+//takePendingCmpct remove and return the compact block waiting for the txs requested from peer
+func takePendingCmpct(hash common.Uint256, peerId uint64) *pendingCmpct {
+	value, ok := cmpctCache.Get(hash)
+	if !ok || value.(*pendingCmpct).peerId != peerId {
+		return nil
+	}
+	cmpctCache.Remove(hash)
+	return value.(*pendingCmpct)
+}
+
+//requestFullBlock request the block which can not be reconstructed from compact block
+func requestFullBlock(p2p p2p.P2P, remotePeer *peer.Peer, hash common.Uint256) {
+	err := p2p.Send(remotePeer, msgpack.NewBlkDataReq(hash))
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
 // ConsensusHandle handles the consensus message from peer
 func ConsensusHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Debugf("[p2p]receive consensus message:%v,%d", data.Addr, data.Id)
@@ -206,6 +465,7 @@ func ConsensusHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID,
 		var consensus = data.Payload.(*msgTypes.Consensus)
 		if err := consensus.Cons.Verify(); err != nil {
 			log.Warn(err)
+			Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_INVALID_CONSENSUS, "invalid consensus message")
 			return
 		}
 		consensus.Cons.PeerId = data.Id
@@ -217,6 +477,15 @@ func ConsensusHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID,
 func NotFoundHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	var notFound = data.Payload.(*msgTypes.NotFound)
 	log.Debug("[p2p]receive notFound message, hash is ", notFound.Hash)
+
+	//the peer lacks the block of compact block sent, or the announced tx
+	if pending := takePendingCmpct(notFound.Hash, data.Id); pending != nil {
+		if remotePeer := p2p.GetPeer(data.Id); remotePeer != nil {
+			requestFullBlock(p2p, remotePeer, notFound.Hash)
+		}
+		return
+	}
+	retryTxn(p2p, notFound.Hash, data.Id)
 }
 
 // TransactionHandle handles the transaction message from peer
@@ -225,6 +494,7 @@ func TransactionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID
 
 	var trn = data.Payload.(*msgTypes.Trn)
 
+	receivedTxn(trn.Txn.Hash())
 	if !txCache.Contains(trn.Txn.Hash()) {
 		txCache.Add(trn.Txn.Hash(), nil)
 		actor.AddTransaction(trn.Txn)
@@ -233,6 +503,87 @@ func TransactionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID
 	}
 }
 
+//requestTxn request the announced tx from peer, or record peer as another announcer if it is being requested
+func requestTxn(p2p p2p.P2P, peerId uint64, hash common.Uint256) {
+	txReqLock.Lock()
+	if value, ok := txReqCache.Get(hash); ok {
+		req := value.(*txRequest)
+		if req.peerId != peerId && len(req.announcers) < msgCommon.MAX_TX_ANNOUNCERS {
+			for _, id := range req.announcers {
+				if id == peerId {
+					txReqLock.Unlock()
+					return
+				}
+			}
+			req.announcers = append(req.announcers, peerId)
+		}
+		txReqLock.Unlock()
+		return
+	}
+	txReqCache.Add(hash, &txRequest{
+		peerId: peerId,
+		timer: time.AfterFunc(msgCommon.TX_REQ_TIMEOUT*time.Second, func() {
+			retryTxn(p2p, hash, peerId)
+		}),
+	})
+	txReqLock.Unlock()
+
+	sendTxnReq(p2p, peerId, hash)
+}
+
+//retryTxn request the tx from the next announcer after the request to peer timed out or not found
+func retryTxn(p2p p2p.P2P, hash common.Uint256, peerId uint64) {
+	txReqLock.Lock()
+	value, ok := txReqCache.Get(hash)
+	if !ok || value.(*txRequest).peerId != peerId {
+		txReqLock.Unlock()
+		return
+	}
+	req := value.(*txRequest)
+	req.timer.Stop()
+	if !txCache.Contains(hash) {
+		for len(req.announcers) > 0 {
+			next := req.announcers[0]
+			req.announcers = req.announcers[1:]
+			if p2p.GetPeer(next) == nil {
+				continue
+			}
+			req.peerId = next
+			req.timer = time.AfterFunc(msgCommon.TX_REQ_TIMEOUT*time.Second, func() {
+				retryTxn(p2p, hash, next)
+			})
+			txReqLock.Unlock()
+
+			log.Debugf("[p2p]request tx %x from next announcer %d", hash, next)
+			sendTxnReq(p2p, next, hash)
+			return
+		}
+	}
+	txReqCache.Remove(hash)
+	txReqLock.Unlock()
+}
+
+//receivedTxn stop requesting the tx from announcers
+func receivedTxn(hash common.Uint256) {
+	txReqLock.Lock()
+	defer txReqLock.Unlock()
+	if value, ok := txReqCache.Get(hash); ok {
+		value.(*txRequest).timer.Stop()
+		txReqCache.Remove(hash)
+	}
+}
+
+func sendTxnReq(p2p p2p.P2P, peerId uint64, hash common.Uint256) {
+	remotePeer := p2p.GetPeer(peerId)
+	if remotePeer == nil {
+		return
+	}
+	err := p2p.Send(remotePeer, msgpack.NewTxnDataReq(hash))
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
 // VersionHandle handles version handshake protocol from peer
 func VersionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Trace("[p2p]receive version message", data.Addr, data.Id)
@@ -254,20 +605,21 @@ func VersionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 	nodeAddr := addrIp + ":" +
 		strconv.Itoa(int(version.P.SyncPort))
 	if config.DefConfig.P2PNode.ReservedPeersOnly && len(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers) > 0 {
-		found := false
-		for _, addr := range config.DefConfig.P2PNode.ReservedCfg.ReservedPeers {
-			if strings.HasPrefix(data.Addr, addr) {
-				log.Debug("[p2p]peer in reserved list", data.Addr)
-				found = true
-				break
-			}
-		}
+		found := msgCommon.CheckReservedPeer(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers, true,
+			data.Addr, remotePeer.Link.GetPublicKey())
 		if !found {
 			remotePeer.Close()
 			log.Debug("[p2p]peer not in reserved list,close", data.Addr)
 			return
 		}
+		log.Debug("[p2p]peer in reserved list", data.Addr)
+	}
 
+	//peer id of authenticated peer must be derived from its node key
+	if pubKey := remotePeer.Link.GetPublicKey(); pubKey != nil && msgCommon.PubKeyToID(pubKey) != version.P.Nonce {
+		remotePeer.Close()
+		log.Warnf("[p2p]peer id %d mismatch node key of %s, close", version.P.Nonce, data.Addr)
+		return
 	}
 
 	if version.P.Nonce == p2p.GetID() {
@@ -322,16 +674,17 @@ func VersionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 		}
 	}
 
-	if version.P.Cap[msgCommon.HTTP_INFO_FLAG] == 0x01 {
-		remotePeer.SetHttpInfoState(true)
-	} else {
-		remotePeer.SetHttpInfoState(false)
-	}
+	remotePeer.SetCapabilities(version.P.Cap)
 	remotePeer.SetHttpInfoPort(version.P.HttpInfoPort)
 
 	remotePeer.UpdateInfo(time.Now(), version.P.Version,
 		version.P.Services, version.P.SyncPort, version.P.Nonce,
 		version.P.Relay, version.P.StartHeight, version.P.SoftVersion)
+	remotePeer.SetConsPort(version.P.ConsPort)
+	if publicAddr, err := config.ParsePublicAddress(version.P.PublicAddr, 0); err == nil {
+		remotePeer.SetPublicAddr(publicAddr)
+	}
+	p2p.ReportObservedAddress(version.P.Nonce, data.Addr, version.P.ObservedAddr)
 	remotePeer.Link.SetID(version.P.Nonce)
 	p2p.AddNbrNode(remotePeer)
 
@@ -345,7 +698,7 @@ func VersionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 	var msg msgTypes.Message
 	if s == msgCommon.INIT {
 		remotePeer.SetState(msgCommon.HAND_SHAKE)
-		msg = msgpack.NewVersion(p2p, ledger.DefLedger.GetCurrentBlockHeight())
+		msg = msgpack.NewVersion(p2p, ledger.DefLedger.GetCurrentBlockHeight(), remotePeer.GetAddr())
 	} else if s == msgCommon.HAND {
 		remotePeer.SetState(msgCommon.HAND_SHAKED)
 		msg = msgpack.NewVerAck()
@@ -387,6 +740,77 @@ func VerAckHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, arg
 	msg := msgpack.NewAddrReq()
 	go p2p.Send(remotePeer, msg)
 
+	//the established peer is alive, and asked for nodes close to self to fill the routing table
+	if remotePeer.GetPort() != 0 {
+		p2p.GetRoutingTable().Seen(remotePeer.GetID(), remotePeer.GetNodeAddr())
+	}
+	if config.DefConfig.P2PNode.EnableDiscovery && remotePeer.GetVersion() >= msgCommon.DISCOVERY_MIN_VERSION {
+		p2p.GetRoutingTable().FindNodeSent(remotePeer.GetID(), p2p.GetID())
+		go p2p.Send(remotePeer, msgpack.NewFindNode(p2p.GetID()))
+	}
+}
+
+//FindNodeHandle handle the request of nodes closest to target id in routing table
+func FindNodeHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive findnode message", data.Addr, data.Id)
+
+	var req = data.Payload.(*msgTypes.FindNode)
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in FindNodeHandle")
+		return
+	}
+
+	nodeAddrs := make([]msgCommon.PeerAddr, 0)
+	//reserved only node does not expose its peers
+	if !config.DefConfig.P2PNode.ReservedPeersOnly {
+		for _, node := range p2p.GetRoutingTable().Closest(req.Target, dht.BUCKET_SIZE+1) {
+			if node.ID == data.Id || len(nodeAddrs) >= dht.BUCKET_SIZE {
+				continue
+			}
+			host, port, err := net.SplitHostPort(node.Addr)
+			if err != nil {
+				continue
+			}
+			portNum, err := strconv.Atoi(port)
+			if err != nil {
+				continue
+			}
+			addr := msgCommon.PeerAddr{
+				Time: node.LastSeen,
+				Port: uint16(portNum),
+				ID:   node.ID,
+			}
+			copy(addr.IpAddr[:], net.ParseIP(host).To16())
+			nodeAddrs = append(nodeAddrs, addr)
+		}
+	}
+
+	msg := msgpack.NewNeighbors(req.Target, nodeAddrs)
+	err := p2p.Send(remotePeer, msg)
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
+//NeighborsHandle add the nodes replied by peer for a findnode request to routing table, they are not trusted
+//until contacted successfully
+func NeighborsHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive neighbors message", data.Addr, data.Id)
+
+	var msg = data.Payload.(*msgTypes.Neighbors)
+	table := p2p.GetRoutingTable()
+	if !table.NeighborsReceived(data.Id, msg.Target) {
+		log.Debugf("[p2p]unsolicited neighbors of target %d from peer %d", msg.Target, data.Id)
+		return
+	}
+	for _, v := range msg.NodeAddrs {
+		if v.ID == p2p.GetID() || v.Port == 0 {
+			continue
+		}
+		var ip net.IP = v.IpAddr[:]
+		table.Add(v.ID, ip.To16().String()+":"+strconv.Itoa(int(v.Port)))
+	}
 }
 
 // AddrHandle handles the neighbor address response message from peer
@@ -436,7 +860,7 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 	reqType := common.InventoryType(dataReq.DataType)
 	hash := dataReq.Hash
 	switch reqType {
-	case common.BLOCK:
+	case common.BLOCK, common.COMPACT_BLOCK:
 		reqID := fmt.Sprintf("%x%s", reqType, hash.ToHexString())
 		data := getRespCacheValue(reqID)
 		var msg msgTypes.Message
@@ -444,6 +868,8 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 			switch data.(type) {
 			case *msgTypes.Block:
 				msg = data.(*msgTypes.Block)
+			case *msgTypes.CompactBlock:
+				msg = data.(*msgTypes.CompactBlock)
 			}
 		}
 		if msg == nil {
@@ -472,7 +898,11 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 				}
 				return
 			}
-			msg = msgpack.NewBlock(block, merkleRoot)
+			if reqType == common.COMPACT_BLOCK {
+				msg = msgpack.NewCompactBlock(block, merkleRoot)
+			} else {
+				msg = msgpack.NewBlock(block, merkleRoot)
+			}
 			saveRespCache(reqID, msg)
 		}
 		err := p2p.Send(remotePeer, msg)
@@ -482,16 +912,20 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 		}
 
 	case common.TRANSACTION:
-		txn, err := ledger.DefLedger.GetTransaction(hash)
-		if err != nil {
+		//the announced transactions are in txnpool until packed in block
+		txn, err := actor.GetTransaction(hash)
+		if err != nil || txn == nil {
+			txn, err = ledger.DefLedger.GetTransaction(hash)
+		}
+		if err != nil || txn == nil {
 			log.Debug("[p2p]Can't get transaction by hash: ",
 				hash, " ,send not found message")
 			msg := msgpack.NewNotFound(hash)
 			err = p2p.Send(remotePeer, msg)
 			if err != nil {
 				log.Warn(err)
-				return
 			}
+			return
 		}
 		msg := msgpack.NewTxn(txn)
 		err = p2p.Send(remotePeer, msg)
@@ -526,15 +960,14 @@ func InvHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args .
 	switch invType {
 	case common.TRANSACTION:
 		log.Debug("[p2p]receive transaction message", id)
-		// TODO check the ID queue
-		id = inv.P.Blk[0]
-		trn, err := ledger.DefLedger.GetTransaction(id)
-		if trn == nil || err != nil {
-			msg := msgpack.NewTxnDataReq(id)
-			err = p2p.Send(remotePeer, msg)
-			if err != nil {
-				log.Warn(err)
-				return
+		for _, id = range inv.P.Blk {
+			//the tx is received, or requested from the announcers in turn
+			if txCache.Contains(id) {
+				continue
+			}
+			trn, err := ledger.DefLedger.GetTransaction(id)
+			if trn == nil || err != nil {
+				requestTxn(p2p, data.Id, id)
 			}
 		}
 	case common.BLOCK:
@@ -552,6 +985,10 @@ func InvHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args .
 				// send the block request
 				log.Infof("[p2p]inv request block hash: %x", id)
 				msg := msgpack.NewBlkDataReq(id)
+				//peer sends the block with short tx ids, which is reconstructed from txnpool
+				if remotePeer.GetVersion() >= msgCommon.COMPACT_RELAY_MIN_VERSION {
+					msg = msgpack.NewCompactBlkDataReq(id)
+				}
 				err = p2p.Send(remotePeer, msg)
 				if err != nil {
 					log.Warn(err)
@@ -577,6 +1014,9 @@ func InvHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args .
 // DisconnectHandle handles the disconnect events
 func DisconnectHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Debug("[p2p]receive disconnect message", data.Addr, data.Id)
+	if disc, ok := data.Payload.(*msgTypes.Disconnected); ok && disc.Malformed != nil {
+		Misbehave(p2p, data.Id, data.Addr, reputation.SCORE_MALFORMED_MSG, disc.Malformed.Error())
+	}
 	p2p.RemoveFromInConnRecord(data.Addr)
 	p2p.RemoveFromOutConnRecord(data.Addr)
 	remotePeer := p2p.GetPeer(data.Id)
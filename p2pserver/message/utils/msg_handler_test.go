@@ -25,10 +25,12 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/TesraSupernet/tesracrypto/keypair"
+	evtActor "github.com/TesraSupernet/tesraevent/actor"
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
@@ -37,12 +39,15 @@ import (
 	"github.com/TesraSupernet/Tesra/core/payload"
 	ct "github.com/TesraSupernet/Tesra/core/types"
 	"github.com/TesraSupernet/Tesra/events"
+	"github.com/TesraSupernet/Tesra/p2pserver/actor/req"
 	msgCommon "github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/dht"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
 	"github.com/TesraSupernet/Tesra/p2pserver/net/netserver"
 	"github.com/TesraSupernet/Tesra/p2pserver/net/protocol"
 	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	tc "github.com/TesraSupernet/Tesra/txnpool/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,16 +57,26 @@ var (
 
 type MockP2P struct {
 	p2p.P2P
-	SentMsgs []types.Message // stores all mock msgs
+	lock     sync.Mutex
+	sentMsgs []types.Message // stores all mock msgs
 }
 
 func (mock *MockP2P) Send(p *peer.Peer, msg types.Message) error {
-	mock.SentMsgs = append(mock.SentMsgs, msg)
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	mock.sentMsgs = append(mock.sentMsgs, msg)
 	return nil
 }
 
+//SentMsgs return the msgs sent, handlers may send msgs in goroutines
+func (mock *MockP2P) SentMsgs() []types.Message {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	return append([]types.Message{}, mock.sentMsgs...)
+}
+
 func NewMockP2p() *MockP2P {
-	return &MockP2P{netserver.NewNetServer(), make([]types.Message, 0)}
+	return &MockP2P{P2P: netserver.NewNetServer(), sentMsgs: make([]types.Message, 0)}
 }
 
 func TestMain(m *testing.M) {
@@ -115,7 +130,7 @@ func TestVersionHandle(t *testing.T) {
 	assert.Nil(t, err)
 
 	// Construct a version packet
-	buf := msgpack.NewVersion(network, 12345)
+	buf := msgpack.NewVersion(network, 12345, "127.0.0.1:50010")
 	version := buf.(*types.Version)
 	version.P.Nonce = testID
 
@@ -216,7 +231,7 @@ func TestAddrReqHandle(t *testing.T) {
 	AddrReqHandle(msg, network, nil)
 
 	// all neighbor peers should be in rsp msg
-	for _, msg := range network.SentMsgs {
+	for _, msg := range network.SentMsgs() {
 		addrMsg, ok := msg.(*types.Addr)
 		if !ok {
 			t.Fatalf("invalid addr msg %s", msg.CmdType())
@@ -277,7 +292,7 @@ func TestAddrReqHandle_maskok(t *testing.T) {
 	AddrReqHandle(msg, network, nil)
 
 	// verify 1.2.3.4 is masked
-	for _, msg := range network.SentMsgs {
+	for _, msg := range network.SentMsgs() {
 		addrMsg, ok := msg.(*types.Addr)
 		if !ok {
 			t.Fatalf("invalid addr msg %s", msg.CmdType())
@@ -336,7 +351,7 @@ func TestAddrReqHandle_unmaskok(t *testing.T) {
 	// Invoke AddrReqHandle to handle the msg
 	AddrReqHandle(msg, network, nil)
 
-	for _, msg := range network.SentMsgs {
+	for _, msg := range network.SentMsgs() {
 		addrMsg, ok := msg.(*types.Addr)
 		if !ok {
 			t.Fatalf("invalid addr msg %s", msg.CmdType())
@@ -535,6 +550,207 @@ func TestBlockHandle(t *testing.T) {
 	network.DelNbrNode(testID)
 }
 
+// TestCompactBlockHandleCollision tests Function CompactBlockHandle handling a compact block whose
+// short id collides in txnpool
+func TestCompactBlockHandleCollision(t *testing.T) {
+	var testID uint64
+	_, testPub, _ := keypair.GenerateKeyPair(keypair.PK_ECDSA, keypair.P256)
+	key := keypair.SerializePublicKey(testPub)
+	err := binary.Read(bytes.NewBuffer(key[:8]), binary.LittleEndian, &(testID))
+	assert.Nil(t, err)
+
+	remotePeer := peer.NewPeer()
+	assert.NotNil(t, remotePeer)
+	remotePeer.UpdateInfo(time.Now(), 1, 12345678, 20336,
+		testID, 0, 12345, "1.5.2")
+	remotePeer.Link.SetAddr("127.0.0.1:50011")
+	network.AddNbrNode(remotePeer)
+	defer network.DelNbrNode(testID)
+
+	txs := make([]*ct.Transaction, 0)
+	for i := 0; i < 2; i++ {
+		mutable := &ct.MutableTransaction{
+			TxType:  ct.InvokeNeo,
+			Nonce:   uint32(i),
+			Payload: &payload.InvokeCode{Code: []byte("tst")},
+		}
+		txn, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, txn)
+	}
+	id := types.ShortTxID(txs[0].Hash())
+
+	//txnpool returns two txs of the same short id
+	txPid := evtActor.Spawn(evtActor.FromFunc(func(c evtActor.Context) {
+		if _, ok := c.Message().(*tc.GetTxnsByShortIDReq); ok && c.Sender() != nil {
+			c.Sender().Request(&tc.GetTxnsByShortIDRsp{
+				Txs: map[uint64][]*ct.Transaction{id: {txs[1], txs[0]}},
+			}, c.Self())
+		}
+	}))
+	req.SetTxnPoolPid(txPid)
+	defer req.SetTxnPoolPid(nil)
+	blkPid := evtActor.Spawn(evtActor.FromFunc(func(c evtActor.Context) {}))
+
+	header := &ct.Header{
+		Height:           ledger.DefLedger.GetCurrentBlockHeight() + 1,
+		TransactionsRoot: common.ComputeMerkleRoot([]common.Uint256{txs[0].Hash()}),
+	}
+	msg := &types.MsgPayload{
+		Id:   testID,
+		Addr: "127.0.0.1:50011",
+		Payload: &types.CompactBlock{
+			Header:     header,
+			ShortIDs:   []uint64{id},
+			MerkleRoot: common.Uint256{1},
+		},
+	}
+	sent := len(network.SentMsgs())
+	CompactBlockHandle(msg, network, blkPid)
+
+	msgs := network.SentMsgs()
+	assert.Equal(t, sent+1, len(msgs))
+	dataReq, ok := msgs[len(msgs)-1].(*types.DataReq)
+	assert.True(t, ok)
+	assert.Equal(t, header.Hash(), dataReq.Hash)
+	assert.Equal(t, 0, network.GetReputation().Score(testID))
+}
+
+func addTestPeer(t *testing.T, addr string) uint64 {
+	var testID uint64
+	_, testPub, _ := keypair.GenerateKeyPair(keypair.PK_ECDSA, keypair.P256)
+	key := keypair.SerializePublicKey(testPub)
+	err := binary.Read(bytes.NewBuffer(key[:8]), binary.LittleEndian, &(testID))
+	assert.Nil(t, err)
+
+	remotePeer := peer.NewPeer()
+	remotePeer.UpdateInfo(time.Now(), 1, 12345678, 20336,
+		testID, 0, 12345, "1.5.2")
+	remotePeer.Link.SetAddr(addr)
+	network.AddNbrNode(remotePeer)
+	return testID
+}
+
+// TestCompactBlockHandleMissingTxs tests the txs missing in txnpool are requested by index to reconstruct the block
+func TestCompactBlockHandleMissingTxs(t *testing.T) {
+	testID := addTestPeer(t, "127.0.0.1:50012")
+	defer network.DelNbrNode(testID)
+
+	txs := make([]*ct.Transaction, 0)
+	for i := 0; i < 3; i++ {
+		mutable := &ct.MutableTransaction{
+			TxType:  ct.InvokeNeo,
+			Nonce:   uint32(i + 10),
+			Payload: &payload.InvokeCode{Code: []byte("tst")},
+		}
+		txn, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, txn)
+	}
+
+	//txnpool has only the first tx
+	txPid := evtActor.Spawn(evtActor.FromFunc(func(c evtActor.Context) {
+		if _, ok := c.Message().(*tc.GetTxnsByShortIDReq); ok && c.Sender() != nil {
+			c.Sender().Request(&tc.GetTxnsByShortIDRsp{
+				Txs: map[uint64][]*ct.Transaction{types.ShortTxID(txs[0].Hash()): {txs[0]}},
+			}, c.Self())
+		}
+	}))
+	req.SetTxnPoolPid(txPid)
+	defer req.SetTxnPoolPid(nil)
+	blocks := make(chan *msgCommon.AppendBlock, 1)
+	blkPid := evtActor.Spawn(evtActor.FromFunc(func(c evtActor.Context) {
+		if blk, ok := c.Message().(*msgCommon.AppendBlock); ok {
+			blocks <- blk
+		}
+	}))
+
+	hashes := []common.Uint256{txs[0].Hash(), txs[1].Hash(), txs[2].Hash()}
+	header := &ct.Header{
+		Height:           ledger.DefLedger.GetCurrentBlockHeight() + 1,
+		TransactionsRoot: common.ComputeMerkleRoot(hashes),
+	}
+	block := &ct.Block{Header: header, Transactions: txs}
+	cmpct := msgpack.NewCompactBlock(block, common.Uint256{1}).(*types.CompactBlock)
+	sent := len(network.SentMsgs())
+	CompactBlockHandle(&types.MsgPayload{
+		Id:      testID,
+		Addr:    "127.0.0.1:50012",
+		Payload: cmpct,
+	}, network, blkPid)
+
+	msgs := network.SentMsgs()
+	assert.Equal(t, sent+1, len(msgs))
+	txnReq, ok := msgs[len(msgs)-1].(*types.BlockTxnReq)
+	assert.True(t, ok)
+	assert.Equal(t, header.Hash(), txnReq.BlockHash)
+	assert.Equal(t, []uint32{1, 2}, txnReq.Indexes)
+
+	//txs replied by another peer are ignored
+	BlockTxnHandle(&types.MsgPayload{
+		Id:      testID + 1,
+		Addr:    "127.0.0.1:50013",
+		Payload: msgpack.NewBlockTxn(header.Hash(), txs[1:]),
+	}, network, blkPid)
+	BlockTxnHandle(&types.MsgPayload{
+		Id:      testID,
+		Addr:    "127.0.0.1:50012",
+		Payload: msgpack.NewBlockTxn(header.Hash(), txs[1:]),
+	}, network, blkPid)
+
+	select {
+	case blk := <-blocks:
+		assert.Equal(t, testID, blk.FromID)
+		assert.Equal(t, header.Hash(), blk.Block.Hash())
+		assert.Equal(t, txs, blk.Block.Transactions)
+	case <-time.After(time.Second):
+		t.Fatal("compact block is not reconstructed")
+	}
+	assert.Equal(t, sent+1, len(network.SentMsgs()))
+	assert.Equal(t, 0, network.GetReputation().Score(testID))
+}
+
+// TestNotFoundHandleRetriesAnnouncer tests the announced tx is requested from the next announcer when not found
+func TestNotFoundHandleRetriesAnnouncer(t *testing.T) {
+	firstID := addTestPeer(t, "127.0.0.1:50014")
+	defer network.DelNbrNode(firstID)
+	secondID := addTestPeer(t, "127.0.0.1:50015")
+	defer network.DelNbrNode(secondID)
+
+	hash := common.Uint256{0x43, 1}
+	inv := func(id uint64, addr string) *types.MsgPayload {
+		return &types.MsgPayload{
+			Id:      id,
+			Addr:    addr,
+			Payload: msgpack.NewInv(msgpack.NewInvPayload(common.TRANSACTION, []common.Uint256{hash})),
+		}
+	}
+	sent := len(network.SentMsgs())
+	InvHandle(inv(firstID, "127.0.0.1:50014"), network, nil)
+	InvHandle(inv(secondID, "127.0.0.1:50015"), network, nil)
+	//the tx is requested from the first announcer only
+	assert.Equal(t, sent+1, len(network.SentMsgs()))
+
+	notFound := func(id uint64, addr string) *types.MsgPayload {
+		return &types.MsgPayload{Id: id, Addr: addr, Payload: msgpack.NewNotFound(hash)}
+	}
+	//not found from a peer not requested is ignored
+	NotFoundHandle(notFound(secondID, "127.0.0.1:50015"), network, nil)
+	assert.Equal(t, sent+1, len(network.SentMsgs()))
+
+	NotFoundHandle(notFound(firstID, "127.0.0.1:50014"), network, nil)
+	msgs := network.SentMsgs()
+	assert.Equal(t, sent+2, len(msgs))
+	dataReq, ok := msgs[len(msgs)-1].(*types.DataReq)
+	assert.True(t, ok)
+	assert.Equal(t, hash, dataReq.Hash)
+
+	//no announcer left
+	NotFoundHandle(notFound(secondID, "127.0.0.1:50015"), network, nil)
+	assert.Equal(t, sent+2, len(network.SentMsgs()))
+	assert.False(t, txReqCache.Contains(hash))
+}
+
 // TestConsensusHandle tests Function ConsensusHandle handling a consensus message
 func TestConsensusHandle(t *testing.T) {
 	var testID uint64
@@ -622,6 +838,35 @@ func TestAddrHandle(t *testing.T) {
 	AddrHandle(msg, network, nil)
 }
 
+// TestNeighborsHandle tests Function NeighborsHandle only accepting the neighbors replied to findnode
+func TestNeighborsHandle(t *testing.T) {
+	table := network.GetRoutingTable()
+	remoteID := network.GetID() ^ 1<<40
+	nodeID := network.GetID() ^ 1<<41
+	addr := msgCommon.PeerAddr{Port: 20338, ID: nodeID}
+	copy(addr.IpAddr[:], net.ParseIP("192.168.1.1").To16())
+	msg := &types.MsgPayload{
+		Id:      remoteID,
+		Addr:    "127.0.0.1:50010",
+		Payload: msgpack.NewNeighbors(network.GetID(), []msgCommon.PeerAddr{addr}),
+	}
+
+	NeighborsHandle(msg, network, nil)
+	for _, node := range table.Candidates(nodeID, dht.BUCKET_SIZE) {
+		assert.NotEqual(t, nodeID, node.ID, "unsolicited neighbors")
+	}
+
+	table.FindNodeSent(remoteID, network.GetID())
+	NeighborsHandle(msg, network, nil)
+	candidates := table.Candidates(nodeID, 1)
+	assert.Equal(t, 1, len(candidates))
+	assert.Equal(t, nodeID, candidates[0].ID)
+	for _, node := range table.Closest(nodeID, dht.BUCKET_SIZE) {
+		assert.NotEqual(t, nodeID, node.ID, "node not contacted is not served")
+	}
+	table.Failed(nodeID)
+}
+
 // TestDataReqHandle tests Function DataReqHandle handling a data req(block/Transaction)
 func TestDataReqHandle(t *testing.T) {
 	var testID uint64
@@ -24,6 +24,7 @@ import (
 	msgCommon "github.com/TesraSupernet/Tesra/p2pserver/common"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
 	"github.com/TesraSupernet/Tesra/p2pserver/net/protocol"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 // MessageHandler defines the unified api for each net message
@@ -35,8 +36,11 @@ type MessageRouter struct {
 	msgHandlers map[string]MessageHandler // Msg handler mapped to msg type
 	RecvChan    chan *types.MsgPayload    // The channel to handle sync msg
 	stopRecvCh  chan bool                 // To stop sync channel
+	ConsChan    chan *types.MsgPayload    // The channel to handle msg from consensus overlay
+	stopConsCh  chan bool                 // To stop consensus channel
 	p2p         p2p.P2P                   // Refer to the p2p network
 	pid         *actor.PID                // P2P actor
+	limiter     *reputation.RateLimiter   // Limit the msg rate of each peer
 }
 
 // NewMsgRouter returns a message router object
@@ -51,7 +55,10 @@ func (this *MessageRouter) init(p2p p2p.P2P) {
 	this.msgHandlers = make(map[string]MessageHandler)
 	this.RecvChan = p2p.GetMsgChan()
 	this.stopRecvCh = make(chan bool)
+	this.ConsChan = p2p.GetConsMsgChan()
+	this.stopConsCh = make(chan bool)
 	this.p2p = p2p
+	this.limiter = reputation.NewRateLimiter(reputation.DefaultRates)
 
 	// Register message handler
 	this.RegisterMsgHandler(msgCommon.VERSION_TYPE, VersionHandle)
@@ -69,6 +76,13 @@ func (this *MessageRouter) init(p2p p2p.P2P) {
 	this.RegisterMsgHandler(msgCommon.NOT_FOUND_TYPE, NotFoundHandle)
 	this.RegisterMsgHandler(msgCommon.TX_TYPE, TransactionHandle)
 	this.RegisterMsgHandler(msgCommon.DISCONNECT_TYPE, DisconnectHandle)
+	this.RegisterMsgHandler(msgCommon.FINDNODE_TYPE, FindNodeHandle)
+	this.RegisterMsgHandler(msgCommon.NEIGHBORS_TYPE, NeighborsHandle)
+	this.RegisterMsgHandler(msgCommon.CMPCT_BLOCK_TYPE, CompactBlockHandle)
+	this.RegisterMsgHandler(msgCommon.GET_SKEL_TYPE, SkeletonReqHandle)
+	this.RegisterMsgHandler(msgCommon.SKELETON_TYPE, SkeletonHandle)
+	this.RegisterMsgHandler(msgCommon.GET_BLKTXN_TYPE, BlockTxnReqHandle)
+	this.RegisterMsgHandler(msgCommon.BLKTXN_TYPE, BlockTxnHandle)
 }
 
 // RegisterMsgHandler registers msg handler with the msg type
@@ -91,6 +105,7 @@ func (this *MessageRouter) SetPID(pid *actor.PID) {
 // Start starts the loop to handle the message from the network
 func (this *MessageRouter) Start() {
 	go this.hookChan(this.RecvChan, this.stopRecvCh)
+	go this.hookChan(this.ConsChan, this.stopConsCh)
 	log.Debug("[p2p]MessageRouter start to parse p2p message...")
 }
 
@@ -102,6 +117,13 @@ func (this *MessageRouter) hookChan(channel chan *types.MsgPayload,
 		case data, ok := <-channel:
 			if ok {
 				msgType := data.Payload.CmdType()
+				if msgType == msgCommon.DISCONNECT_TYPE {
+					this.limiter.Remove(data.Id)
+				} else if !this.limiter.Allow(data.Id, msgType) {
+					log.Debugf("[p2p]drop %s message from %d exceeding rate limit", msgType, data.Id)
+					Misbehave(this.p2p, data.Id, data.Addr, reputation.SCORE_RATE_LIMIT, msgType+" rate limit")
+					continue
+				}
 
 				handler, ok := this.msgHandlers[msgType]
 				if ok {
@@ -127,4 +149,7 @@ func (this *MessageRouter) Stop() {
 	if this.stopRecvCh != nil {
 		this.stopRecvCh <- true
 	}
+	if this.stopConsCh != nil {
+		this.stopConsCh <- true
+	}
 }
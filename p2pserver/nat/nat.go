@@ -0,0 +1,231 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//Package nat maps the p2p port on the NAT gateway by UPnP or NAT-PMP, so nodes behind home routers are reachable
+package nat
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common/log"
+)
+
+const (
+	MAP_LIFETIME     = 20 * time.Minute //lifetime of port mapping requested
+	MAP_REFRESH      = 15 * time.Minute //time to refresh port mapping before it expires
+	DISCOVER_TIMEOUT = 3 * time.Second  //timeout of discovering NAT gateway
+)
+
+//Interface is a NAT gateway able to map ports
+type Interface interface {
+	//AddMapping map the external port to internal port of self, return the external port actually mapped
+	AddMapping(protocol string, extPort, intPort int, name string, lifetime time.Duration) (int, error)
+	DeleteMapping(protocol string, extPort, intPort int) error
+	ExternalIP() (net.IP, error)
+	String() string
+}
+
+//Parse return the NAT gateway of spec, which is one of "none", "any", "upnp", "pmp" and "pmp:<gateway ip>". Nil
+//is returned for "none" or empty spec
+func Parse(spec string) (Interface, error) {
+	mech := strings.ToLower(spec)
+	arg := ""
+	if i := strings.Index(mech, ":"); i >= 0 {
+		mech, arg = mech[:i], spec[i+1:]
+	}
+	switch mech {
+	case "", "none", "off":
+		return nil, nil
+	case "any", "auto", "on":
+		return Any(), nil
+	case "upnp":
+		return UPnP(), nil
+	case "pmp", "natpmp", "nat-pmp":
+		if arg == "" {
+			return PMP(nil), nil
+		}
+		ip := net.ParseIP(arg)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid gateway ip %s", arg)
+		}
+		return PMP(ip), nil
+	default:
+		return nil, fmt.Errorf("unknown NAT mechanism %s", spec)
+	}
+}
+
+//Map add the port mapping of protocol tcp or udp and refresh it until quit is closed, then delete the mapping.
+//onMapped is called with the external address each time the mapping is added
+func Map(m Interface, quit chan struct{}, protocol string, port int, name string, onMapped func(ip net.IP, port int)) {
+	extPort := 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			mapped, err := m.AddMapping(protocol, port, port, name, MAP_LIFETIME)
+			if err != nil {
+				log.Warnf("[p2p]map %s port %d by %s error:%s", protocol, port, m, err)
+			} else if ip, err := m.ExternalIP(); err != nil {
+				log.Warnf("[p2p]get external ip by %s error:%s", m, err)
+			} else {
+				if mapped != extPort {
+					log.Infof("[p2p]mapped %s port %d to %s:%d by %s", protocol, port, ip, mapped, m)
+				}
+				extPort = mapped
+				onMapped(ip, mapped)
+			}
+			timer.Reset(MAP_REFRESH)
+		case <-quit:
+			if extPort != 0 {
+				if err := m.DeleteMapping(protocol, extPort, port); err != nil {
+					log.Debugf("[p2p]delete port mapping by %s error:%s", m, err)
+				}
+			}
+			return
+		}
+	}
+}
+
+//autodisc discovers the NAT gateway when it's first used
+type autodisc struct {
+	what  string
+	find  func() Interface
+	once  sync.Once
+	found Interface
+}
+
+//Any return the NAT gateway of UPnP or NAT-PMP, whichever is found first
+func Any() Interface {
+	return &autodisc{what: "any", find: func() Interface {
+		results := make(chan Interface, 2)
+		go func() {
+			results <- discoverUPnP(ssdpAddr)
+		}()
+		go func() {
+			results <- discoverPMP(potentialGateways())
+		}()
+		for i := 0; i < 2; i++ {
+			if found := <-results; found != nil {
+				return found
+			}
+		}
+		return nil
+	}}
+}
+
+//UPnP return the NAT gateway found by UPnP
+func UPnP() Interface {
+	return &autodisc{what: "upnp", find: func() Interface {
+		return discoverUPnP(ssdpAddr)
+	}}
+}
+
+//PMP return the NAT-PMP gateway, which is found in potential gateways if nil
+func PMP(gateway net.IP) Interface {
+	if gateway != nil {
+		return newPMP(&net.UDPAddr{IP: gateway, Port: PMP_PORT})
+	}
+	return &autodisc{what: "pmp", find: func() Interface {
+		return discoverPMP(potentialGateways())
+	}}
+}
+
+//discover return the NAT gateway found, nil if not found
+func (this *autodisc) discover() Interface {
+	this.once.Do(func() {
+		this.found = this.find()
+	})
+	return this.found
+}
+
+func (this *autodisc) AddMapping(protocol string, extPort, intPort int, name string, lifetime time.Duration) (int,
+	error) {
+	found := this.discover()
+	if found == nil {
+		return 0, fmt.Errorf("no %s NAT gateway found", this.what)
+	}
+	return found.AddMapping(protocol, extPort, intPort, name, lifetime)
+}
+
+func (this *autodisc) DeleteMapping(protocol string, extPort, intPort int) error {
+	found := this.discover()
+	if found == nil {
+		return fmt.Errorf("no %s NAT gateway found", this.what)
+	}
+	return found.DeleteMapping(protocol, extPort, intPort)
+}
+
+func (this *autodisc) ExternalIP() (net.IP, error) {
+	found := this.discover()
+	if found == nil {
+		return nil, fmt.Errorf("no %s NAT gateway found", this.what)
+	}
+	return found.ExternalIP()
+}
+
+func (this *autodisc) String() string {
+	if found := this.discover(); found != nil {
+		return found.String()
+	}
+	return this.what
+}
+
+//potentialGateways return the .1 addresses of private ipv4 networks of interfaces, which are gateways of most home
+//networks
+func potentialGateways() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	gateways := make([]net.IP, 0)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipnet.IP.To4()
+			if ip == nil || !isPrivate(ip) {
+				continue
+			}
+			gateway := ip.Mask(ipnet.Mask).To4()
+			gateway[3] |= 1
+			if !gateway.Equal(ip) {
+				gateways = append(gateways, gateway)
+			}
+		}
+	}
+	return gateways
+}
+
+//isPrivate return whether the ipv4 address is in private networks
+func isPrivate(ip net.IP) bool {
+	return ip[0] == 10 || (ip[0] == 172 && ip[1]&0xf0 == 16) || (ip[0] == 192 && ip[1] == 168)
+}
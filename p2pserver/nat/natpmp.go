@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	PMP_PORT            = 5351                   //port of NAT-PMP gateway
+	PMP_INITIAL_TIMEOUT = 250 * time.Millisecond //timeout of first request, doubled on each retry
+	PMP_RETRIES         = 4                      //max requests sent
+)
+
+//pmp is the NAT-PMP gateway of rfc 6886
+type pmp struct {
+	gateway *net.UDPAddr
+}
+
+func newPMP(gateway *net.UDPAddr) *pmp {
+	return &pmp{gateway: gateway}
+}
+
+//discoverPMP return the first gateway which answers the external address request, nil if none
+func discoverPMP(gateways []net.IP) Interface {
+	found := make(chan Interface, len(gateways))
+	for _, gateway := range gateways {
+		go func(gateway net.IP) {
+			client := newPMP(&net.UDPAddr{IP: gateway, Port: PMP_PORT})
+			if _, err := client.ExternalIP(); err != nil {
+				found <- nil
+				return
+			}
+			found <- client
+		}(gateway)
+	}
+	for range gateways {
+		if client := <-found; client != nil {
+			return client
+		}
+	}
+	return nil
+}
+
+func (this *pmp) AddMapping(protocol string, extPort, intPort int, name string, lifetime time.Duration) (int,
+	error) {
+	resp, err := this.mapping(protocol, extPort, intPort, uint32(lifetime/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	//the gateway may map another external port if the suggested one is in use
+	return int(binary.BigEndian.Uint16(resp[10:12])), nil
+}
+
+func (this *pmp) DeleteMapping(protocol string, extPort, intPort int) error {
+	//mapping is deleted by requesting zero lifetime and external port of the internal port
+	_, err := this.mapping(protocol, 0, intPort, 0)
+	return err
+}
+
+func (this *pmp) ExternalIP() (net.IP, error) {
+	resp, err := this.request([]byte{0, 0}, 12)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(resp[8], resp[9], resp[10], resp[11]), nil
+}
+
+func (this *pmp) String() string {
+	return fmt.Sprintf("NAT-PMP(%s)", this.gateway.IP)
+}
+
+//mapping send the mapping request of protocol and return the response
+func (this *pmp) mapping(protocol string, extPort, intPort int, lifetime uint32) ([]byte, error) {
+	req := make([]byte, 12)
+	switch protocol {
+	case "udp":
+		req[1] = 1
+	case "tcp":
+		req[1] = 2
+	default:
+		return nil, fmt.Errorf("unknown protocol %s", protocol)
+	}
+	binary.BigEndian.PutUint16(req[4:6], uint16(intPort))
+	binary.BigEndian.PutUint16(req[6:8], uint16(extPort))
+	binary.BigEndian.PutUint32(req[8:12], lifetime)
+	return this.request(req, 16)
+}
+
+//request send the request to gateway, and retry with doubled timeout until the response of opcode is received
+func (this *pmp) request(req []byte, respLen int) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, this.gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 16)
+	timeout := PMP_INITIAL_TIMEOUT
+	for i := 0; i < PMP_RETRIES; i++ {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		timeout *= 2
+		n, err := conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			return nil, err
+		}
+		if n < respLen || buf[0] != 0 || buf[1] != req[1]|0x80 {
+			continue
+		}
+		if code := binary.BigEndian.Uint16(buf[2:4]); code != 0 {
+			return nil, fmt.Errorf("NAT-PMP result code %d", code)
+		}
+		return buf[:n], nil
+	}
+	return nil, errors.New("NAT-PMP request timeout")
+}
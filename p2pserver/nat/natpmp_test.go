@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//startPMPGateway run a stand-in NAT-PMP gateway, which maps external port plus one and records the lifetimes
+func startPMPGateway(t *testing.T, extIP net.IP, lifetimes chan uint32) *net.UDPAddr {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(t, err)
+	go func() {
+		defer conn.Close()
+		buf := make([]byte, 16)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			resp := make([]byte, 16)
+			resp[1] = buf[1] | 0x80
+			if buf[1] == 0 && n == 2 {
+				copy(resp[8:12], extIP.To4())
+				conn.WriteToUDP(resp[:12], from)
+				continue
+			}
+			copy(resp[8:10], buf[4:6])
+			extPort := binary.BigEndian.Uint16(buf[6:8])
+			if extPort != 0 {
+				extPort++
+			}
+			binary.BigEndian.PutUint16(resp[10:12], extPort)
+			copy(resp[12:16], buf[8:12])
+			lifetimes <- binary.BigEndian.Uint32(buf[8:12])
+			conn.WriteToUDP(resp, from)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestPMP(t *testing.T) {
+	lifetimes := make(chan uint32, 2)
+	gateway := newPMP(startPMPGateway(t, net.IPv4(8, 8, 4, 4), lifetimes))
+
+	ip, err := gateway.ExternalIP()
+	assert.Nil(t, err)
+	assert.Equal(t, "8.8.4.4", ip.String())
+
+	extPort, err := gateway.AddMapping("tcp", 25766, 25766, "test", MAP_LIFETIME)
+	assert.Nil(t, err)
+	assert.Equal(t, 25767, extPort)
+	assert.Equal(t, uint32(MAP_LIFETIME/time.Second), <-lifetimes)
+
+	assert.Nil(t, gateway.DeleteMapping("tcp", extPort, 25766))
+	assert.Equal(t, uint32(0), <-lifetimes)
+
+	_, err = gateway.AddMapping("sctp", 25766, 25766, "test", MAP_LIFETIME)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ssdpAddr is the multicast address of UPnP discovery
+var ssdpAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+//the device types of internet gateway searched, and the prefixes of service types mapping ports
+var (
+	igdTypes = []string{
+		"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+		"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+	}
+	wanServicePrefixes = []string{
+		"urn:schemas-upnp-org:service:WANIPConnection:",
+		"urn:schemas-upnp-org:service:WANPPPConnection:",
+	}
+)
+
+//upnp is the WAN connection service of an UPnP internet gateway device
+type upnp struct {
+	serviceType string
+	controlURL  string
+	localIP     net.IP //address of self in the network of gateway
+	client      *http.Client
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	Services []upnpService `xml:"serviceList>service"`
+	Devices  []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+//discoverUPnP search internet gateway devices by SSDP at addr, and return the first one with WAN connection service,
+//nil if none answers in time
+func discoverUPnP(addr *net.UDPAddr) Interface {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(DISCOVER_TIMEOUT))
+
+	for _, st := range igdTypes {
+		req := "M-SEARCH * HTTP/1.1\r\n" +
+			"HOST: 239.255.255.250:1900\r\n" +
+			"ST: " + st + "\r\n" +
+			"MAN: \"ssdp:discover\"\r\n" +
+			"MX: 2\r\n\r\n"
+		if _, err := conn.WriteToUDP([]byte(req), addr); err != nil {
+			return nil
+		}
+	}
+	buf := make([]byte, 2048)
+	tried := make(map[string]bool)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := resp.Header.Get("Location")
+		if location == "" || tried[location] {
+			continue
+		}
+		tried[location] = true
+		if client, err := newUPnP(location); err == nil {
+			return client
+		}
+	}
+}
+
+//newUPnP return the WAN connection service in device description at location
+func newUPnP(location string) (*upnp, error) {
+	client := &http.Client{Timeout: DISCOVER_TIMEOUT}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get device description status %s", resp.Status)
+	}
+	var root upnpRoot
+	if err := xml.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return nil, fmt.Errorf("decode device description error:%s", err)
+	}
+	service := findWANService(&root.Device)
+	if service == nil {
+		return nil, errors.New("no WAN connection service")
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if root.URLBase != "" {
+		if base, err = url.Parse(root.URLBase); err != nil {
+			return nil, err
+		}
+	}
+	control, err := base.Parse(service.ControlURL)
+	if err != nil {
+		return nil, err
+	}
+	port := control.Port()
+	if port == "" {
+		port = "80"
+	}
+	conn, err := net.Dial("udp4", net.JoinHostPort(control.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	return &upnp{
+		serviceType: service.ServiceType,
+		controlURL:  control.String(),
+		localIP:     localIP,
+		client:      client,
+	}, nil
+}
+
+//findWANService return the first WAN connection service of device and its embedded devices
+func findWANService(device *upnpDevice) *upnpService {
+	for i := range device.Services {
+		for _, prefix := range wanServicePrefixes {
+			if strings.HasPrefix(device.Services[i].ServiceType, prefix) {
+				return &device.Services[i]
+			}
+		}
+	}
+	for i := range device.Devices {
+		if service := findWANService(&device.Devices[i]); service != nil {
+			return service
+		}
+	}
+	return nil
+}
+
+func (this *upnp) AddMapping(protocol string, extPort, intPort int, name string, lifetime time.Duration) (int,
+	error) {
+	_, err := this.call("AddPortMapping", []string{
+		"NewRemoteHost", "",
+		"NewExternalPort", strconv.Itoa(extPort),
+		"NewProtocol", strings.ToUpper(protocol),
+		"NewInternalPort", strconv.Itoa(intPort),
+		"NewInternalClient", this.localIP.String(),
+		"NewEnabled", "1",
+		"NewPortMappingDescription", name,
+		"NewLeaseDuration", strconv.Itoa(int(lifetime / time.Second)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return extPort, nil
+}
+
+func (this *upnp) DeleteMapping(protocol string, extPort, intPort int) error {
+	_, err := this.call("DeletePortMapping", []string{
+		"NewRemoteHost", "",
+		"NewExternalPort", strconv.Itoa(extPort),
+		"NewProtocol", strings.ToUpper(protocol),
+	})
+	return err
+}
+
+func (this *upnp) ExternalIP() (net.IP, error) {
+	resp, err := this.call("GetExternalIPAddress", nil)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(soapValue(resp, "NewExternalIPAddress"))
+	if ip == nil {
+		return nil, errors.New("invalid external ip address")
+	}
+	return ip, nil
+}
+
+func (this *upnp) String() string {
+	return fmt.Sprintf("UPnP(%s)", this.controlURL)
+}
+
+//call invoke the action of service with args of name and value pairs, and return the response body
+func (this *upnp) call(action string, args []string) ([]byte, error) {
+	body := &bytes.Buffer{}
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(body, `<u:%s xmlns:u="%s">`, action, this.serviceType)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(body, "<%s>", args[i])
+		xml.EscapeText(body, []byte(args[i+1]))
+		fmt.Fprintf(body, "</%s>", args[i])
+	}
+	fmt.Fprintf(body, "</u:%s></s:Body></s:Envelope>", action)
+
+	req, err := http.NewRequest("POST", this.controlURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+this.serviceType+"#"+action+`"`)
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s failed with status %s:%s", action, resp.Status, soapValue(data, "errorDescription"))
+	}
+	return data, nil
+}
+
+//soapValue return the text of first element named name in soap response, empty if not found
+func soapValue(data []byte, name string) string {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			var value string
+			if err := decoder.DecodeElement(&value, &start); err != nil {
+				return ""
+			}
+			return strings.TrimSpace(value)
+		}
+	}
+}
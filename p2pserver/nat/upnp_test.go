@@ -0,0 +1,142 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package nat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl/IPConn</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+//startIGD run a stand-in internet gateway device answering SSDP search, and return the SSDP address
+func startIGD(t *testing.T, actions chan string) *net.UDPAddr {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(testDescription))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		action := r.Header.Get("SOAPAction")
+		actions <- action + " " + string(body)
+		switch {
+		case strings.HasSuffix(action, `#GetExternalIPAddress"`):
+			fmt.Fprint(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">`+
+				`<s:Body><u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">`+
+				`<NewExternalIPAddress>8.8.8.8</NewExternalIPAddress></u:GetExternalIPAddressResponse></s:Body></s:Envelope>`)
+		case strings.Contains(string(body), "<NewExternalPort>1</NewExternalPort>"):
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `<s:Envelope><s:Body><s:Fault><detail><UPnPError><errorCode>718</errorCode>`+
+				`<errorDescription>ConflictInMappingEntry</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`)
+		}
+	}))
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(t, err)
+	go func() {
+		defer server.Close()
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(string(buf[:n]), "M-SEARCH") {
+				conn.WriteToUDP([]byte("HTTP/1.1 200 OK\r\nST: upnp:rootdevice\r\nLOCATION: "+server.URL+
+					"/desc.xml\r\n\r\n"), from)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestUPnP(t *testing.T) {
+	actions := make(chan string, 4)
+	gateway := discoverUPnP(startIGD(t, actions))
+	assert.NotNil(t, gateway)
+
+	ip, err := gateway.ExternalIP()
+	assert.Nil(t, err)
+	assert.Equal(t, "8.8.8.8", ip.String())
+	assert.Contains(t, <-actions, "WANIPConnection:1#GetExternalIPAddress")
+
+	extPort, err := gateway.AddMapping("tcp", 25766, 25766, "test", MAP_LIFETIME)
+	assert.Nil(t, err)
+	assert.Equal(t, 25766, extPort)
+	action := <-actions
+	assert.Contains(t, action, "#AddPortMapping")
+	assert.Contains(t, action, "<NewProtocol>TCP</NewProtocol>")
+	assert.Contains(t, action, "<NewInternalClient>127.0.0.1</NewInternalClient>")
+	assert.Contains(t, action, "<NewLeaseDuration>1200</NewLeaseDuration>")
+
+	_, err = gateway.AddMapping("tcp", 1, 25766, "test", MAP_LIFETIME)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ConflictInMappingEntry")
+	<-actions
+
+	assert.Nil(t, gateway.DeleteMapping("tcp", 25766, 25766))
+	assert.Contains(t, <-actions, "#DeletePortMapping")
+}
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{"", "none"} {
+		m, err := Parse(spec)
+		assert.Nil(t, err)
+		assert.Nil(t, m)
+	}
+	m, err := Parse("pmp:192.168.1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "NAT-PMP(192.168.1.1)", m.String())
+	m, err = Parse("upnp")
+	assert.Nil(t, err)
+	assert.NotNil(t, m)
+	_, err = Parse("pmp:router")
+	assert.NotNil(t, err)
+	_, err = Parse("stun")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package netserver
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/common/set"
+	"github.com/TesraSupernet/Tesra/p2pserver/nat"
+)
+
+//ownAddress keeps the addresses reaching self, and the public address advertised to peers
+type ownAddress struct {
+	sync.RWMutex
+	addrs    set.StringSet
+	public   string            //public ip:port advertised to peers
+	source   int               //source of public address
+	observed map[string]*observation //ip of self observed by peers, one per source network of peers
+}
+
+//observation is the ip of self observed by peer
+type observation struct {
+	id uint64
+	ip string
+}
+
+//sourceNetwork return the /24 network of ipv4 or the /48 network of ipv6 addr, peers in the same network are
+//counted as one source of observations
+func sourceNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+//isPublicIP return whether the ip is routable in internet
+func isPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return !(ip4[0] == 10 || (ip4[0] == 172 && ip4[1]&0xf0 == 16) || (ip4[0] == 192 && ip4[1] == 168) ||
+			(ip4[0] == 100 && ip4[1]&0xc0 == 64))
+	}
+	return ip[0]&0xfe != 0xfc
+}
+
+//startNAT set the public address of config, and map the sync port of transport on NAT gateway in background
+func (this *NetServer) startNAT() {
+	if config.DefConfig.P2PNode.PublicAddress != "" {
+		this.setPublicAddress(config.DefConfig.P2PNode.PublicAddress, common.ADDR_SOURCE_CONFIG)
+	}
+	gateway, err := nat.Parse(config.DefConfig.P2PNode.NAT)
+	if err != nil {
+		log.Warnf("[p2p]NAT port mapping disabled:%s", err)
+		return
+	}
+	if gateway == nil {
+		return
+	}
+	protocol := "tcp"
+	if this.transport.Name() == common.TRANSPORT_QUIC {
+		protocol = "udp"
+	}
+	this.natQuit = make(chan struct{})
+	go nat.Map(gateway, this.natQuit, protocol, int(this.GetPort()), "tesra p2p", func(ip net.IP, port int) {
+		if isPublicIP(ip) {
+			this.setPublicAddress(net.JoinHostPort(ip.String(), strconv.Itoa(port)), common.ADDR_SOURCE_NAT)
+		}
+	})
+}
+
+//SetOwnAddress record the address reaching self, which is never connected
+func (this *NetServer) SetOwnAddress(addr string) {
+	this.own.Lock()
+	defer this.own.Unlock()
+	if !this.own.addrs.Has(addr) {
+		log.Infof("[p2p]set own address %s", addr)
+		this.own.addrs.Insert(addr)
+	}
+}
+
+//IsOwnAddress return whether the address reaches self
+func (this *NetServer) IsOwnAddress(addr string) bool {
+	this.own.RLock()
+	defer this.own.RUnlock()
+	return this.own.addrs.Has(addr)
+}
+
+//GetPublicAddress return the public ip:port advertised to peers, empty if unknown
+func (this *NetServer) GetPublicAddress() string {
+	this.own.RLock()
+	defer this.own.RUnlock()
+	return this.own.public
+}
+
+//setPublicAddress advertise the public address, unless the current one is of higher priority source
+func (this *NetServer) setPublicAddress(addr string, source int) {
+	this.own.Lock()
+	if source < this.own.source || addr == this.own.public {
+		this.own.Unlock()
+		return
+	}
+	this.own.public = addr
+	this.own.source = source
+	this.own.Unlock()
+
+	log.Infof("[p2p]advertise public address %s", addr)
+	this.SetOwnAddress(addr)
+}
+
+//ReportObservedAddress record the address of self observed by peer at remoteAddr. Peers in the same network vote
+//once, and the ip agreed by most networks, at least OBSERVED_ADDR_MIN_REPORTS of them, is advertised with the sync
+//port if no better public address is known
+func (this *NetServer) ReportObservedAddress(id uint64, remoteAddr, addr string) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return
+	}
+	source := sourceNetwork(remoteAddr)
+	if source == "" {
+		return
+	}
+
+	this.own.Lock()
+	if _, ok := this.own.observed[source]; !ok && len(this.own.observed) >= common.MAX_OBSERVED_REPORTS {
+		this.own.Unlock()
+		return
+	}
+	this.own.observed[source] = &observation{id: id, ip: ip.String()}
+	counts := make(map[string]int)
+	best := ""
+	for _, observed := range this.own.observed {
+		counts[observed.ip]++
+		if counts[observed.ip] > counts[best] {
+			best = observed.ip
+		}
+	}
+	agreed := counts[best] >= common.OBSERVED_ADDR_MIN_REPORTS && counts[best]*2 > len(this.own.observed)
+	this.own.Unlock()
+
+	if agreed {
+		this.setPublicAddress(net.JoinHostPort(best, strconv.Itoa(int(this.GetPort()))), common.ADDR_SOURCE_OBSERVED)
+	}
+}
+
+//removeObservedAddress remove the observation of disconnected peer
+func (this *NetServer) removeObservedAddress(id uint64) {
+	this.own.Lock()
+	defer this.own.Unlock()
+	for source, observed := range this.own.observed {
+		if observed.id == id {
+			delete(this.own.observed, source)
+		}
+	}
+}
@@ -20,7 +20,7 @@ package netserver
 
 import (
 	"errors"
-	"math/rand"
+	"fmt"
 	"net"
 	"strings"
 	"sync"
@@ -31,36 +31,49 @@ import (
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
 	"github.com/TesraSupernet/Tesra/p2pserver/common/set"
+	"github.com/TesraSupernet/Tesra/p2pserver/dht"
+	"github.com/TesraSupernet/Tesra/p2pserver/link"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
 	"github.com/TesraSupernet/Tesra/p2pserver/net/protocol"
 	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 //NewNetServer return the net object in p2p
 func NewNetServer() p2p.P2P {
 	n := &NetServer{
-		NetChan: make(chan *types.MsgPayload, common.CHAN_CAPABILITY),
+		NetChan:  make(chan *types.MsgPayload, common.CHAN_CAPABILITY),
+		ConsChan: make(chan *types.MsgPayload, common.CHAN_CAPABILITY),
 	}
 
 	n.PeerAddrMap.PeerAddress = make(map[string]*peer.Peer)
 
-	n.init()
+	n.initErr = n.init()
 	return n
 }
 
 //NetServer represent all the actions in net layer
 type NetServer struct {
-	base     peer.PeerCom
-	listener net.Listener
-	NetChan  chan *types.MsgPayload
+	base      peer.PeerCom
+	transport link.Transport
+	listener  net.Listener
+	NetChan   chan *types.MsgPayload
+	ConsChan  chan *types.MsgPayload //consensus messages from consensus overlay
 	connectingNodes
 	PeerAddrMap
 	Np            *peer.NbrPeers
 	connectLock   sync.Mutex
 	inConnRecord  InConnectionRecord
 	outConnRecord OutConnectionRecord
-	OwnAddress    string //network`s own address(ip : sync port),which get from version check
+	own           ownAddress
+	key           *common.NodeKey
+	initErr       error //error of init, the server fails to start with it
+	table         *dht.RoutingTable
+	reputation    *reputation.Reputation
+	signer        common.ValidatorSigner
+	overlay       *consensusOverlay
+	natQuit       chan struct{}
 }
 
 //InConnectionRecord include all addr connected
@@ -106,25 +119,52 @@ func (this *NetServer) init() error {
 
 	this.base.SetRelay(true)
 
-	rand.Seed(time.Now().UnixNano())
-	id := rand.Uint64()
+	transport, err := newTransport(config.DefConfig.P2PNode.Transport, config.DefConfig.P2PNode.IsTLS)
+	if err != nil {
+		log.Errorf("[p2p]init transport error:%s", err)
+		return err
+	}
+	this.transport = transport
 
-	this.base.SetID(id)
+	//a new node key is generated only if the key file not exist, a broken key file must not change the node identity
+	key, err := common.LoadNodeKey(config.DefConfig.P2PNode.NodeKeyPath)
+	if err != nil {
+		log.Errorf("[p2p]load node key error:%s", err)
+		return err
+	}
+	this.key = key
+	this.base.SetID(key.ID())
+	this.table = dht.NewRoutingTable(key.ID())
+	this.reputation = reputation.NewReputation(config.DefConfig.P2PNode.BanListPath)
+	this.reputation.SetExempt(this.banExempt)
+	for _, entry := range config.DefConfig.P2PNode.ReservedCfg.ReservedPeers {
+		if _, err := common.ParseReservedPeer(entry); err != nil {
+			log.Warnf("[p2p]ignore reserved peer:%s", err)
+		}
+	}
 
-	log.Infof("[p2p]init peer ID to %d", this.base.GetID())
+	log.Infof("[p2p]init peer ID to %d, node public key %x", this.base.GetID(), []byte(key.PublicKey))
 	this.Np = &peer.NbrPeers{}
 	this.Np.Init()
 
 	this.connectingNodes.ConnectingAddrs = set.NewStringSet()
 	this.inConnRecord.InConnectingAddrs = set.NewStringSet()
 	this.outConnRecord.OutConnectingAddrs = set.NewStringSet()
+	this.own.addrs = set.NewStringSet()
+	this.own.observed = make(map[string]*observation)
 
 	return nil
 }
 
 //InitListen start listening on the config port
-func (this *NetServer) Start() {
+func (this *NetServer) Start() error {
+	if this.initErr != nil {
+		return this.initErr
+	}
 	this.startListening()
+	this.startOverlay()
+	this.startNAT()
+	return nil
 }
 
 //GetVersion return self peer`s version
@@ -163,6 +203,14 @@ func (this *NetServer) GetPort() uint16 {
 	return this.base.GetPort()
 }
 
+//GetConsPort return the port of consensus overlay, 0 if not in overlay
+func (this *NetServer) GetConsPort() uint16 {
+	if this.overlay == nil {
+		return 0
+	}
+	return this.overlay.port
+}
+
 //GetHttpInfoPort return the port support info via http
 func (this *NetServer) GetHttpInfoPort() uint16 {
 	return this.base.GetHttpInfoPort()
@@ -193,6 +241,16 @@ func (this *NetServer) GetConnectionCnt() uint32 {
 	return this.Np.GetNbrNodeCnt()
 }
 
+//GetRoutingTable return the routing table of discovery
+func (this *NetServer) GetRoutingTable() *dht.RoutingTable {
+	return this.table
+}
+
+//GetReputation return the scores and bans of peers
+func (this *NetServer) GetReputation() *reputation.Reputation {
+	return this.reputation
+}
+
 //AddNbrNode add peer to nbr peer list
 func (this *NetServer) AddNbrNode(remotePeer *peer.Peer) {
 	this.Np.AddNbrNode(remotePeer)
@@ -200,6 +258,7 @@ func (this *NetServer) AddNbrNode(remotePeer *peer.Peer) {
 
 //DelNbrNode delete nbr peer by id
 func (this *NetServer) DelNbrNode(id uint64) (*peer.Peer, bool) {
+	this.removeObservedAddress(id)
 	return this.Np.DelNbrNode(id)
 }
 
@@ -213,8 +272,13 @@ func (this *NetServer) NodeEstablished(id uint64) bool {
 	return this.Np.NodeEstablished(id)
 }
 
-//Xmit called by actor, broadcast msg
+//Xmit called by actor, broadcast msg. Consensus msg is sent by consensus overlay first, and the validators not in
+//overlay receive it from sync links
 func (this *NetServer) Xmit(msg types.Message) {
+	if this.overlay != nil && msg.CmdType() == common.CONSENSUS_TYPE {
+		this.Np.BroadcastExcept(msg, this.overlay.broadcast(msg))
+		return
+	}
 	this.Np.Broadcast(msg)
 }
 
@@ -223,6 +287,35 @@ func (this *NetServer) GetMsgChan() chan *types.MsgPayload {
 	return this.NetChan
 }
 
+//GetConsMsgChan return the channel of consensus messages from consensus overlay
+func (this *NetServer) GetConsMsgChan() chan *types.MsgPayload {
+	return this.ConsChan
+}
+
+//SetValidatorSigner set the signer of validator before start, which enables consensus overlay
+func (this *NetServer) SetValidatorSigner(signer common.ValidatorSigner) {
+	this.signer = signer
+}
+
+//SetValidators update the public key ids of current validators allowed in consensus overlay
+func (this *NetServer) SetValidators(validators []string) {
+	if this.overlay != nil {
+		this.overlay.setValidators(validators)
+	}
+}
+
+//SendConsensus send consensus msg to peer by consensus overlay, or by sync link if the peer is not in overlay
+func (this *NetServer) SendConsensus(id uint64, msg types.Message) error {
+	if this.overlay != nil && this.overlay.send(id, msg) {
+		return nil
+	}
+	p := this.Np.GetPeer(id)
+	if p == nil || !this.Np.NodeEstablished(id) {
+		return fmt.Errorf("[p2p]no valid neighbor peer: %d", id)
+	}
+	return p.Send(msg)
+}
+
 //Tx send data buf to peer
 func (this *NetServer) Send(p *peer.Peer, msg types.Message) error {
 	if p != nil {
@@ -272,41 +365,38 @@ func (this *NetServer) Connect(addr string) error {
 	}
 	this.connectLock.Unlock()
 
-	isTls := config.DefConfig.P2PNode.IsTLS
-	var conn net.Conn
-	var err error
 	var remotePeer *peer.Peer
-	if isTls {
-		conn, err = TLSDial(addr)
-		if err != nil {
-			this.RemoveFromConnectingList(addr)
-			log.Debugf("[p2p]connect %s failed:%s", addr, err.Error())
-			return err
-		}
-	} else {
-		conn, err = nonTLSDial(addr)
-		if err != nil {
-			this.RemoveFromConnectingList(addr)
-			log.Debugf("[p2p]connect %s failed:%s", addr, err.Error())
-			return err
-		}
+	raw, err := this.transport.Dial(addr)
+	if err != nil {
+		this.RemoveFromConnectingList(addr)
+		log.Debugf("[p2p]connect %s failed:%s", addr, err.Error())
+		return err
 	}
 
-	addr = conn.RemoteAddr().String()
+	addr = raw.RemoteAddr().String()
 	log.Debugf("[p2p]peer %s connect with %s with %s",
-		conn.LocalAddr().String(), conn.RemoteAddr().String(),
-		conn.RemoteAddr().Network())
+		raw.LocalAddr().String(), raw.RemoteAddr().String(),
+		raw.RemoteAddr().Network())
+
+	conn, pubKey, err := this.authenticate(raw, addr)
+	if err != nil {
+		conn.Close()
+		this.RemoveFromConnectingList(addr)
+		log.Warnf("[p2p]authenticate %s failed:%s", addr, err)
+		return err
+	}
 
 	this.AddOutConnRecord(addr)
 	remotePeer = peer.NewPeer()
 	this.AddPeerAddress(addr, remotePeer)
 	remotePeer.Link.SetAddr(addr)
-	remotePeer.Link.SetConn(conn)
+	setLinkConn(remotePeer.Link, raw, conn)
+	remotePeer.Link.SetPublicKey(pubKey)
 	remotePeer.AttachChan(this.NetChan)
 	go remotePeer.Link.Rx()
 	remotePeer.SetState(common.HAND)
 
-	version := msgpack.NewVersion(this, ledger.DefLedger.GetCurrentBlockHeight())
+	version := msgpack.NewVersion(this, ledger.DefLedger.GetCurrentBlockHeight(), addr)
 	err = remotePeer.Send(version)
 	if err != nil {
 		this.RemoveFromOutConnRecord(addr)
@@ -325,6 +415,12 @@ func (this *NetServer) Halt() {
 	if this.listener != nil {
 		this.listener.Close()
 	}
+	if this.overlay != nil {
+		this.overlay.stop()
+	}
+	if this.natQuit != nil {
+		close(this.natQuit)
+	}
 }
 
 //establishing the connection to remote peers and listening for inbound peers
@@ -346,17 +442,36 @@ func (this *NetServer) startListening() error {
 	return nil
 }
 
+//startOverlay start consensus overlay if the node is a validator with consensus port configured. Failure of overlay
+//is not fatal, consensus messages are sent by sync links then
+func (this *NetServer) startOverlay() {
+	port := config.DefConfig.P2PNode.ConsensusPort
+	if this.signer == nil || !config.DefConfig.Consensus.EnableConsensus || port == 0 {
+		return
+	}
+	if port == config.DefConfig.P2PNode.NodePort {
+		log.Errorf("[p2p]consensus port %d conflicts with sync port, consensus overlay disabled", port)
+		return
+	}
+	overlay := newConsensusOverlay(this, this.signer, uint16(port))
+	if err := overlay.start(); err != nil {
+		log.Errorf("[p2p]start consensus overlay error:%s, consensus overlay disabled", err)
+		return
+	}
+	this.overlay = overlay
+}
+
 // startNetListening starts a sync listener on the port for the inbound peer
 func (this *NetServer) startNetListening(port uint16) error {
 	var err error
-	this.listener, err = createListener(port)
+	this.listener, err = this.transport.Listen(port)
 	if err != nil {
-		log.Error("[p2p]failed to create sync listener")
+		log.Errorf("[p2p]failed to create sync listener:%s", err)
 		return errors.New("[p2p]failed to create sync listener")
 	}
 
 	go this.startNetAccept(this.listener)
-	log.Infof("[p2p]start listen on sync port %d", port)
+	log.Infof("[p2p]start listen on sync port %d by %s", port, this.transport.Name())
 	return nil
 }
 
@@ -373,7 +488,7 @@ func (this *NetServer) startNetAccept(listener net.Listener) {
 		log.Debug("[p2p]remote sync node connect with ",
 			conn.RemoteAddr(), conn.LocalAddr())
 		if !this.AddrValid(conn.RemoteAddr().String()) {
-			log.Warnf("[p2p]remote %s not in reserved list, close it ", conn.RemoteAddr())
+			log.Warnf("[p2p]remote %s not in reserved list or banned, close it ", conn.RemoteAddr())
 			conn.Close()
 			continue
 		}
@@ -405,17 +520,82 @@ func (this *NetServer) startNetAccept(listener net.Listener) {
 			continue
 		}
 
-		remotePeer := peer.NewPeer()
 		addr := conn.RemoteAddr().String()
 		this.AddInConnRecord(addr)
+		go this.startInboundPeer(conn, addr)
+	}
+}
+
+//startInboundPeer authenticate the inbound connection if required and start receiving from the peer
+func (this *NetServer) startInboundPeer(raw net.Conn, addr string) {
+	conn, pubKey, err := this.authenticate(raw, addr)
+	if err != nil {
+		conn.Close()
+		this.RemoveFromInConnRecord(addr)
+		log.Warnf("[p2p]authenticate %s failed:%s", addr, err)
+		return
+	}
+
+	remotePeer := peer.NewPeer()
+	this.AddPeerAddress(addr, remotePeer)
+
+	remotePeer.Link.SetAddr(addr)
+	remotePeer.Link.SetInbound(true)
+	setLinkConn(remotePeer.Link, raw, conn)
+	remotePeer.Link.SetPublicKey(pubKey)
+	remotePeer.AttachChan(this.NetChan)
+	go remotePeer.Link.Rx()
+}
+
+//setLinkConn set the conn of link, which may be encrypted from the raw conn of transport, and the streams of raw
+//conn if it's multiplexed
+func setLinkConn(l *link.Link, raw net.Conn, conn net.Conn) {
+	l.SetConn(conn)
+	if streams, ok := raw.(link.StreamConn); ok {
+		l.SetStreams(streams)
+	}
+}
 
-		this.AddPeerAddress(addr, remotePeer)
+//banExempt return whether the peer is never banned for misbehavior, which is a reserved peer or a validator
+//connected in consensus overlay
+func (this *NetServer) banExempt(id uint64, addr string) bool {
+	if this.overlay != nil && this.overlay.isConnected(id) {
+		return true
+	}
+	rsv := config.DefConfig.P2PNode.ReservedCfg.ReservedPeers
+	if len(rsv) == 0 {
+		return false
+	}
+	var pubKey []byte
+	if remotePeer := this.GetPeer(id); remotePeer != nil {
+		pubKey = remotePeer.Link.GetPublicKey()
+	}
+	return common.CheckReservedPeer(rsv, true, addr, pubKey)
+}
 
-		remotePeer.Link.SetAddr(addr)
-		remotePeer.Link.SetConn(conn)
-		remotePeer.AttachChan(this.NetChan)
-		go remotePeer.Link.Rx()
+//AuthRequired return whether peers must be authenticated by node keys, which is required by config or reserved
+//peers pinning public keys
+func (this *NetServer) AuthRequired() bool {
+	return config.DefConfig.P2PNode.AuthEnabled ||
+		common.HasPinnedKeys(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers)
+}
+
+//authenticate run the authenticated handshake over connection if required, and check the public key of peer with
+//reserved peers. Return the encrypted connection and public key of peer, or the original connection if not required
+func (this *NetServer) authenticate(conn net.Conn, addr string) (net.Conn, []byte, error) {
+	if !this.AuthRequired() {
+		return conn, nil, nil
+	}
+	secure, pubKey, err := link.Handshake(conn, this.key, config.DefConfig.P2PNode.NetworkMagic)
+	if err != nil {
+		return conn, nil, err
+	}
+	rsv := config.DefConfig.P2PNode.ReservedCfg.ReservedPeers
+	reservedOnly := config.DefConfig.P2PNode.ReservedPeersOnly && len(rsv) > 0
+	if !common.CheckReservedPeer(rsv, reservedOnly, addr, pubKey) {
+		return conn, nil, fmt.Errorf("public key %x not allowed by reserved peers", []byte(pubKey))
 	}
+	return secure, pubKey, nil
 }
 
 //record the peer which is going to be dialed and sent version message but not in establish state
@@ -599,27 +779,16 @@ func (this *NetServer) GetOutConnRecordLen() int {
 
 //AddrValid whether the addr could be connect or accept
 func (this *NetServer) AddrValid(addr string) bool {
+	if this.reputation != nil && this.reputation.IsBanned(addr) {
+		log.Debugf("[p2p]address %s is banned", addr)
+		return false
+	}
 	if config.DefConfig.P2PNode.ReservedPeersOnly && len(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers) > 0 {
-		for _, ip := range config.DefConfig.P2PNode.ReservedCfg.ReservedPeers {
-			if strings.HasPrefix(addr, ip) {
-				log.Info("[p2p]found reserved peer :", addr)
-				return true
-			}
+		if common.CheckReservedPeer(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers, true, addr, nil) {
+			log.Info("[p2p]found reserved peer :", addr)
+			return true
 		}
 		return false
 	}
 	return true
 }
-
-//check own network address
-func (this *NetServer) IsOwnAddress(addr string) bool {
-	return addr == this.OwnAddress
-}
-
-//Set own network address
-func (this *NetServer) SetOwnAddress(addr string) {
-	if addr != this.OwnAddress {
-		log.Infof("[p2p]set own address %s", addr)
-		this.OwnAddress = addr
-	}
-}
@@ -20,12 +20,17 @@ package netserver
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
 	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 	"github.com/stretchr/testify/require"
 )
 
@@ -185,3 +190,56 @@ func TestOutConnAPI(t *testing.T) {
 	a.Equal(server.IsAddrInOutConnRecord("192.168.1.1:300"), false, "fail to test IsAddrInOutConnRecord")
 	a.Equal(server.IsAddrInOutConnRecord("192.168.1.1:200"), true, "fail to test IsAddrInOutConnRecord")
 }
+
+func TestPublicAddress(t *testing.T) {
+	server := NewNetServer().(*NetServer)
+
+	//the observations of non public ip are ignored, and the ip agreed by most peer networks is advertised
+	server.ReportObservedAddress(1, "3.0.0.1:20338", "192.168.1.2:30000")
+	server.ReportObservedAddress(2, "3.0.1.1:20338", "8.8.8.8:30001")
+	server.ReportObservedAddress(3, "3.0.2.1:20338", "8.8.8.8:30002")
+	require.Equal(t, "", server.GetPublicAddress())
+	//peers in the same network vote once
+	server.ReportObservedAddress(7, "3.0.2.2:20338", "8.8.8.8:30002")
+	server.ReportObservedAddress(8, "3.0.2.3:20338", "8.8.8.8:30002")
+	require.Equal(t, "", server.GetPublicAddress())
+	server.ReportObservedAddress(4, "3.0.3.1:20338", "8.8.4.4:30003")
+	server.ReportObservedAddress(5, "3.0.4.1:20338", "8.8.8.8:30004")
+	addr := "8.8.8.8:" + strconv.Itoa(int(server.GetPort()))
+	require.Equal(t, addr, server.GetPublicAddress())
+	require.True(t, server.IsOwnAddress(addr))
+
+	//the address mapped on NAT replaces the observed one, and the configured one is never replaced
+	server.setPublicAddress("8.8.8.8:30000", common.ADDR_SOURCE_NAT)
+	require.Equal(t, "8.8.8.8:30000", server.GetPublicAddress())
+	server.setPublicAddress("1.1.1.1:25766", common.ADDR_SOURCE_CONFIG)
+	server.setPublicAddress("8.8.8.8:30000", common.ADDR_SOURCE_NAT)
+	server.ReportObservedAddress(6, "3.0.5.1:20338", "8.8.8.8:30005")
+	require.Equal(t, "1.1.1.1:25766", server.GetPublicAddress())
+	require.True(t, server.IsOwnAddress(addr))
+}
+
+func TestNetServerBrokenNodeKey(t *testing.T) {
+	path := "test_nodekey"
+	defer os.Remove(path)
+	require.Nil(t, ioutil.WriteFile(path, []byte("broken"), 0600))
+	keyPath := config.DefConfig.P2PNode.NodeKeyPath
+	config.DefConfig.P2PNode.NodeKeyPath = path
+	defer func() { config.DefConfig.P2PNode.NodeKeyPath = keyPath }()
+
+	server := NewNetServer()
+	require.NotNil(t, server.Start())
+}
+
+func TestBanExemptReservedPeer(t *testing.T) {
+	rsv := config.DefConfig.P2PNode.ReservedCfg.ReservedPeers
+	config.DefConfig.P2PNode.ReservedCfg.ReservedPeers = []string{"10.0.0.1"}
+	defer func() { config.DefConfig.P2PNode.ReservedCfg.ReservedPeers = rsv }()
+
+	server := NewNetServer().(*NetServer)
+	rep := server.GetReputation()
+	require.False(t, rep.Misbehave(1, "10.0.0.1:20338", reputation.SCORE_INVALID_BLOCK, "invalid block"))
+	require.False(t, rep.IsBanned("10.0.0.1:20338"))
+	require.True(t, rep.Misbehave(2, "10.0.0.2:20338", reputation.SCORE_INVALID_BLOCK, "invalid block"))
+	require.True(t, rep.IsBanned("10.0.0.2:20338"))
+}
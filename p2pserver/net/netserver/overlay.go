@@ -0,0 +1,383 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package netserver
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/tesracrypto/keypair"
+	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/link"
+	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
+)
+
+//consensusOverlay is the fully meshed network between validators on a separate port. It carries consensus messages
+//only, over connections authenticated by both node keys and validator keys, so public gossip can't delay them
+type consensusOverlay struct {
+	server     *NetServer
+	signer     common.ValidatorSigner
+	port       uint16
+	transport  link.Transport
+	listener   net.Listener
+	lock       sync.RWMutex
+	validators map[string]bool         //public key ids of current validators
+	peers      map[uint64]*overlayPeer //connected validators by peer id
+	dialing    map[string]bool         //overlay addresses being connected
+	failed     map[string]time.Time    //time of the last failed connection by overlay address
+	handshakes chan struct{}           //slots of inbound connections in handshake
+	quit       chan struct{}
+}
+
+//overlayPeer is a validator connected in consensus overlay
+type overlayPeer struct {
+	id        uint64
+	addr      string
+	validator string //public key id of validator
+	outbound  bool
+	queue     *link.SendQueue
+}
+
+//newConsensusOverlay return the consensus overlay listening on port
+func newConsensusOverlay(server *NetServer, signer common.ValidatorSigner, port uint16) *consensusOverlay {
+	return &consensusOverlay{
+		server:     server,
+		signer:     signer,
+		port:       port,
+		validators: make(map[string]bool),
+		peers:      make(map[uint64]*overlayPeer),
+		dialing:    make(map[string]bool),
+		failed:     make(map[string]time.Time),
+		handshakes: make(chan struct{}, common.OVERLAY_MAX_HANDSHAKES),
+		quit:       make(chan struct{}),
+	}
+}
+
+//validatorID return the public key id of validator, the same as the peer id of vbft chain config
+func validatorID(pubKey keypair.PublicKey) string {
+	return hex.EncodeToString(keypair.SerializePublicKey(pubKey))
+}
+
+//start listen on overlay port and keep connecting the validators in neighbors
+func (this *consensusOverlay) start() error {
+	transport, err := newTransport(config.DefConfig.P2PNode.ConsensusTransport, false)
+	if err != nil {
+		return err
+	}
+	listener, err := transport.Listen(this.port)
+	if err != nil {
+		return err
+	}
+	this.transport = transport
+	this.listener = listener
+	go this.accept()
+	go this.keepConnected()
+	log.Infof("[p2p]start listen on consensus port %d by %s", this.port, transport.Name())
+	return nil
+}
+
+//stop close the listener and all overlay connections
+func (this *consensusOverlay) stop() {
+	close(this.quit)
+	if this.listener != nil {
+		this.listener.Close()
+	}
+	for _, p := range this.getPeers() {
+		p.queue.Close()
+	}
+}
+
+//accept accepts the overlay connections from validators, when self is a current validator. Connections beyond
+//OVERLAY_MAX_HANDSHAKES in handshake are refused
+func (this *consensusOverlay) accept() {
+	for {
+		conn, err := this.listener.Accept()
+		if err != nil {
+			log.Infof("[p2p]stop accepting consensus connections:%s", err)
+			return
+		}
+		addr := conn.RemoteAddr().String()
+		if !this.server.AddrValid(addr) || !this.isValidator(validatorID(this.signer.PublicKey())) {
+			conn.Close()
+			continue
+		}
+		select {
+		case this.handshakes <- struct{}{}:
+		default:
+			log.Debugf("[p2p]too many consensus connections in handshake, refuse %s", addr)
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer func() { <-this.handshakes }()
+			if err := this.setup(conn, addr, false); err != nil {
+				log.Infof("[p2p]consensus connection from %s refused:%s", addr, err)
+			}
+		}()
+	}
+}
+
+//keepConnected connect the validators in neighbors which are not in overlay periodically
+func (this *consensusOverlay) keepConnected() {
+	ticker := time.NewTicker(common.OVERLAY_CHECK_INTERVAL * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			this.connectValidators()
+		case <-this.quit:
+			return
+		}
+	}
+}
+
+//connectValidators dial the overlay port of the neighbors advertising it, when self is a current validator
+func (this *consensusOverlay) connectValidators() {
+	if !this.isValidator(validatorID(this.signer.PublicKey())) {
+		return
+	}
+	now := time.Now()
+	for _, p := range this.server.Np.GetNeighbors() {
+		if p.GetState() != common.ESTABLISH || p.GetConsPort() == 0 || this.isConnected(p.GetID()) {
+			continue
+		}
+		ip, err := common.ParseIPAddr(p.GetAddr())
+		if err != nil {
+			continue
+		}
+		addr := ip + ":" + strconv.Itoa(int(p.GetConsPort()))
+
+		this.lock.Lock()
+		last, failed := this.failed[addr]
+		if this.dialing[addr] || (failed && now.Sub(last) < common.OVERLAY_RETRY_INTERVAL*time.Second) {
+			this.lock.Unlock()
+			continue
+		}
+		this.dialing[addr] = true
+		this.lock.Unlock()
+
+		go this.connect(addr)
+	}
+}
+
+//connect dial the overlay address of validator
+func (this *consensusOverlay) connect(addr string) {
+	conn, err := this.transport.Dial(addr)
+	if err == nil {
+		err = this.setup(conn, addr, true)
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.dialing, addr)
+	if err != nil {
+		log.Debugf("[p2p]connect consensus overlay %s failed:%s", addr, err)
+		this.failed[addr] = time.Now()
+	} else {
+		delete(this.failed, addr)
+	}
+}
+
+//setup authenticate the node and validator of connection, and start the overlay peer
+func (this *consensusOverlay) setup(conn net.Conn, addr string, outbound bool) error {
+	secure, nodeKey, err := link.Handshake(conn, this.server.key, config.DefConfig.P2PNode.NetworkMagic)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	id := common.PubKeyToID(nodeKey)
+	if id == this.server.GetID() {
+		conn.Close()
+		return errors.New("connected to self")
+	}
+	pubKey, err := link.ValidatorHandshake(secure, this.signer, this.server.key.PublicKey, nodeKey, outbound,
+		func(pubKey keypair.PublicKey) error {
+			if !this.isValidator(validatorID(pubKey)) {
+				return fmt.Errorf("%s is not a current validator", validatorID(pubKey))
+			}
+			return nil
+		})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	validator := validatorID(pubKey)
+
+	p := &overlayPeer{
+		id:        id,
+		addr:      addr,
+		validator: validator,
+		outbound:  outbound,
+	}
+	p.queue = link.NewSendQueue(secure, func() {
+		this.removePeer(p)
+	})
+	if !this.addPeer(p) {
+		conn.Close()
+		return errors.New("duplicate connection")
+	}
+	p.queue.Start()
+	go this.rx(p, secure)
+	log.Infof("[p2p]validator %d at %s joined consensus overlay", id, addr)
+	return nil
+}
+
+//rx push the consensus messages from overlay peer to the consensus channel
+func (this *consensusOverlay) rx(p *overlayPeer, conn net.Conn) {
+	defer p.queue.Close()
+	reader := bufio.NewReaderSize(conn, common.MAX_BUF_LEN)
+	for {
+		msg, payloadSize, err := types.ReadMessage(reader)
+		if err != nil {
+			log.Infof("[p2p]error read from consensus overlay %s :%s", p.addr, err)
+			return
+		}
+		if msg.CmdType() != common.CONSENSUS_TYPE {
+			log.Warnf("[p2p]unexpected %s message from consensus overlay %s", msg.CmdType(), p.addr)
+			return
+		}
+		this.server.ConsChan <- &types.MsgPayload{
+			Id:          p.id,
+			Addr:        p.addr,
+			PayloadSize: payloadSize,
+			Payload:     msg,
+		}
+	}
+}
+
+//dialer return the peer id of node which dialed the connection
+func (this *overlayPeer) dialer(localID uint64) uint64 {
+	if this.outbound {
+		return localID
+	}
+	return this.id
+}
+
+//addPeer add the overlay peer, return false if the existing connection with the peer is kept
+func (this *consensusOverlay) addPeer(p *overlayPeer) bool {
+	this.lock.Lock()
+	old, ok := this.peers[p.id]
+	localID := this.server.GetID()
+	//when both validators connected each other at the same time, both sides keep the one dialed by the lower id
+	if ok && old.dialer(localID) < p.dialer(localID) {
+		this.lock.Unlock()
+		return false
+	}
+	this.peers[p.id] = p
+	this.lock.Unlock()
+
+	if ok {
+		old.queue.Close()
+	}
+	return true
+}
+
+//removePeer remove the overlay peer if it's not replaced by another connection
+func (this *consensusOverlay) removePeer(p *overlayPeer) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.peers[p.id] == p {
+		delete(this.peers, p.id)
+		log.Infof("[p2p]validator %d at %s left consensus overlay", p.id, p.addr)
+	}
+}
+
+//getPeers return all overlay peers
+func (this *consensusOverlay) getPeers() []*overlayPeer {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	peers := make([]*overlayPeer, 0, len(this.peers))
+	for _, p := range this.peers {
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+//isConnected return whether the peer is in overlay
+func (this *consensusOverlay) isConnected(id uint64) bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	_, ok := this.peers[id]
+	return ok
+}
+
+//isValidator return whether the public key id is of a current validator
+func (this *consensusOverlay) isValidator(id string) bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.validators[id]
+}
+
+//setValidators update current validators, and disconnect the overlay peers no longer validators
+func (this *consensusOverlay) setValidators(ids []string) {
+	validators := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		validators[id] = true
+	}
+	this.lock.Lock()
+	this.validators = validators
+	this.lock.Unlock()
+
+	for _, p := range this.getPeers() {
+		if !validators[p.validator] {
+			p.queue.Close()
+		}
+	}
+}
+
+//send queue the message to overlay peer, return false if the peer is not in overlay or its queue is full
+func (this *consensusOverlay) send(id uint64, msg types.Message) bool {
+	this.lock.RLock()
+	p, ok := this.peers[id]
+	this.lock.RUnlock()
+	if !ok {
+		return false
+	}
+	sink := comm.NewZeroCopySink(nil)
+	types.WriteMessage(sink, msg)
+	if err := p.queue.Push(sink.Bytes()); err != nil {
+		log.Warnf("[p2p]send to consensus overlay %s failed:%s", p.addr, err)
+		return false
+	}
+	return true
+}
+
+//broadcast queue the message to all overlay peers, return the ids of peers queued
+func (this *consensusOverlay) broadcast(msg types.Message) map[uint64]bool {
+	sink := comm.NewZeroCopySink(nil)
+	types.WriteMessage(sink, msg)
+	sent := make(map[uint64]bool)
+	for _, p := range this.getPeers() {
+		if err := p.queue.Push(sink.Bytes()); err != nil {
+			log.Warnf("[p2p]send to consensus overlay %s failed:%s", p.addr, err)
+			continue
+		}
+		sent[p.id] = true
+	}
+	return sent
+}
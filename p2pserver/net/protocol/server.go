@@ -21,18 +21,21 @@ package p2p
 
 import (
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/dht"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
 	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 )
 
 //P2P represent the net interface of p2p package
 type P2P interface {
-	Start()
+	Start() error
 	Halt()
 	Connect(addr string) error
 	GetID() uint64
 	GetVersion() uint32
 	GetPort() uint16
+	GetConsPort() uint16
 	GetHttpInfoPort() uint16
 	GetRelay() bool
 	GetHeight() uint64
@@ -47,6 +50,10 @@ type P2P interface {
 	IsPeerEstablished(p *peer.Peer) bool
 	Send(p *peer.Peer, msg types.Message) error
 	GetMsgChan() chan *types.MsgPayload
+	GetConsMsgChan() chan *types.MsgPayload
+	SetValidatorSigner(signer common.ValidatorSigner)
+	SetValidators(validators []string)
+	SendConsensus(id uint64, msg types.Message) error
 	GetPeerFromAddr(addr string) *peer.Peer
 	AddOutConnectingList(addr string) (added bool)
 	GetOutConnRecordLen() int
@@ -62,5 +69,9 @@ type P2P interface {
 	Xmit(msg types.Message)
 	SetOwnAddress(addr string)
 	IsOwnAddress(addr string) bool
+	GetPublicAddress() string
+	ReportObservedAddress(id uint64, remoteAddr, addr string)
 	IsAddrFromConnecting(addr string) bool
+	GetRoutingTable() *dht.RoutingTable
+	GetReputation() *reputation.Reputation
 }
@@ -27,7 +27,6 @@ import (
 	"os"
 	"reflect"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -43,6 +42,7 @@ import (
 	"github.com/TesraSupernet/Tesra/p2pserver/net/netserver"
 	p2pnet "github.com/TesraSupernet/Tesra/p2pserver/net/protocol"
 	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+	"github.com/TesraSupernet/Tesra/p2pserver/reputation"
 	evtActor "github.com/TesraSupernet/tesraevent/actor"
 )
 
@@ -58,6 +58,7 @@ type P2PServer struct {
 	quitSyncRecent chan bool
 	quitOnline     chan bool
 	quitHeartBeat  chan bool
+	quitDiscovery  chan bool
 }
 
 //ReconnectAddrs contain addr need to reconnect
@@ -81,6 +82,7 @@ func NewServer() *P2PServer {
 	p.quitSyncRecent = make(chan bool)
 	p.quitOnline = make(chan bool)
 	p.quitHeartBeat = make(chan bool)
+	p.quitDiscovery = make(chan bool)
 	return p
 }
 
@@ -92,7 +94,9 @@ func (this *P2PServer) GetConnectionCnt() uint32 {
 //Start create all services
 func (this *P2PServer) Start() error {
 	if this.network != nil {
-		this.network.Start()
+		if err := this.network.Start(); err != nil {
+			return err
+		}
 	} else {
 		return errors.New("[p2p]network invalid")
 	}
@@ -102,6 +106,10 @@ func (this *P2PServer) Start() error {
 		return errors.New("[p2p]msg router invalid")
 	}
 	this.tryRecentPeers()
+	if discoveryEnabled() {
+		this.loadRoutingTable()
+		go this.discoveryService()
+	}
 	go this.connectSeedService()
 	go this.syncUpRecentPeers()
 	go this.keepOnlineService()
@@ -116,6 +124,7 @@ func (this *P2PServer) Stop() {
 	this.quitSyncRecent <- true
 	this.quitOnline <- true
 	this.quitHeartBeat <- true
+	close(this.quitDiscovery)
 	this.msgRouter.Stop()
 	this.blockSync.Close()
 }
@@ -125,6 +134,11 @@ func (this *P2PServer) GetNetWork() p2pnet.P2P {
 	return this.network
 }
 
+//SetValidatorSigner set the signer of validator before start, which enables consensus overlay between validators
+func (this *P2PServer) SetValidatorSigner(signer common.ValidatorSigner) {
+	this.network.SetValidatorSigner(signer)
+}
+
 //GetPort return two network port
 func (this *P2PServer) GetPort() uint16 {
 	return this.network.GetPort()
@@ -148,7 +162,12 @@ func (this *P2PServer) Xmit(message interface{}) error {
 	case *types.Transaction:
 		log.Debug("[p2p]TX transaction message")
 		txn := message.(*types.Transaction)
-		msg = msgpack.NewTxn(txn)
+		//announce the hash to the peers supporting tx inv, which request the tx if not received yet
+		utils.AddKnownTxn(txn.Hash())
+		invPayload := msgpack.NewInvPayload(comm.TRANSACTION, []comm.Uint256{txn.Hash()})
+		this.network.GetNp().BroadcastByVersion(msgpack.NewInv(invPayload), common.COMPACT_RELAY_MIN_VERSION,
+			msgpack.NewTxn(txn))
+		return nil
 	case *msgtypes.ConsensusPayload:
 		log.Debug("[p2p]TX consensus message")
 		consensusPayload := message.(*msgtypes.ConsensusPayload)
@@ -179,6 +198,77 @@ func (this *P2PServer) Send(p *peer.Peer, msg msgtypes.Message,
 	return errors.New("[p2p]send to a not ESTABLISH peer")
 }
 
+//GetPeerScores return the established nbr peers with their misbehavior scores
+func (this *P2PServer) GetPeerScores() []reputation.PeerScore {
+	scores := make(map[uint64]reputation.PeerScore)
+	for _, ps := range this.network.GetReputation().Scores() {
+		scores[ps.ID] = ps
+	}
+	peers := make([]reputation.PeerScore, 0)
+	for _, p := range this.network.GetNeighbors() {
+		if p.GetState() != common.ESTABLISH {
+			continue
+		}
+		ps, ok := scores[p.GetID()]
+		if !ok {
+			ps = reputation.PeerScore{ID: p.GetID()}
+		}
+		ps.Addr = p.Link.GetAddr()
+		peers = append(peers, ps)
+	}
+	return peers
+}
+
+//GetPeerInfos return the detail of established nbr peers for inspection
+func (this *P2PServer) GetPeerInfos() []common.PeerInfo {
+	scores := make(map[uint64]int)
+	for _, ps := range this.network.GetReputation().Scores() {
+		scores[ps.ID] = ps.Score
+	}
+	statuses := this.blockSync.SyncStatuses()
+	infos := make([]common.PeerInfo, 0)
+	for _, p := range this.network.GetNeighbors() {
+		if p.GetState() != common.ESTABLISH {
+			continue
+		}
+		info := common.PeerInfo{
+			ID:             p.GetID(),
+			Addr:           p.Link.GetAddr(),
+			NodeAddr:       p.GetNodeAddr(),
+			Direction:      common.DIRECTION_OUTBOUND,
+			ConnectedSince: p.Link.GetConnTime().Unix(),
+			Version:        p.GetVersion(),
+			SoftVersion:    p.GetSoftVersion(),
+			Height:         p.GetHeight(),
+			Latency:        float64(p.GetLatency()) / float64(time.Millisecond),
+			Score:          scores[p.GetID()],
+			Traffic:        p.Link.GetTraffic(),
+		}
+		if p.Link.IsInbound() {
+			info.Direction = common.DIRECTION_INBOUND
+		}
+		status, ok := statuses[p.GetID()]
+		if !ok {
+			status = common.SyncStatus{Role: common.SYNC_ROLE_NONE}
+		}
+		info.Sync = status
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+//BanPeer ban the host of address for duration, and close the nbr peers of the host
+func (this *P2PServer) BanPeer(addr string, duration time.Duration) {
+	host := reputation.Host(addr)
+	this.network.GetReputation().Ban(host, duration, "banned by admin")
+	for _, p := range this.network.GetNeighbors() {
+		if reputation.Host(p.Link.GetAddr()) == host {
+			log.Infof("[p2p]close banned peer %d %s", p.GetID(), p.Link.GetAddr())
+			p.Close()
+		}
+	}
+}
+
 // GetID returns local node id
 func (this *P2PServer) GetID() uint64 {
 	return this.network.GetID()
@@ -199,6 +289,11 @@ func (this *P2PServer) OnHeaderReceive(fromID uint64, headers []*types.Header) {
 	this.blockSync.OnHeaderReceive(fromID, headers)
 }
 
+// OnSkeletonReceive adds the skeleton headers of header sync from network
+func (this *P2PServer) OnSkeletonReceive(fromID uint64, headers []*types.Header) {
+	this.blockSync.OnSkeletonReceive(fromID, headers)
+}
+
 // OnBlockReceive adds the block from network
 func (this *P2PServer) OnBlockReceive(fromID uint64, blockSize uint32,
 	block *types.Block, merkleRoot comm.Uint256) {
@@ -245,7 +340,7 @@ func (this *P2PServer) blockSyncFinished() bool {
 
 //WaitForSyncBlkFinish compare the height of self and remote peer in loop
 func (this *P2PServer) WaitForSyncBlkFinish() {
-	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
+	consensusType := config.DefConfig.Genesis.GetConsensusType(this.ledger.GetCurrentBlockHeight() + 1)
 	if consensusType == "solo" {
 		return
 	}
@@ -348,7 +443,7 @@ func (this *P2PServer) reachMinConnection() bool {
 		//just sync
 		return true
 	}
-	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
+	consensusType := config.DefConfig.Genesis.GetConsensusType(this.ledger.GetCurrentBlockHeight() + 1)
 	if consensusType == "" {
 		consensusType = "dbft"
 	}
@@ -511,6 +606,7 @@ func (this *P2PServer) pingTo(peers []*peer.Peer) {
 		if p.GetState() == common.ESTABLISH {
 			height := this.ledger.GetCurrentBlockHeight()
 			ping := msgpack.NewPingMsg(uint64(height))
+			p.MarkPingSent()
 			go this.Send(p, ping, false)
 		}
 	}
@@ -20,9 +20,10 @@ package peer
 
 import (
 	"fmt"
+	"net"
+	"strconv"
 	"sync"
 
-	comm "github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
 	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
 )
@@ -35,14 +36,44 @@ type NbrPeers struct {
 
 //Broadcast tranfer msg buffer to all establish peer
 func (this *NbrPeers) Broadcast(msg types.Message) {
-	sink := comm.NewZeroCopySink(nil)
-	types.WriteMessage(sink, msg)
+	encoded := newEncodedMsg(msg)
 
 	this.RLock()
 	defer this.RUnlock()
 	for _, node := range this.List {
 		if node.linkState == common.ESTABLISH && node.GetRelay() {
-			node.SendRaw(msg.CmdType(), sink.Bytes())
+			node.sendEncoded(encoded)
+		}
+	}
+}
+
+//BroadcastExcept tranfer msg buffer to all establish peer except the excluded ones
+func (this *NbrPeers) BroadcastExcept(msg types.Message, excluded map[uint64]bool) {
+	encoded := newEncodedMsg(msg)
+
+	this.RLock()
+	defer this.RUnlock()
+	for id, node := range this.List {
+		if node.linkState == common.ESTABLISH && node.GetRelay() && !excluded[id] {
+			node.sendEncoded(encoded)
+		}
+	}
+}
+
+//BroadcastByVersion broadcast msg to the peers of min version at least, and legacy msg to the others
+func (this *NbrPeers) BroadcastByVersion(msg types.Message, minVersion uint32, legacy types.Message) {
+	encoded, legacyEncoded := newEncodedMsg(msg), newEncodedMsg(legacy)
+
+	this.RLock()
+	defer this.RUnlock()
+	for _, node := range this.List {
+		if node.linkState != common.ESTABLISH || !node.GetRelay() {
+			continue
+		}
+		if node.GetVersion() >= minVersion {
+			node.sendEncoded(encoded)
+		} else {
+			node.sendEncoded(legacyEncoded)
 		}
 	}
 }
@@ -119,9 +150,15 @@ func (this *NbrPeers) GetNeighborAddrs() []common.PeerAddr {
 		}
 		var addr common.PeerAddr
 		addr.IpAddr, _ = p.GetAddr16()
+		addr.Port = p.GetPort()
+		//advertise the public address announced by peer instead of the one of sync link
+		if host, port, err := net.SplitHostPort(p.GetPublicAddr()); err == nil {
+			portNum, _ := strconv.Atoi(port)
+			copy(addr.IpAddr[:], net.ParseIP(host).To16())
+			addr.Port = uint16(portNum)
+		}
 		addr.Time = p.GetTimeStamp()
 		addr.Services = p.GetServices()
-		addr.Port = p.GetPort()
 		addr.ID = p.GetID()
 		addrs = append(addrs, addr)
 	}
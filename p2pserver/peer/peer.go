@@ -20,13 +20,16 @@ package peer
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	comm "github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/p2pserver/common"
 	conn "github.com/TesraSupernet/Tesra/p2pserver/link"
@@ -41,6 +44,7 @@ type PeerCom struct {
 	relay        bool
 	httpInfoPort uint16
 	port         uint16
+	consPort     uint16
 	height       uint64
 	softVersion  string
 }
@@ -95,6 +99,16 @@ func (this *PeerCom) GetPort() uint16 {
 	return this.port
 }
 
+// SetConsPort sets a peer's consensus overlay port
+func (this *PeerCom) SetConsPort(port uint16) {
+	this.consPort = port
+}
+
+// GetConsPort returns a peer's consensus overlay port
+func (this *PeerCom) GetConsPort() uint16 {
+	return this.consPort
+}
+
 // SetHttpInfoPort sets a peer's http info port
 func (this *PeerCom) SetHttpInfoPort(port uint16) {
 	this.httpInfoPort = port
@@ -127,13 +141,16 @@ func (this *PeerCom) GetSoftVersion() string {
 
 //Peer represent the node in p2p
 type Peer struct {
-	base      PeerCom
-	cap       [32]byte
-	Link      *conn.Link
-	linkState uint32
-	txnCnt    uint64
-	rxTxnCnt  uint64
-	connLock  sync.RWMutex
+	base       PeerCom
+	cap        [32]byte
+	Link       *conn.Link
+	linkState  uint32
+	txnCnt     uint64
+	rxTxnCnt   uint64
+	connLock   sync.RWMutex
+	publicAddr string
+	pingTime   int64 //send time of the ping waiting for pong in nanosecond, 0 if none
+	latency    int64 //round trip time of last ping in nanosecond
 }
 
 //NewPeer return new peer without publickey initial
@@ -196,10 +213,66 @@ func (this *Peer) GetPort() uint16 {
 	return this.Link.GetPort()
 }
 
-//SendTo call sync link to send buffer
-func (this *Peer) SendRaw(msgType string, msgPayload []byte) error {
+//SetConsPort set peer`s consensus overlay port, 0 if not a validator in overlay
+func (this *Peer) SetConsPort(port uint16) {
+	this.base.SetConsPort(port)
+}
+
+//GetConsPort return peer`s consensus overlay port
+func (this *Peer) GetConsPort() uint16 {
+	return this.base.GetConsPort()
+}
+
+//SetPublicAddr set the public ip:port announced by peer
+func (this *Peer) SetPublicAddr(addr string) {
+	this.publicAddr = addr
+}
+
+//GetPublicAddr return the public ip:port announced by peer, empty if not announced
+func (this *Peer) GetPublicAddr() string {
+	return this.publicAddr
+}
+
+//GetNodeAddr return the address for other nodes to connect the peer, which is the public address announced, or the ip
+//of sync link with the sync port
+func (this *Peer) GetNodeAddr() string {
+	if this.publicAddr != "" {
+		return this.publicAddr
+	}
+	addr16, _ := this.GetAddr16()
+	var ip net.IP = addr16[:]
+	return ip.To16().String() + ":" + strconv.Itoa(int(this.GetPort()))
+}
+
+//MarkPingSent record the send time of ping to measure latency by pong. The earlier ping waiting for pong is kept
+//unless it's lost after keepalive timeout, since the pong to it may still come
+func (this *Peer) MarkPingSent() {
+	now := time.Now().UnixNano()
+	sent := atomic.LoadInt64(&this.pingTime)
+	if sent != 0 && now-sent < int64(common.KEEPALIVE_TIMEOUT*time.Second) {
+		return
+	}
+	atomic.CompareAndSwapInt64(&this.pingTime, sent, now)
+}
+
+//OnPong update latency by the ping waiting for pong, the unsolicited pong is ignored
+func (this *Peer) OnPong() {
+	sent := atomic.SwapInt64(&this.pingTime, 0)
+	if sent == 0 {
+		return
+	}
+	atomic.StoreInt64(&this.latency, time.Now().UnixNano()-sent)
+}
+
+//GetLatency return the round trip time of last ping, 0 if not measured
+func (this *Peer) GetLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&this.latency))
+}
+
+//SendRaw call sync link to send buffer over the stream of class
+func (this *Peer) SendRaw(class conn.StreamClass, msgPayload []byte) error {
 	if this.Link != nil && this.Link.Valid() {
-		return this.Link.SendRaw(msgPayload)
+		return this.Link.SendRaw(class, msgPayload)
 	}
 	return errors.New("[p2p]sync link invalid")
 }
@@ -273,10 +346,74 @@ func (this *Peer) AttachChan(msgchan chan *types.MsgPayload) {
 
 //Send transfer buffer by sync or cons link
 func (this *Peer) Send(msg types.Message) error {
-	sink := comm.NewZeroCopySink(nil)
-	types.WriteMessage(sink, msg)
+	return this.sendEncoded(newEncodedMsg(msg))
+}
+
+//sendEncoded send the message in the encoding of peer
+func (this *Peer) sendEncoded(encoded *encodedMsg) error {
+	buf, err := encoded.bytesFor(this)
+	if err != nil {
+		return err
+	}
+	return this.SendRaw(encoded.class, buf)
+}
+
+//SetCapabilities set the cap flags advertised by peer in version
+func (this *Peer) SetCapabilities(cap [32]byte) {
+	this.cap = cap
+}
+
+//HasCapability return whether peer advertised the cap flag
+func (this *Peer) HasCapability(flag int) bool {
+	return this.cap[flag] == 0x01
+}
+
+//Supports return whether peer advertised the capability required by the message type
+func (this *Peer) Supports(cmdType string) bool {
+	flag, ok := types.RequiredCapability(cmdType)
+	return !ok || this.HasCapability(flag)
+}
+
+//compress return whether the message sent to peer should be compressed, which requires compression enabled by both
+//nodes
+func (this *Peer) compress(cmdType string) bool {
+	return config.DefConfig.P2PNode.EnableCompression && this.HasCapability(common.COMPRESSION_FLAG) &&
+		types.IsCompressible(cmdType)
+}
+
+//encodedMsg is the message serialized on demand for each encoding, so broadcasting serializes the message at most
+//once plain and once compressed
+type encodedMsg struct {
+	msg        types.Message
+	class      conn.StreamClass
+	plain      []byte
+	compressed []byte
+}
 
-	return this.SendRaw(msg.CmdType(), sink.Bytes())
+func newEncodedMsg(msg types.Message) *encodedMsg {
+	return &encodedMsg{msg: msg, class: conn.StreamOf(msg)}
+}
+
+//bytesFor return the serialized message for peer, or error if peer doesn't support the message type
+func (this *encodedMsg) bytesFor(p *Peer) ([]byte, error) {
+	cmdType := this.msg.CmdType()
+	if !p.Supports(cmdType) {
+		return nil, fmt.Errorf("[p2p]peer %d not support %s", p.GetID(), cmdType)
+	}
+	if p.compress(cmdType) {
+		if this.compressed == nil {
+			sink := comm.NewZeroCopySink(nil)
+			types.WriteMessage(sink, types.NewCompressed(this.msg))
+			this.compressed = sink.Bytes()
+		}
+		return this.compressed, nil
+	}
+	if this.plain == nil {
+		sink := comm.NewZeroCopySink(nil)
+		types.WriteMessage(sink, this.msg)
+		this.plain = sink.Bytes()
+	}
+	return this.plain, nil
 }
 
 //SetHttpInfoState set peer`s httpinfo state
@@ -19,8 +19,12 @@
 package peer
 
 import (
+	"bytes"
 	"testing"
 	"time"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/TesraSupernet/Tesra/p2pserver/message/types"
 )
 
 func initTestPeer() *Peer {
@@ -102,3 +106,53 @@ func TestUpdatePeer(t *testing.T) {
 	p.DumpInfo()
 
 }
+
+func TestEncodedMsg(t *testing.T) {
+	legacy, p := initTestPeer(), initTestPeer()
+	var cap [32]byte
+	cap[common.COMPRESSION_FLAG] = 0x01
+	p.SetCapabilities(cap)
+
+	encoded := newEncodedMsg(&types.Skeleton{})
+	plain, err := encoded.bytesFor(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := encoded.bytesFor(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(plain, compressed) {
+		t.Error("skeleton not compressed for peer supporting compression")
+	}
+	msg, _, err := types.ReadMessage(bytes.NewBuffer(compressed))
+	if err != nil || msg.CmdType() != common.SKELETON_TYPE {
+		t.Errorf("read compressed skeleton error:%v", err)
+	}
+
+	ping := newEncodedMsg(&types.Ping{})
+	plain, _ = ping.bytesFor(legacy)
+	compressed, _ = ping.bytesFor(p)
+	if !bytes.Equal(plain, compressed) {
+		t.Error("ping compressed")
+	}
+
+	if _, err := newEncodedMsg(types.NewCompressed(&types.Ping{})).bytesFor(legacy); err == nil {
+		t.Error("compressed msg sent to peer without compression capability")
+	}
+}
+
+func TestPeerLatency(t *testing.T) {
+	p := initTestPeer()
+	p.OnPong()
+	if p.GetLatency() != 0 {
+		t.Error("latency measured by unsolicited pong")
+	}
+	p.MarkPingSent()
+	time.Sleep(10 * time.Millisecond)
+	p.MarkPingSent()
+	p.OnPong()
+	if p.GetLatency() < 10*time.Millisecond {
+		t.Errorf("latency %s not measured from the earlier ping", p.GetLatency())
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package reputation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+)
+
+//Rate is the limit of a message type, Burst messages are allowed at once and refilled at PerSecond
+type Rate struct {
+	PerSecond float64
+	Burst     float64
+}
+
+//DefaultRates is the limits of the message types requesting data, other types are not limited
+var DefaultRates = map[string]Rate{
+	common.GetADDR_TYPE:     {PerSecond: 0.1, Burst: 3},
+	common.FINDNODE_TYPE:    {PerSecond: 1, Burst: 10},
+	common.PING_TYPE:        {PerSecond: 1, Burst: 10},
+	common.GET_HEADERS_TYPE: {PerSecond: 20, Burst: 50},
+	common.GET_SKEL_TYPE:    {PerSecond: 1, Burst: 5},
+	common.GET_BLOCKS_TYPE:  {PerSecond: 20, Burst: 50},
+	common.GET_BLKTXN_TYPE:  {PerSecond: 20, Burst: 50},
+	common.GET_DATA_TYPE:    {PerSecond: 500, Burst: 1000},
+	common.INV_TYPE:         {PerSecond: 1000, Burst: 2000},
+	common.TX_TYPE:          {PerSecond: 1000, Burst: 2000},
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+//RateLimiter limit the messages of each peer by message type with token buckets
+type RateLimiter struct {
+	lock    sync.Mutex
+	rates   map[string]Rate
+	buckets map[uint64]map[string]*bucket
+}
+
+//NewRateLimiter return a rate limiter of rates by message type
+func NewRateLimiter(rates map[string]Rate) *RateLimiter {
+	return &RateLimiter{
+		rates:   rates,
+		buckets: make(map[uint64]map[string]*bucket),
+	}
+}
+
+//Allow return whether the message of type from peer is in the limit
+func (this *RateLimiter) Allow(id uint64, msgType string) bool {
+	rate, ok := this.rates[msgType]
+	if !ok {
+		return true
+	}
+	now := time.Now()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	peerBuckets, ok := this.buckets[id]
+	if !ok {
+		peerBuckets = make(map[string]*bucket)
+		this.buckets[id] = peerBuckets
+	}
+	b, ok := peerBuckets[msgType]
+	if !ok {
+		b = &bucket{tokens: rate.Burst, last: now}
+		peerBuckets[msgType] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate.PerSecond
+	if b.tokens > rate.Burst {
+		b.tokens = rate.Burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+//Remove the buckets of disconnected peer
+func (this *RateLimiter) Remove(id uint64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.buckets, id)
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+
+//Package reputation provides misbehavior scores, timed bans and message rate limits of p2p peers
+package reputation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common/log"
+)
+
+const (
+	BAN_THRESHOLD = 100          //peer is banned when its score reaches the threshold
+	DEFAULT_BAN   = 24 * 60 * 60 //default ban duration in sec
+	SCORE_DECAY   = 60           //score decreases by one every decay seconds
+	MAX_SCORE_CNT = 10000        //max peers tracked
+)
+
+//misbehavior scores
+const (
+	SCORE_MALFORMED_MSG     = 50  //message failed to decode or checksum mismatch
+	SCORE_INVALID_BLOCK     = 100 //block failed to verify
+	SCORE_INVALID_HEADER    = 50  //header failed to verify
+	SCORE_INVALID_CONSENSUS = 20  //consensus message failed to verify
+	SCORE_ERROR_RESP        = 10  //wrong header or block responded to sync request
+	SCORE_TIMEOUT           = 2   //no response of sync request
+	SCORE_RATE_LIMIT        = 5   //message exceeded rate limit
+	SCORE_UNREQUESTED       = 5   //unsolicited or useless message
+)
+
+//PeerScore is the misbehavior score of a peer
+type PeerScore struct {
+	ID         uint64 `json:"id"`
+	Addr       string `json:"addr"`
+	Score      int    `json:"score"`
+	LastReason string `json:"last_reason"`
+	LastUpdate int64  `json:"last_update"`
+}
+
+//decay the score by the time since last update
+func (this *PeerScore) decay(now int64) {
+	elapsed := int((now - this.LastUpdate) / SCORE_DECAY)
+	if elapsed <= 0 {
+		return
+	}
+	this.Score -= elapsed
+	if this.Score < 0 {
+		this.Score = 0
+	}
+	this.LastUpdate += int64(elapsed) * SCORE_DECAY
+}
+
+//BanEntry is a banned host
+type BanEntry struct {
+	Host   string `json:"host"`
+	Until  int64  `json:"until"` //unix time when the ban expires
+	Reason string `json:"reason"`
+}
+
+//Reputation tracks the scores of peers by id and the bans by host, bans are persisted to file if path is set
+type Reputation struct {
+	lock   sync.Mutex
+	path   string
+	scores map[uint64]*PeerScore
+	bans   map[string]*BanEntry
+	exempt func(id uint64, addr string) bool //peers never banned for misbehavior, such as reserved peers
+}
+
+//NewReputation return reputation with the unexpired bans loaded from path
+func NewReputation(path string) *Reputation {
+	this := &Reputation{
+		path:   path,
+		scores: make(map[uint64]*PeerScore),
+		bans:   make(map[string]*BanEntry),
+	}
+	if path == "" {
+		return this
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return this
+	}
+	entries := make([]*BanEntry, 0)
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		log.Warnf("[p2p]parse ban list %s fail:%s", path, err)
+		return this
+	}
+	now := time.Now().Unix()
+	for _, entry := range entries {
+		if entry.Until > now {
+			this.bans[entry.Host] = entry
+		}
+	}
+	return this
+}
+
+//SetExempt set the check of peers exempt from misbehavior bans, they are still scored. Bans by admin are not affected
+func (this *Reputation) SetExempt(exempt func(id uint64, addr string) bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.exempt = exempt
+}
+
+//Host return the host of address, or the address itself if it has no port
+func Host(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+//Misbehave add score to peer for the reason, and ban the host of peer if score reaches threshold. Return
+//whether the peer is banned. Exempt peers have the score reset instead of banned
+func (this *Reputation) Misbehave(id uint64, addr string, score int, reason string) bool {
+	this.lock.Lock()
+	now := time.Now().Unix()
+	ps, ok := this.scores[id]
+	if !ok {
+		if len(this.scores) >= MAX_SCORE_CNT {
+			this.expireScores(now)
+		}
+		if len(this.scores) >= MAX_SCORE_CNT {
+			this.evictScore()
+		}
+		ps = &PeerScore{ID: id, LastUpdate: now}
+		this.scores[id] = ps
+	}
+	ps.decay(now)
+	ps.Addr = addr
+	ps.Score += score
+	ps.LastReason = reason
+	total := ps.Score
+	banned := total >= BAN_THRESHOLD
+	if banned {
+		delete(this.scores, id)
+	}
+	exempt := this.exempt
+	this.lock.Unlock()
+
+	log.Debugf("[p2p]peer %d %s misbehaved:%s, score %d", id, addr, reason, total)
+	if banned && exempt != nil && exempt(id, addr) {
+		log.Warnf("[p2p]peer %d %s exempt from ban:%s", id, addr, reason)
+		return false
+	}
+	if banned {
+		log.Warnf("[p2p]ban peer %d %s:%s", id, addr, reason)
+		this.Ban(Host(addr), DEFAULT_BAN*time.Second, reason)
+	}
+	return banned
+}
+
+//expireScores remove the scores decayed to zero
+func (this *Reputation) expireScores(now int64) {
+	for id, ps := range this.scores {
+		ps.decay(now)
+		if ps.Score == 0 {
+			delete(this.scores, id)
+		}
+	}
+}
+
+//evictScore remove the lowest score, the least recently updated one if tied
+func (this *Reputation) evictScore() {
+	var evict *PeerScore
+	for _, ps := range this.scores {
+		if evict == nil || ps.Score < evict.Score || (ps.Score == evict.Score && ps.LastUpdate < evict.LastUpdate) {
+			evict = ps
+		}
+	}
+	if evict != nil {
+		delete(this.scores, evict.ID)
+	}
+}
+
+//Scores return the scores of peers which misbehaved recently
+func (this *Reputation) Scores() []PeerScore {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.expireScores(time.Now().Unix())
+	scores := make([]PeerScore, 0, len(this.scores))
+	for _, ps := range this.scores {
+		scores = append(scores, *ps)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	return scores
+}
+
+//Score return the current score of peer
+func (this *Reputation) Score(id uint64) int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	ps, ok := this.scores[id]
+	if !ok {
+		return 0
+	}
+	ps.decay(time.Now().Unix())
+	return ps.Score
+}
+
+//Ban the host for duration
+func (this *Reputation) Ban(host string, duration time.Duration, reason string) {
+	this.lock.Lock()
+	this.bans[host] = &BanEntry{
+		Host:   host,
+		Until:  time.Now().Add(duration).Unix(),
+		Reason: reason,
+	}
+	this.lock.Unlock()
+	this.save()
+}
+
+//Unban the host, return false if the host is not banned
+func (this *Reputation) Unban(host string) bool {
+	this.lock.Lock()
+	_, ok := this.bans[host]
+	delete(this.bans, host)
+	this.lock.Unlock()
+	if ok {
+		this.save()
+	}
+	return ok
+}
+
+//IsBanned return whether the host of address is banned
+func (this *Reputation) IsBanned(addr string) bool {
+	host := Host(addr)
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	entry, ok := this.bans[host]
+	if !ok {
+		return false
+	}
+	if entry.Until <= time.Now().Unix() {
+		delete(this.bans, host)
+		return false
+	}
+	return true
+}
+
+//BanList return the unexpired bans
+func (this *Reputation) BanList() []BanEntry {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	now := time.Now().Unix()
+	entries := make([]BanEntry, 0, len(this.bans))
+	for host, entry := range this.bans {
+		if entry.Until <= now {
+			delete(this.bans, host)
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Host < entries[j].Host
+	})
+	return entries
+}
+
+//save persist the bans
+func (this *Reputation) save() {
+	if this.path == "" {
+		return
+	}
+	buf, err := json.Marshal(this.BanList())
+	if err != nil {
+		log.Warnf("[p2p]package ban list fail:%s", err)
+		return
+	}
+	if err := ioutil.WriteFile(this.path, buf, 0644); err != nil {
+		log.Warnf("[p2p]write ban list %s fail:%s", this.path, err)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package reputation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/p2pserver/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMisbehaveBan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reputation")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peers.ban")
+
+	rep := NewReputation(path)
+	assert.False(t, rep.Misbehave(1, "127.0.0.1:20338", SCORE_INVALID_HEADER, "invalid header"))
+	assert.Equal(t, SCORE_INVALID_HEADER, rep.Score(1))
+	assert.False(t, rep.IsBanned("127.0.0.1:20339"))
+	assert.True(t, rep.Misbehave(1, "127.0.0.1:20338", SCORE_INVALID_HEADER, "invalid header"))
+	assert.Equal(t, 0, rep.Score(1))
+	assert.True(t, rep.IsBanned("127.0.0.1:20339"))
+
+	loaded := NewReputation(path)
+	assert.True(t, loaded.IsBanned("127.0.0.1"))
+	assert.Equal(t, 1, len(loaded.BanList()))
+	assert.True(t, loaded.Unban("127.0.0.1"))
+	assert.False(t, loaded.Unban("127.0.0.1"))
+	assert.Equal(t, 0, len(NewReputation(path).BanList()))
+
+	loaded.Ban("10.0.0.1", -time.Second, "expired")
+	assert.False(t, loaded.IsBanned("10.0.0.1:20338"))
+}
+
+func TestMisbehaveExempt(t *testing.T) {
+	rep := NewReputation("")
+	rep.SetExempt(func(id uint64, addr string) bool { return id == 1 })
+	assert.False(t, rep.Misbehave(1, "127.0.0.1:20338", SCORE_INVALID_BLOCK, "invalid block"))
+	assert.Equal(t, 0, rep.Score(1))
+	assert.False(t, rep.IsBanned("127.0.0.1:20338"))
+	assert.True(t, rep.Misbehave(2, "127.0.0.2:20338", SCORE_INVALID_BLOCK, "invalid block"))
+	assert.True(t, rep.IsBanned("127.0.0.2:20338"))
+}
+
+func TestScoreEviction(t *testing.T) {
+	rep := NewReputation("")
+	for id := uint64(1); id <= MAX_SCORE_CNT; id++ {
+		rep.Misbehave(id, "127.0.0.1:20338", SCORE_INVALID_HEADER, "invalid header")
+	}
+	rep.scores[3].Score = SCORE_TIMEOUT
+	rep.scores[3].LastUpdate -= 1
+	rep.scores[4].Score = SCORE_TIMEOUT
+	rep.scores[4].LastUpdate -= 2
+
+	rep.Misbehave(MAX_SCORE_CNT+1, "127.0.0.1:20338", SCORE_TIMEOUT, "sync request timeout")
+	assert.Equal(t, MAX_SCORE_CNT, len(rep.scores))
+	assert.Equal(t, 0, rep.Score(4))
+	assert.Equal(t, SCORE_TIMEOUT, rep.Score(3))
+	assert.Equal(t, SCORE_TIMEOUT, rep.Score(MAX_SCORE_CNT+1))
+
+	rep.Misbehave(MAX_SCORE_CNT+2, "127.0.0.1:20338", SCORE_TIMEOUT, "sync request timeout")
+	assert.Equal(t, 0, rep.Score(3))
+	assert.Equal(t, SCORE_TIMEOUT, rep.Score(MAX_SCORE_CNT+1))
+	assert.Equal(t, SCORE_INVALID_HEADER, rep.Score(1))
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(map[string]Rate{common.PING_TYPE: {PerSecond: 0, Burst: 2}})
+	assert.True(t, limiter.Allow(1, common.PING_TYPE))
+	assert.True(t, limiter.Allow(1, common.PING_TYPE))
+	assert.False(t, limiter.Allow(1, common.PING_TYPE))
+	assert.True(t, limiter.Allow(2, common.PING_TYPE))
+	assert.True(t, limiter.Allow(1, common.PONG_TYPE))
+	limiter.Remove(1)
+	assert.True(t, limiter.Allow(1, common.PING_TYPE))
+}
@@ -0,0 +1,419 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/common/log"
+	"github.com/TesraSupernet/Tesra/core/types"
+	p2pComm "github.com/TesraSupernet/Tesra/p2pserver/common"
+	msgpack "github.com/TesraSupernet/Tesra/p2pserver/message/msg_pack"
+	"github.com/TesraSupernet/Tesra/p2pserver/peer"
+)
+
+const (
+	SYNC_SKELETON_MIN_DISTANCE    = 2 * p2pComm.MAX_BLK_HDR_CNT  //Sync headers by skeleton when the best node is ahead more than the distance
+	SYNC_SKELETON_SPAN            = SYNC_MAX_HEADER_FORWARD_SIZE //Headers synced in one skeleton round
+	SYNC_CHECKPOINT_SPAN          = 20000                        //Skeleton round is extended to the next checkpoint within the span
+	SYNC_SKELETON_REQUEST_TIMEOUT = 5                            //s, Request skeleton timeout time
+	SYNC_SEGMENT_REQUEST_TIMEOUT  = 5                            //s, Request segment timeout time, then request it from another node
+)
+
+//headerSegment is the headers between two skeleton headers, which is downloaded from one node
+type headerSegment struct {
+	height    uint32          //Height of the header before segment
+	prevHash  common.Uint256  //Hash of the header before segment
+	last      *types.Header   //Skeleton header ending the segment
+	headers   []*types.Header //Nil until received
+	nodeId    uint64          //The node requested, 0 means not requested
+	startTime time.Time
+	failed    map[uint64]bool //Nodes timeout or responded error headers
+}
+
+//fill the segment with headers if they are linked from prev hash to the last skeleton header
+func (this *headerSegment) fill(headers []*types.Header) error {
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Height < headers[j].Height
+	})
+	if uint32(len(headers)) != this.last.Height-this.height {
+		return fmt.Errorf("segment %d - %d got %d headers", this.height+1, this.last.Height, len(headers))
+	}
+	prevHash := this.prevHash
+	for i, header := range headers {
+		if header.Height != this.height+uint32(i)+1 || header.PrevBlockHash != prevHash {
+			return fmt.Errorf("segment header height %d not linked", header.Height)
+		}
+		prevHash = header.Hash()
+	}
+	if prevHash != this.last.Hash() {
+		return fmt.Errorf("segment %d - %d not linked to skeleton", this.height+1, this.last.Height)
+	}
+	this.headers = headers
+	return nil
+}
+
+//skeletonRound sync the headers from current header height to end height. The skeleton headers at every
+//MAX_BLK_HDR_CNT heights are requested from one node, then the segments between them are filled from
+//several nodes in parallel, and the headers are added to ledger once all segments linked
+type skeletonRound struct {
+	height     uint32             //Current header height when the round starts
+	hash       common.Uint256     //Current header hash when the round starts
+	heights    []uint32           //Heights of skeleton headers
+	checkpoint *config.Checkpoint //Set if the round ends at the checkpoint
+	nodeId     uint64             //The node requested skeleton
+	startTime  time.Time
+	segments   []*headerSegment //Nil until skeleton received
+}
+
+//skeletonHeights return the heights of skeleton from height to end
+func skeletonHeights(height, end uint32) []uint32 {
+	heights := make([]uint32, 0)
+	for h := height + p2pComm.MAX_BLK_HDR_CNT; h < end; h += p2pComm.MAX_BLK_HDR_CNT {
+		heights = append(heights, h)
+	}
+	return append(heights, end)
+}
+
+//setSkeleton check the skeleton headers and split the round into segments
+func (this *skeletonRound) setSkeleton(headers []*types.Header) error {
+	if len(headers) != len(this.heights) {
+		return fmt.Errorf("got %d skeleton headers, expected %d", len(headers), len(this.heights))
+	}
+	for i, header := range headers {
+		if header.Height != this.heights[i] {
+			return fmt.Errorf("skeleton header height %d, expected %d", header.Height, this.heights[i])
+		}
+	}
+	last := headers[len(headers)-1]
+	if this.checkpoint != nil && last.Hash() != this.checkpoint.Hash {
+		return fmt.Errorf("skeleton header height %d not match checkpoint", last.Height)
+	}
+	segments := make([]*headerSegment, 0, len(headers))
+	height, prevHash := this.height, this.hash
+	for _, header := range headers {
+		segments = append(segments, &headerSegment{
+			height:   height,
+			prevHash: prevHash,
+			last:     header,
+			failed:   make(map[uint64]bool),
+		})
+		height, prevHash = header.Height, header.Hash()
+	}
+	this.segments = segments
+	return nil
+}
+
+//complete return whether all segments filled
+func (this *skeletonRound) complete() bool {
+	for _, segment := range this.segments {
+		if segment.headers == nil {
+			return false
+		}
+	}
+	return this.segments != nil
+}
+
+//nextCheckpoint return the first checkpoint above height
+func (this *BlockSyncMgr) nextCheckpoint(height uint32) *config.Checkpoint {
+	for _, checkpoint := range this.checkpoints {
+		if checkpoint.Height > height {
+			return checkpoint
+		}
+	}
+	return nil
+}
+
+//checkCheckpoints return error if any header conflicts with checkpoints
+func (this *BlockSyncMgr) checkCheckpoints(headers []*types.Header) error {
+	for _, checkpoint := range this.checkpoints {
+		for _, header := range headers {
+			if header.Height == checkpoint.Height && header.Hash() != checkpoint.Hash {
+				return fmt.Errorf("header height %d not match checkpoint", header.Height)
+			}
+		}
+	}
+	return nil
+}
+
+//syncSkeleton sync headers by skeleton when far behind the best node, return false if headers should be
+//synced from one node
+func (this *BlockSyncMgr) syncSkeleton() bool {
+	this.lock.RLock()
+	round := this.skeleton
+	this.lock.RUnlock()
+	if round != nil {
+		this.requestSegments()
+		return true
+	}
+	if this.getFlightHeaderCount() > 0 {
+		return false
+	}
+	curBlockHeight := this.ledger.GetCurrentBlockHeight()
+	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
+	if curHeaderHeight-curBlockHeight >= SYNC_MAX_HEADER_FORWARD_SIZE {
+		return false
+	}
+	reqNode := this.getSkeletonNode(curHeaderHeight)
+	if reqNode == nil {
+		return false
+	}
+	nodeHeight := uint32(reqNode.GetHeight())
+	end := curHeaderHeight + SYNC_SKELETON_SPAN
+	if end > nodeHeight {
+		end = nodeHeight
+	}
+	round = &skeletonRound{
+		height:    curHeaderHeight,
+		hash:      this.ledger.GetCurrentHeaderHash(),
+		nodeId:    reqNode.GetID(),
+		startTime: time.Now(),
+	}
+	checkpoint := this.nextCheckpoint(curHeaderHeight)
+	if checkpoint != nil && checkpoint.Height <= nodeHeight && checkpoint.Height <= curHeaderHeight+SYNC_CHECKPOINT_SPAN {
+		end = checkpoint.Height
+		round.checkpoint = checkpoint
+	}
+	round.heights = skeletonHeights(curHeaderHeight, end)
+
+	this.lock.Lock()
+	if this.skeleton != nil {
+		this.lock.Unlock()
+		return true
+	}
+	this.skeleton = round
+	this.lock.Unlock()
+
+	msg := msgpack.NewSkeletonReq(round.heights)
+	err := this.server.Send(reqNode, msg, false)
+	if err != nil {
+		log.Warnf("[p2p]syncSkeleton failed to send skeleton req:%s", err)
+	} else {
+		this.appendReqTime(reqNode.GetID())
+	}
+	log.Infof("Header skeleton sync request height:%d - %d", curHeaderHeight+1, end)
+	return true
+}
+
+//getSkeletonNode return the node supporting skeleton with highest weight, which is ahead of height more than
+//SYNC_SKELETON_MIN_DISTANCE
+func (this *BlockSyncMgr) getSkeletonNode(height uint32) *peer.Peer {
+	weights := this.getAllNodeWeights()
+	sort.Sort(sort.Reverse(weights))
+	for _, w := range weights {
+		n := this.server.getNode(w.id)
+		if n == nil || n.GetState() != p2pComm.ESTABLISH || n.GetVersion() < p2pComm.SKELETON_SYNC_MIN_VERSION {
+			continue
+		}
+		if n.GetHeight() >= uint64(height)+SYNC_SKELETON_MIN_DISTANCE {
+			return n
+		}
+	}
+	return nil
+}
+
+//requestSegments request the segments not requested or timeout from the nodes not busy with other segments
+func (this *BlockSyncMgr) requestSegments() {
+	weights := this.getAllNodeWeights()
+	sort.Sort(sort.Reverse(weights))
+	nodes := make([]*peer.Peer, 0, len(weights))
+	for _, w := range weights {
+		n := this.server.getNode(w.id)
+		if n != nil && n.GetState() == p2pComm.ESTABLISH {
+			nodes = append(nodes, n)
+		}
+	}
+	now := time.Now()
+	timeoutNodes := make([]uint64, 0)
+	reqNodes := make([]*peer.Peer, 0)
+	reqSegments := make([]*headerSegment, 0)
+
+	this.lock.Lock()
+	round := this.skeleton
+	if round == nil || round.segments == nil {
+		this.lock.Unlock()
+		return
+	}
+	busy := make(map[uint64]bool)
+	for _, segment := range round.segments {
+		if segment.headers != nil || segment.nodeId == 0 {
+			continue
+		}
+		if int(now.Sub(segment.startTime).Seconds()) < SYNC_SEGMENT_REQUEST_TIMEOUT {
+			busy[segment.nodeId] = true
+			continue
+		}
+		log.Tracef("[p2p]requestSegments segment %d - %d from id:%d timeout", segment.height+1, segment.last.Height, segment.nodeId)
+		timeoutNodes = append(timeoutNodes, segment.nodeId)
+		segment.failed[segment.nodeId] = true
+		segment.nodeId = 0
+	}
+	for _, segment := range round.segments {
+		if segment.headers != nil || segment.nodeId != 0 {
+			continue
+		}
+		var reqNode *peer.Peer
+		for _, n := range nodes {
+			if busy[n.GetID()] || segment.failed[n.GetID()] || n.GetHeight() < uint64(segment.last.Height) {
+				continue
+			}
+			reqNode = n
+			break
+		}
+		if reqNode == nil {
+			//all nodes failed, retry them next time
+			if len(segment.failed) > 0 && len(busy) == 0 {
+				segment.failed = make(map[uint64]bool)
+			}
+			continue
+		}
+		busy[reqNode.GetID()] = true
+		segment.nodeId = reqNode.GetID()
+		segment.startTime = now
+		reqNodes = append(reqNodes, reqNode)
+		reqSegments = append(reqSegments, segment)
+	}
+	this.lock.Unlock()
+
+	for _, nodeId := range timeoutNodes {
+		this.addTimeoutCnt(nodeId)
+	}
+	for i, reqNode := range reqNodes {
+		msg := msgpack.NewHeadersRangeReq(reqSegments[i].prevHash, reqSegments[i].last.Hash())
+		err := this.server.Send(reqNode, msg, false)
+		if err != nil {
+			log.Warnf("[p2p]requestSegments reqNode ID:%d Send error:%s", reqNode.GetID(), err)
+		} else {
+			this.appendReqTime(reqNode.GetID())
+		}
+	}
+}
+
+//checkSkeletonTimeout give up the round if skeleton not received in time
+func (this *BlockSyncMgr) checkSkeletonTimeout() {
+	this.lock.Lock()
+	round := this.skeleton
+	timeout := round != nil && round.segments == nil &&
+		int(time.Now().Sub(round.startTime).Seconds()) >= SYNC_SKELETON_REQUEST_TIMEOUT
+	if timeout {
+		this.skeleton = nil
+	}
+	this.lock.Unlock()
+	if timeout {
+		log.Tracef("[p2p]checkSkeletonTimeout skeleton from id:%d timeout after:%d s", round.nodeId, SYNC_SKELETON_REQUEST_TIMEOUT)
+		this.addTimeoutCnt(round.nodeId)
+	}
+}
+
+//OnSkeletonReceive receive skeleton headers from net
+func (this *BlockSyncMgr) OnSkeletonReceive(fromID uint64, headers []*types.Header) {
+	this.lock.Lock()
+	round := this.skeleton
+	if round == nil || round.nodeId != fromID || round.segments != nil {
+		this.lock.Unlock()
+		return
+	}
+	err := round.setSkeleton(headers)
+	if err != nil {
+		this.skeleton = nil
+	}
+	this.lock.Unlock()
+	if err != nil {
+		log.Warnf("[p2p]OnSkeletonReceive from id:%d error:%s", fromID, err)
+		this.addErrorRespCnt(fromID)
+		return
+	}
+	this.requestSegments()
+}
+
+//onSegmentReceive fill the segment of skeleton round with headers, return false if the headers are not of
+//any segment
+func (this *BlockSyncMgr) onSegmentReceive(fromID uint64, headers []*types.Header) bool {
+	this.lock.Lock()
+	round := this.skeleton
+	if round == nil || round.segments == nil {
+		this.lock.Unlock()
+		return false
+	}
+	var segment *headerSegment
+	for _, seg := range round.segments {
+		if seg.headers == nil && seg.nodeId == fromID {
+			segment = seg
+			break
+		}
+	}
+	if segment == nil {
+		this.lock.Unlock()
+		return false
+	}
+	err := segment.fill(headers)
+	if err != nil {
+		segment.failed[fromID] = true
+		segment.nodeId = 0
+	}
+	complete := round.complete()
+	if complete {
+		this.skeleton = nil
+	}
+	this.lock.Unlock()
+
+	if err != nil {
+		log.Warnf("[p2p]onSegmentReceive from id:%d error:%s", fromID, err)
+		this.addErrorRespCnt(fromID)
+		this.requestSegments()
+		return true
+	}
+	if complete {
+		this.addSkeletonHeaders(round)
+	}
+	return true
+}
+
+//addSkeletonHeaders add the headers of completed round to ledger. The signatures are not verified if the
+//round ends at a checkpoint and checkpoint sync is enabled
+func (this *BlockSyncMgr) addSkeletonHeaders(round *skeletonRound) {
+	if this.ledger.GetCurrentHeaderHash() != round.hash {
+		log.Infof("[p2p]skeleton sync from height %d is outdated", round.height)
+		return
+	}
+	headers := make([]*types.Header, 0, round.heights[len(round.heights)-1]-round.height)
+	for _, segment := range round.segments {
+		headers = append(headers, segment.headers...)
+	}
+	var err error
+	if round.checkpoint != nil && config.DefConfig.P2PNode.CheckpointSync {
+		err = this.ledger.AddCheckpointHeaders(headers, round.checkpoint.Hash)
+	} else {
+		err = this.ledger.AddHeaders(headers)
+	}
+	if err != nil {
+		//segments are linked to skeleton, so the skeleton is invalid
+		this.addErrorRespCnt(round.nodeId)
+		log.Warnf("[p2p]addSkeletonHeaders AddHeaders error:%s", err)
+		return
+	}
+	log.Infof("Header skeleton receive height:%d - %d", round.height+1, headers[len(headers)-1].Height)
+	this.addEmptyBlocks(round.nodeId, headers)
+	go this.saveBlock()
+	this.syncHeader()
+}
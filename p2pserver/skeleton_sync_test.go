@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/common/config"
+	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkeletonRound(t *testing.T) {
+	assert.Equal(t, []uint32{510, 1010, 1200}, skeletonHeights(10, 1200))
+	assert.Equal(t, []uint32{510}, skeletonHeights(10, 510))
+
+	chain := make([]*types.Header, 0)
+	prevHash := common.Uint256{1}
+	for height := uint32(11); height <= 1200; height++ {
+		header := &types.Header{PrevBlockHash: prevHash, Height: height}
+		chain = append(chain, header)
+		prevHash = header.Hash()
+	}
+	skeleton := []*types.Header{chain[499], chain[999], chain[1189]}
+
+	round := &skeletonRound{
+		height:     10,
+		hash:       common.Uint256{1},
+		heights:    skeletonHeights(10, 1200),
+		checkpoint: &config.Checkpoint{Height: 1200, Hash: common.Uint256{2}},
+	}
+	assert.NotNil(t, round.setSkeleton(skeleton))
+	assert.NotNil(t, round.setSkeleton(skeleton[:2]))
+	round.checkpoint.Hash = chain[1189].Hash()
+	assert.Nil(t, round.setSkeleton(skeleton))
+	assert.Equal(t, 3, len(round.segments))
+
+	assert.NotNil(t, round.segments[1].fill(chain[0:500]))
+	assert.NotNil(t, round.segments[1].fill(chain[501:1000]))
+	assert.Nil(t, round.segments[1].fill(chain[500:1000]))
+	assert.False(t, round.complete())
+	assert.Nil(t, round.segments[0].fill(chain[0:500]))
+	assert.Nil(t, round.segments[2].fill(chain[1000:]))
+	assert.True(t, round.complete())
+}
@@ -45,6 +45,7 @@ func setupSigSvr() *cli.App {
 		//cli setting
 		utils.CliAddressFlag,
 		utils.CliRpcPortFlag,
+		utils.CliUnixSocketFlag,
 		utils.CliABIPathFlag,
 	}
 	app.Commands = []cli.Command{
@@ -88,6 +89,11 @@ func startSigSvr(ctx *cli.Context) {
 		return
 	}
 	go cmdsvr.DefCliRpcSvr.Start(rpcAddress, rpcPort)
+	unixSocket := ctx.String(utils.GetFlagName(utils.CliUnixSocketFlag))
+	if unixSocket != "" {
+		go cmdsvr.DefCliRpcSvr.StartUnix(unixSocket)
+		log.Infof("Sig server listing on unix socket: %s", unixSocket)
+	}
 
 	abiPath := ctx.GlobalString(utils.GetFlagName(utils.CliABIPathFlag))
 	abi.DefAbiMgr.Init(abiPath)
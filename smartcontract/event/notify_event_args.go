@@ -19,6 +19,8 @@
 package event
 
 import (
+	"encoding/json"
+
 	"github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/vm/neovm/types"
 )
@@ -46,3 +48,27 @@ type ExecuteNotify struct {
 	GasConsumed uint64
 	Notify      []*NotifyEventInfo
 }
+
+//Topic return the topic of event notify, which is the first state of notify, or the states itself if they are not
+//a list. States are normalized through json, so the topic is the same after the notify is stored
+func (this *NotifyEventInfo) Topic() string {
+	data, err := json.Marshal(this.States)
+	if err != nil {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return ""
+	}
+	if list, ok := value.([]interface{}); ok {
+		if len(list) == 0 {
+			return ""
+		}
+		value = list[0]
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	data, _ = json.Marshal(value)
+	return string(data)
+}
@@ -273,6 +273,8 @@ func InitConfig(native *native.NativeService) ([]byte, error) {
 		HashMsgDelay:         configuration.HashMsgDelay,
 		PeerHandshakeTimeout: configuration.PeerHandshakeTimeout,
 		MaxBlockChangeView:   configuration.MaxBlockChangeView,
+		BlockTime:            configuration.BlockTime,
+		MaxIdleTime:          configuration.MaxIdleTime,
 	}
 	err = putConfig(native, contract, config)
 	if err != nil {
@@ -1082,6 +1084,9 @@ func UpdateConfig(native *native.NativeService) ([]byte, error) {
 	if configuration.MaxBlockChangeView < 10000 {
 		return utils.BYTE_FALSE, fmt.Errorf("updateConfig. MaxBlockChangeView must >= 10000")
 	}
+	if err := checkBlockTime(configuration.BlockTime, configuration.MaxIdleTime, configuration.BlockMsgDelay); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("updateConfig. %s", err)
+	}
 
 	preConfig := &PreConfig{
 		Configuration: configuration,
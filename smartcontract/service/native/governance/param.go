@@ -352,9 +352,27 @@ type Configuration struct {
 	HashMsgDelay         uint32
 	PeerHandshakeTimeout uint32
 	MaxBlockChangeView   uint32
+	BlockTime            uint32
+	MaxIdleTime          uint32
 }
 
+//CONFIGURATION_EXT_VERSION flags the extended fields of Configuration
+const CONFIGURATION_EXT_VERSION byte = 1
+
 func (this *Configuration) Serialization(sink *common.ZeroCopySink) {
+	this.serializeBase(sink)
+	this.serializeExt(sink)
+}
+
+func (this *Configuration) Deserialization(source *common.ZeroCopySource) error {
+	err := this.deserializeBase(source)
+	if err != nil {
+		return err
+	}
+	return this.deserializeExt(source)
+}
+
+func (this *Configuration) serializeBase(sink *common.ZeroCopySink) {
 	utils.EncodeVarUint(sink, uint64(this.N))
 	utils.EncodeVarUint(sink, uint64(this.C))
 	utils.EncodeVarUint(sink, uint64(this.K))
@@ -365,7 +383,18 @@ func (this *Configuration) Serialization(sink *common.ZeroCopySink) {
 	utils.EncodeVarUint(sink, uint64(this.MaxBlockChangeView))
 }
 
-func (this *Configuration) Deserialization(source *common.ZeroCopySource) error {
+//serializeExt write extended fields behind the version byte. Nothing is written when they are
+//not set, so the configuration stored before keeps the same encoding
+func (this *Configuration) serializeExt(sink *common.ZeroCopySink) {
+	if this.BlockTime == 0 && this.MaxIdleTime == 0 {
+		return
+	}
+	sink.WriteByte(CONFIGURATION_EXT_VERSION)
+	utils.EncodeVarUint(sink, uint64(this.BlockTime))
+	utils.EncodeVarUint(sink, uint64(this.MaxIdleTime))
+}
+
+func (this *Configuration) deserializeBase(source *common.ZeroCopySource) error {
 	n, err := utils.DecodeVarUint(source)
 	if err != nil {
 		return fmt.Errorf("utils.ReadVarUint, deserialize n error: %v", err)
@@ -433,19 +462,50 @@ func (this *Configuration) Deserialization(source *common.ZeroCopySource) error
 	return nil
 }
 
+//deserializeExt read extended fields behind the version byte. Trailing bytes without the version
+//byte were ignored before the extension existed, so they are still ignored to keep old inputs valid
+func (this *Configuration) deserializeExt(source *common.ZeroCopySource) error {
+	this.BlockTime = 0
+	this.MaxIdleTime = 0
+	version, eof := source.NextByte()
+	if eof || version != CONFIGURATION_EXT_VERSION {
+		return nil
+	}
+	blockTime, err := utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("utils.ReadVarUint, deserialize blockTime error: %v", err)
+	}
+	maxIdleTime, err := utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("utils.ReadVarUint, deserialize maxIdleTime error: %v", err)
+	}
+	if blockTime > math.MaxUint32 {
+		return fmt.Errorf("blockTime larger than max of uint32")
+	}
+	if maxIdleTime > math.MaxUint32 {
+		return fmt.Errorf("maxIdleTime larger than max of uint32")
+	}
+	this.BlockTime = uint32(blockTime)
+	this.MaxIdleTime = uint32(maxIdleTime)
+	return nil
+}
+
 type PreConfig struct {
 	Configuration *Configuration
 	SetView       uint32
 }
 
+//extended fields of Configuration are written after SetView, which keeps the encoding of
+//PreConfig stored before
 func (this *PreConfig) Serialization(sink *common.ZeroCopySink) {
-	this.Configuration.Serialization(sink)
+	this.Configuration.serializeBase(sink)
 	utils.EncodeVarUint(sink, uint64(this.SetView))
+	this.Configuration.serializeExt(sink)
 }
 
 func (this *PreConfig) Deserialization(source *common.ZeroCopySource) error {
 	config := new(Configuration)
-	err := config.Deserialization(source)
+	err := config.deserializeBase(source)
 	if err != nil {
 		return fmt.Errorf("utils.ReadVarUint, deserialize configuration error: %v", err)
 	}
@@ -456,6 +516,10 @@ func (this *PreConfig) Deserialization(source *common.ZeroCopySource) error {
 	if setView > math.MaxUint32 {
 		return fmt.Errorf("setView larger than max of uint32")
 	}
+	err = config.deserializeExt(source)
+	if err != nil {
+		return fmt.Errorf("deserialize configuration error: %v", err)
+	}
 	this.Configuration = config
 	this.SetView = uint32(setView)
 	return nil
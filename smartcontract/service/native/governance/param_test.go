@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2019 The TesraSupernet Authors
+ * This file is part of The TesraSupernet library.
+ *
+ * The TesraSupernet is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The TesraSupernet is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The TesraSupernet.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package governance
+
+import (
+	"testing"
+
+	"github.com/TesraSupernet/Tesra/common"
+	"github.com/TesraSupernet/Tesra/smartcontract/service/native/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreConfig_Serialize_Deserialize(t *testing.T) {
+	configuration := &Configuration{N: 7, C: 2, K: 7, L: 112, BlockMsgDelay: 10000, HashMsgDelay: 10000,
+		PeerHandshakeTimeout: 10, MaxBlockChangeView: 10000}
+	for _, blockTime := range []uint32{0, 5000} {
+		configuration.BlockTime = blockTime
+		configuration.MaxIdleTime = blockTime * 12
+		preConfig := &PreConfig{Configuration: configuration, SetView: 3}
+		sink := common.NewZeroCopySink(nil)
+		preConfig.Serialization(sink)
+		decoded := new(PreConfig)
+		assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+		assert.Equal(t, preConfig, decoded)
+
+		sink = common.NewZeroCopySink(nil)
+		configuration.Serialization(sink)
+		decodedConfig := new(Configuration)
+		assert.Nil(t, decodedConfig.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+		assert.Equal(t, configuration, decodedConfig)
+	}
+}
+
+func TestPreConfig_Deserialize_Legacy(t *testing.T) {
+	sink := common.NewZeroCopySink(nil)
+	for _, v := range []uint64{7, 2, 7, 112, 10000, 10000, 10, 10000, 3} {
+		utils.EncodeVarUint(sink, v)
+	}
+	legacy := sink.Bytes()
+
+	configuration := &Configuration{N: 7, C: 2, K: 7, L: 112, BlockMsgDelay: 10000, HashMsgDelay: 10000,
+		PeerHandshakeTimeout: 10, MaxBlockChangeView: 10000}
+	decoded := new(PreConfig)
+	assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(legacy)))
+	assert.Equal(t, &PreConfig{Configuration: configuration, SetView: 3}, decoded)
+
+	sink = common.NewZeroCopySink(nil)
+	decoded.Serialization(sink)
+	assert.Equal(t, legacy, sink.Bytes())
+
+	// trailing bytes without the version byte are ignored as before
+	decoded = new(PreConfig)
+	assert.Nil(t, decoded.Deserialization(common.NewZeroCopySource(append(legacy, 2, 1, 1))))
+	assert.Equal(t, &PreConfig{Configuration: configuration, SetView: 3}, decoded)
+
+	// truncated extended fields behind the version byte
+	decoded = new(PreConfig)
+	assert.NotNil(t, decoded.Deserialization(common.NewZeroCopySource(append(legacy, CONFIGURATION_EXT_VERSION, 0xfd))))
+}
@@ -309,6 +309,20 @@ func validatePeerPubKeyFormat(pubkey string) error {
 	return nil
 }
 
+//checkBlockTime check target block time and max idle time of empty blocks, 0 means not set
+func checkBlockTime(blockTime, maxIdleTime, blockMsgDelay uint32) error {
+	if blockTime != 0 && blockTime < 1000 {
+		return fmt.Errorf("BlockTime must >= 1000")
+	}
+	if maxIdleTime != 0 && maxIdleTime < 3*blockMsgDelay {
+		return fmt.Errorf("MaxIdleTime must >= 3*BlockMsgDelay")
+	}
+	if maxIdleTime != 0 && maxIdleTime < blockTime {
+		return fmt.Errorf("MaxIdleTime must >= BlockTime")
+	}
+	return nil
+}
+
 func CheckVBFTConfig(configuration *config.VBFTConfig) error {
 	if configuration.C == 0 {
 		return fmt.Errorf("initConfig. C can not be 0 in config")
@@ -337,6 +351,9 @@ func CheckVBFTConfig(configuration *config.VBFTConfig) error {
 	if configuration.MinInitStake < 10000 {
 		return fmt.Errorf("initConfig. MinInitStake must >= 10000")
 	}
+	if err := checkBlockTime(configuration.BlockTime, configuration.MaxIdleTime, configuration.BlockMsgDelay); err != nil {
+		return fmt.Errorf("initConfig. %s", err)
+	}
 	if len(configuration.VrfProof) < 128 {
 		return fmt.Errorf("initConfig. VrfProof must >= 128")
 	}
@@ -49,6 +49,8 @@ var (
 	HASH160_GAS                   uint64 = 20
 	HASH256_GAS                   uint64 = 20
 	OPCODE_GAS                    uint64 = 1
+	STORAGE_QUOTA                 uint64 = 0 // Max storage bytes per contract, 0 means unlimited.
+	STORAGE_BYTE_GAS              uint64 = 0 // Storage rent per byte added.
 
 	PER_UNIT_CODE_LEN    int = 1024
 	METHOD_LENGTH_LIMIT  int = 1024
@@ -124,6 +126,8 @@ var (
 	HASH256_NAME              = "HASH256"
 	UINT_DEPLOY_CODE_LEN_NAME = "Deploy.Code.Gas"
 	UINT_INVOKE_CODE_LEN_NAME = "Invoke.Code.Gas"
+	STORAGE_QUOTA_NAME        = "Storage.Quota"
+	STORAGE_BYTE_GAS_NAME     = "Storage.Byte.Gas"
 
 	GAS_TABLE = initGAS_TABLE()
 
@@ -148,6 +152,8 @@ var (
 		UINT_DEPLOY_CODE_LEN_NAME,
 		UINT_INVOKE_CODE_LEN_NAME,
 		config.WASM_GAS_FACTOR,
+		STORAGE_QUOTA_NAME,
+		STORAGE_BYTE_GAS_NAME,
 	}
 
 	INIT_GAS_TABLE = map[string]uint64{
@@ -204,5 +210,8 @@ func initGAS_TABLE() *sync.Map {
 
 	m.Store(config.WASM_GAS_FACTOR, config.DEFAULT_WASM_GAS_FACTOR)
 
+	m.Store(STORAGE_QUOTA_NAME, STORAGE_QUOTA)
+	m.Store(STORAGE_BYTE_GAS_NAME, STORAGE_BYTE_GAS)
+
 	return &m
 }
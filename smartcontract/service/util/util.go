@@ -36,7 +36,7 @@ func BuildNeoVMParamEvalStack(params []interface{}) (*neovm.ValueStack, error) {
 		return nil, err
 	}
 
-	exec := neovm.NewExecutor(builder.ToArray(), neovm.VmFeatureFlag{true, true})
+	exec := neovm.NewExecutor(builder.ToArray(), neovm.VmFeatureFlag{DisableHasKey: true, AllowReaderEOF: true})
 	err = exec.Execute()
 	if err != nil {
 		return nil, err
@@ -21,6 +21,7 @@ package storage
 import (
 	comm "github.com/TesraSupernet/Tesra/common"
 	"github.com/TesraSupernet/Tesra/core/payload"
+	"github.com/TesraSupernet/Tesra/core/states"
 	"github.com/TesraSupernet/Tesra/core/store/common"
 	"github.com/TesraSupernet/Tesra/core/store/overlaydb"
 	"github.com/syndtr/goleveldb/leveldb/util"
@@ -142,6 +143,73 @@ func (self *CacheDB) delete(prefix common.DataEntryPrefix, key []byte) {
 	self.memdb.Delete(self.keyScratch)
 }
 
+//StorageUsageDelta is the change of storage usage of a contract
+type StorageUsageDelta struct {
+	Bytes int64
+	Keys  int64
+}
+
+//AccumulateStorageUsage add the change of storage usage from old value to new value of storage key to deltas.
+//Empty value means the key not exist
+func AccumulateStorageUsage(deltas map[comm.Address]*StorageUsageDelta, key, oldValue, newValue []byte) {
+	if len(key) < 1+comm.ADDR_LEN || key[0] != byte(common.ST_STORAGE) {
+		return
+	}
+	var address comm.Address
+	copy(address[:], key[1:])
+	delta, ok := deltas[address]
+	if !ok {
+		delta = &StorageUsageDelta{}
+		deltas[address] = delta
+	}
+	if len(oldValue) != 0 {
+		delta.Bytes -= int64(len(key) - 1 + len(oldValue))
+		delta.Keys--
+	}
+	if len(newValue) != 0 {
+		delta.Bytes += int64(len(key) - 1 + len(newValue))
+		delta.Keys++
+	}
+}
+
+//StorageUsageDeltas return the changes of storage usage of contracts made in cache
+func (self *CacheDB) StorageUsageDeltas() (map[comm.Address]*StorageUsageDelta, error) {
+	deltas := make(map[comm.Address]*StorageUsageDelta)
+	var err error
+	self.memdb.ForEach(func(key, val []byte) {
+		if err != nil || key[0] != byte(common.ST_STORAGE) {
+			return
+		}
+		var old []byte
+		old, err = self.backend.Get(key)
+		AccumulateStorageUsage(deltas, key, old, val)
+	})
+	return deltas, err
+}
+
+//GetStorageUsage return the storage usage of contract at the end of previous block, and the changes of transactions
+//before in block if updated by them
+func (self *CacheDB) GetStorageUsage(address comm.Address) (*states.StorageUsage, error) {
+	value, err := self.get(common.IX_STORAGE_USAGE, address[:])
+	if err != nil {
+		return nil, err
+	}
+	usage := &states.StorageUsage{}
+	if len(value) == 0 {
+		return usage, nil
+	}
+	err = usage.Deserialization(comm.NewZeroCopySource(value))
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+//PutStorageUsage update the storage usage of contract
+func (self *CacheDB) PutStorageUsage(address comm.Address, usage *states.StorageUsage) {
+	self.put(common.IX_STORAGE_USAGE, address[:], comm.SerializeToBytes(usage))
+}
+
 func (self *CacheDB) NewIterator(key []byte) common.StoreIterator {
 	pkey := make([]byte, 1+len(key))
 	pkey[0] = byte(common.ST_STORAGE)
@@ -20,6 +20,7 @@
 package common
 
 import (
+	"encoding/binary"
 	"sort"
 	"sync"
 
@@ -48,7 +49,14 @@ type TXEntry struct {
 // in the ledger.
 type TXPool struct {
 	sync.RWMutex
-	txList map[common.Uint256]*TXEntry // Transactions which have been verified
+	txList   map[common.Uint256]*TXEntry // Transactions which have been verified
+	shortIDs map[uint64][]common.Uint256 // Index of the verified transactions by short id, ids may collide
+}
+
+// ShortTxID returns the short id of a transaction, by which the
+// transactions of a compact block are looked up.
+func ShortTxID(hash common.Uint256) uint64 {
+	return binary.LittleEndian.Uint64(hash[:8])
 }
 
 // Init creates a new transaction pool to gather.
@@ -56,6 +64,32 @@ func (tp *TXPool) Init() {
 	tp.Lock()
 	defer tp.Unlock()
 	tp.txList = make(map[common.Uint256]*TXEntry)
+	tp.shortIDs = make(map[uint64][]common.Uint256)
+}
+
+// addTx adds a transaction to the list and the short id index.
+func (tp *TXPool) addTx(txHash common.Uint256, txEntry *TXEntry) {
+	id := ShortTxID(txHash)
+	tp.txList[txHash] = txEntry
+	tp.shortIDs[id] = append(tp.shortIDs[id], txHash)
+}
+
+// delTx removes a transaction from the list and the short id index.
+func (tp *TXPool) delTx(txHash common.Uint256) {
+	delete(tp.txList, txHash)
+	id := ShortTxID(txHash)
+	hashes := tp.shortIDs[id]
+	for i, hash := range hashes {
+		if hash == txHash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			break
+		}
+	}
+	if len(hashes) == 0 {
+		delete(tp.shortIDs, id)
+	} else {
+		tp.shortIDs[id] = hashes
+	}
 }
 
 // AddTxList adds a valid transaction to the transaction pool. If the
@@ -72,7 +106,7 @@ func (tp *TXPool) AddTxList(txEntry *TXEntry) bool {
 		return false
 	}
 
-	tp.txList[txHash] = txEntry
+	tp.addTx(txHash, txEntry)
 	return true
 }
 
@@ -84,7 +118,7 @@ func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) error {
 	defer tp.Unlock()
 	for _, tx := range txs {
 		if _, ok := tp.txList[tx.Hash()]; ok {
-			delete(tp.txList, tx.Hash())
+			tp.delTx(tx.Hash())
 			cleaned++
 		}
 	}
@@ -102,7 +136,7 @@ func (tp *TXPool) DelTxList(tx *types.Transaction) bool {
 	if _, ok := tp.txList[txHash]; !ok {
 		return false
 	}
-	delete(tp.txList, txHash)
+	tp.delTx(txHash)
 	return true
 }
 
@@ -170,6 +204,21 @@ func (tp *TXPool) GetTransaction(hash common.Uint256) *types.Transaction {
 	return tp.txList[hash].Tx
 }
 
+// GetTransactionsByShortID returns the transactions of the short ids
+// contained in the pool, indexed by short id. All the transactions
+// whose short ids collide are returned.
+func (tp *TXPool) GetTransactionsByShortID(ids []uint64) map[uint64][]*types.Transaction {
+	tp.RLock()
+	defer tp.RUnlock()
+	txs := make(map[uint64][]*types.Transaction)
+	for _, id := range ids {
+		for _, txHash := range tp.shortIDs[id] {
+			txs[id] = append(txs[id], tp.txList[txHash].Tx)
+		}
+	}
+	return txs
+}
+
 // GetTxStatus returns a transaction status if it is contained in the pool
 // and nil otherwise.
 func (tp *TXPool) GetTxStatus(hash common.Uint256) *TxStatus {
@@ -186,6 +235,17 @@ func (tp *TXPool) GetTxStatus(hash common.Uint256) *TxStatus {
 	return ret
 }
 
+// GetTransactions returns all the txs in the pool.
+func (tp *TXPool) GetTransactions() []*types.Transaction {
+	tp.RLock()
+	defer tp.RUnlock()
+	txList := make([]*types.Transaction, 0, len(tp.txList))
+	for _, txEntry := range tp.txList {
+		txList = append(txList, txEntry.Tx)
+	}
+	return txList
+}
+
 // GetTransactionCount returns the tx number of the pool.
 func (tp *TXPool) GetTransactionCount() int {
 	tp.RLock()
@@ -214,7 +274,7 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 		}
 
 		if !tp.compareTxHeight(txEntry, height) {
-			delete(tp.txList, tx.Hash())
+			tp.delTx(tx.Hash())
 			res.OldTxs = append(res.OldTxs, txEntry.Tx)
 			continue
 		}
@@ -242,7 +302,7 @@ func (tp *TXPool) RemoveTxsBelowGasPrice(gasPrice uint64) {
 	defer tp.Unlock()
 	for _, txEntry := range tp.txList {
 		if txEntry.Tx.GasPrice < gasPrice {
-			delete(tp.txList, txEntry.Tx.Hash())
+			tp.delTx(txEntry.Tx.Hash())
 		}
 	}
 }
@@ -255,7 +315,7 @@ func (tp *TXPool) Remain() []*types.Transaction {
 	txList := make([]*types.Transaction, 0, len(tp.txList))
 	for _, txEntry := range tp.txList {
 		txList = append(txList, txEntry.Tx)
-		delete(tp.txList, txEntry.Tx.Hash())
+		tp.delTx(txEntry.Tx.Hash())
 	}
 
 	return txList
@@ -99,3 +99,57 @@ func TestTxPool(t *testing.T) {
 		return
 	}
 }
+
+func TestTxPoolShortID(t *testing.T) {
+	txPool := &TXPool{}
+	txPool.Init()
+
+	txEntry := &TXEntry{
+		Tx:    txn,
+		Attrs: []*TXAttr{},
+	}
+	assert.True(t, txPool.AddTxList(txEntry))
+
+	id := ShortTxID(txn.Hash())
+	txs := txPool.GetTransactionsByShortID([]uint64{id, id + 1})
+	assert.Equal(t, map[uint64][]*types.Transaction{id: {txn}}, txs)
+
+	assert.True(t, txPool.DelTxList(txn))
+	txs = txPool.GetTransactionsByShortID([]uint64{id})
+	assert.Equal(t, 0, len(txs))
+}
+
+func TestTxPoolShortIDCollision(t *testing.T) {
+	txPool := &TXPool{}
+	txPool.Init()
+
+	mutable := &types.MutableTransaction{
+		TxType:  types.InvokeNeo,
+		Nonce:   txn.Nonce + 1,
+		Payload: &payload.InvokeCode{Code: []byte{}},
+	}
+	other, err := mutable.IntoImmutable()
+	assert.Nil(t, err)
+
+	//the hash of other tx is forged to collide with txn
+	hash := txn.Hash()
+	collided := other.Hash()
+	copy(collided[:8], hash[:8])
+	txPool.Lock()
+	txPool.addTx(hash, &TXEntry{Tx: txn})
+	txPool.addTx(collided, &TXEntry{Tx: other})
+	txPool.Unlock()
+
+	id := ShortTxID(hash)
+	txs := txPool.GetTransactionsByShortID([]uint64{id})
+	assert.Equal(t, map[uint64][]*types.Transaction{id: {txn, other}}, txs)
+
+	assert.True(t, txPool.DelTxList(txn))
+	txs = txPool.GetTransactionsByShortID([]uint64{id})
+	assert.Equal(t, map[uint64][]*types.Transaction{id: {other}}, txs)
+
+	txPool.Lock()
+	txPool.delTx(collided)
+	txPool.Unlock()
+	assert.Equal(t, 0, len(txPool.GetTransactionsByShortID([]uint64{id})))
+}
@@ -149,6 +149,20 @@ type CheckTxnRsp struct {
 	Ok bool
 }
 
+// GetTxnsByShortIDReq specifies the api that how to get the verified
+// transactions of a compact block.
+// Input: short ids of the transactions.
+type GetTxnsByShortIDReq struct {
+	ShortIDs []uint64
+}
+
+// GetTxnsByShortIDRsp returns the transactions found for
+// GetTxnsByShortIDReq, indexed by short id. A short id maps to
+// several transactions if they collide.
+type GetTxnsByShortIDRsp struct {
+	Txs map[uint64][]*types.Transaction
+}
+
 // GetTxnStatusReq specifies the api that how to get a transaction
 // status.
 // Input: a transaction hash.
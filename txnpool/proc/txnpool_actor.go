@@ -230,6 +230,17 @@ func (ta *TxActor) Receive(context actor.Context) {
 				context.Self())
 		}
 
+	case *tc.GetTxnsByShortIDReq:
+		sender := context.Sender()
+
+		log.Debugf("txpool-tx actor receives getting txs by short id req from %v", sender)
+
+		res := ta.server.getTxsByShortID(msg.ShortIDs)
+		if sender != nil {
+			sender.Request(&tc.GetTxnsByShortIDRsp{Txs: res},
+				context.Self())
+		}
+
 	case *tc.GetTxnStats:
 		sender := context.Sender()
 
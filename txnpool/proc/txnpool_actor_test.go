@@ -115,6 +115,12 @@ func TestTxActor(t *testing.T) {
 	result, err = future.Result()
 	assert.Nil(t, err)
 
+	id := tc.ShortTxID(txn.Hash())
+	future = txPid.RequestFuture(&tc.GetTxnsByShortIDReq{ShortIDs: []uint64{id, id + 1}}, 1*time.Second)
+	result, err = future.Result()
+	assert.Nil(t, err)
+	assert.Equal(t, map[uint64][]*types.Transaction{id: {txn}}, result.(*tc.GetTxnsByShortIDRsp).Txs)
+
 	future = txPid.RequestFuture(&tc.GetTxnStats{}, 2*time.Second)
 	result, err = future.Result()
 	assert.Nil(t, err)
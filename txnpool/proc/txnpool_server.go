@@ -524,6 +524,11 @@ func (s *TXPoolServer) getPendingTxs(byCount bool) []*tx.Transaction {
 	return ret
 }
 
+// getTxsByShortID returns the verified txs of the short ids
+func (s *TXPoolServer) getTxsByShortID(ids []uint64) map[uint64][]*tx.Transaction {
+	return s.txPool.GetTransactionsByShortID(ids)
+}
+
 // cleanTransactionList cleans the txs in the block from the ledger
 func (s *TXPoolServer) cleanTransactionList(txs []*tx.Transaction, height uint32) {
 	s.txPool.CleanTransactionList(txs)
@@ -23,6 +23,7 @@ import (
 	"github.com/TesraSupernet/Tesra/common/log"
 	"github.com/TesraSupernet/Tesra/core/ledger"
 	"github.com/TesraSupernet/Tesra/core/types"
+	"github.com/TesraSupernet/Tesra/core/vote"
 	"github.com/TesraSupernet/Tesra/errors"
 	"github.com/TesraSupernet/Tesra/validator/db"
 	vatypes "github.com/TesraSupernet/Tesra/validator/types"
@@ -77,6 +78,8 @@ func (self *validator) Receive(context actor.Context) {
 			errCode = errors.ErrUnknown
 		} else if exist {
 			errCode = errors.ErrDuplicatedTx
+		} else if msg.Tx.TxType == types.Bookkeeper {
+			errCode = verifyBookkeeperTx(msg.Tx, height+1)
 		}
 
 		response := &vatypes.CheckResponse{
@@ -105,6 +108,19 @@ func (self *validator) Receive(context actor.Context) {
 
 }
 
+func verifyBookkeeperTx(tx *types.Transaction, height uint32) errors.ErrCode {
+	state, err := ledger.DefLedger.GetBookkeeperState()
+	if err != nil {
+		log.Warn("query bookkeeper state error:", err)
+		return errors.ErrUnknown
+	}
+	if err := vote.VerifyBookkeeperTx(state, height, tx); err != nil {
+		log.Debugf("stateful-validator: invalid bookkeeper tx %x: %s", tx.Hash(), err)
+		return errors.ErrTransactionPayload
+	}
+	return errors.ErrNoError
+}
+
 func (self *validator) VerifyType() vatypes.VerifyType {
 	return vatypes.Stateful
 }